@@ -0,0 +1,358 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"asterisk-monitor/storage"
+	"asterisk-monitor/types"
+)
+
+// Состояния оповещения
+const (
+	StatePending  = "pending"
+	StateFiring   = "firing"
+	StateResolved = "resolved"
+)
+
+// maxHistory - сколько снятых оповещений хранится в памяти
+const maxHistory = 100
+
+// Alert - оповещение по одному ряду метрики
+type Alert struct {
+	Rule       string            `json:"rule"`
+	Metric     string            `json:"metric"`
+	Severity   string            `json:"severity"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	State      string            `json:"state"`
+	Value      float64           `json:"value"`
+	Threshold  float64           `json:"threshold"`
+	Message    string            `json:"message"`
+	StartedAt  time.Time         `json:"started_at"`
+	LastSeen   time.Time         `json:"last_seen"`
+	FiredAt    time.Time         `json:"fired_at,omitempty"`
+	ResolvedAt time.Time         `json:"resolved_at,omitempty"`
+
+	Acknowledged bool      `json:"acknowledged,omitempty"`
+	AckNote      string    `json:"ack_note,omitempty"`
+	AckAt        time.Time `json:"ack_at,omitempty"`
+	Silenced     bool      `json:"silenced,omitempty"`
+}
+
+// Key возвращает идентификатор оповещения: правило и ряд метрики
+func (a Alert) Key() string {
+	return a.Rule + "/" + storage.SeriesKey(a.Metric, a.Labels)
+}
+
+type compiledRule struct {
+	rule     types.AlertRule
+	selector map[string]string
+}
+
+// Engine вычисляет правила оповещений по измерениям и хранит их состояние
+type Engine struct {
+	mu       sync.RWMutex
+	enabled  bool
+	rules    []compiledRule
+	labels   map[string]string // метки, добавляемые ко всем измерениям
+	active   map[string]*Alert
+	history  []Alert
+	silences []Silence
+	acks     map[string]Ack // подтверждения по ключу оповещения
+	journal  *Journal
+
+	stateMod   time.Time // время изменения файла состояния при последнем чтении
+	journalMod time.Time // время изменения истории при последнем чтении
+}
+
+// NewEngine создает движок оповещений. Ко всем измерениям добавляется
+// метка server с именем хоста, чтобы правила можно было ограничить сервером.
+func NewEngine(rules []types.AlertRule, enabled bool) (*Engine, error) {
+	hostname, _ := os.Hostname()
+
+	e := &Engine{
+		labels: map[string]string{"server": hostname},
+		active: make(map[string]*Alert),
+	}
+	return e, e.Configure(rules, enabled)
+}
+
+// Configure заменяет набор правил. Некорректные правила пропускаются,
+// ошибки по ним возвращаются вместе. Оповещения удаленных правил снимаются.
+func (e *Engine) Configure(rules []types.AlertRule, enabled bool) error {
+	var errs []error
+	var compiled []compiledRule
+	names := make(map[string]bool)
+
+	for _, rule := range rules {
+		if err := Validate(rule); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		names[rule.Name] = true
+		compiled = append(compiled, compiledRule{rule: rule, selector: ParseLabels(rule.Labels)})
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.enabled = enabled
+	e.rules = compiled
+	for key, alert := range e.active {
+		if !enabled || !names[alert.Rule] {
+			delete(e.active, key)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Enabled сообщает, включены ли оповещения
+func (e *Engine) Enabled() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.enabled
+}
+
+// Evaluate применяет правила к измерениям и возвращает оповещения,
+// которые сработали или были сняты в этом цикле. unknown - метрики, источник
+// которых не ответил: их оповещения остаются в прежнем состоянии.
+func (e *Engine) Evaluate(samples []types.Sample, now time.Time, unknown ...string) []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.enabled {
+		return nil
+	}
+	// Тишины и подтверждения могли измениться в другом процессе
+	_ = e.syncStateLocked(now)
+
+	var changed []Alert
+	seen := make(map[string]bool)
+	held := make(map[string]bool, len(unknown))
+	for _, metric := range unknown {
+		held[metric] = true
+	}
+
+	for _, cr := range e.rules {
+		for _, sample := range samples {
+			if sample.Metric != cr.rule.Metric || held[sample.Metric] {
+				continue
+			}
+
+			labels := e.withGlobalLabels(sample.Labels)
+			if !matchLabels(cr.selector, labels) {
+				continue
+			}
+
+			alert := Alert{Rule: cr.rule.Name, Metric: sample.Metric, Labels: sample.Labels}
+			key := alert.Key()
+			seen[key] = true
+
+			if a := e.active[key]; a != nil {
+				if transition := e.update(cr.rule, a, sample, now); transition {
+					changed = e.record(changed, *a, now)
+				}
+				if a.State == StateResolved {
+					delete(e.active, key)
+				}
+				continue
+			}
+
+			if !Breached(cr.rule.Op, sample.Value, cr.rule.Threshold) {
+				continue
+			}
+
+			alert.Severity = cr.rule.Severity
+			alert.Threshold = cr.rule.Threshold
+			alert.State = StatePending
+			alert.StartedAt = now
+			e.applyAckLocked(&alert)
+			e.active[key] = &alert
+			if transition := e.update(cr.rule, &alert, sample, now); transition {
+				changed = e.record(changed, alert, now)
+			}
+		}
+	}
+
+	// Ряды, пропавшие из измерений (например, удаленный пир), снимаются.
+	// Ряды неизвестных метрик не пропали: их источник не ответил.
+	for key, a := range e.active {
+		if seen[key] || held[a.Metric] {
+			continue
+		}
+		if a.State == StateFiring {
+			e.resolve(a, now)
+			changed = e.record(changed, *a, now)
+		}
+		delete(e.active, key)
+	}
+
+	// Подтверждения снятых оповещений удаляет только ведущий процесс, у второго
+	// набор активных оповещений может отличаться
+	if e.leaderLocked() {
+		if e.pruneAcksLocked() {
+			_ = e.saveStateLocked(now)
+		}
+	} else if e.journal != nil {
+		e.refreshHistoryLocked(now)
+	}
+
+	return changed
+}
+
+// record записывает переход в журнал и добавляет его в список изменений,
+// если оповещение не попадает под тишину
+func (e *Engine) record(changed []Alert, a Alert, now time.Time) []Alert {
+	e.writeJournal(Event{Time: now, Type: a.State, Alert: a})
+	if a.Silenced {
+		return changed
+	}
+	return append(changed, a)
+}
+
+// update обновляет состояние оповещения и сообщает о переходе firing/resolved
+func (e *Engine) update(rule types.AlertRule, a *Alert, sample types.Sample, now time.Time) bool {
+	value := sample.Value
+	a.Value = value
+	a.LastSeen = now
+	a.Message = describe(rule, a, sample.Note)
+	a.Silenced = e.silencedLocked(*a, now)
+
+	switch a.State {
+	case StatePending:
+		if !Breached(rule.Op, value, rule.Threshold) {
+			a.State = StateResolved
+			return false
+		}
+		if now.Sub(a.StartedAt) >= time.Duration(rule.For)*time.Second {
+			a.State = StateFiring
+			a.FiredAt = now
+			return true
+		}
+	case StateFiring:
+		if Recovered(rule, value) {
+			e.resolve(a, now)
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Engine) resolve(a *Alert, now time.Time) {
+	a.State = StateResolved
+	a.ResolvedAt = now
+
+	e.history = append([]Alert{*a}, e.history...)
+	if len(e.history) > maxHistory {
+		e.history = e.history[:maxHistory]
+	}
+}
+
+func (e *Engine) withGlobalLabels(labels map[string]string) map[string]string {
+	merged := make(map[string]string, len(labels)+len(e.labels))
+	for key, value := range e.labels {
+		merged[key] = value
+	}
+	for key, value := range labels {
+		merged[key] = value
+	}
+	return merged
+}
+
+// Active возвращает сработавшие и ожидающие оповещения: сначала сработавшие,
+// затем критические, затем более новые
+func (e *Engine) Active() []Alert {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	alerts := make([]Alert, 0, len(e.active))
+	for _, a := range e.active {
+		alerts = append(alerts, *a)
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].State != alerts[j].State {
+			return alerts[i].State == StateFiring
+		}
+		if alerts[i].Severity != alerts[j].Severity {
+			return alerts[i].Severity == SeverityCritical
+		}
+		return alerts[i].StartedAt.After(alerts[j].StartedAt)
+	})
+	return alerts
+}
+
+// Firing возвращает только сработавшие оповещения
+func (e *Engine) Firing() []Alert {
+	var firing []Alert
+	for _, a := range e.Active() {
+		if a.State == StateFiring {
+			firing = append(firing, a)
+		}
+	}
+	return firing
+}
+
+// History возвращает недавно снятые оповещения, новые первыми
+func (e *Engine) History() []Alert {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return append([]Alert(nil), e.history...)
+}
+
+// Run периодически собирает измерения и вычисляет правила до отмены контекста.
+// collect возвращает измерения и неизвестные метрики (см. Evaluate). onChange
+// вызывается после каждого цикла, в котором изменилось состояние оповещений.
+func (e *Engine) Run(ctx context.Context, interval time.Duration, collect func() ([]types.Sample, []string), onChange func([]Alert)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if e.Enabled() {
+			samples, unknown := collect()
+			if changed := e.Evaluate(samples, time.Now(), unknown...); len(changed) > 0 && onChange != nil {
+				onChange(changed)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// describe формирует текст оповещения, note из измерения добавляется в конец
+func describe(rule types.AlertRule, a *Alert, note string) string {
+	var labels []string
+	for _, key := range sortedLabelKeys(a.Labels) {
+		labels = append(labels, key+"="+a.Labels[key])
+	}
+
+	subject := rule.Metric
+	if len(labels) > 0 {
+		subject += "{" + strings.Join(labels, ",") + "}"
+	}
+	message := fmt.Sprintf("%s: %s = %g (%s %g)", rule.Name, subject, a.Value, rule.Op, rule.Threshold)
+	if note != "" {
+		message += "; " + note
+	}
+	return message
+}
+
+func sortedLabelKeys(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,278 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+var testStart = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+func newTestEngine(t *testing.T, rules ...types.AlertRule) *Engine {
+	t.Helper()
+	e, err := NewEngine(rules, true)
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	return e
+}
+
+func cpuRule(forSeconds int, hysteresis float64) types.AlertRule {
+	return types.AlertRule{
+		Name: "cpu", Metric: types.MetricCPUUsage, Op: ">", Threshold: 90,
+		For: forSeconds, Hysteresis: hysteresis, Severity: SeverityWarning,
+	}
+}
+
+func cpuSamples(value float64) []types.Sample {
+	return []types.Sample{{Metric: types.MetricCPUUsage, Value: value}}
+}
+
+// activeState возвращает состояние единственного активного оповещения или ""
+func activeState(t *testing.T, e *Engine) string {
+	t.Helper()
+	active := e.Active()
+	switch len(active) {
+	case 0:
+		return ""
+	case 1:
+		return active[0].State
+	}
+	t.Fatalf("%d active alerts, want at most one", len(active))
+	return ""
+}
+
+func changedStates(changed []Alert) string {
+	var states string
+	for _, a := range changed {
+		if states != "" {
+			states += ","
+		}
+		states += a.State
+	}
+	return states
+}
+
+func TestEvaluate(t *testing.T) {
+	// Шаг цикла: значение метрики, пропавший ряд или неизвестная метрика
+	type step struct {
+		at      int // секунды от начала
+		value   float64
+		missing bool
+		unknown bool
+
+		changed string // переходы, о которых сообщил Evaluate
+		state   string // состояние активного оповещения после шага
+	}
+	tests := []struct {
+		name  string
+		rule  types.AlertRule
+		steps []step
+	}{
+		{
+			name: "fires without for",
+			rule: cpuRule(0, 0),
+			steps: []step{
+				{at: 0, value: 50},
+				{at: 10, value: 95, changed: StateFiring, state: StateFiring},
+				{at: 20, value: 96, state: StateFiring},
+				{at: 30, value: 50, changed: StateResolved},
+			},
+		},
+		{
+			name: "for delays firing",
+			rule: cpuRule(60, 0),
+			steps: []step{
+				{at: 0, value: 95, state: StatePending},
+				{at: 30, value: 95, state: StatePending},
+				{at: 60, value: 95, changed: StateFiring, state: StateFiring},
+			},
+		},
+		{
+			name: "pending recovers silently",
+			rule: cpuRule(60, 0),
+			steps: []step{
+				{at: 0, value: 95, state: StatePending},
+				{at: 30, value: 80},
+				// Новое нарушение отсчитывает for заново
+				{at: 40, value: 95, state: StatePending},
+				{at: 90, value: 95, state: StatePending},
+				{at: 100, value: 95, changed: StateFiring, state: StateFiring},
+			},
+		},
+		{
+			name: "hysteresis on resolve",
+			rule: cpuRule(0, 10),
+			steps: []step{
+				{at: 0, value: 95, changed: StateFiring, state: StateFiring},
+				{at: 10, value: 85, state: StateFiring},
+				{at: 20, value: 80.5, state: StateFiring},
+				{at: 30, value: 80, changed: StateResolved},
+			},
+		},
+		{
+			name: "disappeared series resolves",
+			rule: cpuRule(0, 0),
+			steps: []step{
+				{at: 0, value: 95, changed: StateFiring, state: StateFiring},
+				{at: 10, missing: true, changed: StateResolved},
+			},
+		},
+		{
+			name: "disappeared pending series is dropped",
+			rule: cpuRule(60, 0),
+			steps: []step{
+				{at: 0, value: 95, state: StatePending},
+				{at: 10, missing: true},
+			},
+		},
+		{
+			name: "unknown metric holds firing alert",
+			rule: cpuRule(0, 0),
+			steps: []step{
+				{at: 0, value: 95, changed: StateFiring, state: StateFiring},
+				{at: 10, missing: true, unknown: true, state: StateFiring},
+				// Значение из непрочитанного источника не снимает оповещение
+				{at: 20, value: 10, unknown: true, state: StateFiring},
+				{at: 30, value: 95, state: StateFiring},
+				{at: 40, value: 50, changed: StateResolved},
+			},
+		},
+		{
+			name: "unknown metric keeps pending start",
+			rule: cpuRule(60, 0),
+			steps: []step{
+				{at: 0, value: 95, state: StatePending},
+				{at: 30, missing: true, unknown: true, state: StatePending},
+				{at: 60, value: 95, changed: StateFiring, state: StateFiring},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		e := newTestEngine(t, tt.rule)
+		for _, st := range tt.steps {
+			var samples []types.Sample
+			if !st.missing {
+				samples = cpuSamples(st.value)
+			}
+			var unknown []string
+			if st.unknown {
+				unknown = []string{types.MetricCPUUsage}
+			}
+
+			changed := e.Evaluate(samples, testStart.Add(time.Duration(st.at)*time.Second), unknown...)
+			if got := changedStates(changed); got != st.changed {
+				t.Errorf("%s, %ds: changed %q, want %q", tt.name, st.at, got, st.changed)
+			}
+			if got := activeState(t, e); got != st.state {
+				t.Errorf("%s, %ds: state %q, want %q", tt.name, st.at, got, st.state)
+			}
+		}
+	}
+}
+
+func TestEvaluateSeriesByLabels(t *testing.T) {
+	rule := types.AlertRule{Name: "peer_down", Metric: types.MetricPeerUp, Op: "==", Threshold: 0, Severity: SeverityCritical, Labels: "peer=trunk1"}
+	e := newTestEngine(t, rule)
+
+	samples := []types.Sample{
+		{Metric: types.MetricPeerUp, Value: 0, Labels: map[string]string{"peer": "trunk1"}},
+		{Metric: types.MetricPeerUp, Value: 0, Labels: map[string]string{"peer": "100"}},
+	}
+	changed := e.Evaluate(samples, testStart)
+	if len(changed) != 1 || changed[0].Labels["peer"] != "trunk1" {
+		t.Fatalf("changed = %+v, want only trunk1 firing", changed)
+	}
+	if changed[0].Key() != "peer_down/"+changed[0].Metric+"{peer=trunk1}" {
+		t.Errorf("key = %s", changed[0].Key())
+	}
+}
+
+func TestEvaluateSilence(t *testing.T) {
+	e := newTestEngine(t, cpuRule(0, 0))
+
+	silence, err := e.AddSilence(map[string]string{"rule": "cpu"}, 10*time.Minute, "maintenance", testStart)
+	if err != nil {
+		t.Fatalf("AddSilence: %v", err)
+	}
+
+	// Переход под тишиной не передается в уведомления, но оповещение активно
+	if changed := e.Evaluate(cpuSamples(95), testStart); len(changed) != 0 {
+		t.Errorf("silenced alert reported: %+v", changed)
+	}
+	active := e.Active()
+	if len(active) != 1 || active[0].State != StateFiring || !active[0].Silenced {
+		t.Fatalf("active = %+v, want a silenced firing alert", active)
+	}
+	if got, ok := e.SilenceFor(active[0], testStart); !ok || got.ID != silence.ID {
+		t.Errorf("SilenceFor = %+v, %v", got, ok)
+	}
+
+	// После окончания тишины переходы снова передаются
+	later := testStart.Add(11 * time.Minute)
+	if changed := e.Evaluate(cpuSamples(95), later); len(changed) != 0 {
+		t.Errorf("no transition, but changed = %+v", changed)
+	}
+	if active := e.Active(); len(active) != 1 || active[0].Silenced {
+		t.Errorf("active = %+v, want the silence expired", active)
+	}
+	if changed := e.Evaluate(cpuSamples(50), later.Add(time.Minute)); changedStates(changed) != StateResolved {
+		t.Errorf("changed = %+v, want resolved", changed)
+	}
+
+	// Досрочно снятая тишина больше не действует
+	silence, _ = e.AddSilence(map[string]string{"severity": SeverityWarning}, time.Hour, "", later)
+	if !e.RemoveSilence(silence.ID, later) {
+		t.Fatal("RemoveSilence: silence not found")
+	}
+	if changed := e.Evaluate(cpuSamples(95), later.Add(2*time.Minute)); changedStates(changed) != StateFiring {
+		t.Errorf("changed = %+v, want firing after the silence was removed", changed)
+	}
+}
+
+func TestAcknowledge(t *testing.T) {
+	e := newTestEngine(t, cpuRule(0, 0))
+
+	changed := e.Evaluate(cpuSamples(95), testStart)
+	if len(changed) != 1 {
+		t.Fatalf("changed = %+v, want one firing alert", changed)
+	}
+	key := changed[0].Key()
+
+	if err := e.Acknowledge("cpu/missing", "", testStart); err == nil {
+		t.Error("no error for an inactive alert")
+	}
+	if err := e.Acknowledge(key, "looking", testStart.Add(time.Minute)); err != nil {
+		t.Fatalf("Acknowledge: %v", err)
+	}
+	// Подтверждение не скрывает оповещение и держится, пока оно активно
+	e.Evaluate(cpuSamples(96), testStart.Add(2*time.Minute))
+	firing := e.Firing()
+	if len(firing) != 1 || !firing[0].Acknowledged || firing[0].AckNote != "looking" {
+		t.Fatalf("firing = %+v, want the acknowledged alert", firing)
+	}
+
+	// Новое срабатывание после снятия не наследует подтверждение
+	e.Evaluate(cpuSamples(50), testStart.Add(3*time.Minute))
+	changed = e.Evaluate(cpuSamples(95), testStart.Add(4*time.Minute))
+	if len(changed) != 1 || changed[0].Acknowledged {
+		t.Errorf("changed = %+v, want a new unacknowledged alert", changed)
+	}
+	if history := e.History(); len(history) != 1 || history[0].State != StateResolved {
+		t.Errorf("history = %+v, want one resolved alert", history)
+	}
+}
+
+func TestConfigureDropsRemovedRules(t *testing.T) {
+	e := newTestEngine(t, cpuRule(0, 0))
+	e.Evaluate(cpuSamples(95), testStart)
+
+	if err := e.Configure([]types.AlertRule{{Name: "bad"}}, true); err == nil {
+		t.Error("no error for an invalid rule")
+	}
+	if active := e.Active(); len(active) != 0 {
+		t.Errorf("active = %+v, want alerts of the removed rule dropped", active)
+	}
+}
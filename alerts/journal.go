@@ -0,0 +1,212 @@
+package alerts
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"asterisk-monitor/storage"
+)
+
+// Типы событий журнала, кроме переходов состояния firing/resolved
+const (
+	EventAcknowledged = "acknowledged"
+	EventSilenced     = "silenced"
+	EventUnsilenced   = "unsilenced"
+)
+
+// Event - запись журнала оповещений
+type Event struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Alert   Alert     `json:"alert,omitempty"`
+	Silence *Silence  `json:"silence,omitempty"`
+	Note    string    `json:"note,omitempty"`
+}
+
+// maxJournalSize - размер журнала, после которого он переименовывается в .1.
+// Хранится одно предыдущее поколение, его хватает на неделю истории.
+const maxJournalSize = 5 * 1024 * 1024
+
+// Journal - файл истории оповещений в формате JSON lines и файл состояния с
+// тишинами и подтверждениями. Историю ведет один процесс, получивший блокировку
+// писателя; состояние меняют и интерфейс, и фоновый режим.
+type Journal struct {
+	mu        sync.Mutex
+	path      string
+	statePath string
+	lock      *storage.WriterLock
+}
+
+// OpenJournal открывает файл истории path и файл состояния statePath,
+// создавая каталоги при необходимости
+func OpenJournal(path, statePath string) (*Journal, error) {
+	for _, dir := range []string{filepath.Dir(path), filepath.Dir(statePath)} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	return &Journal{path: path, statePath: statePath, lock: storage.NewWriterLock(path + ".lock")}, nil
+}
+
+// Path возвращает путь к файлу истории
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// Writer сообщает, что этот процесс ведет историю. Если писатель завершился,
+// блокировку получает процесс, который вызовет Writer следующим.
+func (j *Journal) Writer() bool {
+	return j.lock.Acquire()
+}
+
+// Append дописывает событие в конец файла. Файл больше maxJournalSize
+// переименовывается в .1, прошлое поколение удаляется.
+func (j *Journal) Append(event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if info, err := os.Stat(j.path); err == nil && info.Size() >= maxJournalSize {
+		if err := os.Rename(j.path, j.path+".1"); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// ModTime возвращает время последней записи в историю
+func (j *Journal) ModTime() time.Time {
+	info, err := os.Stat(j.path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// Events возвращает события новее since, старые первыми, включая предыдущее
+// поколение журнала. Поврежденные строки пропускаются.
+func (j *Journal) Events(since time.Time) ([]Event, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var events []Event
+	for _, path := range []string{j.path + ".1", j.path} {
+		read, err := readEvents(path, since)
+		if err != nil {
+			return events, err
+		}
+		events = append(events, read...)
+	}
+	return events, nil
+}
+
+func readEvents(path string, since time.Time) ([]Event, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		if event.Time.Before(since) {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+// UseJournal подключает журнал: снятые оповещения за последнюю неделю
+// загружаются в историю, тишины и подтверждения - из файла состояния
+func (e *Engine) UseJournal(j *Journal, now time.Time) error {
+	events, err := j.Events(now.AddDate(0, 0, -7))
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.journal = j
+	e.journalMod = j.ModTime()
+	e.loadHistoryLocked(events)
+	if stateErr := e.syncStateLocked(now); stateErr != nil && err == nil {
+		err = stateErr
+	}
+	return err
+}
+
+func (e *Engine) loadHistoryLocked(events []Event) {
+	e.history = nil
+	for i := len(events) - 1; i >= 0 && len(e.history) < maxHistory; i-- {
+		if events[i].Type == StateResolved {
+			e.history = append(e.history, events[i].Alert)
+		}
+	}
+}
+
+// refreshHistoryLocked перечитывает историю, которую ведет другой процесс
+func (e *Engine) refreshHistoryLocked(now time.Time) {
+	mod := e.journal.ModTime()
+	if mod.Equal(e.journalMod) {
+		return
+	}
+	events, err := e.journal.Events(now.AddDate(0, 0, -7))
+	if err != nil {
+		return
+	}
+	e.journalMod = mod
+	e.loadHistoryLocked(events)
+}
+
+// Journal возвращает подключенный файл истории или nil
+func (e *Engine) Journal() *Journal {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.journal
+}
+
+// Leader сообщает, что этот процесс ведет историю оповещений и отправляет
+// уведомления. Интерфейс и фоновый режим вычисляют оповещения независимо,
+// ведущим становится тот, кто первым получил блокировку журнала.
+func (e *Engine) Leader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leaderLocked()
+}
+
+func (e *Engine) leaderLocked() bool {
+	return e.journal == nil || e.journal.Writer()
+}
+
+func (e *Engine) writeJournal(event Event) {
+	if e.journal == nil || !e.journal.Writer() {
+		return
+	}
+	// Ошибка записи истории не должна мешать вычислению оповещений
+	_ = e.journal.Append(event)
+	e.journalMod = e.journal.ModTime()
+}
@@ -0,0 +1,100 @@
+package alerts
+
+import (
+	"fmt"
+	"strings"
+
+	"asterisk-monitor/types"
+)
+
+// Уровни важности оповещений
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Validate проверяет правило оповещения
+func Validate(rule types.AlertRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("alert rule without name")
+	}
+	if rule.Metric == "" {
+		return fmt.Errorf("alert rule %q: metric is required", rule.Name)
+	}
+	switch rule.Op {
+	case ">", ">=", "<", "<=", "==", "!=":
+	default:
+		return fmt.Errorf("alert rule %q: unsupported comparator %q", rule.Name, rule.Op)
+	}
+	switch rule.Severity {
+	case SeverityWarning, SeverityCritical:
+	default:
+		return fmt.Errorf("alert rule %q: unsupported severity %q", rule.Name, rule.Severity)
+	}
+	if rule.For < 0 || rule.Hysteresis < 0 {
+		return fmt.Errorf("alert rule %q: for and hysteresis must not be negative", rule.Name)
+	}
+	return nil
+}
+
+// Breached сообщает, нарушает ли значение порог правила
+func Breached(op string, value, threshold float64) bool {
+	switch op {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	case "==":
+		return value == threshold
+	case "!=":
+		return value != threshold
+	}
+	return false
+}
+
+// Recovered сообщает, вернулось ли значение за порог с учетом гистерезиса
+func Recovered(rule types.AlertRule, value float64) bool {
+	threshold := rule.Threshold
+	switch rule.Op {
+	case ">", ">=":
+		threshold -= rule.Hysteresis
+	case "<", "<=":
+		threshold += rule.Hysteresis
+	}
+	return !Breached(rule.Op, value, threshold)
+}
+
+// ParseLabels разбирает селектор меток вида "peer=trunk1,server=pbx1"
+func ParseLabels(spec string) map[string]string {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			continue
+		}
+		labels[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return labels
+}
+
+// matchLabels сообщает, содержит ли набор меток все метки селектора
+func matchLabels(selector, labels map[string]string) bool {
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// StatusFor возвращает статус CheckResult для уровня важности
+func StatusFor(severity string) string {
+	if severity == SeverityCritical {
+		return "error"
+	}
+	return "warning"
+}
@@ -0,0 +1,170 @@
+package alerts
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Silence - временная тишина для оповещений, подходящих под селектор меток.
+// Кроме меток измерения селектор может использовать rule, metric и severity.
+type Silence struct {
+	ID        string            `json:"id"`
+	Matchers  map[string]string `json:"matchers"`
+	Comment   string            `json:"comment,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	Until     time.Time         `json:"until"`
+}
+
+// Matches сообщает, подходит ли оповещение под тишину
+func (s Silence) Matches(labels map[string]string) bool {
+	return len(s.Matchers) > 0 && matchLabels(s.Matchers, labels)
+}
+
+// alertLabels возвращает метки оповещения вместе с служебными rule, metric, severity
+func (e *Engine) alertLabels(a Alert) map[string]string {
+	labels := e.withGlobalLabels(a.Labels)
+	labels["rule"] = a.Rule
+	labels["metric"] = a.Metric
+	labels["severity"] = a.Severity
+	return labels
+}
+
+// MatchersFor возвращает селектор, который подходит ровно под это оповещение
+func (e *Engine) MatchersFor(a Alert) map[string]string {
+	matchers := map[string]string{"rule": a.Rule}
+	for key, value := range a.Labels {
+		matchers[key] = value
+	}
+	return matchers
+}
+
+// AddSilence добавляет тишину на duration и возвращает ее. Тишина сохраняется
+// в файле состояния, поэтому действует и в другом процессе, и после перезапуска.
+func (e *Engine) AddSilence(matchers map[string]string, duration time.Duration, comment string, now time.Time) (Silence, error) {
+	if len(matchers) == 0 {
+		return Silence{}, fmt.Errorf("silence requires at least one matcher")
+	}
+	if duration <= 0 {
+		return Silence{}, fmt.Errorf("silence duration must be positive")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Тишины другого процесса не должны потеряться при записи файла состояния
+	_ = e.syncStateLocked(now)
+	silence := Silence{
+		ID:        strconv.FormatInt(now.UnixNano(), 36),
+		Matchers:  matchers,
+		Comment:   comment,
+		CreatedAt: now,
+		Until:     now.Add(duration),
+	}
+	e.silences = append(e.silences, silence)
+	e.refreshSilencedLocked(now)
+
+	e.writeJournal(Event{Time: now, Type: EventSilenced, Silence: &silence, Note: comment})
+	if err := e.saveStateLocked(now); err != nil {
+		return silence, fmt.Errorf("silence is active until restart, saving failed: %w", err)
+	}
+	return silence, nil
+}
+
+// RemoveSilence досрочно снимает тишину
+func (e *Engine) RemoveSilence(id string, now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_ = e.syncStateLocked(now)
+	for i, silence := range e.silences {
+		if silence.ID != id {
+			continue
+		}
+		e.silences = append(e.silences[:i], e.silences[i+1:]...)
+		e.refreshSilencedLocked(now)
+		e.writeJournal(Event{Time: now, Type: EventUnsilenced, Silence: &silence})
+		// При ошибке записи тишина вернется после перезапуска, ее можно снять снова
+		_ = e.saveStateLocked(now)
+		return true
+	}
+	return false
+}
+
+// Silences возвращает действующие тишины, ближайшие к окончанию первыми
+func (e *Engine) Silences(now time.Time) []Silence {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_ = e.syncStateLocked(now)
+	e.expireSilencesLocked(now)
+	silences := append([]Silence(nil), e.silences...)
+	sort.Slice(silences, func(i, j int) bool {
+		return silences[i].Until.Before(silences[j].Until)
+	})
+	return silences
+}
+
+// SilenceFor возвращает тишину, под которую попадает оповещение
+func (e *Engine) SilenceFor(a Alert, now time.Time) (Silence, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	labels := e.alertLabels(a)
+	for _, silence := range e.silences {
+		if now.Before(silence.Until) && silence.Matches(labels) {
+			return silence, true
+		}
+	}
+	return Silence{}, false
+}
+
+func (e *Engine) silencedLocked(a Alert, now time.Time) bool {
+	e.expireSilencesLocked(now)
+
+	labels := e.alertLabels(a)
+	for _, silence := range e.silences {
+		if silence.Matches(labels) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Engine) expireSilencesLocked(now time.Time) {
+	active := e.silences[:0]
+	for _, silence := range e.silences {
+		if now.Before(silence.Until) {
+			active = append(active, silence)
+		}
+	}
+	e.silences = active
+}
+
+func (e *Engine) refreshSilencedLocked(now time.Time) {
+	for _, a := range e.active {
+		a.Silenced = e.silencedLocked(*a, now)
+	}
+}
+
+// Acknowledge отмечает активное оповещение как принятое в работу. Подтверждение
+// сохраняется в файле состояния и действует, пока оповещение не снято.
+func (e *Engine) Acknowledge(key, note string, now time.Time) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	a := e.active[key]
+	if a == nil {
+		return fmt.Errorf("alert %s is not active", key)
+	}
+
+	_ = e.syncStateLocked(now)
+	if e.acks == nil {
+		e.acks = make(map[string]Ack)
+	}
+	e.acks[key] = Ack{Note: note, At: now}
+	e.applyAckLocked(a)
+	e.writeJournal(Event{Time: now, Type: EventAcknowledged, Alert: *a, Note: note})
+	return e.saveStateLocked(now)
+}
@@ -0,0 +1,118 @@
+package alerts
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// Ack - подтверждение оповещения. Хранится, пока оповещение активно.
+type Ack struct {
+	Note string    `json:"note,omitempty"`
+	At   time.Time `json:"at"`
+}
+
+// savedState - содержимое файла состояния: тишины и подтверждения переживают
+// перезапуск и видны обоим процессам, интерфейсу и фоновому режиму
+type savedState struct {
+	Silences []Silence      `json:"silences"`
+	Acks     map[string]Ack `json:"acks,omitempty"`
+}
+
+// stateModTime возвращает время изменения файла состояния
+func (j *Journal) stateModTime() time.Time {
+	info, err := os.Stat(j.statePath)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+func (j *Journal) loadState() (savedState, error) {
+	var state savedState
+	data, err := os.ReadFile(j.statePath)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	return state, json.Unmarshal(data, &state)
+}
+
+// saveState атомарно заменяет файл состояния, чтобы другой процесс не прочитал
+// его наполовину записанным
+func (j *Journal) saveState(state savedState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := j.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.statePath)
+}
+
+// syncStateLocked перечитывает тишины и подтверждения, если файл состояния
+// изменился, например после действия в другом процессе
+func (e *Engine) syncStateLocked(now time.Time) error {
+	if e.journal == nil {
+		return nil
+	}
+	mod := e.journal.stateModTime()
+	if mod.Equal(e.stateMod) {
+		return nil
+	}
+
+	state, err := e.journal.loadState()
+	if err != nil {
+		return err
+	}
+	e.stateMod = mod
+	e.silences = state.Silences
+	e.acks = state.Acks
+	e.expireSilencesLocked(now)
+	e.refreshSilencedLocked(now)
+	for _, a := range e.active {
+		e.applyAckLocked(a)
+	}
+	return nil
+}
+
+// saveStateLocked записывает тишины и подтверждения в файл состояния
+func (e *Engine) saveStateLocked(now time.Time) error {
+	if e.journal == nil {
+		return nil
+	}
+	e.expireSilencesLocked(now)
+	if err := e.journal.saveState(savedState{Silences: e.silences, Acks: e.acks}); err != nil {
+		return err
+	}
+	e.stateMod = e.journal.stateModTime()
+	return nil
+}
+
+// applyAckLocked отмечает оповещение подтвержденным, если подтверждение сохранено
+func (e *Engine) applyAckLocked(a *Alert) {
+	ack, ok := e.acks[a.Key()]
+	if !ok {
+		return
+	}
+	a.Acknowledged = true
+	a.AckNote = ack.Note
+	a.AckAt = ack.At
+}
+
+// pruneAcksLocked удаляет подтверждения снятых оповещений и сообщает, были ли такие
+func (e *Engine) pruneAcksLocked() bool {
+	pruned := false
+	for key := range e.acks {
+		if e.active[key] == nil {
+			delete(e.acks, key)
+			pruned = true
+		}
+	}
+	return pruned
+}
@@ -0,0 +1,149 @@
+package anomaly
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+// Ряды, для которых строится базовая линия
+const (
+	SeriesActiveCalls  = "active_calls"
+	SeriesCallAttempts = "call_attempts" // попыток вызовов в минуту
+)
+
+const (
+	// Шаг, до которого усредняется история. Совпадает с шагом прореживания
+	// хранилища, поэтому сырые и прореженные дни дают одинаковый вес.
+	slotStep = 5 * time.Minute
+
+	hoursPerWeek = 7 * 24
+)
+
+// Querier определяет источник истории измерений
+type Querier interface {
+	Query(metric string, from, to time.Time) ([]types.Sample, error)
+}
+
+// Bucket - статистика ряда за один час недели по 5-минутным интервалам
+type Bucket struct {
+	Count int
+	Mean  float64
+	m2    float64
+	zeros int
+}
+
+// add добавляет значение (алгоритм Уэлфорда)
+func (b *Bucket) add(value float64) {
+	b.Count++
+	delta := value - b.Mean
+	b.Mean += delta / float64(b.Count)
+	b.m2 += delta * (value - b.Mean)
+	if value == 0 {
+		b.zeros++
+	}
+}
+
+// StdDev возвращает выборочное стандартное отклонение
+func (b Bucket) StdDev() float64 {
+	if b.Count < 2 {
+		return 0
+	}
+	return math.Sqrt(b.m2 / float64(b.Count-1))
+}
+
+// ZeroRatio возвращает долю интервалов с нулевым значением
+func (b Bucket) ZeroRatio() float64 {
+	if b.Count == 0 {
+		return 0
+	}
+	return float64(b.zeros) / float64(b.Count)
+}
+
+// Baseline - ожидаемые значения ряда для каждого часа недели
+type Baseline struct {
+	Buckets [hoursPerWeek]Bucket
+}
+
+// HourOfWeek возвращает номер часа недели по местному времени, начиная с воскресенья
+func HourOfWeek(t time.Time) int {
+	t = t.Local()
+	return int(t.Weekday())*24 + t.Hour()
+}
+
+// Expected возвращает статистику для часа недели, в который попадает t
+func (b *Baseline) Expected(t time.Time) Bucket {
+	return b.Buckets[HourOfWeek(t)]
+}
+
+// Learn строит базовые линии активных вызовов и попыток вызовов
+// по истории за последние weeks недель
+func Learn(source Querier, now time.Time, weeks int) (map[string]*Baseline, error) {
+	from := now.AddDate(0, 0, -7*weeks)
+
+	calls, err := source.Query(types.MetricActiveCalls, from, now)
+	if err != nil {
+		return nil, err
+	}
+	processed, err := source.Query(types.MetricCallsProcessed, from, now)
+	if err != nil {
+		return nil, err
+	}
+
+	active := &Baseline{}
+	for _, s := range averageSlots(calls) {
+		active.Buckets[HourOfWeek(s.start)].add(s.value)
+	}
+
+	// Нулевой счетчик означает, что Asterisk был недоступен, такие измерения пропускаем
+	var available []types.Sample
+	for _, sample := range processed {
+		if sample.Value > 0 {
+			available = append(available, sample)
+		}
+	}
+
+	attempts := &Baseline{}
+	counters := averageSlots(available)
+	for i := 1; i < len(counters); i++ {
+		prev, cur := counters[i-1], counters[i]
+		// Пропуски в данных и сброс счетчика при перезапуске не дают честной разницы
+		if cur.start.Sub(prev.start) != slotStep || cur.value < prev.value {
+			continue
+		}
+		rate := (cur.value - prev.value) / slotStep.Minutes()
+		attempts.Buckets[HourOfWeek(cur.start)].add(rate)
+	}
+
+	return map[string]*Baseline{
+		SeriesActiveCalls:  active,
+		SeriesCallAttempts: attempts,
+	}, nil
+}
+
+type slot struct {
+	start time.Time
+	value float64
+}
+
+// averageSlots усредняет измерения по 5-минутным интервалам
+func averageSlots(samples []types.Sample) []slot {
+	sums := make(map[int64]float64)
+	counts := make(map[int64]int)
+	for _, sample := range samples {
+		start := sample.Timestamp.Truncate(slotStep).Unix()
+		sums[start] += sample.Value
+		counts[start]++
+	}
+
+	slots := make([]slot, 0, len(sums))
+	for start, sum := range sums {
+		slots = append(slots, slot{start: time.Unix(start, 0), value: sum / float64(counts[start])})
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].start.Before(slots[j].start)
+	})
+	return slots
+}
@@ -0,0 +1,196 @@
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+const (
+	// Как часто базовая линия переучивается по истории
+	relearnInterval = time.Hour
+
+	// Ноль не считается аномалией, если он встречался в этом часе недели чаще
+	zeroRatioLimit = 0.05
+)
+
+type counterPoint struct {
+	at    time.Time
+	value float64
+}
+
+// Detector сравнивает текущий объем вызовов с базовой линией, оценивает
+// рост памяти и числа потоков Asterisk, прогнозирует заполнение дисков и
+// выдает измерения аномалий, которые затем вычисляются правилами оповещений
+type Detector struct {
+	mu        sync.Mutex
+	source    Querier
+	settings  types.AnomalyConfig
+	baselines map[string]*Baseline
+	learnedAt time.Time
+	counter   []counterPoint // показания счетчика вызовов за последние 5 минут
+	rss       growthTrend
+	threads   growthTrend
+	seeded    bool                    // окна роста заполнены историей
+	mounts    map[string]*growthTrend // заполненность файловых систем по точке монтирования
+	dirs      map[string]*growthTrend // размеры каталогов Asterisk по пути
+}
+
+// NewDetector создает детектор. source может быть nil, тогда аномалии объема
+// вызовов не вычисляются, а окна роста набираются только из новых измерений.
+func NewDetector(source Querier, cfg types.AnomalyConfig) *Detector {
+	return &Detector{
+		source:   source,
+		settings: cfg,
+		rss:      growthTrend{metric: types.MetricProcessRSS},
+		threads:  growthTrend{metric: types.MetricProcessThreads},
+	}
+}
+
+// Configure заменяет настройки и переучивает базовую линию при следующем измерении
+func (d *Detector) Configure(cfg types.AnomalyConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if cfg.LeakWindow != d.settings.LeakWindow {
+		d.seeded = false
+	}
+	if cfg.ForecastWindow != d.settings.ForecastWindow {
+		d.mounts, d.dirs = nil, nil
+	}
+	d.settings = cfg
+	d.learnedAt = time.Time{}
+}
+
+func (d *Detector) learnLocked(now time.Time) error {
+	d.learnedAt = now
+
+	weeks := d.settings.LearnWeeks
+	if weeks <= 0 {
+		weeks = 4
+	}
+	baselines, err := Learn(d.source, now, weeks)
+	if err != nil {
+		return err
+	}
+	d.baselines = baselines
+	return nil
+}
+
+// seedLocked задает окна роста памяти и потоков и один раз заполняет их
+// историей, дальше они пополняются измерениями
+func (d *Detector) seedLocked(now time.Time) {
+	hours := d.settings.LeakWindow
+	if hours <= 0 {
+		hours = 6
+	}
+	for _, trend := range []*growthTrend{&d.rss, &d.threads} {
+		trend.window = time.Duration(hours) * time.Hour
+		// При ошибке чтения истории ряд набирается из новых измерений
+		_ = trend.seed(d.source, now)
+	}
+	d.seeded = true
+}
+
+// Observe находит в измерениях активные вызовы и счетчик вызовов и возвращает
+// для каждого ряда z-оценку отклонения от базовой линии и признак неожиданного нуля.
+// Пока истории для текущего часа недели недостаточно, ряд пропускается.
+// Рост памяти, потоков и прогноз заполнения дисков вычисляются всегда: настройка
+// enabled и наличие хранилища влияют только на аномалии объема вызовов.
+func (d *Detector) Observe(samples []types.Sample, now time.Time) []types.Sample {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	calls := d.settings.Enabled && d.source != nil
+	if calls && now.Sub(d.learnedAt) >= relearnInterval {
+		// При ошибке чтения истории остается прежняя базовая линия
+		_ = d.learnLocked(now)
+	}
+	if !d.seeded {
+		d.seedLocked(now)
+	}
+
+	var result []types.Sample
+	for _, sample := range samples {
+		switch sample.Metric {
+		case types.MetricActiveCalls:
+			if calls {
+				result = append(result, d.score(SeriesActiveCalls, sample.Value, now)...)
+			}
+		case types.MetricCallsProcessed:
+			if !calls {
+				continue
+			}
+			if rate, ok := d.attemptRate(sample.Value, now); ok {
+				result = append(result, d.score(SeriesCallAttempts, rate, now)...)
+			}
+		case types.MetricProcessRSS:
+			result = append(result, d.observeRSS(sample.Value, now)...)
+		case types.MetricProcessThreads:
+			result = append(result, d.observeThreads(sample.Value, now)...)
+		case types.MetricMountUsage:
+			result = append(result, d.observeMount(sample, now)...)
+		case types.MetricDirSize:
+			result = append(result, d.observeDir(sample, now)...)
+		}
+	}
+	return result
+}
+
+// attemptRate возвращает число попыток вызовов в минуту за последние 5 минут
+func (d *Detector) attemptRate(value float64, now time.Time) (float64, bool) {
+	// Нулевой счетчик - Asterisk недоступен, уменьшение - перезапуск
+	if value <= 0 {
+		d.counter = nil
+		return 0, false
+	}
+	if n := len(d.counter); n > 0 && value < d.counter[n-1].value {
+		d.counter = nil
+	}
+	d.counter = append(d.counter, counterPoint{at: now, value: value})
+
+	// Оставляем одну точку старше окна, чтобы разница покрывала все 5 минут
+	for len(d.counter) > 2 && now.Sub(d.counter[1].at) >= slotStep {
+		d.counter = d.counter[1:]
+	}
+
+	first := d.counter[0]
+	elapsed := now.Sub(first.at)
+	if elapsed < time.Minute {
+		return 0, false
+	}
+	return (value - first.value) / elapsed.Minutes(), true
+}
+
+// score сравнивает наблюдение с ожиданием для текущего часа недели
+func (d *Detector) score(series string, observed float64, now time.Time) []types.Sample {
+	baseline := d.baselines[series]
+	if baseline == nil {
+		return nil
+	}
+	bucket := baseline.Expected(now)
+	if bucket.Count == 0 || bucket.Count < d.settings.MinSamples {
+		return nil
+	}
+
+	// Разброс не меньше пуассоновского, иначе в тихие часы любой вызов был бы аномалией
+	spread := math.Max(bucket.StdDev(), math.Sqrt(math.Max(bucket.Mean, 1)))
+	zscore := math.Round((observed-bucket.Mean)/spread*100) / 100
+
+	zero := 0.0
+	if observed == 0 && bucket.Mean >= d.settings.MinExpected && bucket.ZeroRatio() < zeroRatioLimit {
+		zero = 1
+	}
+
+	labels := map[string]string{"series": series}
+	note := fmt.Sprintf("expected %.1f ± %.1f, observed %.1f (%s baseline, %d intervals)",
+		bucket.Mean, bucket.StdDev(), observed, now.Local().Format("Mon 15:00"), bucket.Count)
+
+	return []types.Sample{
+		{Metric: types.MetricCallVolumeScore, Labels: labels, Value: zscore, Timestamp: now, Note: note},
+		{Metric: types.MetricCallVolumeZero, Labels: labels, Value: zero, Timestamp: now, Note: note},
+	}
+}
@@ -0,0 +1,123 @@
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+// MaxForecastDays - прогноз для файловой системы, которая не заполняется.
+// Бесконечность нельзя сохранить в истории, поэтому прогноз ограничен годом.
+const MaxForecastDays = 365
+
+// defaultForecastWindow - окно оценки роста занятого места, если оно не задано
+const defaultForecastWindow = 48 * time.Hour
+
+// ForecastWindow возвращает окно оценки роста занятого места из настроек
+func ForecastWindow(cfg types.AnomalyConfig) time.Duration {
+	if cfg.ForecastWindow <= 0 {
+		return defaultForecastWindow
+	}
+	return time.Duration(cfg.ForecastWindow) * time.Hour
+}
+
+// DaysUntilFull оценивает по истории mount_usage_pct одной файловой системы,
+// через сколько дней она заполнится. ok равен false, пока история не покрывает
+// половину окна window.
+func DaysUntilFull(samples []types.Sample, window time.Duration) (days float64, ok bool) {
+	trend := growthTrend{metric: types.MetricMountUsage, window: window}
+	for _, sample := range samples {
+		trend.add(sample.Timestamp, sample.Value)
+	}
+	slope, _, ok := trend.growth()
+	if !ok {
+		return 0, false
+	}
+	return daysUntilFull(trend.points[len(trend.points)-1].value, slope), true
+}
+
+// daysUntilFull возвращает дни до заполнения при заполненности usedPct и росте
+// slope процентов в час. Рост неустойчив по природе (записи днем, ротация
+// логов ночью), поэтому коэффициент детерминации не проверяется.
+func daysUntilFull(usedPct, slope float64) float64 {
+	if slope <= 0 {
+		return MaxForecastDays
+	}
+	days := math.Max(100-usedPct, 0) / (slope * 24)
+	return math.Min(math.Round(days*10)/10, MaxForecastDays)
+}
+
+// observeMount добавляет заполненность файловой системы и возвращает прогноз
+// числа дней до ее заполнения
+func (d *Detector) observeMount(sample types.Sample, now time.Time) []types.Sample {
+	mount := sample.Labels["mount"]
+	if d.mounts == nil {
+		d.mounts = make(map[string]*growthTrend)
+	}
+	trend := d.diskTrend(d.mounts, mount, types.MetricMountUsage, map[string]string{"mount": mount}, now)
+	trend.add(now, sample.Value)
+
+	slope, _, ok := trend.growth()
+	if !ok {
+		return nil
+	}
+
+	days := daysUntilFull(sample.Value, slope)
+	first := trend.points[0]
+	note := fmt.Sprintf("%s %.1f%% -> %.1f%% used over %s, %+.2f%%/day", mount,
+		first.value, sample.Value, now.Sub(first.at).Round(time.Hour), slope*24)
+	if days < MaxForecastDays {
+		note += fmt.Sprintf(", full in ~%.1f days", days)
+	}
+
+	return []types.Sample{{Metric: types.MetricMountDaysUntilFull, Labels: sample.Labels, Value: days, Timestamp: now, Note: note}}
+}
+
+// observeDir добавляет размер каталога Asterisk и возвращает скорость его роста
+// в мегабайтах в сутки
+func (d *Detector) observeDir(sample types.Sample, now time.Time) []types.Sample {
+	dir := sample.Labels["dir"]
+	if d.dirs == nil {
+		d.dirs = make(map[string]*growthTrend)
+	}
+	trend := d.diskTrend(d.dirs, dir, types.MetricDirSize, map[string]string{"dir": dir}, now)
+	trend.add(now, sample.Value)
+
+	slope, _, ok := trend.growth()
+	if !ok {
+		return nil
+	}
+
+	perDay := math.Round(slope*24*100) / 100
+	first := trend.points[0]
+	note := fmt.Sprintf("%s %.0f MB -> %.0f MB over %s, %+.1f MB/day", dir,
+		first.value, sample.Value, now.Sub(first.at).Round(time.Hour), perDay)
+
+	return []types.Sample{{Metric: types.MetricDirGrowth, Labels: sample.Labels, Value: perDay, Timestamp: now, Note: note}}
+}
+
+// diskTrend возвращает ряд key из trends. Новый ряд заполняется историей из
+// хранилища, чтобы прогноз был доступен сразу после запуска монитора.
+func (d *Detector) diskTrend(trends map[string]*growthTrend, key, metric string, labels map[string]string, now time.Time) *growthTrend {
+	if trend, ok := trends[key]; ok {
+		return trend
+	}
+
+	trend := &growthTrend{metric: metric, labels: labels, window: ForecastWindow(d.settings)}
+	// При ошибке чтения истории ряд набирается из новых измерений
+	_ = trend.seed(d.source, now)
+	trends[key] = trend
+	return trend
+}
+
+// hasLabels сообщает, что labels содержит все метки want
+func hasLabels(labels, want map[string]string) bool {
+	for key, value := range want {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+const (
+	// Минимальный шаг между точками, по которым оценивается рост памяти и потоков
+	leakPointStep = time.Minute
+
+	// Рост считается устойчивым, если линейная модель объясняет не меньше этой доли разброса
+	leakMinR2 = 0.8
+
+	// Падение значения вдвое считается перезапуском процесса, история сбрасывается
+	leakRestartRatio = 0.5
+)
+
+// growthTrend хранит ряд (RSS и число потоков Asterisk, заполненность диска)
+// за окно и оценивает линейный рост
+type growthTrend struct {
+	metric string
+	labels map[string]string // метки ряда при чтении истории, nil - без меток
+	window time.Duration
+	points []counterPoint
+}
+
+// add добавляет измерение ряда
+func (t *growthTrend) add(at time.Time, value float64) {
+	if n := len(t.points); n > 0 {
+		last := t.points[n-1]
+		if value < last.value*leakRestartRatio {
+			t.points = nil
+		} else if at.Sub(last.at) < leakPointStep {
+			return
+		}
+	}
+	t.points = append(t.points, counterPoint{at: at, value: value})
+
+	cutoff := at.Add(-t.window)
+	for len(t.points) > 0 && t.points[0].at.Before(cutoff) {
+		t.points = t.points[1:]
+	}
+}
+
+// growth возвращает наклон линейной регрессии в единицах ряда в час и коэффициент детерминации.
+// Оценка дается, только если точки покрывают не меньше половины окна.
+func (t *growthTrend) growth() (slope, r2 float64, ok bool) {
+	n := len(t.points)
+	if n < 10 || t.points[n-1].at.Sub(t.points[0].at) < t.window/2 {
+		return 0, 0, false
+	}
+
+	origin := t.points[0].at
+	var sumX, sumY float64
+	for _, p := range t.points {
+		sumX += p.at.Sub(origin).Hours()
+		sumY += p.value
+	}
+	meanX, meanY := sumX/float64(n), sumY/float64(n)
+
+	var sxx, sxy, syy float64
+	for _, p := range t.points {
+		dx := p.at.Sub(origin).Hours() - meanX
+		dy := p.value - meanY
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	if sxx == 0 {
+		return 0, 0, false
+	}
+
+	slope = sxy / sxx
+	if syy > 0 {
+		r2 = sxy * sxy / (sxx * syy)
+	}
+	return slope, r2, true
+}
+
+// seed заполняет окно историей из хранилища
+func (t *growthTrend) seed(source Querier, now time.Time) error {
+	if source == nil {
+		return nil
+	}
+	samples, err := source.Query(t.metric, now.Add(-t.window), now)
+	if err != nil {
+		return err
+	}
+	t.points = nil
+	for _, sample := range samples {
+		if hasLabels(sample.Labels, t.labels) {
+			t.add(sample.Timestamp, sample.Value)
+		}
+	}
+	return nil
+}
+
+// observeRSS добавляет измерение RSS и возвращает оценку утечки памяти.
+// Значение метрики равно скорости роста, если рост устойчивый, иначе 0.
+func (d *Detector) observeRSS(mb float64, now time.Time) []types.Sample {
+	d.rss.add(now, mb)
+
+	slope, r2, ok := d.rss.growth()
+	if !ok {
+		return nil
+	}
+
+	value := 0.0
+	if r2 >= leakMinR2 {
+		value = math.Round(slope*100) / 100
+	}
+
+	first := d.rss.points[0]
+	note := fmt.Sprintf("RSS %.0f MB -> %.0f MB over %s, trend %.1f MB/h (R² %.2f)",
+		first.value, mb, now.Sub(first.at).Round(time.Minute), slope, r2)
+
+	return []types.Sample{{Metric: types.MetricRSSGrowth, Value: value, Timestamp: now, Note: note}}
+}
+
+// observeThreads добавляет число потоков Asterisk и возвращает скорость их
+// роста в потоках в час, если рост устойчивый, иначе 0. Потоки, которые
+// только создаются и не завершаются, означают зависшие задачи или утечку.
+func (d *Detector) observeThreads(threads float64, now time.Time) []types.Sample {
+	d.threads.add(now, threads)
+
+	slope, r2, ok := d.threads.growth()
+	if !ok {
+		return nil
+	}
+
+	value := 0.0
+	if r2 >= leakMinR2 {
+		value = math.Round(slope*100) / 100
+	}
+
+	first := d.threads.points[0]
+	note := fmt.Sprintf("threads %.0f -> %.0f over %s, trend %.1f/h (R² %.2f)",
+		first.value, threads, now.Sub(first.at).Round(time.Minute), slope, r2)
+
+	return []types.Sample{{Metric: types.MetricThreadGrowth, Value: value, Timestamp: now, Note: note}}
+}
@@ -0,0 +1,297 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	monitor "asterisk-monitor/monitors"
+	"asterisk-monitor/types"
+)
+
+// pluginCheck - проверка в режиме Nagios/Icinga плагина
+type pluginCheck struct {
+	name        string
+	description string
+	warning     string // порог по умолчанию
+	critical    string
+	run         func(env Env, warn, crit nagiosRange) (types.CheckResult, []perfData)
+}
+
+func pluginChecks() []pluginCheck {
+	return []pluginCheck{
+		{"service", "Asterisk systemd service state", "", "", checkService},
+		{"peers", "SIP peers online ratio, %", "90:", "50:", checkPeers},
+		{"trunks", "Unregistered SIP trunks", "", "0", checkTrunks},
+		{"calls", "Active calls", "", "", checkCalls},
+		{"disk", "Disk usage, %", "80", "90", checkDisk},
+		{"certs", "Days until the first TLS certificate expires", "30:", "7:", checkCerts},
+		{"security", "Quick security scan score, %", "80:", "60:", checkSecurity},
+	}
+}
+
+func runCheck(env Env, args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		printCheckUsage(env)
+		return NagiosUnknown
+	}
+
+	var selected *pluginCheck
+	for _, c := range pluginChecks() {
+		if c.name == args[0] {
+			c := c
+			selected = &c
+			break
+		}
+	}
+	if selected == nil {
+		fmt.Fprintf(env.Stdout, "UNKNOWN - unknown check %q\n", args[0])
+		return NagiosUnknown
+	}
+
+	fs, _ := newFlagSet(env, "check "+selected.name)
+	warnFlag := fs.String("warning", selected.warning, "порог предупреждения (формат Nagios)")
+	critFlag := fs.String("critical", selected.critical, "критический порог (формат Nagios)")
+	fs.StringVar(warnFlag, "w", selected.warning, "короткая форма --warning")
+	fs.StringVar(critFlag, "c", selected.critical, "короткая форма --critical")
+	if err := fs.Parse(args[1:]); err != nil {
+		return NagiosUnknown
+	}
+
+	warn, err := parseRange(*warnFlag)
+	if err != nil {
+		fmt.Fprintf(env.Stdout, "UNKNOWN - %v\n", err)
+		return NagiosUnknown
+	}
+	crit, err := parseRange(*critFlag)
+	if err != nil {
+		fmt.Fprintf(env.Stdout, "UNKNOWN - %v\n", err)
+		return NagiosUnknown
+	}
+
+	result, perf := selected.run(env, warn, crit)
+	code := statusToNagios(result.Status)
+
+	line := fmt.Sprintf("ASTERISK %s %s - %s", strings.ToUpper(selected.name), nagiosStateNames[code], result.Message)
+	if len(perf) > 0 {
+		parts := make([]string, 0, len(perf))
+		for _, p := range perf {
+			parts = append(parts, p.String())
+		}
+		line += " | " + strings.Join(parts, " ")
+	}
+	fmt.Fprintln(env.Stdout, line)
+
+	return code
+}
+
+func printCheckUsage(env Env) {
+	fmt.Fprintln(env.Stdout, "UNKNOWN - usage: check <name> [--warning RANGE] [--critical RANGE]")
+	fmt.Fprintln(env.Stdout, "Checks:")
+	for _, c := range pluginChecks() {
+		fmt.Fprintf(env.Stdout, "  %-9s %s (default -w %q -c %q)\n", c.name, c.description, c.warning, c.critical)
+	}
+}
+
+// thresholdResult возвращает CheckResult по значению и порогам
+func thresholdResult(name string, value float64, warn, crit nagiosRange, message string) types.CheckResult {
+	status := "success"
+	switch {
+	case crit.alert(value):
+		status = "error"
+	case warn.alert(value):
+		status = "warning"
+	}
+
+	return types.CheckResult{
+		Name:      name,
+		Status:    status,
+		Message:   message,
+		Timestamp: time.Now(),
+	}
+}
+
+func checkService(env Env, warn, crit nagiosRange) (types.CheckResult, []perfData) {
+	state := env.Monitor.GetServiceStatus()
+	up := 0.0
+	status := "error"
+	if state == "active" {
+		up = 1
+		status = "success"
+	}
+
+	return types.CheckResult{
+		Name:      "service",
+		Status:    status,
+		Message:   fmt.Sprintf("asterisk service is %s", state),
+		Timestamp: time.Now(),
+	}, []perfData{{label: "up", value: up, min: "0", max: "1"}}
+}
+
+// asteriskDown возвращает CRITICAL, если Asterisk не работает: пиры, транки
+// и звонки без него недоступны, и UNKNOWN скрыл бы аварию
+func asteriskDown(env Env, name string) (types.CheckResult, bool) {
+	status := env.Monitor.GetAsteriskStatus()
+	if status == types.AsteriskRunning {
+		return types.CheckResult{}, false
+	}
+	return types.CheckResult{
+		Name:      name,
+		Status:    "error",
+		Message:   "asterisk " + status,
+		Timestamp: time.Now(),
+	}, true
+}
+
+func checkPeers(env Env, warn, crit nagiosRange) (types.CheckResult, []perfData) {
+	if result, down := asteriskDown(env, "peers"); down {
+		return result, nil
+	}
+
+	peers, err := env.Monitor.GetSIPPeers(context.Background())
+	if err != nil {
+		return unknownResult("peers", "sip show peers: "+err.Error()), nil
+	}
+	online, total := monitor.CountPeers(peers)
+	if total == 0 {
+		return unknownResult("peers", "no SIP peers found"), nil
+	}
+
+	ratio := float64(online) * 100 / float64(total)
+	result := thresholdResult("peers", ratio, warn, crit,
+		fmt.Sprintf("%d of %d peers online (%.1f%%)", online, total, ratio))
+
+	return result, []perfData{
+		{label: "online_ratio", value: math.Round(ratio*10) / 10, uom: "%", warn: warn.raw, crit: crit.raw, min: "0", max: "100"},
+		{label: "online", value: float64(online), min: "0"},
+		{label: "total", value: float64(total), min: "0"},
+	}
+}
+
+func checkTrunks(env Env, warn, crit nagiosRange) (types.CheckResult, []perfData) {
+	if result, down := asteriskDown(env, "trunks"); down {
+		return result, nil
+	}
+
+	registrations, err := env.Monitor.GetSIPRegistrations(context.Background())
+	if err != nil {
+		return unknownResult("trunks", "sip show registry: "+err.Error()), nil
+	}
+	if len(registrations) == 0 {
+		return unknownResult("trunks", "no SIP registrations configured"), nil
+	}
+
+	var failed []string
+	for _, reg := range registrations {
+		if reg.State != "Registered" {
+			failed = append(failed, fmt.Sprintf("%s@%s (%s)", reg.Username, reg.Host, reg.State))
+		}
+	}
+	sort.Strings(failed)
+
+	message := fmt.Sprintf("%d of %d trunks registered", len(registrations)-len(failed), len(registrations))
+	if len(failed) > 0 {
+		message += ", unregistered: " + strings.Join(failed, ", ")
+	}
+
+	result := thresholdResult("trunks", float64(len(failed)), warn, crit, message)
+	return result, []perfData{
+		{label: "unregistered", value: float64(len(failed)), warn: warn.raw, crit: crit.raw, min: "0", max: fmt.Sprint(len(registrations))},
+		{label: "registered", value: float64(len(registrations) - len(failed)), min: "0"},
+	}
+}
+
+func checkCalls(env Env, warn, crit nagiosRange) (types.CheckResult, []perfData) {
+	if result, down := asteriskDown(env, "calls"); down {
+		return result, nil
+	}
+
+	calls := env.Monitor.GetActiveCallsCount()
+	result := thresholdResult("calls", float64(calls), warn, crit, fmt.Sprintf("%d active calls", calls))
+	return result, []perfData{
+		{label: "calls", value: float64(calls), warn: warn.raw, crit: crit.raw, min: "0"},
+	}
+}
+
+// checkDisk проверяет все контролируемые точки монтирования, статус задает самая заполненная
+func checkDisk(env Env, warn, crit nagiosRange) (types.CheckResult, []perfData) {
+	mounts := env.Monitor.GetMountUsage()
+	if len(mounts) == 0 {
+		return unknownResult("disk", "no mount points configured"), nil
+	}
+
+	var parts []string
+	var perf []perfData
+	worst := -1.0
+	for _, mount := range mounts {
+		if mount.Error != "" {
+			return unknownResult("disk", mount.Path+": "+mount.Error), nil
+		}
+		parts = append(parts, fmt.Sprintf("%s %.1f%%", mount.Path, mount.UsedPct))
+		perf = append(perf, perfData{
+			label: "disk_usage_" + mount.Path, value: mount.UsedPct, uom: "%",
+			warn: warn.raw, crit: crit.raw, min: "0", max: "100",
+		})
+		worst = math.Max(worst, mount.UsedPct)
+	}
+
+	return thresholdResult("disk", worst, warn, crit, "disk usage "+strings.Join(parts, ", ")), perf
+}
+
+func checkCerts(env Env, warn, crit nagiosRange) (types.CheckResult, []perfData) {
+	certs, err := env.Monitor.GetCertificates(monitor.DefaultCertDir)
+	if err != nil {
+		return unknownResult("certs", err.Error()), nil
+	}
+	if len(certs) == 0 {
+		return types.CheckResult{
+			Name:      "certs",
+			Status:    "success",
+			Message:   "no TLS certificates found in " + monitor.DefaultCertDir,
+			Timestamp: time.Now(),
+		}, nil
+	}
+
+	first := certs[0]
+	result := thresholdResult("certs", float64(first.DaysLeft), warn, crit,
+		fmt.Sprintf("%d certificates, %s (%s) expires in %d days", len(certs), first.Subject, first.Path, first.DaysLeft))
+	return result, []perfData{
+		{label: "days_left", value: float64(first.DaysLeft), warn: warn.raw, crit: crit.raw},
+		{label: "certificates", value: float64(len(certs)), min: "0"},
+	}
+}
+
+func checkSecurity(env Env, warn, crit nagiosRange) (types.CheckResult, []perfData) {
+	results := monitor.RunChecks(context.Background(), env.Monitor.SecurityChecks(false))
+	score := monitor.SecurityScore(results)
+
+	var issues []string
+	for _, r := range results {
+		if r.Status == "warning" || r.Status == "error" {
+			issues = append(issues, r.Name)
+		}
+	}
+
+	message := fmt.Sprintf("security score %d%%", score)
+	if len(issues) > 0 {
+		message += ", issues: " + strings.Join(issues, ", ")
+	}
+
+	result := thresholdResult("security", float64(score), warn, crit, message)
+	return result, []perfData{
+		{label: "score", value: float64(score), uom: "%", warn: warn.raw, crit: crit.raw, min: "0", max: "100"},
+		{label: "issues", value: float64(len(issues)), min: "0"},
+	}
+}
+
+func unknownResult(name, message string) types.CheckResult {
+	return types.CheckResult{
+		Name:      name,
+		Status:    "unknown",
+		Message:   message,
+		Timestamp: time.Now(),
+	}
+}
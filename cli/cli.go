@@ -0,0 +1,167 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	monitor "asterisk-monitor/monitors"
+	"asterisk-monitor/types"
+)
+
+// Коды выхода подкоманд
+const (
+	ExitOK      = 0
+	ExitFailure = 1
+	ExitUsage   = 2
+)
+
+// Monitor определяет операции монитора, доступные из командной строки
+type Monitor interface {
+	GetAsteriskStatus() string
+	GetSystemMetrics(ctx context.Context) (types.SystemMetrics, error)
+	GetActiveChannels(ctx context.Context) ([]types.ChannelInfo, error)
+	GetServiceStatus() string
+	GetSIPPeers(ctx context.Context) ([]types.SIPPeer, error)
+	GetSIPRegistrations(ctx context.Context) ([]types.SIPRegistration, error)
+	GetActiveCallsCount() int
+	GetMountUsage() []types.MountUsage
+	GetCertificates(dir string) ([]types.CertInfo, error)
+	DiagnosticChecks(full bool) []monitor.Check
+	SecurityChecks(full bool) []monitor.Check
+	CreateBackup(ctx context.Context, backupPath string, progress func(types.CheckResult)) (string, []types.CheckResult)
+	RestoreBackup(ctx context.Context, backupFile string, progress func(types.CheckResult)) []types.CheckResult
+	ListBackups(backupPath string) ([]types.BackupInfo, error)
+}
+
+// Env - окружение выполнения подкоманды
+type Env struct {
+	Monitor Monitor
+	Config  *types.Config
+	Stdout  io.Writer
+	Stderr  io.Writer
+}
+
+type command struct {
+	name  string
+	usage string
+	run   func(env Env, args []string) int
+}
+
+func commands() []command {
+	return []command{
+		{"status", "status [--json]", runStatus},
+		{"channels", "channels [--json]", runChannels},
+		{"peers", "peers [--json]", runPeers},
+		{"diagnose", "diagnose [--full] [--json]", runDiagnose},
+		{"security-scan", "security-scan [--full] [--json]", runSecurityScan},
+		{"backup", "backup create|list|restore [--path DIR] [--json] [FILE]", runBackup},
+		{"notify-test", "notify-test [--notifier NAME] [--json]", runNotifyTest},
+		{"check", "check service|peers|trunks|calls|disk|certs|security [-w RANGE] [-c RANGE]", runCheck},
+		{"help", "help", runHelp},
+	}
+}
+
+// Run выполняет подкоманду и возвращает код выхода
+func Run(env Env, args []string) int {
+	if len(args) == 0 {
+		return runHelp(env, nil)
+	}
+
+	for _, cmd := range commands() {
+		if cmd.name == args[0] {
+			return cmd.run(env, args[1:])
+		}
+	}
+
+	fmt.Fprintf(env.Stderr, "unknown command %q\n\n", args[0])
+	runHelp(Env{Stdout: env.Stderr}, nil)
+	return ExitUsage
+}
+
+func runHelp(env Env, args []string) int {
+	fmt.Fprintln(env.Stdout, "Usage: asterisk-monitor [--exporter|--daemon] | <command> [options]")
+	fmt.Fprintln(env.Stdout)
+	fmt.Fprintln(env.Stdout, "Commands:")
+	for _, cmd := range commands() {
+		fmt.Fprintf(env.Stdout, "  %s\n", cmd.usage)
+	}
+	fmt.Fprintln(env.Stdout)
+	fmt.Fprintln(env.Stdout, "Exit codes: 0 - success, 1 - failure or failed checks, 2 - usage error")
+	fmt.Fprintln(env.Stdout, "The check command follows the Nagios plugin API: 0 - OK, 1 - WARNING, 2 - CRITICAL, 3 - UNKNOWN")
+	return ExitOK
+}
+
+// newFlagSet создает набор флагов подкоманды с общим флагом --json
+func newFlagSet(env Env, name string) (*flag.FlagSet, *bool) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(env.Stderr)
+	asJSON := fs.Bool("json", false, "вывод в формате JSON")
+	return fs, asJSON
+}
+
+// parseInterspersed разбирает флаги в любом месте аргументов, а не только до
+// первого позиционного, и возвращает позиционные аргументы. После "--" все
+// аргументы считаются позиционными.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+func writeJSON(env Env, value interface{}) int {
+	encoder := json.NewEncoder(env.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		fmt.Fprintf(env.Stderr, "encode json: %v\n", err)
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+func writeTable(env Env, headers []string, rows [][]string) {
+	w := tabwriter.NewWriter(env.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+}
+
+// checksExitCode возвращает ExitFailure, если хотя бы одна проверка завершилась ошибкой
+func checksExitCode(results []types.CheckResult) int {
+	for _, result := range results {
+		if result.Status == "error" {
+			return ExitFailure
+		}
+	}
+	return ExitOK
+}
+
+func writeChecks(env Env, results []types.CheckResult) {
+	var rows [][]string
+	for _, result := range results {
+		message := strings.ReplaceAll(result.Message, "\n", " ")
+		if result.Error != "" {
+			message += " (" + result.Error + ")"
+		}
+		rows = append(rows, []string{strings.ToUpper(result.Status), result.Name, message})
+	}
+	writeTable(env, []string{"STATUS", "CHECK", "MESSAGE"}, rows)
+}
@@ -0,0 +1,317 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	monitor "asterisk-monitor/monitors"
+	"asterisk-monitor/notify"
+	"asterisk-monitor/types"
+)
+
+func runStatus(env Env, args []string) int {
+	fs, asJSON := newFlagSet(env, "status")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+
+	ctx := context.Background()
+	metrics, _ := env.Monitor.GetSystemMetrics(ctx)
+	// Недоступный Asterisk виден по состоянию сервиса, пиров тогда нет
+	peers, _ := env.Monitor.GetSIPPeers(ctx)
+	metrics.OnlinePeers, metrics.TotalPeers = monitor.CountPeers(peers)
+	code := ExitOK
+	if metrics.ServiceState != "active" {
+		code = ExitFailure
+	}
+
+	if *asJSON {
+		if writeJSON(env, metrics) != ExitOK {
+			return ExitFailure
+		}
+		return code
+	}
+
+	rows := [][]string{
+		{"Service", metrics.ServiceState},
+		{"PID", metrics.AsteriskPID},
+		{"Uptime", metrics.Uptime},
+		{"Load Average", metrics.LoadAverage},
+		{"CPU Usage", fmt.Sprintf("%.1f%%", metrics.CPUUsage)},
+		{"Memory Usage", fmt.Sprintf("%.1f%%", metrics.MemoryUsage)},
+		{"Disk Usage", fmt.Sprintf("%.1f%%", metrics.DiskUsage)},
+		{"Active Calls", strconv.Itoa(metrics.ActiveCalls)},
+		{"SIP Peers", fmt.Sprintf("%d/%d", metrics.OnlinePeers, metrics.TotalPeers)},
+	}
+	if p := metrics.Process; p != nil {
+		fds := "n/a"
+		if p.OpenFDs >= 0 {
+			fds = fmt.Sprintf("%d/%d", p.OpenFDs, p.MaxFDs)
+		}
+		rows = append(rows,
+			[]string{"Asterisk CPU", fmt.Sprintf("%.1f%%", p.CPUPercent)},
+			[]string{"Asterisk RSS", fmt.Sprintf("%.1f MB", float64(p.RSSBytes)/(1024*1024))},
+			[]string{"Asterisk Threads", strconv.Itoa(p.Threads)},
+			[]string{"Asterisk Open Files", fds},
+		)
+	}
+	for _, mount := range metrics.Mounts {
+		value := fmt.Sprintf("%.1f%%", mount.UsedPct)
+		if mount.Error != "" {
+			value = "error: " + mount.Error
+		}
+		rows = append(rows, []string{"Disk " + mount.Path, value})
+	}
+
+	writeTable(env, []string{"METRIC", "VALUE"}, rows)
+	return code
+}
+
+func runChannels(env Env, args []string) int {
+	fs, asJSON := newFlagSet(env, "channels")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+
+	if code := requireAsterisk(env); code != ExitOK {
+		return code
+	}
+
+	channels, err := env.Monitor.GetActiveChannels(context.Background())
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "list channels: %v\n", err)
+		return ExitFailure
+	}
+	if *asJSON {
+		if channels == nil {
+			channels = []types.ChannelInfo{}
+		}
+		return writeJSON(env, channels)
+	}
+
+	var rows [][]string
+	for _, channel := range channels {
+		rows = append(rows, []string{channel.Name, channel.State, channel.Duration, channel.CallerID, channel.Application})
+	}
+	writeTable(env, []string{"CHANNEL", "STATE", "DURATION", "CALLERID", "APPLICATION"}, rows)
+	return ExitOK
+}
+
+func runPeers(env Env, args []string) int {
+	fs, asJSON := newFlagSet(env, "peers")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+
+	if code := requireAsterisk(env); code != ExitOK {
+		return code
+	}
+
+	peers, err := env.Monitor.GetSIPPeers(context.Background())
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "list peers: %v\n", err)
+		return ExitFailure
+	}
+	if *asJSON {
+		if peers == nil {
+			peers = []types.SIPPeer{}
+		}
+		return writeJSON(env, peers)
+	}
+
+	var rows [][]string
+	for _, peer := range peers {
+		rows = append(rows, []string{peer.Name, peer.Host, peer.Status, peer.Latency})
+	}
+	writeTable(env, []string{"PEER", "HOST", "STATUS", "LATENCY"}, rows)
+	return ExitOK
+}
+
+func runDiagnose(env Env, args []string) int {
+	fs, asJSON := newFlagSet(env, "diagnose")
+	full := fs.Bool("full", false, "полная диагностика")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+
+	results := monitor.RunChecks(context.Background(), env.Monitor.DiagnosticChecks(*full))
+	if *asJSON {
+		if writeJSON(env, results) != ExitOK {
+			return ExitFailure
+		}
+	} else {
+		writeChecks(env, results)
+	}
+	return checksExitCode(results)
+}
+
+func runSecurityScan(env Env, args []string) int {
+	fs, asJSON := newFlagSet(env, "security-scan")
+	full := fs.Bool("full", false, "полный аудит безопасности")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+
+	results := monitor.RunChecks(context.Background(), env.Monitor.SecurityChecks(*full))
+	score := monitor.SecurityScore(results)
+
+	if *asJSON {
+		report := struct {
+			Score   int                 `json:"score"`
+			Results []types.CheckResult `json:"results"`
+		}{score, results}
+		if writeJSON(env, report) != ExitOK {
+			return ExitFailure
+		}
+	} else {
+		writeChecks(env, results)
+		fmt.Fprintf(env.Stdout, "\nSecurity Score: %d%%\n", score)
+	}
+	return checksExitCode(results)
+}
+
+func runBackup(env Env, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(env.Stderr, "usage: backup create|list|restore [--path DIR] [--json] [FILE]")
+		return ExitUsage
+	}
+
+	action := args[0]
+	fs, asJSON := newFlagSet(env, "backup "+action)
+	path := fs.String("path", monitor.DefaultBackupPath, "каталог бэкапов")
+	files, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return ExitUsage
+	}
+
+	switch action {
+	case "create":
+		file, results := env.Monitor.CreateBackup(context.Background(), *path, nil)
+		if *asJSON {
+			report := struct {
+				File    string              `json:"file"`
+				Results []types.CheckResult `json:"results"`
+			}{file, results}
+			if writeJSON(env, report) != ExitOK {
+				return ExitFailure
+			}
+		} else {
+			writeChecks(env, results)
+		}
+		return finalResultCode(results)
+
+	case "list":
+		backups, err := env.Monitor.ListBackups(*path)
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "list backups: %v\n", err)
+			return ExitFailure
+		}
+		if *asJSON {
+			if backups == nil {
+				backups = []types.BackupInfo{}
+			}
+			return writeJSON(env, backups)
+		}
+
+		var rows [][]string
+		for _, backup := range backups {
+			rows = append(rows, []string{backup.Path, monitor.FormatBytes(backup.Size), backup.Modified.Format("2006-01-02 15:04:05")})
+		}
+		writeTable(env, []string{"FILE", "SIZE", "MODIFIED"}, rows)
+		return ExitOK
+
+	case "restore":
+		if len(files) != 1 {
+			fmt.Fprintln(env.Stderr, "usage: backup restore [--json] FILE")
+			return ExitUsage
+		}
+
+		results := env.Monitor.RestoreBackup(context.Background(), files[0], nil)
+		if *asJSON {
+			if writeJSON(env, results) != ExitOK {
+				return ExitFailure
+			}
+		} else {
+			writeChecks(env, results)
+		}
+		return finalResultCode(results)
+	}
+
+	fmt.Fprintf(env.Stderr, "unknown backup action %q\n", action)
+	return ExitUsage
+}
+
+// finalResultCode возвращает код выхода по итоговому (последнему) результату операции
+func finalResultCode(results []types.CheckResult) int {
+	if len(results) == 0 || results[len(results)-1].Status != "success" {
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+// requireAsterisk проверяет, что Asterisk запущен, иначе сообщает об ошибке
+func requireAsterisk(env Env) int {
+	if status := env.Monitor.GetAsteriskStatus(); status != types.AsteriskRunning {
+		fmt.Fprintf(env.Stderr, "asterisk is %s\n", status)
+		return ExitFailure
+	}
+	return ExitOK
+}
+
+func runNotifyTest(env Env, args []string) int {
+	fs, asJSON := newFlagSet(env, "notify-test")
+	name := fs.String("notifier", "", "имя канала уведомлений (по умолчанию все)")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+
+	dispatcher, err := notify.NewDispatcher(env.Config)
+	if err != nil {
+		fmt.Fprintf(env.Stderr, "notifiers: %v\n", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	results := dispatcher.SendTest(ctx, *name)
+	if len(results) == 0 {
+		fmt.Fprintln(env.Stderr, "no enabled notifiers configured")
+		return ExitFailure
+	}
+
+	names := make([]string, 0, len(results))
+	for notifier := range results {
+		names = append(names, notifier)
+	}
+	sort.Strings(names)
+
+	code := ExitOK
+	var checks []types.CheckResult
+	for _, notifier := range names {
+		result := types.CheckResult{
+			Name:      notifier,
+			Status:    "success",
+			Message:   "test notification sent",
+			Timestamp: time.Now(),
+		}
+		if err := results[notifier]; err != nil {
+			result.Status = "error"
+			result.Message = "test notification failed"
+			result.Error = err.Error()
+			code = ExitFailure
+		}
+		checks = append(checks, result)
+	}
+
+	if *asJSON {
+		if writeJSON(env, checks) != ExitOK {
+			return ExitFailure
+		}
+	} else {
+		writeChecks(env, checks)
+	}
+	return code
+}
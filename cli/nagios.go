@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Коды выхода по Nagios plugin API
+const (
+	NagiosOK       = 0
+	NagiosWarning  = 1
+	NagiosCritical = 2
+	NagiosUnknown  = 3
+)
+
+// nagiosRange - диапазон порога в формате Nagios: [@]start:end.
+// "10" - тревога вне 0..10, "10:" - ниже 10, "~:10" - выше 10,
+// "@10:20" - тревога внутри 10..20.
+type nagiosRange struct {
+	set    bool
+	start  float64
+	end    float64
+	inside bool
+	raw    string
+}
+
+func parseRange(raw string) (nagiosRange, error) {
+	r := nagiosRange{raw: raw}
+	if raw == "" {
+		return r, nil
+	}
+	r.set = true
+
+	spec := raw
+	if strings.HasPrefix(spec, "@") {
+		r.inside = true
+		spec = spec[1:]
+	}
+
+	start, end := "0", spec
+	if idx := strings.Index(spec, ":"); idx >= 0 {
+		start, end = spec[:idx], spec[idx+1:]
+	}
+
+	switch start {
+	case "~":
+		r.start = math.Inf(-1)
+	case "":
+		r.start = 0
+	default:
+		value, err := strconv.ParseFloat(start, 64)
+		if err != nil {
+			return r, fmt.Errorf("invalid threshold %q", raw)
+		}
+		r.start = value
+	}
+
+	if end == "" {
+		r.end = math.Inf(1)
+	} else {
+		value, err := strconv.ParseFloat(end, 64)
+		if err != nil {
+			return r, fmt.Errorf("invalid threshold %q", raw)
+		}
+		r.end = value
+	}
+
+	if r.start > r.end {
+		return r, fmt.Errorf("invalid threshold %q: start is greater than end", raw)
+	}
+	return r, nil
+}
+
+// alert сообщает, нарушает ли значение диапазон
+func (r nagiosRange) alert(value float64) bool {
+	if !r.set {
+		return false
+	}
+	outside := value < r.start || value > r.end
+	if r.inside {
+		return !outside
+	}
+	return outside
+}
+
+// perfData - одно значение производительности в выводе плагина
+type perfData struct {
+	label string
+	value float64
+	uom   string
+	warn  string
+	crit  string
+	min   string
+	max   string
+}
+
+func (p perfData) String() string {
+	label := p.label
+	if strings.ContainsAny(label, " '=") {
+		label = "'" + strings.ReplaceAll(label, "'", "''") + "'"
+	}
+	return fmt.Sprintf("%s=%s%s;%s;%s;%s;%s", label,
+		strconv.FormatFloat(p.value, 'f', -1, 64), p.uom, p.warn, p.crit, p.min, p.max)
+}
+
+// statusToNagios переводит статус CheckResult в код выхода плагина
+func statusToNagios(status string) int {
+	switch status {
+	case "success":
+		return NagiosOK
+	case "warning":
+		return NagiosWarning
+	case "error":
+		return NagiosCritical
+	}
+	return NagiosUnknown
+}
+
+var nagiosStateNames = map[int]string{
+	NagiosOK:       "OK",
+	NagiosWarning:  "WARNING",
+	NagiosCritical: "CRITICAL",
+	NagiosUnknown:  "UNKNOWN",
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		raw    string
+		start  float64
+		end    float64
+		inside bool
+	}{
+		{"10", 0, 10, false},
+		{"10:", 10, math.Inf(1), false},
+		{"~:10", math.Inf(-1), 10, false},
+		{"5:10", 5, 10, false},
+		{"@10:20", 10, 20, true},
+		{":7", 0, 7, false},
+		{"-5:-1", -5, -1, false},
+	}
+	for _, tt := range tests {
+		r, err := parseRange(tt.raw)
+		if err != nil {
+			t.Errorf("parseRange(%q): %v", tt.raw, err)
+			continue
+		}
+		if !r.set || r.start != tt.start || r.end != tt.end || r.inside != tt.inside || r.raw != tt.raw {
+			t.Errorf("parseRange(%q) = %+v, want %v..%v inside=%v", tt.raw, r, tt.start, tt.end, tt.inside)
+		}
+	}
+
+	if r, err := parseRange(""); err != nil || r.set {
+		t.Errorf("parseRange(\"\") = %+v, %v, want an unset range", r, err)
+	}
+	for _, raw := range []string{"abc", "10:x", "20:10", "~:~"} {
+		if _, err := parseRange(raw); err == nil {
+			t.Errorf("parseRange(%q): no error", raw)
+		}
+	}
+}
+
+func TestRangeAlert(t *testing.T) {
+	tests := []struct {
+		raw   string
+		value float64
+		want  bool
+	}{
+		{"10", 5, false},
+		{"10", 0, false},
+		{"10", 10, false},
+		{"10", 11, true},
+		{"10", -1, true},
+		{"10:", 10, false},
+		{"10:", 9.9, true},
+		{"~:10", -100, false},
+		{"~:10", 10.5, true},
+		{"@10:20", 15, true},
+		{"@10:20", 10, true},
+		{"@10:20", 9, false},
+		{"@10:20", 21, false},
+		{"", 1e9, false},
+	}
+	for _, tt := range tests {
+		r, err := parseRange(tt.raw)
+		if err != nil {
+			t.Fatalf("parseRange(%q): %v", tt.raw, err)
+		}
+		if got := r.alert(tt.value); got != tt.want {
+			t.Errorf("range %q alert(%v) = %v, want %v", tt.raw, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestPerfDataString(t *testing.T) {
+	tests := []struct {
+		perf perfData
+		want string
+	}{
+		{
+			perfData{label: "online_ratio", value: 87.5, uom: "%", warn: "90:", crit: "50:", min: "0", max: "100"},
+			"online_ratio=87.5%;90:;50:;0;100",
+		},
+		{perfData{label: "calls", value: 3, min: "0"}, "calls=3;;;0;"},
+		{perfData{label: "load", value: 0.000125}, "load=0.000125;;;;"},
+		// Метки с пробелами, '=' и кавычками берутся в кавычки
+		{perfData{label: "disk_usage_/var/lib asterisk", value: 42, uom: "%"}, "'disk_usage_/var/lib asterisk'=42%;;;;"},
+		{perfData{label: "it's", value: 1}, "'it''s'=1;;;;"},
+		{perfData{label: "a=b", value: 1}, "'a=b'=1;;;;"},
+	}
+	for _, tt := range tests {
+		if got := tt.perf.String(); got != tt.want {
+			t.Errorf("perfData %+v = %q, want %q", tt.perf, got, tt.want)
+		}
+	}
+}
+
+func TestStatusToNagios(t *testing.T) {
+	for status, want := range map[string]int{
+		"success": NagiosOK,
+		"warning": NagiosWarning,
+		"error":   NagiosCritical,
+		"info":    NagiosUnknown,
+		"":        NagiosUnknown,
+	} {
+		if got := statusToNagios(status); got != want {
+			t.Errorf("statusToNagios(%q) = %d, want %d", status, got, want)
+		}
+	}
+}
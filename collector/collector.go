@@ -0,0 +1,279 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Как часто планировщик проверяет, каким источникам пора обновиться
+const scheduleTick = time.Second
+
+// Source описывает источник данных
+type Source struct {
+	Name     string
+	Interval time.Duration // период фонового обновления, 0 - только по запросу
+	TTL      time.Duration // сколько значение считается свежим, 0 - два интервала
+	Timeout  time.Duration // срок одного обновления, 0 - без срока
+	Fetch    func(ctx context.Context) (any, error)
+}
+
+// Status - состояние источника для отображения в интерфейсе
+type Status struct {
+	Name      string
+	UpdatedAt time.Time     // время последнего успешного обновления
+	Duration  time.Duration // длительность последнего обновления
+	Err       error         // ошибка последнего обновления, nil после успешного
+	ErrorAt   time.Time
+	Fetching  bool
+	Fetches   int // выполненных обновлений
+	Coalesced int // запросов, присоединившихся к уже идущему обновлению
+}
+
+// Age возвращает возраст значения относительно now
+func (s Status) Age(now time.Time) time.Duration {
+	if s.UpdatedAt.IsZero() {
+		return 0
+	}
+	return now.Sub(s.UpdatedAt)
+}
+
+// flight - выполняющееся обновление источника, которого ждут все запросившие
+type flight struct {
+	done  chan struct{}
+	value any
+	err   error
+}
+
+type entry struct {
+	source  Source
+	value   any
+	status  Status
+	flight  *flight
+	nextRun time.Time
+}
+
+func (e *entry) ttl() time.Duration {
+	if e.source.TTL > 0 {
+		return e.source.TTL
+	}
+	// Плановое обновление приходит с опозданием на время опроса, поэтому
+	// значение считается свежим до следующего планового обновления с запасом
+	return 2 * e.source.Interval
+}
+
+// Collector обновляет источники по расписанию и хранит последние значения.
+// Одновременные запросы одного источника объединяются в одно обновление,
+// поэтому виды могут читать кэш, не запуская собственные команды Asterisk CLI.
+type Collector struct {
+	mu       sync.Mutex
+	entries  map[string]*entry
+	order    []string
+	onUpdate func(name string)
+}
+
+// New создает коллектор с источниками sources. Первые плановые обновления
+// распределяются по интервалу, чтобы источники не опрашивали Asterisk одновременно.
+func New(sources ...Source) *Collector {
+	c := &Collector{entries: make(map[string]*entry)}
+	now := time.Now()
+	for i, source := range sources {
+		c.entries[source.Name] = &entry{
+			source:  source,
+			status:  Status{Name: source.Name},
+			nextRun: now.Add(source.Interval * time.Duration(i) / time.Duration(len(sources))),
+		}
+		c.order = append(c.order, source.Name)
+	}
+	return c
+}
+
+// OnUpdate задает функцию, которая вызывается после каждого обновления источника
+// (успешного или с ошибкой). Вызывается из горутины обновления.
+func (c *Collector) OnUpdate(fn func(name string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onUpdate = fn
+}
+
+// Get возвращает значение источника из кэша, если оно свежее, иначе обновляет источник
+func (c *Collector) Get(ctx context.Context, name string) (any, error) {
+	return c.GetWithin(ctx, name, -1)
+}
+
+// GetWithin возвращает значение из кэша, если оно не старше maxAge, иначе обновляет
+// источник. Отрицательный maxAge означает TTL источника, нулевой - обновить всегда.
+func (c *Collector) GetWithin(ctx context.Context, name string, maxAge time.Duration) (any, error) {
+	c.mu.Lock()
+	e, ok := c.entries[name]
+	if !ok {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("unknown source %q", name)
+	}
+	if maxAge < 0 {
+		maxAge = e.ttl()
+	}
+	if maxAge > 0 && !e.status.UpdatedAt.IsZero() && time.Since(e.status.UpdatedAt) < maxAge {
+		value := e.value
+		c.mu.Unlock()
+		return value, nil
+	}
+	f := c.startLocked(e)
+	c.mu.Unlock()
+
+	return wait(ctx, f)
+}
+
+// Refresh обновляет источник независимо от возраста значения. Если обновление
+// уже выполняется, запрос присоединяется к нему.
+func (c *Collector) Refresh(ctx context.Context, name string) (any, error) {
+	return c.GetWithin(ctx, name, 0)
+}
+
+// Peek возвращает последнее значение источника и его состояние без обновления
+func (c *Collector) Peek(name string) (any, Status) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[name]
+	if !ok {
+		return nil, Status{Name: name, Err: fmt.Errorf("unknown source %q", name)}
+	}
+	return e.value, e.status
+}
+
+// Statuses возвращает состояние всех источников в порядке регистрации
+func (c *Collector) Statuses() []Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	statuses := make([]Status, 0, len(c.order))
+	for _, name := range c.order {
+		statuses = append(statuses, c.entries[name].status)
+	}
+	return statuses
+}
+
+// Run обновляет источники с ненулевым интервалом по расписанию до отмены ctx
+func (c *Collector) Run(ctx context.Context) {
+	ticker := time.NewTicker(scheduleTick)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		c.mu.Lock()
+		for _, name := range c.order {
+			e := c.entries[name]
+			// Обновление по запросу вида тоже сдвигает плановое
+			if e.source.Interval <= 0 || now.Before(e.nextRun) {
+				continue
+			}
+			c.startLocked(e)
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// startLocked запускает обновление источника или возвращает уже идущее
+func (c *Collector) startLocked(e *entry) *flight {
+	if e.flight != nil {
+		e.status.Coalesced++
+		return e.flight
+	}
+
+	f := &flight{done: make(chan struct{})}
+	e.flight = f
+	e.status.Fetching = true
+	start := time.Now()
+	e.nextRun = start.Add(e.source.Interval)
+
+	go func() {
+		// Обновление не привязано к контексту запросившего: его результат нужен
+		// и остальным ожидающим, и кэшу
+		ctx := context.Background()
+		if e.source.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, e.source.Timeout)
+			defer cancel()
+		}
+		f.value, f.err = e.source.Fetch(ctx)
+		if f.err == nil && ctx.Err() != nil {
+			f.err = ctx.Err()
+		}
+
+		c.mu.Lock()
+		e.flight = nil
+		e.status.Fetching = false
+		e.status.Fetches++
+		e.status.Duration = time.Since(start)
+		if f.err != nil {
+			e.status.Err = f.err
+			e.status.ErrorAt = time.Now()
+		} else {
+			e.value = f.value
+			e.status.Err = nil
+			e.status.UpdatedAt = time.Now()
+		}
+		onUpdate := c.onUpdate
+		c.mu.Unlock()
+
+		close(f.done)
+		if onUpdate != nil {
+			onUpdate(e.source.Name)
+		}
+	}()
+
+	return f
+}
+
+// wait ждет завершения обновления или отмены ctx
+func wait(ctx context.Context, f *flight) (any, error) {
+	select {
+	case <-f.done:
+		return f.value, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Cached возвращает последнее значение источника name типа T без обновления.
+// Пока источник не обновлялся, возвращается нулевое значение.
+func Cached[T any](c *Collector, name string) (T, Status) {
+	value, status := c.Peek(name)
+	typed, _ := value.(T)
+	return typed, status
+}
+
+// Fresh возвращает значение источника name типа T без обновления, если
+// последнее обновление удалось и значение не старше TTL. Иначе возвращается
+// ошибка источника: устаревшее значение нельзя выдавать за новое измерение.
+func Fresh[T any](c *Collector, name string) (T, error) {
+	var zero T
+
+	c.mu.Lock()
+	e, ok := c.entries[name]
+	if !ok {
+		c.mu.Unlock()
+		return zero, fmt.Errorf("unknown source %q", name)
+	}
+	value, status, ttl := e.value, e.status, e.ttl()
+	c.mu.Unlock()
+
+	switch {
+	case status.Err != nil:
+		return zero, status.Err
+	case status.UpdatedAt.IsZero():
+		return zero, fmt.Errorf("source %q has no data yet", name)
+	case ttl > 0 && status.Age(time.Now()) >= ttl:
+		return zero, fmt.Errorf("source %q is stale: updated %s ago", name, status.Age(time.Now()).Round(time.Second))
+	}
+	typed, _ := value.(T)
+	return typed, nil
+}
@@ -0,0 +1,219 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// counterSource возвращает источник, который отдает номер обновления
+func counterSource(name string, ttl time.Duration, fetches *atomic.Int32) Source {
+	return Source{
+		Name: name,
+		TTL:  ttl,
+		Fetch: func(ctx context.Context) (any, error) {
+			return int(fetches.Add(1)), nil
+		},
+	}
+}
+
+// waitFor ждет выполнения условия, которое выполняется в горутине обновления
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestGetCachesWithinTTL(t *testing.T) {
+	var fetches atomic.Int32
+	c := New(counterSource("src", time.Hour, &fetches))
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		value, err := c.Get(ctx, "src")
+		if err != nil || value != 1 {
+			t.Fatalf("Get #%d = %v, %v, want cached 1", i, value, err)
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("fetches = %d, want 1", n)
+	}
+
+	// Refresh и нулевой maxAge обновляют независимо от возраста
+	if value, err := c.Refresh(ctx, "src"); err != nil || value != 2 {
+		t.Errorf("Refresh = %v, %v, want 2", value, err)
+	}
+	if value, err := c.GetWithin(ctx, "src", time.Hour); err != nil || value != 2 {
+		t.Errorf("GetWithin(hour) = %v, %v, want cached 2", value, err)
+	}
+
+	if _, err := c.Get(ctx, "missing"); err == nil {
+		t.Error("no error for an unknown source")
+	}
+}
+
+func TestGetRefetchesAfterTTL(t *testing.T) {
+	var fetches atomic.Int32
+	c := New(counterSource("src", 20*time.Millisecond, &fetches))
+	ctx := context.Background()
+
+	if _, err := c.Get(ctx, "src"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if value, err := c.Get(ctx, "src"); err != nil || value != 2 {
+		t.Errorf("Get after TTL = %v, %v, want 2", value, err)
+	}
+}
+
+func TestConcurrentGetsCoalesce(t *testing.T) {
+	const callers = 5
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	c := New(Source{
+		Name: "slow",
+		TTL:  time.Hour,
+		Fetch: func(ctx context.Context) (any, error) {
+			fetches.Add(1)
+			<-release
+			return "value", nil
+		},
+	})
+
+	var wg sync.WaitGroup
+	results := make([]any, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = c.Get(context.Background(), "slow")
+		}(i)
+	}
+
+	waitFor(t, "callers to join the fetch", func() bool {
+		_, status := c.Peek("slow")
+		return status.Fetching && status.Coalesced == callers-1
+	})
+	close(release)
+	wg.Wait()
+
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("fetches = %d, want 1", n)
+	}
+	for i, result := range results {
+		if result != "value" {
+			t.Errorf("caller %d got %v", i, result)
+		}
+	}
+	if _, status := c.Peek("slow"); status.Fetches != 1 || status.Fetching {
+		t.Errorf("status = %+v, want one finished fetch", status)
+	}
+}
+
+func TestCanceledWaiterDoesNotCancelFetch(t *testing.T) {
+	release := make(chan struct{})
+	c := New(Source{
+		Name: "slow",
+		TTL:  time.Hour,
+		Fetch: func(ctx context.Context) (any, error) {
+			<-release
+			return 42, ctx.Err()
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Get(ctx, "slow"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Get = %v, want context.Canceled", err)
+	}
+
+	// Обновление завершается и попадает в кэш для остальных
+	close(release)
+	waitFor(t, "the fetch to finish", func() bool {
+		_, status := c.Peek("slow")
+		return status.Fetches == 1
+	})
+	if value, err := c.Get(context.Background(), "slow"); err != nil || value != 42 {
+		t.Errorf("Get = %v, %v, want cached 42", value, err)
+	}
+}
+
+func TestFetchErrorKeepsLastValue(t *testing.T) {
+	fail := errors.New("asterisk not responding")
+	var failing atomic.Bool
+	c := New(Source{
+		Name: "peers",
+		TTL:  time.Hour,
+		Fetch: func(ctx context.Context) (any, error) {
+			if failing.Load() {
+				return nil, fail
+			}
+			return []string{"100"}, nil
+		},
+	})
+	ctx := context.Background()
+
+	if _, err := c.Get(ctx, "peers"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if value, err := Fresh[[]string](c, "peers"); err != nil || len(value) != 1 {
+		t.Fatalf("Fresh = %v, %v, want the fetched value", value, err)
+	}
+
+	failing.Store(true)
+	if _, err := c.Refresh(ctx, "peers"); !errors.Is(err, fail) {
+		t.Fatalf("Refresh = %v, want %v", err, fail)
+	}
+
+	// Кэш хранит последнее удачное значение, но свежим оно не считается
+	value, status := Cached[[]string](c, "peers")
+	if len(value) != 1 || !errors.Is(status.Err, fail) || status.ErrorAt.IsZero() {
+		t.Errorf("Cached = %v, %+v, want the last value with the error", value, status)
+	}
+	if value, err := Fresh[[]string](c, "peers"); !errors.Is(err, fail) || value != nil {
+		t.Errorf("Fresh = %v, %v, want no value and %v", value, err, fail)
+	}
+
+	failing.Store(false)
+	if _, err := c.Refresh(ctx, "peers"); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if _, status := c.Peek("peers"); status.Err != nil {
+		t.Errorf("error not cleared after a successful fetch: %v", status.Err)
+	}
+}
+
+func TestFreshRejectsMissingAndStaleValues(t *testing.T) {
+	var fetches atomic.Int32
+	c := New(counterSource("src", 20*time.Millisecond, &fetches))
+
+	if _, err := Fresh[int](c, "src"); err == nil {
+		t.Error("no error before the first fetch")
+	}
+	if _, err := Fresh[int](c, "missing"); err == nil {
+		t.Error("no error for an unknown source")
+	}
+
+	if _, err := c.Get(context.Background(), "src"); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if value, err := Fresh[int](c, "src"); err != nil || value != 1 {
+		t.Errorf("Fresh = %v, %v, want 1", value, err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, err := Fresh[int](c, "src"); err == nil {
+		t.Error("no error for a value older than TTL")
+	}
+	if value, _ := Cached[int](c, "src"); value != 1 {
+		t.Errorf("Cached = %v, want the stale value 1", value)
+	}
+}
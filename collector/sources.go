@@ -0,0 +1,87 @@
+package collector
+
+import (
+	"context"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+// Имена стандартных источников и типы их значений
+const (
+	SourceSystem         = "system"         // types.SystemMetrics
+	SourceStatus         = "status"         // types.AsteriskProcess
+	SourcePeers          = "peers"          // []types.SIPPeer
+	SourceRegistrations  = "registrations"  // []types.SIPRegistration
+	SourceChannels       = "channels"       // []types.ChannelInfo
+	SourceCallQuality    = "call_quality"   // []types.CallQuality
+	SourceLifecycle      = "lifecycle"      // types.LifecycleSnapshot
+	SourceTaskprocessors = "taskprocessors" // types.TaskprocessorReport
+	SourceNetwork        = "network"        // types.NetworkHealth
+)
+
+// Регистрации меняются редко, их достаточно обновлять раз в несколько интервалов.
+// Перезапуски определяются по времени запуска Asterisk, поэтому снимок для
+// хронологии тоже можно снимать реже: он запускает systemctl.
+const (
+	registrationsFactor = 6
+	lifecycleFactor     = 6
+)
+
+// sourceTimeout ограничивает одно обновление источника
+const sourceTimeout = 30 * time.Second
+
+// Monitor определяет данные, которые коллектор получает от монитора
+type Monitor interface {
+	GetSystemMetrics(ctx context.Context) (types.SystemMetrics, error)
+	DiscoverAsterisk(ctx context.Context) types.AsteriskProcess
+	GetSIPPeers(ctx context.Context) ([]types.SIPPeer, error)
+	GetSIPRegistrations(ctx context.Context) ([]types.SIPRegistration, error)
+	GetActiveChannels(ctx context.Context) ([]types.ChannelInfo, error)
+	GetCallQuality(ctx context.Context) ([]types.CallQuality, error)
+	LifecycleSnapshot(ctx context.Context) types.LifecycleSnapshot
+	GetTaskprocessors(ctx context.Context) types.TaskprocessorReport
+	GetNetworkHealth() types.NetworkHealth
+}
+
+// Sources возвращает стандартные источники монитора, обновляемые каждые interval.
+// Ошибка команды Asterisk CLI возвращается как ошибка источника, в кэше остается
+// последнее удачное значение. Число пиров в системных метриках источник не
+// заполняет: чтобы не запускать "sip show peers" дважды, его считают по
+// источнику пиров (см. monitor.CountPeers).
+func Sources(mon Monitor, interval time.Duration) []Source {
+	source := func(name string, every time.Duration, fetch func(ctx context.Context) (any, error)) Source {
+		return Source{Name: name, Interval: every, Timeout: sourceTimeout, Fetch: fetch}
+	}
+
+	return []Source{
+		source(SourceSystem, interval, func(ctx context.Context) (any, error) {
+			return mon.GetSystemMetrics(ctx)
+		}),
+		// Проверка консоли должна укладываться в срок обновления
+		source(SourceStatus, interval, func(ctx context.Context) (any, error) {
+			return mon.DiscoverAsterisk(ctx), nil
+		}),
+		source(SourcePeers, interval, func(ctx context.Context) (any, error) {
+			return mon.GetSIPPeers(ctx)
+		}),
+		source(SourceChannels, interval, func(ctx context.Context) (any, error) {
+			return mon.GetActiveChannels(ctx)
+		}),
+		source(SourceCallQuality, interval, func(ctx context.Context) (any, error) {
+			return mon.GetCallQuality(ctx)
+		}),
+		source(SourceRegistrations, interval*registrationsFactor, func(ctx context.Context) (any, error) {
+			return mon.GetSIPRegistrations(ctx)
+		}),
+		source(SourceLifecycle, interval*lifecycleFactor, func(ctx context.Context) (any, error) {
+			return mon.LifecycleSnapshot(ctx), nil
+		}),
+		source(SourceTaskprocessors, interval, func(ctx context.Context) (any, error) {
+			return mon.GetTaskprocessors(ctx), nil
+		}),
+		source(SourceNetwork, interval, func(ctx context.Context) (any, error) {
+			return mon.GetNetworkHealth(), nil
+		}),
+	}
+}
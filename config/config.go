@@ -1,15 +1,22 @@
 package config
 
 import (
+    "errors"
+    "fmt"
     "os"
     "path/filepath"
+    "sort"
+    "strings"
+    "sync/atomic"
 
     "asterisk-monitor/types"
     "gopkg.in/ini.v1"
 )
 
 type ConfigManager struct {
-    config     *types.Config
+    // Конфигурация не меняется на месте: Load и Update подменяют ее целиком,
+    // поэтому задачи фонового режима читают через Get согласованный снимок
+    config     atomic.Pointer[types.Config]
     configPath string
 }
 
@@ -17,12 +24,13 @@ func NewConfigManager() *ConfigManager {
     homeDir, _ := os.UserHomeDir()
     configPath := filepath.Join(homeDir, ".asterisk-monitor", "config.ini")
     
-    return &ConfigManager{
-        config:     &types.Config{},
-        configPath: configPath,
-    }
+    cm := &ConfigManager{configPath: configPath}
+    cm.config.Store(&types.Config{})
+    return cm
 }
 
+// Load читает файл в новую конфигурацию и подменяет текущую, только если файл
+// прочитан и проверен. При ошибке остается прежняя конфигурация.
 func (cm *ConfigManager) Load() error {
     if _, err := os.Stat(cm.configPath); os.IsNotExist(err) {
         return cm.CreateDefault()
@@ -33,7 +41,220 @@ func (cm *ConfigManager) Load() error {
         return err
     }
     
-    return cfg.MapTo(cm.config)
+    // Значения по умолчанию для секций, отсутствующих в старых файлах конфигурации
+    next := &types.Config{}
+    applyDefaults(next)
+    if err := cfg.MapTo(next); err != nil {
+        return err
+    }
+    
+    next.Alerts, err = loadAlertRules(cfg, defaultAlertRules())
+    if err != nil {
+        return err
+    }
+    
+    next.Notifiers, err = loadNotifiers(cfg)
+    if err != nil {
+        return err
+    }
+    
+    if err := validate(next); err != nil {
+        return err
+    }
+    cm.config.Store(next)
+    return nil
+}
+
+// validate проверяет значения, с которыми планировщик и получатели работать не
+// могут. Ошибки в правилах оповещений сообщает движок оповещений.
+func validate(config *types.Config) error {
+    var errs []error
+    nonNegative := func(name string, value int) {
+        if value < 0 {
+            errs = append(errs, fmt.Errorf("%s must not be negative: %d", name, value))
+        }
+    }
+    
+    nonNegative("monitoring.refresh_interval", config.Monitoring.RefreshInterval)
+    nonNegative("monitoring.log_retention", config.Monitoring.LogRetention)
+    nonNegative("daemon.metrics_interval", config.Daemon.MetricsInterval)
+    nonNegative("daemon.quick_check_interval", config.Daemon.QuickCheckInterval)
+    nonNegative("daemon.full_check_interval", config.Daemon.FullCheckInterval)
+    nonNegative("daemon.security_interval", config.Daemon.SecurityInterval)
+    nonNegative("exporter.interval", config.Exporter.Interval)
+    nonNegative("exporter.checks_interval", config.Exporter.ChecksInterval)
+    nonNegative("push.interval", config.Push.Interval)
+    nonNegative("push.batch_size", config.Push.BatchSize)
+    nonNegative("push.buffer_size", config.Push.BufferSize)
+    nonNegative("watchdog.interval", config.Watchdog.Interval)
+    nonNegative("watchdog.probe_timeout", config.Watchdog.ProbeTimeout)
+    
+    return errors.Join(errs...)
+}
+
+// Префиксы именованных секций
+const (
+    alertSectionPrefix    = "alert."
+    notifierSectionPrefix = "notifier."
+)
+
+// alertEnabledKey - ключ секции [alert.<name>], которым отключается правило
+const alertEnabledKey = "enabled"
+
+// loadAlertRules дополняет правила по умолчанию секциями [alert.<name>].
+// Секция с именем правила по умолчанию меняет только заданные в ней ключи,
+// enabled = false удаляет правило. Новые правила идут после стандартных по имени.
+func loadAlertRules(cfg *ini.File, defaults []types.AlertRule) ([]types.AlertRule, error) {
+    rules := make(map[string]types.AlertRule, len(defaults))
+    var names []string
+    for _, rule := range defaults {
+        rules[rule.Name] = rule
+        names = append(names, rule.Name)
+    }
+    
+    var added []string
+    disabled := make(map[string]bool)
+    for _, section := range cfg.Sections() {
+        if !strings.HasPrefix(section.Name(), alertSectionPrefix) {
+            continue
+        }
+        name := strings.TrimPrefix(section.Name(), alertSectionPrefix)
+        
+        rule, ok := rules[name]
+        if !ok {
+            rule = types.AlertRule{Severity: "warning"}
+            added = append(added, name)
+        }
+        if err := section.MapTo(&rule); err != nil {
+            return nil, err
+        }
+        rule.Name = name
+        rules[name] = rule
+        disabled[name] = !section.Key(alertEnabledKey).MustBool(true)
+    }
+    
+    sort.Strings(added)
+    var result []types.AlertRule
+    for _, name := range append(names, added...) {
+        if !disabled[name] {
+            result = append(result, rules[name])
+        }
+    }
+    return result, nil
+}
+
+// loadNotifiers читает каналы уведомлений из секций [notifier.<name>]
+func loadNotifiers(cfg *ini.File) ([]types.NotifierConfig, error) {
+    var notifiers []types.NotifierConfig
+    
+    for _, section := range cfg.Sections() {
+        if !strings.HasPrefix(section.Name(), notifierSectionPrefix) {
+            continue
+        }
+        
+        notifier := types.NotifierConfig{Enabled: true, Severities: "warning,critical"}
+        if err := section.MapTo(&notifier); err != nil {
+            return nil, err
+        }
+        notifier.Name = strings.TrimPrefix(section.Name(), notifierSectionPrefix)
+        notifiers = append(notifiers, notifier)
+    }
+    
+    sort.Slice(notifiers, func(i, j int) bool {
+        return notifiers[i].Name < notifiers[j].Name
+    })
+    return notifiers, nil
+}
+
+// applyDefaults заполняет значения по умолчанию для дополнительных секций
+func applyDefaults(config *types.Config) {
+    config.Asterisk.RunDir = "/var/run/asterisk"
+    config.Asterisk.Transport = "socket"
+    config.Asterisk.DumpDirs = "/tmp,/var/lib/asterisk,/var/spool/asterisk,/var/lib/systemd/coredump"
+    config.Asterisk.DataDirs = "/var/spool/asterisk,/var/spool/asterisk/monitor,/var/log/asterisk,/var/lib/asterisk"
+    
+    config.Monitoring.MountPoints = "/"
+    
+    config.Exporter = types.ExporterConfig{
+        Listen:         ":9260",
+        Interval:       15,
+        ChecksInterval: 60,
+    }
+    
+    config.Daemon = types.DaemonConfig{
+        MetricsInterval:    60,
+        QuickCheckInterval: 300,
+        FullCheckInterval:  3600,
+        SecurityInterval:   86400,
+    }
+    
+    config.Push = types.PushConfig{
+        Format:     "influx",
+        Endpoint:   "udp://127.0.0.1:8089",
+        Prefix:     "asterisk",
+        Interval:   10,
+        BatchSize:  500,
+        BufferSize: 10000,
+    }
+    
+    config.Notifications = types.NotificationsConfig{
+        GroupWait:      30,
+        RepeatInterval: 3600,
+        RateLimit:      20,
+    }
+    
+    config.Anomaly = types.AnomalyConfig{
+        Enabled:        true,
+        LearnWeeks:     4,
+        MinSamples:     24,
+        MinExpected:    2,
+        LeakWindow:     6,
+        ForecastWindow: 48,
+    }
+    
+    // Диагностика запускает много команд, поэтому обновляется реже остальных вкладок
+    config.Refresh = types.RefreshConfig{
+        Diagnostics: 60,
+    }
+    
+    // Сторож только пишет журнал, перезапуск включается явно
+    config.Watchdog = types.WatchdogConfig{
+        Interval:      30,
+        ProbeTimeout:  5,
+        ProbeAMI:      true,
+        Failures:      3,
+        Action:        "none",
+        Unit:          "asterisk",
+        MaxAttempts:   3,
+        AttemptWindow: 3600,
+        Cooldown:      600,
+        Capture:       true,
+    }
+    
+    config.Alerts = defaultAlertRules()
+}
+
+// defaultAlertRules возвращает правила по умолчанию. Они повторяют прежние
+// встроенные пороги и добавляют оповещения об аномалиях объема вызовов и
+// ресурсах процесса Asterisk.
+func defaultAlertRules() []types.AlertRule {
+    return []types.AlertRule{
+        {Name: "high_active_calls", Metric: types.MetricActiveCalls, Op: ">", Threshold: 10, Severity: "warning"},
+        {Name: "high_cpu", Metric: types.MetricCPUUsage, Op: ">", Threshold: 80, For: 60, Hysteresis: 5, Severity: "warning"},
+        {Name: "asterisk_down", Metric: types.MetricAsteriskUp, Op: "==", Threshold: 0, Severity: "critical"},
+        {Name: "call_volume_spike", Metric: types.MetricCallVolumeScore, Op: ">", Threshold: 3, For: 300, Hysteresis: 1, Severity: "warning"},
+        {Name: "call_volume_drop", Metric: types.MetricCallVolumeScore, Op: "<", Threshold: -3, For: 300, Hysteresis: 1, Severity: "warning"},
+        {Name: "call_volume_zero", Metric: types.MetricCallVolumeZero, Op: "==", Threshold: 1, For: 300, Severity: "critical"},
+        {Name: "asterisk_fd_limit", Metric: types.MetricProcessFDUsage, Op: ">", Threshold: 80, For: 60, Hysteresis: 5, Severity: "warning"},
+        {Name: "asterisk_memory_leak", Metric: types.MetricRSSGrowth, Op: ">", Threshold: 10, For: 1800, Severity: "warning"},
+        {Name: "asterisk_unexpected_restart", Metric: types.MetricUnexpectedRestarts, Op: ">", Threshold: 0, Severity: "critical"},
+        {Name: "asterisk_taskprocessor_backlog", Metric: types.MetricTaskprocessorsOverHighWater, Op: ">", Threshold: 0, For: 60, Severity: "warning"},
+        {Name: "asterisk_thread_growth", Metric: types.MetricThreadGrowth, Op: ">", Threshold: 10, For: 1800, Severity: "warning"},
+        {Name: "udp_receive_buffer_errors", Metric: types.MetricUDPRcvbufErrors, Op: ">", Threshold: 0, For: 60, Severity: "warning"},
+        {Name: "asterisk_udp_drops", Metric: types.MetricAsteriskUDPDrops, Op: ">", Threshold: 0, For: 60, Severity: "warning"},
+        // Горизонт прогноза заполнения диска - порог правила, дни
+        {Name: "disk_full_forecast", Metric: types.MetricMountDaysUntilFull, Op: "<", Threshold: 7, For: 1800, Severity: "warning"},
+    }
 }
 
 func (cm *ConfigManager) Save() error {
@@ -42,36 +263,121 @@ func (cm *ConfigManager) Save() error {
         return err
     }
     
+    current := cm.Get()
     cfg := ini.Empty()
-    if err := cfg.ReflectFrom(cm.config); err != nil {
+    if err := cfg.ReflectFrom(current); err != nil {
         return err
     }
     
+    enabled := make(map[string]bool, len(current.Alerts))
+    for _, rule := range current.Alerts {
+        enabled[rule.Name] = true
+        section, err := cfg.NewSection(alertSectionPrefix + rule.Name)
+        if err != nil {
+            return err
+        }
+        if err := section.ReflectFrom(&rule); err != nil {
+            return err
+        }
+    }
+    // Удаленное правило по умолчанию иначе вернулось бы при следующей загрузке
+    for _, rule := range defaultAlertRules() {
+        if enabled[rule.Name] {
+            continue
+        }
+        section, err := cfg.NewSection(alertSectionPrefix + rule.Name)
+        if err != nil {
+            return err
+        }
+        if _, err := section.NewKey(alertEnabledKey, "false"); err != nil {
+            return err
+        }
+    }
+    
+    for _, notifier := range current.Notifiers {
+        section, err := cfg.NewSection(notifierSectionPrefix + notifier.Name)
+        if err != nil {
+            return err
+        }
+        if err := section.ReflectFrom(&notifier); err != nil {
+            return err
+        }
+    }
+    
     return cfg.SaveTo(cm.configPath)
 }
 
 func (cm *ConfigManager) CreateDefault() error {
-    cm.config.Asterisk.Host = "localhost"
-    cm.config.Asterisk.AMIPort = "5038"
-    cm.config.Asterisk.Username = "admin"
-    cm.config.Asterisk.Password = "amp111"
+    config := &types.Config{}
+    config.Asterisk.Host = "localhost"
+    config.Asterisk.AMIPort = "5038"
+    config.Asterisk.Username = "admin"
+    config.Asterisk.Password = "amp111"
+    
+    config.Monitoring.RefreshInterval = 5
+    config.Monitoring.EnableAlerts = true
+    config.Monitoring.LogRetention = 30
     
-    cm.config.Monitoring.RefreshInterval = 5
-    cm.config.Monitoring.EnableAlerts = true
-    cm.config.Monitoring.LogRetention = 30
+    config.Security.CheckFirewall = true
+    config.Security.CheckPasswords = true
+    config.Security.CheckSSL = true
     
-    cm.config.Security.CheckFirewall = true
-    cm.config.Security.CheckPasswords = true
-    cm.config.Security.CheckSSL = true
+    applyDefaults(config)
+    cm.config.Store(config)
     
     return cm.Save()
 }
 
+// DaemonStateFile возвращает путь к файлу состояния фонового режима
+func (cm *ConfigManager) DaemonStateFile() string {
+    if file := cm.Get().Daemon.StateFile; file != "" {
+        return file
+    }
+    return filepath.Join(filepath.Dir(cm.configPath), "daemon-state.json")
+}
+
+// AlertHistoryFile возвращает путь к журналу оповещений
+func (cm *ConfigManager) AlertHistoryFile() string {
+    return filepath.Join(filepath.Dir(cm.configPath), "alert-history.jsonl")
+}
+
+// AlertStateFile возвращает путь к файлу тишин и подтверждений оповещений
+func (cm *ConfigManager) AlertStateFile() string {
+    return filepath.Join(filepath.Dir(cm.configPath), "alert-state.json")
+}
+
+// RestartHistoryFile возвращает путь к хронологии запусков и сбоев Asterisk
+func (cm *ConfigManager) RestartHistoryFile() string {
+    return filepath.Join(filepath.Dir(cm.configPath), "restart-history.jsonl")
+}
+
+// RecoveryLogFile возвращает путь к журналу сторожа зависаний
+func (cm *ConfigManager) RecoveryLogFile() string {
+    return filepath.Join(filepath.Dir(cm.configPath), "recovery-log.jsonl")
+}
+
+// HangReportDir возвращает каталог снимков состояния зависшего Asterisk
+func (cm *ConfigManager) HangReportDir() string {
+    return filepath.Join(filepath.Dir(cm.configPath), "hang-reports")
+}
+
+// ModuleBaselineFile возвращает путь к базовой линии модулей Asterisk
+func (cm *ConfigManager) ModuleBaselineFile() string {
+    return filepath.Join(filepath.Dir(cm.configPath), "module-baseline.json")
+}
+
+// DataDir возвращает каталог хранилища временных рядов
+func (cm *ConfigManager) DataDir() string {
+    return filepath.Join(filepath.Dir(cm.configPath), "data")
+}
+
+// Get возвращает текущую конфигурацию. Ее нельзя менять на месте: для
+// изменения нужно передать копию в Update.
 func (cm *ConfigManager) Get() *types.Config {
-    return cm.config
+    return cm.config.Load()
 }
 
 func (cm *ConfigManager) Update(newConfig *types.Config) error {
-    cm.config = newConfig
+    cm.config.Store(newConfig)
     return cm.Save()
 }
\ No newline at end of file
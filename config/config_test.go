@@ -0,0 +1,65 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testManager(t *testing.T, content string) *ConfigManager {
+	t.Helper()
+	cm := NewConfigManager()
+	cm.configPath = filepath.Join(t.TempDir(), "config.ini")
+	writeConfig(t, cm, content)
+	return cm
+}
+
+func writeConfig(t *testing.T, cm *ConfigManager, content string) {
+	t.Helper()
+	if err := os.WriteFile(cm.configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadReplacesConfig(t *testing.T) {
+	cm := testManager(t, "[daemon]\nmetrics_interval = 30\n")
+	if err := cm.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	before := cm.Get()
+	if before.Daemon.MetricsInterval != 30 || before.Daemon.QuickCheckInterval != 300 {
+		t.Fatalf("daemon = %+v, want metrics 30 and default quick checks", before.Daemon)
+	}
+
+	// Перечитывание создает новую конфигурацию, прежний снимок не меняется
+	writeConfig(t, cm, "[daemon]\nmetrics_interval = 10\n")
+	if err := cm.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := cm.Get().Daemon.MetricsInterval; got != 10 {
+		t.Errorf("metrics_interval = %d after reload, want 10", got)
+	}
+	if before.Daemon.MetricsInterval != 30 {
+		t.Errorf("previous snapshot changed to %d", before.Daemon.MetricsInterval)
+	}
+}
+
+func TestLoadKeepsConfigOnError(t *testing.T) {
+	cm := testManager(t, "[daemon]\nmetrics_interval = 30\n")
+	if err := cm.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	for name, content := range map[string]string{
+		"negative interval": "[daemon]\nmetrics_interval = -5\n",
+		"unclosed section":  "[daemon\nmetrics_interval = 10\n",
+	} {
+		writeConfig(t, cm, content)
+		if err := cm.Load(); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+		if got := cm.Get().Daemon.MetricsInterval; got != 30 {
+			t.Errorf("%s: metrics_interval = %d, want the previous 30", name, got)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package console
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultRunDir - каталог astrundir по умолчанию, в нем Asterisk создает asterisk.ctl
+const DefaultRunDir = "/var/run/asterisk"
+
+const (
+	socketName  = "asterisk.ctl"
+	dialTimeout = 5 * time.Second
+
+	// Вывод команды не отделяется от следующего, поэтому после каждой команды
+	// отправляется несуществующая команда с уникальным именем. Ответ на нее
+	// "No such command '<маркер>'" означает, что вывод команды закончился.
+	endMarker = "asterisk-monitor-end-"
+
+	// Строки журнала в старых версиях Asterisk начинаются с этого байта
+	verbosePrefix = 127
+)
+
+// ErrClosed возвращается командами закрытого клиента
+var ErrClosed = errors.New("console client closed")
+
+// SocketPath возвращает путь к управляющему сокету в каталоге runDir
+func SocketPath(runDir string) string {
+	if runDir == "" {
+		runDir = DefaultRunDir
+	}
+	return filepath.Join(runDir, socketName)
+}
+
+// Banner - приветствие Asterisk при подключении
+type Banner struct {
+	Host    string
+	PID     int
+	Version string
+}
+
+// parseBanner разбирает приветствие вида "host/pid/version"
+func parseBanner(data string) (Banner, error) {
+	data = strings.Trim(data, "\x00\r\n ")
+	host, rest, ok := strings.Cut(data, "/")
+	if !ok {
+		return Banner{}, fmt.Errorf("unexpected banner %q", data)
+	}
+	pid, version, _ := strings.Cut(rest, "/")
+	n, err := strconv.Atoi(pid)
+	if err != nil {
+		return Banner{}, fmt.Errorf("unexpected banner %q", data)
+	}
+	return Banner{Host: host, PID: n, Version: version}, nil
+}
+
+// Client выполняет команды Asterisk CLI через управляющий сокет, как asterisk -rx,
+// но без запуска процесса на каждую команду. Соединение держится открытым,
+// команды выполняются по одной. После перезапуска Asterisk клиент подключается заново.
+type Client struct {
+	path   string
+	sem    chan struct{} // занят, пока выполняется команда
+	conn   net.Conn
+	reader *bufio.Reader
+	banner Banner
+	seq    uint64
+	closed bool
+}
+
+// New создает клиент сокета path. Подключение выполняется при первой команде.
+func New(path string) *Client {
+	return &Client{path: path, sem: make(chan struct{}, 1)}
+}
+
+// Path возвращает путь к сокету
+func (c *Client) Path() string {
+	return c.path
+}
+
+// Banner возвращает приветствие Asterisk текущего соединения
+func (c *Client) Banner() (Banner, bool) {
+	if err := c.acquire(context.Background()); err != nil {
+		return Banner{}, false
+	}
+	defer c.release()
+	return c.banner, c.conn != nil
+}
+
+// Command выполняет команду CLI и возвращает ее вывод. Если соединение
+// оборвалось (Asterisk перезапущен), команда повторяется на новом соединении.
+func (c *Client) Command(ctx context.Context, command string) (string, error) {
+	if command == "" || strings.ContainsAny(command, "\x00\r\n") {
+		return "", fmt.Errorf("invalid command %q", command)
+	}
+	if err := c.acquire(ctx); err != nil {
+		return "", err
+	}
+	defer c.release()
+
+	if c.closed {
+		return "", ErrClosed
+	}
+
+	reused := c.conn != nil
+	output, err := c.roundTrip(ctx, command)
+	var stale *staleError
+	if err != nil && reused && errors.As(err, &stale) && ctx.Err() == nil {
+		output, err = c.roundTrip(ctx, command)
+	}
+	return output, err
+}
+
+// Close закрывает соединение. Команды после Close возвращают ErrClosed.
+func (c *Client) Close() error {
+	if err := c.acquire(context.Background()); err != nil {
+		return err
+	}
+	defer c.release()
+
+	c.closed = true
+	c.drop()
+	return nil
+}
+
+func (c *Client) acquire(ctx context.Context) error {
+	select {
+	case c.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) release() {
+	<-c.sem
+}
+
+// staleError - соединение закрыто Asterisk до ответа на команду
+type staleError struct {
+	err error
+}
+
+func (e *staleError) Error() string { return e.err.Error() }
+func (e *staleError) Unwrap() error { return e.err }
+
+// roundTrip отправляет команду и читает ее вывод до ответа на маркер
+func (c *Client) roundTrip(ctx context.Context, command string) (string, error) {
+	if c.conn == nil {
+		if err := c.connect(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	stop := c.watch(ctx)
+	defer stop()
+
+	output, read, err := c.exchange(command)
+	if err != nil {
+		c.drop()
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("%s: %w", c.path, ctx.Err())
+		}
+		err = fmt.Errorf("%s: %w", c.path, err)
+		// Команда не дошла до Asterisk, ее можно повторить
+		if read == 0 {
+			return "", &staleError{err: err}
+		}
+		return "", err
+	}
+	return output, nil
+}
+
+// exchange пишет команду с маркером и возвращает вывод и число прочитанных строк
+func (c *Client) exchange(command string) (string, int, error) {
+	c.seq++
+	marker := fmt.Sprintf("'%s%d'", endMarker, c.seq)
+	// Asterisk разделяет команды, пришедшие одним блоком, нулевым байтом
+	request := command + "\x00" + strings.Trim(marker, "'") + "\x00"
+	if _, err := c.conn.Write([]byte(request)); err != nil {
+		return "", 0, err
+	}
+
+	var output strings.Builder
+	read := 0
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			return "", read, err
+		}
+		read++
+
+		line = strings.ReplaceAll(line, "\x00", "")
+		if strings.Contains(line, marker) {
+			return strings.TrimRight(output.String(), "\n"), read, nil
+		}
+		if line != "" && line[0] == verbosePrefix {
+			continue
+		}
+		output.WriteString(strings.TrimRight(line, "\r\n") + "\n")
+	}
+}
+
+// watch переносит срок и отмену ctx на соединение
+func (c *Client) watch(ctx context.Context) func() {
+	conn := c.conn
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0))
+	})
+	return func() { stop() }
+}
+
+// connect подключается к сокету, читает приветствие и отключает вывод журнала
+// в это соединение, чтобы он не смешивался с выводом команд
+func (c *Client) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: dialTimeout}
+	conn, err := dialer.DialContext(ctx, "unix", c.path)
+	if err != nil {
+		return err
+	}
+
+	c.conn = conn
+	stop := c.watch(ctx)
+	defer stop()
+	if _, ok := ctx.Deadline(); !ok {
+		conn.SetReadDeadline(time.Now().Add(dialTimeout))
+	}
+
+	// Приветствие - первая строка до любых команд, она может прийти по частям
+	c.reader = bufio.NewReader(conn)
+	line, err := c.reader.ReadString('\n')
+	if err == nil {
+		c.banner, err = parseBanner(line)
+	}
+	if err == nil {
+		_, _, err = c.exchange("logger mute silent")
+	}
+	if err != nil {
+		c.drop()
+		return fmt.Errorf("%s: %w", c.path, err)
+	}
+	return nil
+}
+
+// drop закрывает соединение, следующая команда подключится заново
+func (c *Client) drop() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.conn = nil
+	c.reader = nil
+	c.banner = Banner{}
+}
@@ -0,0 +1,309 @@
+package console
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeAsterisk - управляющий сокет, который отвечает как Asterisk: шлет
+// приветствие, выполняет команды, разделенные нулевым байтом, и отвечает
+// "No such command" на маркер клиента
+type fakeAsterisk struct {
+	path string
+	// banner отправляется по частям, чтобы клиент не полагался на один Read
+	banner []string
+	// commands возвращает вывод команды. drop закрывает соединение после
+	// вывода, как при падении Asterisk посреди команды
+	commands map[string]func() (output string, drop bool)
+
+	mu       sync.Mutex
+	conns    []net.Conn
+	accepted int
+	received []string
+}
+
+// startFakeAsterisk запускает сервер с приветствием banner, по умолчанию
+// "pbx1/4242/18.20.0", разделенным на две части
+func startFakeAsterisk(t *testing.T, banner ...string) *fakeAsterisk {
+	t.Helper()
+	if len(banner) == 0 {
+		banner = []string{"pbx1/42", "42/18.20.0\n"}
+	}
+	fake := &fakeAsterisk{
+		path:     filepath.Join(t.TempDir(), socketName),
+		banner:   banner,
+		commands: make(map[string]func() (string, bool)),
+	}
+	listener, err := net.Listen("unix", fake.path)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() {
+		listener.Close()
+		fake.dropConns()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			fake.mu.Lock()
+			fake.conns = append(fake.conns, conn)
+			fake.accepted++
+			fake.mu.Unlock()
+			go fake.serve(conn)
+		}
+	}()
+	return fake
+}
+
+func (f *fakeAsterisk) handle(command, output string) {
+	f.handleFunc(command, func() (string, bool) { return output, false })
+}
+
+func (f *fakeAsterisk) handleFunc(command string, run func() (string, bool)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands[command] = run
+}
+
+func (f *fakeAsterisk) serve(conn net.Conn) {
+	defer conn.Close()
+	for _, part := range f.banner {
+		if _, err := conn.Write([]byte(part)); err != nil {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	reader := bufio.NewReader(conn)
+	for {
+		command, err := reader.ReadString(0)
+		if err != nil {
+			return
+		}
+		command = strings.TrimSuffix(command, "\x00")
+
+		f.mu.Lock()
+		f.received = append(f.received, command)
+		run, ok := f.commands[command]
+		f.mu.Unlock()
+
+		var reply string
+		drop := false
+		switch {
+		case ok:
+			reply, drop = run()
+		case command == "logger mute silent":
+		default:
+			reply = "No such command '" + command + "' (type 'core show help " + command + "' for other commands)\n"
+		}
+		if _, err := conn.Write([]byte(reply)); err != nil || drop {
+			return
+		}
+	}
+}
+
+// dropConns закрывает все соединения, как при перезапуске Asterisk
+func (f *fakeAsterisk) dropConns() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, conn := range f.conns {
+		conn.Close()
+	}
+	f.conns = nil
+}
+
+func (f *fakeAsterisk) connections() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.accepted
+}
+
+func testContext(t *testing.T) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func newTestClient(t *testing.T, fake *fakeAsterisk) *Client {
+	t.Helper()
+	client := New(fake.path)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestParseBanner(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    Banner
+		wantErr bool
+	}{
+		{data: "pbx1/4242/18.20.0\n", want: Banner{Host: "pbx1", PID: 4242, Version: "18.20.0"}},
+		{data: "pbx1/4242/GIT-master-abc/1\x00", want: Banner{Host: "pbx1", PID: 4242, Version: "GIT-master-abc/1"}},
+		{data: "pbx1/4242", want: Banner{Host: "pbx1", PID: 4242}},
+		{data: "pbx1", wantErr: true},
+		{data: "pbx1/pid/18.20.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseBanner(tt.data)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseBanner(%q) error = %v, wantErr %v", tt.data, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseBanner(%q) = %+v, want %+v", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestConnectReadsBannerLine(t *testing.T) {
+	fake := startFakeAsterisk(t)
+	fake.handle("core show uptime", "System uptime: 1 hour\n")
+	client := newTestClient(t, fake)
+
+	if _, ok := client.Banner(); ok {
+		t.Error("banner reported before the first command")
+	}
+	if _, err := client.Command(testContext(t), "core show uptime"); err != nil {
+		t.Fatalf("Command: %v", err)
+	}
+
+	// Приветствие пришло двумя частями, клиент дочитал его до конца строки
+	want := Banner{Host: "pbx1", PID: 4242, Version: "18.20.0"}
+	if got, ok := client.Banner(); !ok || got != want {
+		t.Errorf("Banner = %+v, %v, want %+v", got, ok, want)
+	}
+
+	fake.mu.Lock()
+	received := append([]string(nil), fake.received...)
+	fake.mu.Unlock()
+	if len(received) == 0 || received[0] != "logger mute silent" {
+		t.Errorf("commands = %q, want the log muted first", received)
+	}
+}
+
+func TestConnectRejectsBadBanner(t *testing.T) {
+	fake := startFakeAsterisk(t, "garbage\n")
+	client := newTestClient(t, fake)
+
+	if _, err := client.Command(testContext(t), "core show uptime"); err == nil || !strings.Contains(err.Error(), "unexpected banner") {
+		t.Fatalf("Command = %v, want a banner error", err)
+	}
+	if _, ok := client.Banner(); ok {
+		t.Error("connection kept after a bad banner")
+	}
+}
+
+func TestCommandFraming(t *testing.T) {
+	fake := startFakeAsterisk(t)
+	fake.handle("sip show peers", "Name/username  Host\n100/100        10.0.0.5\r\n1 sip peers\n")
+	fake.handle("core show calls", "")
+	// Строки журнала старых версий начинаются с байта 127 и в вывод не попадают
+	fake.handle("module reload", "\x7f  -- Reloading module 'chan_sip.so'\nModule reloaded\n\x7f[Oct 18 12:00:00] NOTICE: done\n")
+	client := newTestClient(t, fake)
+	ctx := testContext(t)
+
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"sip show peers", "Name/username  Host\n100/100        10.0.0.5\n1 sip peers"},
+		{"core show calls", ""},
+		{"module reload", "Module reloaded"},
+		{"core show bogus", "No such command 'core show bogus' (type 'core show help core show bogus' for other commands)"},
+		// Вывод следующей команды не смешивается с предыдущей
+		{"sip show peers", "Name/username  Host\n100/100        10.0.0.5\n1 sip peers"},
+	}
+	for _, tt := range tests {
+		got, err := client.Command(ctx, tt.command)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.command, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+	if n := fake.connections(); n != 1 {
+		t.Errorf("connections = %d, want one reused connection", n)
+	}
+
+	if _, err := client.Command(ctx, "core show calls\x00core stop now"); err == nil {
+		t.Error("no error for a command with a NUL byte")
+	}
+}
+
+func TestCommandRetriesStaleConnection(t *testing.T) {
+	fake := startFakeAsterisk(t)
+	fake.handle("core show uptime", "System uptime: 1 hour\n")
+	client := newTestClient(t, fake)
+	ctx := testContext(t)
+
+	if _, err := client.Command(ctx, "core show uptime"); err != nil {
+		t.Fatalf("Command: %v", err)
+	}
+
+	// Asterisk перезапущен: команда на старом соединении повторяется на новом
+	fake.dropConns()
+	got, err := client.Command(ctx, "core show uptime")
+	if err != nil || got != "System uptime: 1 hour" {
+		t.Fatalf("Command after restart = %q, %v", got, err)
+	}
+	if n := fake.connections(); n != 2 {
+		t.Errorf("connections = %d, want 2", n)
+	}
+}
+
+func TestCommandDroppedMidOutputIsNotRetried(t *testing.T) {
+	fake := startFakeAsterisk(t)
+	fake.handle("core show uptime", "System uptime: 1 hour\n")
+	var runs atomic.Int32
+	fake.handleFunc("core restart now", func() (string, bool) {
+		runs.Add(1)
+		return "Restarting\n", true
+	})
+	client := newTestClient(t, fake)
+	ctx := testContext(t)
+
+	if _, err := client.Command(ctx, "core show uptime"); err != nil {
+		t.Fatalf("Command: %v", err)
+	}
+
+	// Часть вывода уже прочитана: команда дошла до Asterisk и не повторяется
+	_, err := client.Command(ctx, "core restart now")
+	var stale *staleError
+	if err == nil || errors.As(err, &stale) {
+		t.Fatalf("Command = %v, want a non-retried error", err)
+	}
+	if n := runs.Load(); n != 1 {
+		t.Errorf("command ran %d times, want 1", n)
+	}
+
+	// Следующая команда подключается заново
+	if _, err := client.Command(ctx, "core show uptime"); err != nil {
+		t.Errorf("Command after drop: %v", err)
+	}
+	if n := fake.connections(); n != 2 {
+		t.Errorf("connections = %d, want 2", n)
+	}
+}
+
+func TestClosedClient(t *testing.T) {
+	fake := startFakeAsterisk(t)
+	client := New(fake.path)
+	client.Close()
+	if _, err := client.Command(testContext(t), "core show uptime"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Command = %v, want ErrClosed", err)
+	}
+}
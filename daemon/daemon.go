@@ -0,0 +1,542 @@
+package daemon
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"asterisk-monitor/alerts"
+	"asterisk-monitor/anomaly"
+	"asterisk-monitor/lifecycle"
+	monitor "asterisk-monitor/monitors"
+	"asterisk-monitor/notify"
+	"asterisk-monitor/push"
+	"asterisk-monitor/storage"
+	"asterisk-monitor/types"
+	"asterisk-monitor/watchdog"
+)
+
+// ConfigSource определяет доступ к конфигурации с возможностью перечитать файл
+type ConfigSource interface {
+	Get() *types.Config
+	Load() error
+	DaemonStateFile() string
+	AlertHistoryFile() string
+	AlertStateFile() string
+	RestartHistoryFile() string
+	RecoveryLogFile() string
+	HangReportDir() string
+	ModuleBaselineFile() string
+}
+
+// Daemon выполняет сбор метрик, диагностику и сканирование безопасности
+// по расписанию без терминала
+type Daemon struct {
+	config  ConfigSource
+	monitor *monitor.LinuxMonitor
+	store   *storage.Store
+	jobs    []*job
+
+	// mu защищает state и получатель метрик: задачи выполняются параллельно,
+	// а конфигурация перечитывается в основном цикле
+	mu    sync.Mutex
+	state State
+
+	sink       *push.Sink
+	sinkConfig types.PushConfig
+	calls      *push.CallTracker
+	alerts     *alerts.Engine
+	anomaly    *anomaly.Detector
+	restarts   *lifecycle.Tracker
+	watchdog   *watchdog.Watchdog
+	notifier   *notify.Dispatcher
+}
+
+// maxJobTimeout ограничивает срок задачи с большим интервалом, например сканирования безопасности
+const maxJobTimeout = 10 * time.Minute
+
+// stopTimeout - сколько Run ждет завершения задач после отмены контекста
+const stopTimeout = 10 * time.Second
+
+type job struct {
+	name     string
+	interval time.Duration
+	nextRun  time.Time
+	running  bool // меняется только в основном цикле
+	run      func(ctx context.Context) JobState
+
+	// grace продлевает срок задачи сверх интервала для действий, которые
+	// выполняются дольше проверки, например перезапуска юнита сторожем
+	grace time.Duration
+}
+
+// jobResult - результат задачи, выполненной в отдельной горутине
+type jobResult struct {
+	job   *job
+	start time.Time
+	state JobState
+}
+
+// timeout возвращает срок задачи: она должна завершиться до следующего запуска
+func (j *job) timeout() time.Duration {
+	return min(j.interval, maxJobTimeout) + j.grace
+}
+
+// New создает демон. store может быть nil, тогда метрики не сохраняются.
+func New(config ConfigSource, mon *monitor.LinuxMonitor, store *storage.Store) *Daemon {
+	d := &Daemon{
+		config:  config,
+		monitor: mon,
+		store:   store,
+		state: State{
+			PID:       os.Getpid(),
+			StartedAt: time.Now(),
+			Jobs:      make(map[string]JobState),
+		},
+		calls: push.NewCallTracker(),
+	}
+
+	cfg := config.Get()
+	mon.Configure(cfg)
+	mon.SetModuleBaselineFile(config.ModuleBaselineFile())
+	engine, err := alerts.NewEngine(cfg.Alerts, cfg.Monitoring.EnableAlerts)
+	if err != nil {
+		log.Printf("alert rules: %v", err)
+	}
+	d.alerts = engine
+
+	// Базовая линия вызовов учится по хранилищу, без него аномалии объема вызовов
+	// не вычисляются, а рост памяти и заполнения дисков оценивается по новым измерениям
+	var history anomaly.Querier
+	if store != nil {
+		history = store
+	}
+	d.anomaly = anomaly.NewDetector(history, cfg.Anomaly)
+
+	if journal, err := alerts.OpenJournal(config.AlertHistoryFile(), config.AlertStateFile()); err != nil {
+		log.Printf("alert journal: %v", err)
+	} else if err := engine.UseJournal(journal, time.Now()); err != nil {
+		log.Printf("alert journal: %v", err)
+	}
+
+	journal, err := lifecycle.OpenJournal(config.RestartHistoryFile())
+	if err != nil {
+		log.Printf("restart history: %v", err)
+	}
+	d.restarts, err = lifecycle.NewTracker(journal, time.Now())
+	if err != nil {
+		log.Printf("restart history: %v", err)
+	}
+
+	recoveryLog, err := watchdog.OpenJournal(config.RecoveryLogFile())
+	if err != nil {
+		log.Printf("recovery log: %v", err)
+	}
+	d.watchdog, err = watchdog.New(cfg.Watchdog, mon, recoveryLog, config.HangReportDir(), time.Now())
+	if err != nil {
+		log.Printf("recovery log: %v", err)
+	}
+
+	dispatcher, err := notify.NewDispatcher(cfg)
+	if err != nil {
+		log.Printf("notifiers: %v", err)
+	}
+	d.notifier = dispatcher
+
+	d.jobs = []*job{
+		{name: "metrics", run: d.collectMetrics},
+		{name: "quick_diagnostics", run: func(ctx context.Context) JobState {
+			return d.runChecks(ctx, d.monitor.DiagnosticChecks(false))
+		}},
+		{name: "full_diagnostics", run: func(ctx context.Context) JobState {
+			return d.runChecks(ctx, d.monitor.DiagnosticChecks(true))
+		}},
+		{name: "security_scan", run: func(ctx context.Context) JobState {
+			return d.runChecks(ctx, d.monitor.SecurityChecks(d.config.Get().Daemon.FullSecurityScan))
+		}},
+		{name: "push", run: d.pushMetrics},
+		{name: "watchdog", run: d.checkHealth, grace: watchdog.RestartTimeout},
+	}
+	d.configureSink()
+	d.applySchedule()
+
+	return d
+}
+
+// Run выполняет задачи по расписанию до отмены контекста.
+// Сигнал из reload перечитывает конфигурацию и расписание.
+// Каждая задача выполняется в своей горутине со сроком от контекста демона,
+// поэтому зависший Asterisk не задерживает сторож и обработку сигналов.
+func (d *Daemon) Run(ctx context.Context, reload <-chan os.Signal) error {
+	log.Printf("daemon started, pid %d", d.state.PID)
+	d.saveState()
+
+	go d.notifier.Run(ctx)
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	done := make(chan jobResult)
+	var wg sync.WaitGroup
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("daemon stopping")
+			d.waitJobs(&wg)
+			d.saveState()
+			return nil
+		case <-reload:
+			d.reload()
+		case result := <-done:
+			d.finishJob(result)
+		case now := <-ticker.C:
+			for _, j := range d.jobs {
+				if j.interval <= 0 || j.running || now.Before(j.nextRun) {
+					continue
+				}
+				j.running = true
+				wg.Add(1)
+				go func(j *job, timeout time.Duration) {
+					defer wg.Done()
+					jobCtx, cancel := context.WithTimeout(ctx, timeout)
+					defer cancel()
+
+					start := time.Now()
+					state := j.run(jobCtx)
+					if jobCtx.Err() == context.DeadlineExceeded {
+						log.Printf("%s exceeded its %s deadline", j.name, timeout)
+						state.Errors++
+					}
+					// После остановки цикла результат не ждут
+					select {
+					case done <- jobResult{job: j, start: start, state: state}:
+					case <-ctx.Done():
+					}
+				}(j, j.timeout())
+			}
+		}
+	}
+}
+
+// waitJobs ждет задачи, прерванные отменой контекста, не дольше stopTimeout
+func (d *Daemon) waitJobs(wg *sync.WaitGroup) {
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(stopTimeout):
+		log.Printf("jobs still running after %s, exiting", stopTimeout)
+	}
+}
+
+// finishJob сохраняет результат задачи и планирует следующий запуск
+func (d *Daemon) finishJob(r jobResult) {
+	r.job.running = false
+	r.job.nextRun = time.Now().Add(r.job.interval)
+
+	result := r.state
+	result.LastRun = r.start
+	result.Duration = time.Since(r.start).Round(time.Millisecond).String()
+
+	d.mu.Lock()
+	d.state.Jobs[r.job.name] = result
+	d.mu.Unlock()
+	d.saveState()
+
+	log.Printf("%s finished in %s: %d ok, %d warning, %d error",
+		r.job.name, result.Duration, result.Success, result.Warnings, result.Errors)
+}
+
+// reload перечитывает конфигурацию по SIGHUP. Load подменяет конфигурацию
+// целиком, поэтому выполняющиеся задачи дочитывают прежний снимок, а новые
+// получают новый через Get.
+func (d *Daemon) reload() {
+	if err := d.config.Load(); err != nil {
+		log.Printf("reload config: %v", err)
+		return
+	}
+
+	cfg := d.config.Get()
+	if d.store != nil {
+		d.store.SetRetention(cfg.Monitoring.LogRetention)
+	}
+	d.monitor.Configure(cfg)
+	if err := d.alerts.Configure(cfg.Alerts, cfg.Monitoring.EnableAlerts); err != nil {
+		log.Printf("alert rules: %v", err)
+	}
+	d.anomaly.Configure(cfg.Anomaly)
+	d.watchdog.Configure(cfg.Watchdog)
+	if err := d.notifier.Configure(cfg); err != nil {
+		log.Printf("notifiers: %v", err)
+	}
+	d.configureSink()
+	d.applySchedule()
+
+	d.mu.Lock()
+	d.state.Alerts = d.alerts.Active()
+	d.state.ReloadedAt = time.Now()
+	d.mu.Unlock()
+	d.saveState()
+
+	log.Printf("configuration reloaded")
+}
+
+// applySchedule устанавливает интервалы задач из конфигурации
+func (d *Daemon) applySchedule() {
+	cfg := d.config.Get().Daemon
+	intervals := map[string]int{
+		"metrics":           cfg.MetricsInterval,
+		"quick_diagnostics": cfg.QuickCheckInterval,
+		"full_diagnostics":  cfg.FullCheckInterval,
+		"security_scan":     cfg.SecurityInterval,
+	}
+	if d.currentSink() != nil {
+		intervals["push"] = d.config.Get().Push.Interval
+	}
+	if watch := d.config.Get().Watchdog; watch.Enabled {
+		intervals["watchdog"] = watch.Interval
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, j := range d.jobs {
+		interval := time.Duration(intervals[j.name]) * time.Second
+		if interval == j.interval {
+			continue
+		}
+
+		j.interval = interval
+		// Первый запуск сразу, при изменении интервала - отсчет от последнего запуска
+		if last, ok := d.state.Jobs[j.name]; ok {
+			j.nextRun = last.LastRun.Add(interval)
+		} else {
+			j.nextRun = time.Time{}
+		}
+	}
+}
+
+func (d *Daemon) collectMetrics(ctx context.Context) JobState {
+	now := time.Now()
+	// Ошибки команд Asterisk CLI не прерывают сбор: метрики источника с ошибкой
+	// неизвестны и не снимают оповещения, а превышение срока задания журналирует Run
+	var readings monitor.Readings
+	readings.System, readings.SystemErr = d.monitor.GetSystemMetrics(ctx)
+	readings.Peers, readings.PeersErr = d.monitor.GetSIPPeers(ctx)
+	readings.CallQuality, readings.CallQualityErr = d.monitor.GetCallQuality(ctx)
+	readings.Taskprocessors = d.monitor.GetTaskprocessors(ctx)
+	readings.Network = d.monitor.GetNetworkHealth()
+
+	metrics := readings.System
+	metrics.OnlinePeers, metrics.TotalPeers = monitor.CountPeers(readings.Peers)
+	state := JobState{Metrics: &metrics}
+
+	samples, unknown := readings.Samples(now)
+	d.observeLifecycle(ctx)
+	samples = append(samples, d.restarts.Samples(now)...)
+
+	d.evaluateAlerts(append(d.anomaly.Observe(samples, now), samples...), unknown, now)
+
+	if d.store != nil {
+		if err := d.store.Append(samples...); err != nil {
+			log.Printf("store metrics: %v", err)
+			state.Errors++
+			return state
+		}
+	}
+
+	state.Success++
+	return state
+}
+
+// observeLifecycle записывает в хронологию запуски, перезагрузки и сбои Asterisk
+func (d *Daemon) observeLifecycle(ctx context.Context) {
+	events, err := d.restarts.Observe(d.monitor.LifecycleSnapshot(ctx))
+	if err != nil {
+		log.Printf("restart history: %v", err)
+	}
+	for _, event := range events {
+		log.Printf("asterisk %s: pid %d, previous pid %d; %s", event.Kind, event.PID, event.PrevPID, event.Detail)
+		if !event.Unexpected() {
+			continue
+		}
+		if err := d.monitor.LogProblemCall("CRITICAL", "lifecycle", "asterisk_crash", event.Detail); err != nil {
+			log.Printf("problem log: %v", err)
+		}
+	}
+}
+
+// checkHealth проверяет отзывчивость Asterisk и записывает действия сторожа
+// в лог проблем
+func (d *Daemon) checkHealth(ctx context.Context) JobState {
+	var state JobState
+
+	entries, err := d.watchdog.Check(ctx)
+	if err != nil {
+		log.Printf("recovery log: %v", err)
+	}
+	for _, entry := range entries {
+		log.Printf("watchdog %s: %s %s", entry.Kind, entry.Detail, entry.Error)
+
+		severity := "WARNING"
+		switch {
+		case entry.Kind == watchdog.KindRecovered:
+			severity = "RESOLVED"
+		case entry.Kind == watchdog.KindRestart || entry.Error != "":
+			severity = "CRITICAL"
+		}
+		details := entry.Detail
+		if entry.Report != "" {
+			details += " | report " + entry.Report
+		}
+		if entry.Error != "" {
+			details += " | " + entry.Error
+		}
+		if err := d.monitor.LogProblemCall(severity, "watchdog", entry.Kind, details); err != nil {
+			log.Printf("problem log: %v", err)
+		}
+	}
+
+	switch d.watchdog.Last().State {
+	case types.HealthOK:
+		state.Success++
+	default:
+		state.Errors++
+	}
+	return state
+}
+
+// evaluateAlerts вычисляет правила оповещений и записывает переходы в лог проблем
+func (d *Daemon) evaluateAlerts(samples []types.Sample, unknown []string, now time.Time) {
+	changed := d.alerts.Evaluate(samples, now, unknown...)
+	// Пока открыт интерфейс, уведомления отправляет он
+	if !d.alerts.Leader() {
+		changed = nil
+	}
+	d.notifier.Enqueue(changed, now)
+
+	for _, alert := range changed {
+		severity := strings.ToUpper(alerts.StatusFor(alert.Severity))
+		if alert.State == alerts.StateResolved {
+			severity = "RESOLVED"
+		}
+
+		log.Printf("alert %s %s", alert.State, alert.Message)
+		if err := d.monitor.LogProblemCall(severity, "alerts", alert.Rule, alert.Message); err != nil {
+			log.Printf("problem log: %v", err)
+		}
+	}
+
+	d.mu.Lock()
+	d.state.Alerts = d.alerts.Active()
+	d.mu.Unlock()
+}
+
+// configureSink создает получатель метрик при изменении секции [push]
+func (d *Daemon) configureSink() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	cfg := d.config.Get().Push
+	if d.sink != nil && cfg == d.sinkConfig {
+		return
+	}
+
+	d.sink = nil
+	d.sinkConfig = cfg
+	if !cfg.Enabled {
+		return
+	}
+
+	sink, err := push.New(cfg)
+	if err != nil {
+		log.Printf("push disabled: %v", err)
+		return
+	}
+	d.sink = sink
+	log.Printf("pushing %s metrics to %s", cfg.Format, cfg.Endpoint)
+}
+
+// currentSink возвращает получатель метрик, nil - отправка выключена
+func (d *Daemon) currentSink() *push.Sink {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.sink
+}
+
+// pushMetrics собирает метрики, пиры, каналы и завершенные вызовы и отправляет их
+func (d *Daemon) pushMetrics(ctx context.Context) JobState {
+	var state JobState
+	sink := d.currentSink()
+	if sink == nil {
+		return state
+	}
+
+	now := time.Now()
+	metrics, _ := d.monitor.GetSystemMetrics(ctx)
+	peers, _ := d.monitor.GetSIPPeers(ctx)
+	channels, _ := d.monitor.GetActiveChannels(ctx)
+	metrics.OnlinePeers, metrics.TotalPeers = monitor.CountPeers(peers)
+	state.Metrics = &metrics
+
+	sink.Add(push.SystemPoints(metrics, now)...)
+	sink.Add(push.PeerPoints(peers, now)...)
+	sink.Add(push.ChannelPoints(channels, now)...)
+	// При ошибке трекер не получает пустой список, иначе все идущие вызовы
+	// считались бы завершенными
+	if quality, err := d.monitor.GetCallQuality(ctx); err == nil {
+		sink.Add(push.CallEndPoints(d.calls.Observe(quality), now)...)
+	}
+
+	if err := sink.Flush(ctx); err != nil {
+		log.Printf("push metrics: %v (%d points buffered, %d dropped)", err, sink.Pending(), sink.Dropped())
+		state.Errors++
+		return state
+	}
+
+	state.Success++
+	return state
+}
+
+func (d *Daemon) runChecks(ctx context.Context, checks []monitor.Check) JobState {
+	var state JobState
+
+	for _, check := range checks {
+		// После истечения срока задачи оставшиеся проверки не запускаются
+		if ctx.Err() != nil {
+			state.Errors++
+			break
+		}
+		result := check.Run(ctx)
+		state.Results = append(state.Results, result)
+
+		switch result.Status {
+		case "success":
+			state.Success++
+			continue
+		case "warning":
+			state.Warnings++
+		default:
+			state.Errors++
+		}
+
+		// Проблемные результаты пишем в общий лог проблем
+		details := result.Message
+		if result.Error != "" {
+			details += " | " + result.Error
+		}
+		if err := d.monitor.LogProblemCall(strings.ToUpper(result.Status), "daemon", result.Name, details); err != nil {
+			log.Printf("problem log: %v", err)
+		}
+	}
+
+	return state
+}
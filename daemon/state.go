@@ -0,0 +1,61 @@
+package daemon
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"asterisk-monitor/alerts"
+	"asterisk-monitor/types"
+)
+
+// State - состояние фонового режима, сохраняемое в файл после каждой задачи
+type State struct {
+	PID        int                 `json:"pid"`
+	StartedAt  time.Time           `json:"started_at"`
+	ReloadedAt time.Time           `json:"reloaded_at,omitempty"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+	Jobs       map[string]JobState `json:"jobs"`
+	Alerts     []alerts.Alert      `json:"alerts"`
+}
+
+// JobState - результат последнего запуска задачи
+type JobState struct {
+	LastRun  time.Time            `json:"last_run"`
+	Duration string               `json:"duration"`
+	Success  int                  `json:"success"`
+	Warnings int                  `json:"warnings"`
+	Errors   int                  `json:"errors"`
+	Metrics  *types.SystemMetrics `json:"metrics,omitempty"`
+	Results  []types.CheckResult  `json:"results,omitempty"`
+}
+
+// saveState атомарно записывает состояние в файл
+func (d *Daemon) saveState() {
+	path := d.config.DaemonStateFile()
+
+	d.mu.Lock()
+	d.state.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(d.state, "", "  ")
+	d.mu.Unlock()
+	if err != nil {
+		log.Printf("encode state: %v", err)
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Printf("write state: %v", err)
+		return
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		log.Printf("write state: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		log.Printf("write state: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package exporter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	monitor "asterisk-monitor/monitors"
+	"asterisk-monitor/types"
+)
+
+// Source определяет данные, которые экспортер получает от монитора
+type Source interface {
+	GetSystemMetrics(ctx context.Context) (types.SystemMetrics, error)
+	DiscoverAsterisk(ctx context.Context) types.AsteriskProcess
+	GetSIPPeers(ctx context.Context) ([]types.SIPPeer, error)
+	GetSIPRegistrations(ctx context.Context) ([]types.SIPRegistration, error)
+	GetActiveChannels(ctx context.Context) ([]types.ChannelInfo, error)
+	DiagnosticChecks(full bool) []monitor.Check
+}
+
+// Snapshot - последний собранный набор данных
+type Snapshot struct {
+	Metrics         types.SystemMetrics
+	Asterisk        types.AsteriskProcess
+	Peers           []types.SIPPeer
+	Registrations   []types.SIPRegistration
+	Channels        []types.ChannelInfo
+	Checks          []types.CheckResult
+	CollectedAt     time.Time
+	CollectDuration time.Duration
+	ChecksAt        time.Time
+}
+
+// Collector периодически собирает данные и хранит их в кэше,
+// чтобы запросы /metrics не запускали команды Asterisk CLI
+type Collector struct {
+	source         Source
+	interval       time.Duration
+	checksInterval time.Duration
+
+	mu       sync.RWMutex
+	snapshot Snapshot
+}
+
+// NewCollector создает коллектор с интервалами сбора метрик и проверок
+func NewCollector(source Source, interval, checksInterval time.Duration) *Collector {
+	return &Collector{
+		source:         source,
+		interval:       interval,
+		checksInterval: checksInterval,
+	}
+}
+
+// Run собирает данные по расписанию до отмены контекста
+func (c *Collector) Run(ctx context.Context) {
+	c.collect(ctx, true)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.mu.RLock()
+			checksDue := time.Since(c.snapshot.ChecksAt) >= c.checksInterval
+			c.mu.RUnlock()
+
+			c.collect(ctx, checksDue)
+		}
+	}
+}
+
+// Snapshot возвращает копию последних собранных данных
+func (c *Collector) Snapshot() Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.snapshot
+}
+
+func (c *Collector) collect(ctx context.Context, withChecks bool) {
+	start := time.Now()
+
+	// Ошибка команды Asterisk CLI дает пустой список: устаревшие ряды пиров и
+	// каналов не должны попадать в /metrics, когда Asterisk недоступен
+	var snapshot Snapshot
+	snapshot.Metrics, _ = c.source.GetSystemMetrics(ctx)
+	snapshot.Asterisk = c.source.DiscoverAsterisk(ctx)
+	snapshot.Peers, _ = c.source.GetSIPPeers(ctx)
+	snapshot.Registrations, _ = c.source.GetSIPRegistrations(ctx)
+	snapshot.Channels, _ = c.source.GetActiveChannels(ctx)
+	snapshot.Metrics.OnlinePeers, snapshot.Metrics.TotalPeers = monitor.CountPeers(snapshot.Peers)
+
+	var checks []types.CheckResult
+	if withChecks {
+		checks = monitor.RunChecks(ctx, c.source.DiagnosticChecks(false))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if withChecks {
+		snapshot.Checks = checks
+		snapshot.ChecksAt = time.Now()
+	} else {
+		snapshot.Checks = c.snapshot.Checks
+		snapshot.ChecksAt = c.snapshot.ChecksAt
+	}
+	snapshot.CollectedAt = time.Now()
+	snapshot.CollectDuration = time.Since(start)
+	c.snapshot = snapshot
+}
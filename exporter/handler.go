@@ -0,0 +1,196 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	monitor "asterisk-monitor/monitors"
+	"asterisk-monitor/types"
+)
+
+// Handler отдает метрики из кэша коллектора в текстовом формате Prometheus
+func Handler(c *Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetrics(w, c.Snapshot())
+	})
+}
+
+func writeMetrics(w io.Writer, s Snapshot) {
+	e := &encoder{w: w}
+
+	// Состояние процесса, а не юнита: зависший Asterisk или запущенный без
+	// systemd иначе получил бы неверное значение
+	up := 0.0
+	if s.Asterisk.State == types.AsteriskRunning {
+		up = 1
+	}
+	e.family("asterisk_up", "gauge", "Whether the Asterisk process is running and answers CLI commands.")
+	e.sample("asterisk_up", nil, up)
+
+	e.family("asterisk_monitor_cpu_usage_percent", "gauge", "Host CPU usage in percent.")
+	e.sample("asterisk_monitor_cpu_usage_percent", nil, s.Metrics.CPUUsage)
+
+	e.family("asterisk_monitor_memory_usage_percent", "gauge", "Host memory usage in percent.")
+	e.sample("asterisk_monitor_memory_usage_percent", nil, s.Metrics.MemoryUsage)
+
+	e.family("asterisk_monitor_disk_usage_percent", "gauge", "Usage of the primary monitored filesystem in percent.")
+	e.sample("asterisk_monitor_disk_usage_percent", nil, s.Metrics.DiskUsage)
+
+	e.family("asterisk_monitor_mount_usage_percent", "gauge", "Filesystem usage of monitored mount points in percent.")
+	for _, mount := range s.Metrics.Mounts {
+		if mount.Error == "" {
+			e.sample("asterisk_monitor_mount_usage_percent", labels{"mount", mount.Path}, mount.UsedPct)
+		}
+	}
+
+	e.family("asterisk_monitor_load1", "gauge", "Host load average over 1 minute.")
+	e.sample("asterisk_monitor_load1", nil, s.Metrics.Load1)
+
+	if p := s.Metrics.Process; p != nil {
+		e.family("asterisk_process_cpu_percent", "gauge", "CPU usage of the Asterisk process in percent of one core.")
+		e.sample("asterisk_process_cpu_percent", nil, p.CPUPercent)
+
+		e.family("asterisk_process_resident_memory_bytes", "gauge", "Resident memory size of the Asterisk process.")
+		e.sample("asterisk_process_resident_memory_bytes", nil, float64(p.RSSBytes))
+
+		e.family("asterisk_process_threads", "gauge", "Number of threads of the Asterisk process.")
+		e.sample("asterisk_process_threads", nil, float64(p.Threads))
+
+		if p.OpenFDs >= 0 {
+			e.family("asterisk_process_open_fds", "gauge", "Number of open file descriptors of the Asterisk process.")
+			e.sample("asterisk_process_open_fds", nil, float64(p.OpenFDs))
+		}
+		if p.MaxFDs > 0 {
+			e.family("asterisk_process_max_fds", "gauge", "Soft limit of open file descriptors of the Asterisk process.")
+			e.sample("asterisk_process_max_fds", nil, float64(p.MaxFDs))
+		}
+
+		e.family("asterisk_process_context_switches_total", "counter", "Context switches of the Asterisk process.")
+		e.sample("asterisk_process_context_switches_total", labels{"type", "voluntary"}, float64(p.VoluntaryCtxSwitches))
+		e.sample("asterisk_process_context_switches_total", labels{"type", "nonvoluntary"}, float64(p.NonvoluntaryCtxSwitches))
+	}
+
+	e.family("asterisk_active_calls", "gauge", "Number of active calls reported by Asterisk.")
+	e.sample("asterisk_active_calls", nil, float64(s.Metrics.ActiveCalls))
+
+	e.family("asterisk_sip_peers", "gauge", "Number of SIP peers by reachability.")
+	e.sample("asterisk_sip_peers", labels{"state", "online"}, float64(s.Metrics.OnlinePeers))
+	e.sample("asterisk_sip_peers", labels{"state", "offline"}, float64(s.Metrics.TotalPeers-s.Metrics.OnlinePeers))
+
+	e.family("asterisk_sip_peer_up", "gauge", "Whether the SIP peer is reachable (OK, LAGGED or unmonitored with a known address).")
+	for _, peer := range s.Peers {
+		e.sample("asterisk_sip_peer_up", labels{"peer", peer.Name, "host", peer.Host}, monitor.PeerUpValue(peer))
+	}
+
+	// Статус меняется вместе со значением up, поэтому он вынесен в отдельную
+	// метрику: иначе каждая смена статуса порождает новый временной ряд
+	e.family("asterisk_sip_peer_info", "gauge", "SIP peer status as reported by Asterisk, always 1.")
+	for _, peer := range s.Peers {
+		e.sample("asterisk_sip_peer_info", labels{"peer", peer.Name, "host", peer.Host, "status", peer.Status}, 1)
+	}
+
+	e.family("asterisk_sip_peer_latency_seconds", "gauge", "Qualify round-trip time of the SIP peer.")
+	for _, peer := range s.Peers {
+		if latency, ok := monitor.PeerLatencyMs(peer); ok {
+			e.sample("asterisk_sip_peer_latency_seconds", labels{"peer", peer.Name, "host", peer.Host}, latency/1000)
+		}
+	}
+
+	e.family("asterisk_sip_registration_up", "gauge", "Whether the outbound SIP trunk registration is in the Registered state.")
+	for _, reg := range s.Registrations {
+		registered := 0.0
+		if reg.State == "Registered" {
+			registered = 1
+		}
+		e.sample("asterisk_sip_registration_up", labels{"host", reg.Host, "username", reg.Username}, registered)
+	}
+
+	e.family("asterisk_sip_registration_info", "gauge", "SIP trunk registration state as reported by Asterisk, always 1.")
+	for _, reg := range s.Registrations {
+		e.sample("asterisk_sip_registration_info", labels{"host", reg.Host, "username", reg.Username, "state", reg.State}, 1)
+	}
+
+	e.family("asterisk_channels", "gauge", "Number of active channels by state.")
+	byState := make(map[string]int)
+	for _, channel := range s.Channels {
+		byState[channel.State]++
+	}
+	states := make([]string, 0, len(byState))
+	for state := range byState {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+	for _, state := range states {
+		e.sample("asterisk_channels", labels{"state", state}, float64(byState[state]))
+	}
+
+	e.family("asterisk_monitor_check_status", "gauge", "Result of the diagnostics check: 0 success, 1 warning, 2 error.")
+	for _, check := range s.Checks {
+		e.sample("asterisk_monitor_check_status", labels{"check", check.Name}, checkStatusValue(check.Status))
+	}
+
+	if !s.CollectedAt.IsZero() {
+		e.family("asterisk_monitor_last_collect_timestamp_seconds", "gauge", "Unix time of the last successful collection.")
+		e.sample("asterisk_monitor_last_collect_timestamp_seconds", nil, float64(s.CollectedAt.UnixNano())/1e9)
+
+		e.family("asterisk_monitor_collect_duration_seconds", "gauge", "Duration of the last collection.")
+		e.sample("asterisk_monitor_collect_duration_seconds", nil, s.CollectDuration.Seconds())
+	}
+}
+
+func checkStatusValue(status string) float64 {
+	switch status {
+	case "success":
+		return 0
+	case "warning":
+		return 1
+	}
+	return 2
+}
+
+// labels - пары имя/значение меток в порядке вывода
+type labels []string
+
+type encoder struct {
+	w io.Writer
+}
+
+func (e *encoder) family(name, metricType, help string) {
+	fmt.Fprintf(e.w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(e.w, "# TYPE %s %s\n", name, metricType)
+}
+
+func (e *encoder) sample(name string, l labels, value float64) {
+	var b strings.Builder
+	b.WriteString(name)
+
+	if len(l) > 0 {
+		b.WriteByte('{')
+		for i := 0; i+1 < len(l); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(l[i])
+			b.WriteString(`="`)
+			b.WriteString(escapeLabel(l[i+1]))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	b.WriteByte('\n')
+	io.WriteString(e.w, b.String())
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
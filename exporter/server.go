@@ -0,0 +1,49 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Serve запускает сбор данных и HTTP сервер с /metrics до отмены контекста
+func Serve(ctx context.Context, addr string, c *Collector) error {
+	go c.Run(ctx)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler(c))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("<html><body><h1>Asterisk Monitor Exporter</h1><p><a href=\"/metrics\">Metrics</a></p></body></html>"))
+	})
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+		if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,178 @@
+package lifecycle
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"asterisk-monitor/storage"
+)
+
+// maxJournalSize - размер хронологии, после которого она переименовывается в .1,
+// как журнал оповещений. Хранится одно предыдущее поколение.
+const maxJournalSize = 5 * 1024 * 1024
+
+// Journal - файл хронологии в формате JSON lines. Хронологию ведут и интерфейс,
+// и фоновый режим, но пишет в файл только процесс, получивший блокировку писателя.
+type Journal struct {
+	mu   sync.Mutex
+	path string
+	lock *storage.WriterLock
+}
+
+// OpenJournal открывает файл хронологии, создавая каталог при необходимости
+func OpenJournal(path string) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return &Journal{path: path, lock: storage.NewWriterLock(path + ".lock")}, nil
+}
+
+// Path возвращает путь к файлу хронологии
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// Writer сообщает, что этот процесс пишет хронологию. Если писатель завершился,
+// блокировку получает процесс, который вызовет Writer следующим.
+func (j *Journal) Writer() bool {
+	return j.lock.Acquire()
+}
+
+// Append дописывает события в конец файла. Файл больше maxJournalSize
+// переименовывается в .1, прошлое поколение удаляется.
+func (j *Journal) Append(events ...Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if info, err := os.Stat(j.path); err == nil && info.Size() >= maxJournalSize {
+		if err := os.Rename(j.path, j.path+".1"); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Events возвращает события новее since, старые первыми, включая предыдущее
+// поколение хронологии. Поврежденные строки пропускаются.
+func (j *Journal) Events(since time.Time) ([]Event, error) {
+	events, _, err := j.readSince(position{})
+	filtered := events[:0]
+	for _, event := range events {
+		if !event.Time.Before(since) {
+			filtered = append(filtered, event)
+		}
+	}
+	return filtered, err
+}
+
+// position - место, до которого хронология уже прочитана: файл и смещение
+// в нем. Нулевая позиция - начало предыдущего поколения.
+type position struct {
+	file   os.FileInfo
+	offset int64
+}
+
+// readSince возвращает события, дописанные после pos, и новую позицию.
+// Если файл с тех пор переименован в .1, сначала дочитывается его хвост.
+func (j *Journal) readSince(pos position) ([]Event, position, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	current, err := os.Stat(j.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, pos, err
+	}
+
+	var events []Event
+	if pos.file == nil || current == nil || !os.SameFile(pos.file, current) {
+		if previous, err := os.Stat(j.path + ".1"); err == nil && (pos.file == nil || os.SameFile(pos.file, previous)) {
+			offset := pos.offset
+			if pos.file == nil {
+				offset = 0
+			}
+			read, _, err := readEventsFrom(j.path+".1", offset)
+			if err != nil {
+				return nil, pos, err
+			}
+			events = read
+		}
+		pos = position{}
+	}
+	if current == nil {
+		return events, pos, nil
+	}
+
+	// Файл, ставший короче прочитанного, перезаписан: читаем его сначала
+	if current.Size() < pos.offset {
+		pos.offset = 0
+	}
+	read, offset, err := readEventsFrom(j.path, pos.offset)
+	if err != nil {
+		return events, pos, err
+	}
+	return append(events, read...), position{file: current, offset: offset}, nil
+}
+
+// readEventsFrom читает события файла path начиная со смещения offset и
+// возвращает смещение после последней полной строки. Недописанная строка
+// остается для следующего чтения.
+func readEventsFrom(path string, offset int64) ([]Event, int64, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, offset, nil
+	}
+	if err != nil {
+		return nil, offset, err
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset, err
+	}
+
+	var events []Event
+	reader := bufio.NewReaderSize(file, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return events, offset, nil
+		}
+		if err != nil {
+			return events, offset, err
+		}
+		offset += int64(len(line))
+
+		var event Event
+		if err := json.Unmarshal(bytes.TrimSpace(line), &event); err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+}
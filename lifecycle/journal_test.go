@@ -0,0 +1,179 @@
+package lifecycle
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testStart = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+func openTestJournal(t *testing.T) *Journal {
+	t.Helper()
+	j, err := OpenJournal(filepath.Join(t.TempDir(), "lifecycle.jsonl"))
+	if err != nil {
+		t.Fatalf("OpenJournal: %v", err)
+	}
+	return j
+}
+
+func testEvent(minute int, kind string) Event {
+	return Event{Time: testStart.Add(time.Duration(minute) * time.Minute), Kind: kind, PID: 100 + minute}
+}
+
+func appendEvents(t *testing.T, j *Journal, events ...Event) {
+	t.Helper()
+	if err := j.Append(events...); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+}
+
+// appendRaw дописывает байты в файл хронологии в обход Append
+func appendRaw(t *testing.T, j *Journal, data string) {
+	t.Helper()
+	file, err := os.OpenFile(j.Path(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fillJournal дописывает поврежденную строку, чтобы файл достиг maxJournalSize
+func fillJournal(t *testing.T, j *Journal) {
+	t.Helper()
+	info, err := os.Stat(j.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendRaw(t, j, strings.Repeat("x", maxJournalSize-int(info.Size()))+"\n")
+}
+
+func pids(events []Event) []int {
+	var pids []int
+	for _, event := range events {
+		pids = append(pids, event.PID)
+	}
+	return pids
+}
+
+func samePIDs(events []Event, want ...int) bool {
+	got := pids(events)
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestJournalRotation(t *testing.T) {
+	j := openTestJournal(t)
+	appendEvents(t, j, testEvent(0, KindStart))
+	fillJournal(t, j)
+	appendEvents(t, j, testEvent(1, KindRestart))
+
+	if _, err := os.Stat(j.Path() + ".1"); err != nil {
+		t.Fatalf("journal not rotated: %v", err)
+	}
+	if info, err := os.Stat(j.Path()); err != nil || info.Size() >= maxJournalSize {
+		t.Fatalf("current journal not started anew: %v", err)
+	}
+
+	// Хронология читается по обоим поколениям
+	events, err := j.Events(time.Time{})
+	if err != nil || !samePIDs(events, 100, 101) {
+		t.Errorf("Events = %v, %v, want pids 100, 101", pids(events), err)
+	}
+	events, err = j.Events(testStart.Add(time.Minute))
+	if err != nil || !samePIDs(events, 101) {
+		t.Errorf("Events since = %v, %v, want pid 101", pids(events), err)
+	}
+
+	// Следующая ротация удаляет самое старое поколение
+	fillJournal(t, j)
+	appendEvents(t, j, testEvent(2, KindStop))
+	if events, _ := j.Events(time.Time{}); !samePIDs(events, 101, 102) {
+		t.Errorf("Events = %v after the second rotation, want pids 101, 102", pids(events))
+	}
+}
+
+func TestReadSinceReadsOnlyAppended(t *testing.T) {
+	j := openTestJournal(t)
+
+	events, pos, err := j.readSince(position{})
+	if err != nil || len(events) != 0 {
+		t.Fatalf("readSince on a missing journal = %v, %v", pids(events), err)
+	}
+
+	appendEvents(t, j, testEvent(0, KindStart), testEvent(1, KindReload))
+	events, pos, err = j.readSince(pos)
+	if err != nil || !samePIDs(events, 100, 101) {
+		t.Fatalf("readSince = %v, %v, want pids 100, 101", pids(events), err)
+	}
+
+	appendEvents(t, j, testEvent(2, KindRestart))
+	events, pos, err = j.readSince(pos)
+	if err != nil || !samePIDs(events, 102) {
+		t.Fatalf("readSince = %v, %v, want only the appended pid 102", pids(events), err)
+	}
+
+	// Недописанная строка читается, когда запись завершится
+	data, _ := json.Marshal(testEvent(3, KindStop))
+	appendRaw(t, j, string(data[:10]))
+	events, pos, err = j.readSince(pos)
+	if err != nil || len(events) != 0 {
+		t.Fatalf("readSince = %v, %v, want nothing from a partial line", pids(events), err)
+	}
+	appendRaw(t, j, string(data[10:])+"\n")
+	events, pos, err = j.readSince(pos)
+	if err != nil || !samePIDs(events, 103) {
+		t.Fatalf("readSince = %v, %v, want pid 103", pids(events), err)
+	}
+
+	// После ротации дочитывается хвост прежнего файла, затем новый
+	appendEvents(t, j, testEvent(4, KindStart))
+	fillJournal(t, j)
+	appendEvents(t, j, testEvent(5, KindCrash))
+	events, pos, err = j.readSince(pos)
+	if err != nil || !samePIDs(events, 104, 105) {
+		t.Fatalf("readSince after rotation = %v, %v, want pids 104, 105", pids(events), err)
+	}
+
+	if events, _, _ = j.readSince(pos); len(events) != 0 {
+		t.Errorf("readSince = %v, want nothing new", pids(events))
+	}
+}
+
+func TestTrackerSyncsOtherWriter(t *testing.T) {
+	j := openTestJournal(t)
+	appendEvents(t, j, testEvent(0, KindStart))
+
+	tracker, err := NewTracker(j, testStart.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("NewTracker: %v", err)
+	}
+
+	// Событие, записанное другим процессом, применяется один раз
+	appendEvents(t, j, testEvent(2, KindRestart))
+	tracker.mu.Lock()
+	fresh, err := tracker.syncLocked(testStart.Add(3 * time.Minute))
+	again, _ := tracker.syncLocked(testStart.Add(3 * time.Minute))
+	current := tracker.current
+	tracker.mu.Unlock()
+
+	if err != nil || !samePIDs(fresh, 102) || len(again) != 0 {
+		t.Fatalf("sync = %v, %v, then %v, want pid 102 once", pids(fresh), err, pids(again))
+	}
+	if !current.running || current.pid != 102 {
+		t.Errorf("current = %+v, want the restarted pid 102", current)
+	}
+}
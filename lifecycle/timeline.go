@@ -0,0 +1,51 @@
+package lifecycle
+
+import "time"
+
+// DayCount - число событий хронологии за сутки
+type DayCount struct {
+	Day      time.Time
+	Starts   int
+	Reloads  int
+	Restarts int
+	Stops    int
+	Crashes  int
+}
+
+// Total возвращает число всех событий за сутки
+func (c DayCount) Total() int {
+	return c.Starts + c.Reloads + c.Restarts + c.Stops + c.Crashes
+}
+
+// DailyCounts считает события по суткам за последние days дней, начиная с
+// сегодняшних. Сутки отсчитываются в часовом поясе now.
+func DailyCounts(events []Event, days int, now time.Time) []DayCount {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	counts := make([]DayCount, days)
+	for i := range counts {
+		counts[i].Day = today.AddDate(0, 0, -i)
+	}
+
+	for _, event := range events {
+		at := event.Time.In(now.Location())
+		day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, now.Location())
+		i := int(today.Sub(day).Hours()/24 + 0.5)
+		if i < 0 || i >= days {
+			continue
+		}
+
+		switch event.Kind {
+		case KindStart:
+			counts[i].Starts++
+		case KindReload:
+			counts[i].Reloads++
+		case KindRestart:
+			counts[i].Restarts++
+		case KindStop:
+			counts[i].Stops++
+		case KindCrash:
+			counts[i].Crashes++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,402 @@
+package lifecycle
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+// Виды событий хронологии
+const (
+	KindStart   = "start"   // Asterisk запущен после остановки или замечен впервые
+	KindReload  = "reload"  // перезагрузка конфигурации без перезапуска процесса
+	KindRestart = "restart" // новый процесс без признаков сбоя: systemctl restart, core restart
+	KindStop    = "stop"    // штатная остановка
+	KindCrash   = "crash"   // процесс завершился аварийно или перезапущен после сбоя
+)
+
+const (
+	// Время запуска вычисляется по uptime и плавает на время опроса. Сдвиг
+	// меньше этого значения не считается новым запуском или перезагрузкой.
+	timeTolerance = 10 * time.Second
+
+	// Окно метрики незапланированных перезапусков
+	unexpectedWindow = time.Hour
+
+	// Сколько истории загружается при запуске
+	historyWindow = 24 * time.Hour
+)
+
+// Результаты systemd юнита, означающие аварийное завершение
+var failedResults = map[string]bool{
+	"exit-code": true,
+	"signal":    true,
+	"core-dump": true,
+	"watchdog":  true,
+	"timeout":   true,
+}
+
+// Event - событие хронологии. Для остановки и сбоя без нового процесса PID равен 0.
+type Event struct {
+	Time       time.Time `json:"time"`
+	Kind       string    `json:"kind"`
+	PID        int       `json:"pid,omitempty"`
+	PrevPID    int       `json:"prev_pid,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	ReloadedAt time.Time `json:"reloaded_at,omitempty"`
+	Lifetime   string    `json:"lifetime,omitempty"` // сколько работал прежний процесс
+	UnitResult string    `json:"unit_result,omitempty"`
+	CoreFiles  []string  `json:"core_files,omitempty"`
+	Detail     string    `json:"detail,omitempty"`
+}
+
+// Unexpected сообщает, что событие - незапланированный перезапуск или остановка
+func (e Event) Unexpected() bool {
+	return e.Kind == KindCrash
+}
+
+// instance - процесс Asterisk по последнему снимку
+type instance struct {
+	running    bool
+	pid        int
+	wrapperPID int
+	startedAt  time.Time
+	reloadedAt time.Time
+	nRestarts  int // -1 - неизвестно
+	seen       time.Time
+}
+
+// Tracker сравнивает снимки состояния Asterisk и записывает в хронологию
+// запуски, перезагрузки, перезапуски, остановки и сбои
+type Tracker struct {
+	mu      sync.Mutex
+	journal *Journal
+	current instance
+	known   bool
+	recent  []Event  // события за historyWindow для метрики
+	read    position // до какого места журнал уже применен
+}
+
+// NewTracker создает трекер. Последнее известное состояние Asterisk берется
+// из journal, поэтому перезапуск, случившийся пока монитор не работал, тоже
+// попадает в хронологию. journal может быть nil.
+func NewTracker(journal *Journal, now time.Time) (*Tracker, error) {
+	t := &Tracker{journal: journal}
+	if journal == nil {
+		return t, nil
+	}
+
+	// Состояние восстанавливается по всей истории, метрика - по последним суткам
+	events, read, err := journal.readSince(position{})
+	t.read = read
+	for _, event := range events {
+		t.apply(event)
+		if now.Sub(event.Time) < historyWindow {
+			t.recent = append(t.recent, event)
+		}
+	}
+	return t, err
+}
+
+// apply переносит в текущее состояние результат события из журнала
+func (t *Tracker) apply(event Event) {
+	t.known = true
+	switch event.Kind {
+	case KindStop:
+		t.current = instance{nRestarts: -1, seen: event.Time}
+	case KindCrash:
+		if event.PID == 0 {
+			t.current = instance{nRestarts: -1, seen: event.Time}
+			return
+		}
+		fallthrough
+	case KindStart, KindRestart:
+		t.current = instance{
+			running:    true,
+			pid:        event.PID,
+			startedAt:  event.StartedAt,
+			reloadedAt: event.ReloadedAt,
+			nRestarts:  -1,
+			seen:       event.Time,
+		}
+	case KindReload:
+		t.current.reloadedAt = event.ReloadedAt
+	}
+}
+
+// Observe сравнивает снимок с предыдущим, записывает новые события в журнал и возвращает их.
+// Журнал пишет один процесс: второй только подхватывает из файла записанные им
+// события и возвращает их, чтобы каждое изменение попало в хронологию один раз.
+func (t *Tracker) Observe(snapshot types.LifecycleSnapshot) ([]Event, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var synced []Event
+	if t.journal != nil {
+		var err error
+		if synced, err = t.syncLocked(snapshot.Time); err != nil {
+			return nil, err
+		}
+		if !t.journal.Writer() {
+			t.pruneLocked(snapshot.Time)
+			return synced, nil
+		}
+	}
+
+	prev := t.current
+	cur := instanceOf(snapshot)
+	// Пока консоль не отвечает, время запуска неизвестно: тот же процесс сохраняет прежнее
+	if cur.running && cur.pid == prev.pid && cur.startedAt.IsZero() {
+		cur.startedAt, cur.reloadedAt = prev.startedAt, prev.reloadedAt
+	}
+
+	var events []Event
+	switch {
+	case !t.known:
+		if cur.running {
+			events = append(events, t.newEvent(KindStart, snapshot, cur, prev))
+		}
+	case prev.running && !cur.running:
+		events = append(events, t.stopEvent(snapshot, prev))
+	case !prev.running && cur.running:
+		events = append(events, t.newEvent(KindStart, snapshot, cur, prev))
+	case prev.running && cur.running && newInstance(prev, cur):
+		kind := KindRestart
+		if evidence := crashEvidence(snapshot, prev, true); len(evidence) > 0 {
+			kind = KindCrash
+		}
+		events = append(events, t.newEvent(kind, snapshot, cur, prev))
+	case prev.running && cur.running && reloaded(prev, cur):
+		events = append(events, Event{
+			Time:       cur.reloadedAt,
+			Kind:       KindReload,
+			PID:        cur.pid,
+			StartedAt:  cur.startedAt,
+			ReloadedAt: cur.reloadedAt,
+		})
+	}
+
+	t.known = true
+	t.current = cur
+	t.recent = append(t.recent, events...)
+	t.pruneLocked(snapshot.Time)
+
+	if t.journal == nil {
+		return events, nil
+	}
+	if err := t.journal.Append(events...); err != nil {
+		return append(synced, events...), err
+	}
+	// Свои события уже применены, позиция чтения переносится за них
+	_, read, err := t.journal.readSince(t.read)
+	if err != nil {
+		return append(synced, events...), err
+	}
+	t.read = read
+	return append(synced, events...), nil
+}
+
+// syncLocked применяет события, которые другой процесс дописал в журнал после
+// последнего чтения, и возвращает их. Читается только дописанная часть.
+func (t *Tracker) syncLocked(now time.Time) ([]Event, error) {
+	fresh, read, err := t.journal.readSince(t.read)
+	if err != nil {
+		return nil, err
+	}
+	t.read = read
+	for _, event := range fresh {
+		t.apply(event)
+		if now.Sub(event.Time) < historyWindow {
+			t.recent = append(t.recent, event)
+		}
+	}
+	return fresh, nil
+}
+
+// newEvent описывает появление процесса cur на месте prev
+func (t *Tracker) newEvent(kind string, snapshot types.LifecycleSnapshot, cur, prev instance) Event {
+	event := Event{
+		Time:       snapshot.Time,
+		Kind:       kind,
+		PID:        cur.pid,
+		StartedAt:  cur.startedAt,
+		ReloadedAt: cur.reloadedAt,
+		UnitResult: snapshot.Unit.Result,
+	}
+	if !cur.startedAt.IsZero() {
+		event.Time = cur.startedAt
+	}
+	if !t.known {
+		event.Detail = "first observation"
+		return event
+	}
+
+	if prev.running {
+		event.PrevPID = prev.pid
+		if !prev.startedAt.IsZero() {
+			event.Lifetime = event.Time.Sub(prev.startedAt).Round(time.Second).String()
+		}
+	}
+	if kind == KindCrash {
+		event.CoreFiles = newCoreFiles(snapshot, prev)
+		event.Detail = strings.Join(crashEvidence(snapshot, prev, true), "; ")
+	}
+	return event
+}
+
+// stopEvent описывает исчезновение процесса prev: остановку или сбой
+func (t *Tracker) stopEvent(snapshot types.LifecycleSnapshot, prev instance) Event {
+	event := Event{
+		Time:       snapshot.Time,
+		Kind:       KindStop,
+		PrevPID:    prev.pid,
+		UnitResult: snapshot.Unit.Result,
+	}
+	if !prev.startedAt.IsZero() {
+		event.Lifetime = snapshot.Time.Sub(prev.startedAt).Round(time.Second).String()
+	}
+	if evidence := crashEvidence(snapshot, prev, false); len(evidence) > 0 {
+		event.Kind = KindCrash
+		event.CoreFiles = newCoreFiles(snapshot, prev)
+		event.Detail = strings.Join(evidence, "; ")
+	}
+	return event
+}
+
+// crashEvidence возвращает признаки аварийного завершения прежнего процесса.
+// replaced - процесс уже заменен новым.
+func crashEvidence(snapshot types.LifecycleSnapshot, prev instance, replaced bool) []string {
+	var evidence []string
+	if unit := snapshot.Unit; failedResults[unit.Result] {
+		evidence = append(evidence, "systemd result "+unit.Result)
+	}
+	if unit := snapshot.Unit; prev.nRestarts >= 0 && unit.NRestarts > prev.nRestarts {
+		evidence = append(evidence, fmt.Sprintf("systemd restarted the unit (%d restarts)", unit.NRestarts))
+	}
+	for _, path := range newCoreFiles(snapshot, prev) {
+		evidence = append(evidence, "core file "+path)
+	}
+
+	// safe_asterisk перезапускает Asterisk, только если тот завершился сам
+	wrapper := snapshot.Process.WrapperPID
+	switch {
+	case replaced && prev.wrapperPID != 0 && wrapper == prev.wrapperPID && snapshot.Process.PID != prev.pid:
+		evidence = append(evidence, fmt.Sprintf("safe_asterisk (PID %d) restarted asterisk", wrapper))
+	case !replaced && wrapper != 0:
+		evidence = append(evidence, fmt.Sprintf("safe_asterisk (PID %d) is restarting asterisk", wrapper))
+	}
+	return evidence
+}
+
+// newCoreFiles возвращает дампы, появившиеся после предыдущего снимка
+func newCoreFiles(snapshot types.LifecycleSnapshot, prev instance) []string {
+	since := prev.seen
+	if since.IsZero() {
+		since = prev.startedAt
+	}
+	if since.IsZero() {
+		return nil
+	}
+
+	var paths []string
+	for _, core := range snapshot.CoreFiles {
+		if core.ModTime.After(since) {
+			paths = append(paths, core.Path)
+		}
+	}
+	return paths
+}
+
+// instanceOf возвращает процесс по снимку. Зависший процесс считается работающим.
+func instanceOf(snapshot types.LifecycleSnapshot) instance {
+	process := snapshot.Process
+	running := process.PID != 0 &&
+		(process.State == types.AsteriskRunning || process.State == types.AsteriskUnresponsive)
+	if !running {
+		return instance{nRestarts: snapshot.Unit.NRestarts, seen: snapshot.Time}
+	}
+	return instance{
+		running:    true,
+		pid:        process.PID,
+		wrapperPID: process.WrapperPID,
+		startedAt:  snapshot.StartedAt,
+		reloadedAt: snapshot.ReloadedAt,
+		nRestarts:  snapshot.Unit.NRestarts,
+		seen:       snapshot.Time,
+	}
+}
+
+// newInstance сообщает, что cur - другой процесс. core restart перезапускает
+// Asterisk в том же процессе, поэтому сравнивается и время запуска.
+func newInstance(prev, cur instance) bool {
+	if prev.pid != cur.pid {
+		return true
+	}
+	return !prev.startedAt.IsZero() && !cur.startedAt.IsZero() && cur.startedAt.Sub(prev.startedAt) > timeTolerance
+}
+
+// reloaded сообщает, что конфигурация перезагружалась после предыдущего снимка
+func reloaded(prev, cur instance) bool {
+	return !prev.reloadedAt.IsZero() && !cur.reloadedAt.IsZero() && cur.reloadedAt.Sub(prev.reloadedAt) > timeTolerance
+}
+
+func (t *Tracker) pruneLocked(now time.Time) {
+	cutoff := now.Add(-historyWindow)
+	for len(t.recent) > 0 && t.recent[0].Time.Before(cutoff) {
+		t.recent = t.recent[1:]
+	}
+}
+
+// Samples возвращает число незапланированных перезапусков за последний час.
+// Метрика выдается всегда, чтобы оповещение снималось через час после сбоя.
+func (t *Tracker) Samples(now time.Time) []types.Sample {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	count := 0
+	var last Event
+	for _, event := range t.recent {
+		if event.Unexpected() && now.Sub(event.Time) < unexpectedWindow {
+			count++
+			last = event
+		}
+	}
+
+	sample := types.Sample{Metric: types.MetricUnexpectedRestarts, Value: float64(count), Timestamp: now}
+	if count > 0 {
+		sample.Note = fmt.Sprintf("Asterisk crashed at %s", last.Time.Format("15:04:05"))
+		if last.Detail != "" {
+			sample.Note += ": " + last.Detail
+		}
+	}
+	return []types.Sample{sample}
+}
+
+// Events возвращает хронологию новее since, старые события первыми. Без журнала
+// возвращаются события за последние сутки из памяти.
+func (t *Tracker) Events(since time.Time) ([]Event, error) {
+	var events []Event
+	var err error
+	if t.journal != nil {
+		events, err = t.journal.Events(since)
+	} else {
+		t.mu.Lock()
+		for _, event := range t.recent {
+			if !event.Time.Before(since) {
+				events = append(events, event)
+			}
+		}
+		t.mu.Unlock()
+	}
+
+	// Запуск записывается со временем старта процесса, которое может быть
+	// раньше уже записанных событий
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events, err
+}
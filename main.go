@@ -1,13 +1,30 @@
 package main
 
 import (
+	"asterisk-monitor/alerts"
+	"asterisk-monitor/anomaly"
+	"asterisk-monitor/cli"
+	"asterisk-monitor/collector"
 	"asterisk-monitor/config"
+	"asterisk-monitor/daemon"
+	"asterisk-monitor/exporter"
+	"asterisk-monitor/lifecycle"
 	monitor "asterisk-monitor/monitors"
+	"asterisk-monitor/notify"
+	"asterisk-monitor/storage"
+	"asterisk-monitor/types"
 	"asterisk-monitor/ui"
+	"asterisk-monitor/watchdog"
+	"context"
+	"flag"
 	"fmt"
+	"log"
 	"os"
 	"os/exec"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -22,26 +39,59 @@ type appModel struct {
 	backup      ui.BackupModel
 	debug       ui.DebugModel
 	settings    ui.SettingsModel
+	alerts      ui.AlertsModel
+	internals   ui.InternalsModel
 	monitor     *monitor.LinuxMonitor
+	collector   *collector.Collector
+	alertEngine *alerts.Engine
 }
 
-func initialAppModel(configManager *config.ConfigManager) appModel {
+func initialAppModel(configManager *config.ConfigManager, store ui.MetricsStore, engine *alerts.Engine, dispatcher *notify.Dispatcher, tracker *lifecycle.Tracker, recoveryLog ui.RecoveryLog) appModel {
 	mon := monitor.NewLinuxMonitor()
+	mon.Configure(configManager.Get())
+	mon.SetModuleBaselineFile(configManager.ModuleBaselineFile())
+
+	// Виды читают данные из общего кэша, источники опрашиваются по расписанию
+	cache := collector.New(collector.Sources(mon, refreshInterval(configManager.Get()))...)
+
+	// Сохраненные в настройках правила действуют сразу, без перезапуска
+	applySettings := func(cfg *types.Config) error {
+		mon.SetAlertRules(cfg.Alerts)
+		return engine.Configure(cfg.Alerts, cfg.Monitoring.EnableAlerts)
+	}
 
 	return appModel{
 		currentView: "dashboard",
-		dashboard:   ui.NewDashboardModel(mon),
-		diagnostics: ui.NewDiagnosticsModel(mon),
-		channels:    ui.NewChannelsModel(mon),
+		dashboard:   ui.NewDashboardModel(cache, configManager, store, engine),
+		diagnostics: ui.NewDiagnosticsModel(mon, configManager),
+		channels:    ui.NewChannelsModel(cache, configManager),
 		logs:        ui.NewLogsModel(mon),
 		security:    ui.NewSecurityModel(mon),
 		backup:      ui.NewBackupModel(mon),
 		debug:       ui.NewDebugModel(mon),
-		settings:    ui.NewSettingsModel(configManager),
+		settings:    ui.NewSettingsModel(configManager, applySettings),
+		alerts:      ui.NewAlertsModel(engine, dispatcher),
+		internals: ui.NewInternalsModel(
+			ui.NewTimelineModel(tracker, cache),
+			ui.NewWatchdogModel(mon, recoveryLog, configManager),
+			ui.NewTaskprocessorsModel(cache),
+			ui.NewModulesModel(mon),
+		),
 		monitor:     mon,
+		collector:   cache,
+		alertEngine: engine,
 	}
 }
 
+// refreshInterval возвращает интервал обновления данных интерфейса
+func refreshInterval(cfg *types.Config) time.Duration {
+	interval := time.Duration(cfg.Monitoring.RefreshInterval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return interval
+}
+
 func (m appModel) Init() tea.Cmd {
 	return m.dashboard.Init()
 }
@@ -50,89 +100,123 @@ func (m appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
+	case ui.AlertsUpdatedMsg, ui.NoticeMsg:
+		// Дашборд и вкладка оповещений показывают оповещения, поэтому обновляются в любом виде
+		newModel, newCmd := m.dashboard.Update(msg)
+		m.dashboard = newModel.(ui.DashboardModel)
+		alertsModel, _ := m.alerts.Update(msg)
+		m.alerts = alertsModel.(ui.AlertsModel)
+		return m, newCmd
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "1":
-			m.currentView = "dashboard"
-			cmd = m.dashboard.Init()
-		case "2":
-			m.currentView = "diagnostics"
-			cmd = m.diagnostics.Init()
-		case "3":
-			m.currentView = "channels"
-			cmd = m.channels.Init()
-		case "4":
-			m.currentView = "logs"
-			cmd = m.logs.Init()
-		case "5":
-			m.currentView = "security"
-			cmd = m.security.Init()
-		case "6":
-			m.currentView = "backup"
-			cmd = m.backup.Init()
-		case "7":
-			m.currentView = "settings"
-			cmd = m.settings.Init()
-		case "8":
-			m.currentView = "debug"
-			cmd = m.debug.Init()
+		// Во время ввода заметки клавиши передаются вкладке оповещений
+		if m.currentView == "alerts" && m.alerts.Typing() {
+			break
+		}
+		switch key := msg.String(); key {
+		case "0", "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			cmd = m.switchView(viewKeys[key])
 		case "q", "Q", "ctrl+c":
 			return m, tea.Quit
 		}
 	}
 
+	return m, tea.Batch(cmd, m.updateView(msg))
+}
+
+// viewKeys сопоставляет клавиши 0-9 видам
+var viewKeys = map[string]string{
+	"0": "internals",
+	"1": "dashboard",
+	"2": "diagnostics",
+	"3": "channels",
+	"4": "logs",
+	"5": "security",
+	"6": "backup",
+	"7": "settings",
+	"8": "debug",
+	"9": "alerts",
+}
+
+// switchView делает вид name текущим. Фоновые задачи прежнего вида отменяются.
+func (m *appModel) switchView(name string) tea.Cmd {
+	if name != m.currentView {
+		m.updateView(ui.CancelTasksMsg{})
+	}
+	m.currentView = name
+
+	switch name {
+	case "dashboard":
+		return m.dashboard.Init()
+	case "diagnostics":
+		return m.diagnostics.Init()
+	case "channels":
+		return m.channels.Init()
+	case "logs":
+		return m.logs.Init()
+	case "security":
+		return m.security.Init()
+	case "backup":
+		return m.backup.Init()
+	case "settings":
+		return m.settings.Init()
+	case "debug":
+		return m.debug.Init()
+	case "alerts":
+		return m.alerts.Init()
+	case "internals":
+		return m.internals.Init()
+	}
+	return nil
+}
+
+// updateView передает сообщение текущему виду
+func (m *appModel) updateView(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+
 	switch m.currentView {
 	case "dashboard":
 		newModel, newCmd := m.dashboard.Update(msg)
 		m.dashboard = newModel.(ui.DashboardModel)
-		if newCmd != nil {
-			cmd = newCmd
-		}
+		cmd = newCmd
 	case "diagnostics":
 		newModel, newCmd := m.diagnostics.Update(msg)
 		m.diagnostics = newModel.(ui.DiagnosticsModel)
-		if newCmd != nil {
-			cmd = newCmd
-		}
+		cmd = newCmd
 	case "channels":
 		newModel, newCmd := m.channels.Update(msg)
 		m.channels = newModel.(ui.ChannelsModel)
-		if newCmd != nil {
-			cmd = newCmd
-		}
+		cmd = newCmd
 	case "logs":
 		newModel, newCmd := m.logs.Update(msg)
 		m.logs = newModel.(ui.LogsModel)
-		if newCmd != nil {
-			cmd = newCmd
-		}
+		cmd = newCmd
 	case "security":
 		newModel, newCmd := m.security.Update(msg)
 		m.security = newModel.(ui.SecurityModel)
-		if newCmd != nil {
-			cmd = newCmd
-		}
+		cmd = newCmd
 	case "backup":
 		newModel, newCmd := m.backup.Update(msg)
 		m.backup = newModel.(ui.BackupModel)
-		if newCmd != nil {
-			cmd = newCmd
-		}
+		cmd = newCmd
 	case "debug":
 		newModel, newCmd := m.debug.Update(msg)
 		m.debug = newModel.(ui.DebugModel)
-		if newCmd != nil {
-			cmd = newCmd
-		}
+		cmd = newCmd
 	case "settings":
 		newModel, newCmd := m.settings.Update(msg)
 		m.settings = newModel.(ui.SettingsModel)
-		if newCmd != nil {
-			cmd = newCmd
-		}
+		cmd = newCmd
+	case "alerts":
+		newModel, newCmd := m.alerts.Update(msg)
+		m.alerts = newModel.(ui.AlertsModel)
+		cmd = newCmd
+	case "internals":
+		newModel, newCmd := m.internals.Update(msg)
+		m.internals = newModel.(ui.InternalsModel)
+		cmd = newCmd
 	}
 
-	return m, cmd
+	return cmd
 }
 
 func (m appModel) View() string {
@@ -155,6 +239,10 @@ func (m appModel) View() string {
 		view = m.debug.View()
 	case "settings":
 		view = m.settings.View()
+	case "alerts":
+		view = m.alerts.View()
+	case "internals":
+		view = m.internals.View()
 	default:
 		view = m.dashboard.View()
 	}
@@ -172,6 +260,8 @@ func (m appModel) renderHeader() string {
 		"6: Backup",
 		"7: Settings",
 		"8: Debug",
+		"9: Alerts",
+		"0: Internals",
 	}
 
 	var currentViewName string
@@ -192,17 +282,48 @@ func (m appModel) renderHeader() string {
 		currentViewName = "⚙️ Settings"
 	case "debug":
 		currentViewName = "🐛 Debug"
+	case "alerts":
+		currentViewName = "🚨 Alerts"
+	case "internals":
+		currentViewName = "🔬 Internals"
 	}
 
 	header := fmt.Sprintf("Asterisk Monitor - %s", currentViewName)
 	navigation := strings.Join(views, " | ")
 
-	return ui.TitleStyle.Render(header) + "\n" +
-		ui.InfoStyle.Render(navigation) + "\n" +
-		strings.Repeat("─", 80)
+	result := ui.TitleStyle.Render(header) + "\n" +
+		ui.InfoStyle.Render(navigation) + "\n"
+	if banner := ui.AlertBanner(m.alertEngine); banner != "" {
+		result += banner + "\n"
+	}
+	return result + strings.Repeat("─", 80)
 }
 
 func main() {
+	exporterMode := flag.Bool("exporter", false, "запустить Prometheus экспортер вместо TUI")
+	daemonMode := flag.Bool("daemon", false, "запустить фоновый режим без терминала")
+	listenAddr := flag.String("listen", "", "адрес HTTP сервера экспортера (по умолчанию из config.ini)")
+	flag.Parse()
+
+	// Подкоманды для скриптов выполняются без TUI и интерактивных вопросов
+	if flag.NArg() > 0 {
+		configManager := config.NewConfigManager()
+		if err := configManager.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		}
+
+		mon := monitor.NewLinuxMonitor()
+		mon.Configure(configManager.Get())
+		mon.SetModuleBaselineFile(configManager.ModuleBaselineFile())
+
+		os.Exit(cli.Run(cli.Env{
+			Monitor: mon,
+			Config:  configManager.Get(),
+			Stdout:  os.Stdout,
+			Stderr:  os.Stderr,
+		}, flag.Args()))
+	}
+
 	// Проверяем, установлен ли Asterisk
 	if !isAsteriskInstalled() {
 		fmt.Println("❌ Asterisk не установлен или не найден в PATH")
@@ -213,6 +334,29 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Загружаем конфигурацию
+	configManager := config.NewConfigManager()
+	if err := configManager.Load(); err != nil {
+		fmt.Printf("⚠️  Не удалось загрузить конфигурацию: %v\n", err)
+		fmt.Println("Будет использована конфигурация по умолчанию")
+	}
+
+	if *exporterMode {
+		if err := runExporter(configManager, *listenAddr); err != nil {
+			fmt.Printf("Ошибка экспортера: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *daemonMode {
+		if err := runDaemon(configManager); err != nil {
+			fmt.Printf("Ошибка фонового режима: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Проверяем права доступа
 	if !hasAsteriskAccess() {
 		fmt.Println("⚠️  Предупреждение: возможны проблемы с доступом к Asterisk")
@@ -226,32 +370,209 @@ func main() {
 		}
 	}
 
-	// Загружаем конфигурацию
-	configManager := config.NewConfigManager()
-	if err := configManager.Load(); err != nil {
-		fmt.Printf("⚠️  Не удалось загрузить конфигурацию: %v\n", err)
-		fmt.Println("Будет использована конфигурация по умолчанию")
+	// Открываем хранилище истории метрик
+	var store ui.MetricsStore
+	dataStore, err := storage.Open(configManager.DataDir(), configManager.Get().Monitoring.LogRetention)
+	if err != nil {
+		fmt.Printf("⚠️  Не удалось открыть хранилище метрик: %v\n", err)
+		fmt.Println("История метрик сохраняться не будет")
+	} else {
+		defer dataStore.Close()
+		store = dataStore
 	}
 
 	fmt.Println("🚀 Запуск Asterisk Monitor...")
-	fmt.Println("   Переключение между модулями: 1-5")
+	fmt.Println("   Переключение между модулями: 0-9")
 	fmt.Println("   Для выхода нажмите Ctrl+C или Q")
 
-	model := initialAppModel(configManager)
+	// Движок оповещений работает в фоне и сообщает интерфейсу об изменениях
+	cfg := configManager.Get()
+	engine, err := alerts.NewEngine(cfg.Alerts, cfg.Monitoring.EnableAlerts)
+	if err != nil {
+		fmt.Printf("⚠️  Ошибки в правилах оповещений: %v\n", err)
+	}
+	if journal, err := alerts.OpenJournal(configManager.AlertHistoryFile(), configManager.AlertStateFile()); err != nil {
+		fmt.Printf("⚠️  Не удалось открыть журнал оповещений: %v\n", err)
+	} else if err := engine.UseJournal(journal, time.Now()); err != nil {
+		fmt.Printf("⚠️  Не удалось прочитать журнал оповещений: %v\n", err)
+	}
+
+	dispatcher, err := notify.NewDispatcher(cfg)
+	if err != nil {
+		fmt.Printf("⚠️  Ошибки в настройках уведомлений: %v\n", err)
+	}
+
+	// Хронология перезапусков Asterisk общая с фоновым режимом
+	journal, err := lifecycle.OpenJournal(configManager.RestartHistoryFile())
+	if err != nil {
+		fmt.Printf("⚠️  Не удалось открыть хронологию перезапусков: %v\n", err)
+	}
+	tracker, err := lifecycle.NewTracker(journal, time.Now())
+	if err != nil {
+		fmt.Printf("⚠️  Не удалось прочитать хронологию перезапусков: %v\n", err)
+	}
+
+	// Журнал сторожа пишет фоновый режим, интерфейс его только показывает
+	var recoveryLog ui.RecoveryLog
+	if journal, err := watchdog.OpenJournal(configManager.RecoveryLogFile()); err != nil {
+		fmt.Printf("⚠️  Не удалось открыть журнал сторожа: %v\n", err)
+	} else {
+		recoveryLog = journal
+	}
+
+	model := initialAppModel(configManager, store, engine, dispatcher, tracker, recoveryLog)
 	p := tea.NewProgram(model, tea.WithAltScreen())
+
+	interval := refreshInterval(cfg)
+
+	dispatcher.SetErrorHandler(func(notifier string, err error) {
+		p.Send(ui.NoticeMsg{Text: fmt.Sprintf("Notifier %s: %v", notifier, err)})
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go dispatcher.Run(ctx)
+
+	// После каждого обновления системных метрик измерения сохраняются в историю,
+	// виды узнают об обновлениях источников из сообщений
+	cache := model.collector
+	cache.OnUpdate(func(source string) {
+		if source == collector.SourceSystem && store != nil {
+			samples, _ := cachedSamples(cache, tracker, time.Now())
+			if err := store.Append(samples...); err != nil {
+				p.Send(ui.NoticeMsg{Text: fmt.Sprintf("Failed to store metrics: %v", err)})
+			}
+		}
+		if source == collector.SourceLifecycle {
+			observeLifecycle(cache, tracker, func(text string) { p.Send(ui.NoticeMsg{Text: text}) })
+		}
+		p.Send(ui.CollectorUpdatedMsg{Source: source})
+	})
+	go cache.Run(ctx)
+
+	// Аномалии объема вызовов вычисляются по базовой линии из истории хранилища
+	detector := anomaly.NewDetector(store, cfg.Anomaly)
+	go engine.Run(ctx, interval,
+		func() ([]types.Sample, []string) {
+			samples, unknown := collectSamples(ctx, cache, tracker)
+			return append(samples, detector.Observe(samples, time.Now())...), unknown
+		},
+		func(changed []alerts.Alert) {
+			// Если запущен фоновый режим, историю и уведомления ведет он
+			if engine.Leader() {
+				dispatcher.Enqueue(changed, time.Now())
+			}
+			p.Send(ui.AlertsUpdatedMsg{})
+		})
+
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Ошибка запуска приложения: %v\n", err)
+		if dataStore != nil {
+			dataStore.Close()
+		}
 		os.Exit(1)
 	}
 }
 
+// runExporter отдает метрики в формате Prometheus до получения SIGINT/SIGTERM
+func runExporter(configManager *config.ConfigManager, listen string) error {
+	cfg := configManager.Get().Exporter
+	if listen == "" {
+		listen = cfg.Listen
+	}
+	if cfg.Interval <= 0 || cfg.ChecksInterval <= 0 {
+		return fmt.Errorf("интервалы [exporter] должны быть больше нуля")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	mon := monitor.NewLinuxMonitor()
+	mon.Configure(configManager.Get())
+
+	collector := exporter.NewCollector(mon,
+		time.Duration(cfg.Interval)*time.Second,
+		time.Duration(cfg.ChecksInterval)*time.Second)
+
+	fmt.Printf("📈 Prometheus экспортер слушает %s/metrics\n", listen)
+	return exporter.Serve(ctx, listen, collector)
+}
+
+// runDaemon выполняет проверки по расписанию до SIGINT/SIGTERM, SIGHUP перечитывает конфигурацию
+func runDaemon(configManager *config.ConfigManager) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+
+	store, err := storage.Open(configManager.DataDir(), configManager.Get().Monitoring.LogRetention)
+	if err != nil {
+		log.Printf("metrics store unavailable: %v", err)
+		store = nil
+	} else {
+		defer store.Close()
+	}
+
+	return daemon.New(configManager, monitor.NewLinuxMonitor(), store).Run(ctx, reload)
+}
+
+// collectSamples собирает измерения для вычисления правил оповещений.
+// Свежие значения берутся из кэша, устаревшие источники опрашиваются заново.
+func collectSamples(ctx context.Context, cache *collector.Collector, tracker *lifecycle.Tracker) ([]types.Sample, []string) {
+	for _, source := range []string{collector.SourceSystem, collector.SourcePeers, collector.SourceCallQuality, collector.SourceTaskprocessors, collector.SourceNetwork} {
+		// Ошибку источника вернет cachedSamples
+		_, _ = cache.Get(ctx, source)
+	}
+	return cachedSamples(cache, tracker, time.Now())
+}
+
+// observeLifecycle передает трекеру последний снимок состояния Asterisk и
+// сообщает notice о сбоях и ошибках записи хронологии
+func observeLifecycle(cache *collector.Collector, tracker *lifecycle.Tracker, notice func(string)) {
+	snapshot, _ := collector.Cached[types.LifecycleSnapshot](cache, collector.SourceLifecycle)
+	if snapshot.Time.IsZero() {
+		return
+	}
+
+	events, err := tracker.Observe(snapshot)
+	if err != nil {
+		notice(fmt.Sprintf("Failed to record restart history: %v", err))
+	}
+	for _, event := range events {
+		if event.Unexpected() {
+			notice(fmt.Sprintf("Asterisk crashed (PID %d): %s", event.PrevPID, event.Detail))
+		}
+	}
+}
+
+// cachedSamples преобразует свежие значения источников и хронологию перезапусков
+// в измерения. Источники с ошибкой или устаревшим значением не дают измерений,
+// их метрики возвращаются как неизвестные.
+func cachedSamples(cache *collector.Collector, tracker *lifecycle.Tracker, now time.Time) ([]types.Sample, []string) {
+	var readings monitor.Readings
+	readings.System, readings.SystemErr = collector.Fresh[types.SystemMetrics](cache, collector.SourceSystem)
+	readings.Peers, readings.PeersErr = collector.Fresh[[]types.SIPPeer](cache, collector.SourcePeers)
+	readings.CallQuality, readings.CallQualityErr = collector.Fresh[[]types.CallQuality](cache, collector.SourceCallQuality)
+	readings.Taskprocessors, readings.TaskprocessorsErr = collector.Fresh[types.TaskprocessorReport](cache, collector.SourceTaskprocessors)
+	readings.Network, readings.NetworkErr = collector.Fresh[types.NetworkHealth](cache, collector.SourceNetwork)
+
+	samples, unknown := readings.Samples(now)
+	return append(samples, tracker.Samples(now)...), unknown
+}
+
 func isAsteriskInstalled() bool {
 	_, err := exec.LookPath("asterisk")
 	return err == nil
 }
 
 func hasAsteriskAccess() bool {
-	cmd := exec.Command("asterisk", "-rx", "core show version")
+	// Зависший Asterisk не должен блокировать запуск
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "asterisk", "-rx", "core show version")
 	err := cmd.Run()
 	return err == nil
 }
@@ -0,0 +1,379 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+// DefaultBackupPath - каталог бэкапов по умолчанию
+const DefaultBackupPath = "/tmp/asterisk-backups"
+
+// Номера шагов восстановления (с нуля): остановка Asterisk и копирование
+// текущей конфигурации. До копирования /etc/asterisk не меняется.
+const (
+	restoreStopStep = 1
+	restoreSaveStep = 2
+)
+
+// backupStep - шаг бэкапа или восстановления. Ошибка необязательного шага
+// (например, нет доступа к одному из каталогов) не прерывает процедуру.
+type backupStep struct {
+	cmd      Command
+	optional bool
+	fallback string // сообщение о пропуске необязательного шага
+}
+
+func step(program string, args ...string) backupStep {
+	return backupStep{cmd: Cmd(program, args...)}
+}
+
+func optionalStep(fallback, program string, args ...string) backupStep {
+	return backupStep{cmd: Cmd(program, args...), optional: true, fallback: fallback}
+}
+
+// runStep выполняет шаг. Ошибка необязательного шага становится предупреждением.
+func (m *LinuxMonitor) runStep(ctx context.Context, name string, s backupStep) types.CheckResult {
+	result := m.Run(ctx, name, s.cmd)
+	if result.Status == "error" && s.optional && ctx.Err() == nil {
+		result.Status = "warning"
+		if s.fallback != "" {
+			result.Message = s.fallback
+		}
+	}
+	return result
+}
+
+// stepRecorder накапливает результаты шагов и сообщает о каждом через progress
+type stepRecorder struct {
+	results  []types.CheckResult
+	progress func(types.CheckResult)
+}
+
+func (r *stepRecorder) add(result types.CheckResult) {
+	r.results = append(r.results, result)
+	if r.progress != nil {
+		r.progress(result)
+	}
+}
+
+// CreateBackup создает архив конфигурации и данных Asterisk в каталоге backupPath.
+// Возвращает путь к архиву и результаты всех шагов; последний результат - итоговый.
+// progress (может быть nil) получает результат каждого шага сразу после его завершения.
+// При отмене ctx незавершенный архив удаляется.
+func (m *LinuxMonitor) CreateBackup(ctx context.Context, backupPath string, progress func(types.CheckResult)) (string, []types.CheckResult) {
+	if backupPath == "" {
+		backupPath = DefaultBackupPath
+	}
+
+	steps := &stepRecorder{progress: progress}
+	backupPath, err := ValidatePath(backupPath)
+	if err != nil {
+		steps.add(types.CheckResult{
+			Name:      "Backup Error",
+			Status:    "error",
+			Message:   fmt.Sprintf("Invalid backup path: %v", err),
+			Timestamp: time.Now(),
+		})
+		return "", steps.results
+	}
+
+	timestamp := time.Now().Format("2006-01-02-150405")
+	backupFile := filepath.Join(backupPath, fmt.Sprintf("asterisk-backup-%s.tar.gz", timestamp))
+
+	// Рабочий каталог со случайным именем: в общем /tmp предсказуемое имя
+	// позволило бы подменить его символической ссылкой
+	backupDir, err := os.MkdirTemp("", "asterisk-backup-"+timestamp+"-")
+	if err != nil {
+		steps.add(types.CheckResult{
+			Name:      "Backup Error",
+			Status:    "error",
+			Message:   fmt.Sprintf("Cannot create working directory: %v", err),
+			Timestamp: time.Now(),
+		})
+		return "", steps.results
+	}
+
+	steps.add(types.CheckResult{
+		Name:      "Backup Started",
+		Status:    "info",
+		Message:   fmt.Sprintf("Creating backup to: %s", backupFile),
+		Timestamp: time.Now(),
+	})
+
+	// Каталоги копируются с полными путями (etc/asterisk, var/lib/asterisk...),
+	// в таком виде их ожидает RestoreBackup
+	commands := []backupStep{
+		step("mkdir", "-p", "--", backupPath),
+		optionalStep("No /etc/asterisk access", "cp", "-r", "--parents", "--", "/etc/asterisk", backupDir),
+		optionalStep("No /var/lib/asterisk access", "cp", "-r", "--parents", "--", "/var/lib/asterisk", backupDir),
+		optionalStep("No /var/spool/asterisk access", "cp", "-r", "--parents", "--", "/var/spool/asterisk", backupDir),
+		optionalStep("No /var/log/asterisk access", "cp", "-r", "--parents", "--", "/var/log/asterisk", backupDir),
+		step("tar", "-czf", backupFile, "-C", backupDir, "."),
+		step("rm", "-rf", "--", backupDir),
+		optionalStep("", "chmod", "644", "--", backupFile),
+	}
+
+	for i, cmd := range commands {
+		result := m.runStep(ctx, fmt.Sprintf("Backup Step %d", i+1), cmd)
+		steps.add(result)
+
+		// Если ошибка или отмена, прерываем
+		if result.Status == "error" || ctx.Err() != nil {
+			// Cleanup on error. Выполняется и после отмены ctx.
+			m.Exec(context.Background(), "Cleanup", "rm", "-rf", "--", backupDir, backupFile)
+			if ctx.Err() != nil {
+				steps.add(types.CheckResult{
+					Name:      "Backup Cancelled",
+					Status:    "warning",
+					Message:   fmt.Sprintf("Backup cancelled at step %d, partial files removed", i+1),
+					Timestamp: time.Now(),
+				})
+			}
+			return backupFile, steps.results
+		}
+	}
+
+	// Verify backup
+	verifyResult := m.Exec(ctx, "Verify Backup", "tar", "-tzf", backupFile)
+
+	if fileCount := countLines(verifyResult.Message); verifyResult.Status == "success" && fileCount > 0 {
+		steps.add(types.CheckResult{
+			Name:      "Backup Completed",
+			Status:    "success",
+			Message:   fmt.Sprintf("Backup created successfully: %s (%d files)", backupFile, fileCount),
+			Timestamp: time.Now(),
+		})
+	} else {
+		steps.add(types.CheckResult{
+			Name:      "Backup Completed",
+			Status:    "warning",
+			Message:   fmt.Sprintf("Backup created but verification failed: %s", backupFile),
+			Timestamp: time.Now(),
+		})
+	}
+
+	return backupFile, steps.results
+}
+
+// RestoreBackup восстанавливает конфигурацию и данные Asterisk из архива.
+// Последний результат - итоговый, progress (может быть nil) получает результат
+// каждого шага. Отмена ctx учитывается только до остановки Asterisk: прерванное
+// на середине восстановление оставило бы станцию без конфигурации.
+func (m *LinuxMonitor) RestoreBackup(ctx context.Context, backupFile string, progress func(types.CheckResult)) []types.CheckResult {
+	steps := &stepRecorder{progress: progress}
+	if backupFile == "" {
+		steps.add(types.CheckResult{
+			Name:      "Restore Error",
+			Status:    "error",
+			Message:   "No backup file specified",
+			Timestamp: time.Now(),
+		})
+		return steps.results
+	}
+
+	steps.add(types.CheckResult{
+		Name:      "Restore Started",
+		Status:    "info",
+		Message:   fmt.Sprintf("Starting restore from: %s", backupFile),
+		Timestamp: time.Now(),
+	})
+
+	// Check if backup file exists
+	if err := validateBackupFile(backupFile); err != nil {
+		steps.add(types.CheckResult{
+			Name:      "Restore Error",
+			Status:    "error",
+			Message:   fmt.Sprintf("Cannot restore: %v", err),
+			Timestamp: time.Now(),
+		})
+		return steps.results
+	}
+	backupFile = filepath.Clean(backupFile)
+
+	// Каталог распаковки создается со случайным именем, как и при создании бэкапа
+	restoreDir, err := os.MkdirTemp("", "asterisk-restore-")
+	if err != nil {
+		steps.add(types.CheckResult{
+			Name:      "Restore Error",
+			Status:    "error",
+			Message:   fmt.Sprintf("Cannot create working directory: %v", err),
+			Timestamp: time.Now(),
+		})
+		return steps.results
+	}
+	configBackup := fmt.Sprintf("/etc/asterisk.backup.%d", time.Now().Unix())
+
+	// Содержимое каталога копируется через "каталог/.", без раскрытия * оболочкой
+	commands := []backupStep{
+		step("tar", "-xzf", backupFile, "-C", restoreDir),
+
+		// Stop Asterisk before restore
+		step("systemctl", "stop", "asterisk"),
+
+		// Backup current configuration
+		step("cp", "-r", "--", "/etc/asterisk", configBackup),
+
+		// Restore files
+		optionalStep("No config files to restore", "cp", "-r", "--", restoreDir+"/etc/asterisk/.", "/etc/asterisk/"),
+		optionalStep("No lib files to restore", "cp", "-r", "--", restoreDir+"/var/lib/asterisk/.", "/var/lib/asterisk/"),
+		optionalStep("No spool files to restore", "cp", "-r", "--", restoreDir+"/var/spool/asterisk/.", "/var/spool/asterisk/"),
+
+		// Fix permissions
+		optionalStep("", "chown", "-R", "asterisk:asterisk", "/etc/asterisk/"),
+		optionalStep("", "chown", "-R", "asterisk:asterisk", "/var/lib/asterisk/"),
+		optionalStep("", "chown", "-R", "asterisk:asterisk", "/var/spool/asterisk/"),
+
+		// Start Asterisk
+		step("systemctl", "start", "asterisk"),
+	}
+
+	// Распакованный архив удаляется при любом исходе
+	defer m.Exec(context.Background(), "Cleanup", "rm", "-rf", "--", restoreDir)
+
+	for i, cmd := range commands {
+		// Распаковку еще можно прервать, после остановки Asterisk шаги доводятся до конца
+		stepCtx := ctx
+		if i >= restoreStopStep {
+			stepCtx = context.WithoutCancel(ctx)
+		} else if ctx.Err() != nil {
+			steps.add(types.CheckResult{
+				Name:      "Restore Cancelled",
+				Status:    "warning",
+				Message:   fmt.Sprintf("Restore from %s cancelled before Asterisk was stopped, nothing changed", backupFile),
+				Timestamp: time.Now(),
+			})
+			return steps.results
+		}
+
+		result := m.runStep(stepCtx, fmt.Sprintf("Restore Step %d", i+1), cmd)
+		steps.add(result)
+
+		if result.Status == "error" {
+			steps.add(m.recoverRestore(i, configBackup, backupFile, steps))
+			return steps.results
+		}
+	}
+
+	steps.add(types.CheckResult{
+		Name:      "Restore Completed",
+		Status:    "success",
+		Message:   fmt.Sprintf("Backup restored successfully from: %s", backupFile),
+		Timestamp: time.Now(),
+	})
+	return steps.results
+}
+
+// recoverRestore возвращает станцию в рабочее состояние после ошибки на шаге
+// failed и возвращает итоговый результат. Конфигурация возвращается из копии,
+// только если файлы уже начали заменяться, Asterisk запускается, только если
+// его успели остановить. Результаты аварийных шагов передаются в steps.
+func (m *LinuxMonitor) recoverRestore(failed int, configBackup, backupFile string, steps *stepRecorder) types.CheckResult {
+	emergency := context.Background()
+	message := fmt.Sprintf("Restore from %s failed at step %d", backupFile, failed+1)
+
+	switch {
+	case failed < restoreStopStep:
+		message += " before Asterisk was stopped, nothing changed"
+	case failed == restoreStopStep:
+		// Остановка не удалась, Asterisk не трогаем: он мог продолжить работу
+		message += ", Asterisk could not be stopped, configuration unchanged"
+	default:
+		restored := true
+		if failed > restoreSaveStep {
+			copyBack := m.Exec(emergency, "Emergency Restore", "cp", "-r", "--", configBackup+"/.", "/etc/asterisk/")
+			steps.add(copyBack)
+			restored = copyBack.Status != "error"
+		}
+		start := m.Exec(emergency, "Emergency Start", "systemctl", "start", "asterisk")
+		steps.add(start)
+
+		switch {
+		case !restored:
+			message += fmt.Sprintf(", previous configuration could not be restored, it is saved in %s", configBackup)
+		case failed > restoreSaveStep:
+			message += ", previous configuration restored"
+		default:
+			message += ", configuration unchanged"
+		}
+		if start.Status == "error" {
+			message += ", Asterisk failed to start"
+		} else {
+			message += ", Asterisk started"
+		}
+	}
+
+	return types.CheckResult{
+		Name:      "Restore Failed",
+		Status:    "error",
+		Message:   message,
+		Timestamp: time.Now(),
+	}
+}
+
+// validateBackupFile проверяет путь к архиву, введенный пользователем
+func validateBackupFile(path string) error {
+	clean, err := ValidatePath(path)
+	if err != nil {
+		return fmt.Errorf("invalid backup file: %v", err)
+	}
+	if !strings.HasSuffix(clean, ".tar.gz") && !strings.HasSuffix(clean, ".tgz") {
+		return fmt.Errorf("backup file must be a .tar.gz archive: %s", clean)
+	}
+	info, err := os.Stat(clean)
+	if err != nil || !info.Mode().IsRegular() {
+		return fmt.Errorf("backup file not found: %s", clean)
+	}
+	return nil
+}
+
+// ListBackups возвращает бэкапы в каталоге backupPath, новые первыми
+func (m *LinuxMonitor) ListBackups(backupPath string) ([]types.BackupInfo, error) {
+	if backupPath == "" {
+		backupPath = DefaultBackupPath
+	}
+
+	files, err := filepath.Glob(filepath.Join(backupPath, "asterisk-backup-*.tar.gz"))
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []types.BackupInfo
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		backups = append(backups, types.BackupInfo{
+			Path:     file,
+			Size:     info.Size(),
+			Modified: info.ModTime(),
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Modified.After(backups[j].Modified)
+	})
+	return backups, nil
+}
+
+// FormatBytes возвращает размер в человекочитаемом виде (1.5M, 320K)
+func FormatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%c", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+// DefaultCertDir - каталог, в котором ищутся TLS сертификаты Asterisk
+const DefaultCertDir = "/etc/asterisk"
+
+// GetCertificates возвращает сертификаты из .pem/.crt файлов каталога dir,
+// отсортированные по дате истечения
+func (m *LinuxMonitor) GetCertificates(dir string) ([]types.CertInfo, error) {
+	if dir == "" {
+		dir = DefaultCertDir
+	}
+
+	var certs []types.CertInfo
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Недоступные подкаталоги пропускаем
+			if d != nil && d.IsDir() && path != dir {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".pem" && ext != ".crt" {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		certs = append(certs, parseCertificates(path, data)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(certs, func(i, j int) bool {
+		return certs[i].NotAfter.Before(certs[j].NotAfter)
+	})
+	return certs, nil
+}
+
+// parseCertificates извлекает все блоки CERTIFICATE из PEM данных
+func parseCertificates(path string, data []byte) []types.CertInfo {
+	var certs []types.CertInfo
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+
+		certs = append(certs, types.CertInfo{
+			Path:     path,
+			Subject:  cert.Subject.CommonName,
+			NotAfter: cert.NotAfter,
+			DaysLeft: int(time.Until(cert.NotAfter).Hours() / 24),
+		})
+	}
+
+	return certs
+}
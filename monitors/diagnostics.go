@@ -0,0 +1,125 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"asterisk-monitor/alerts"
+	"asterisk-monitor/types"
+)
+
+// Check - одна именованная проверка диагностики.
+// Run должна завершиться при отмене ctx.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) types.CheckResult
+}
+
+// commandCheck возвращает проверку, которая выполняет cmd и обрабатывает ее
+// вывод функцией filter (может быть nil)
+func (m *LinuxMonitor) commandCheck(name string, cmd Command, filter func(string) string) Check {
+	return Check{
+		Name: name,
+		Run: func(ctx context.Context) types.CheckResult {
+			return filterOutput(m.Run(ctx, name, cmd), filter)
+		},
+	}
+}
+
+// asteriskCheck возвращает проверку, которая выполняет команду Asterisk CLI
+// и обрабатывает ее вывод функцией filter (может быть nil)
+func (m *LinuxMonitor) asteriskCheck(name, command string, filter func(string) string) Check {
+	return Check{
+		Name: name,
+		Run: func(ctx context.Context) types.CheckResult {
+			return filterOutput(m.AsteriskCommand(ctx, name, command), filter)
+		},
+	}
+}
+
+// DiagnosticChecks возвращает проверки быстрой (full=false) или полной диагностики
+func (m *LinuxMonitor) DiagnosticChecks(full bool) []Check {
+	checks := []Check{
+		m.commandCheck("Service Status", Cmd("systemctl", "is-active", "asterisk"), nil),
+		m.commandCheck("Asterisk Process", Cmd("pgrep", "-a", "-x", "asterisk"), firstLines(1)),
+		m.asteriskCheck("Version Info", "core show version", firstLines(1)),
+	}
+
+	if full {
+		checks = append(checks,
+			m.asteriskCheck("Codecs", "core show translation", firstLines(5)),
+			m.asteriskCheck("Dialplan", "dialplan show", counting("Context")),
+			Check{Name: "Modules", Run: m.checkModules},
+			m.commandCheck("Network", Cmd("ping", "-c", "2", "8.8.8.8"), matching("packet loss", "Network test failed")),
+			Check{Name: "Ports", Run: m.checkPorts},
+			m.commandCheck("System Load", Cmd("uptime"), nil),
+		)
+	}
+
+	checks = append(checks,
+		Check{Name: "SIP Peers", Run: m.checkSIPPeers},
+		Check{Name: "Active Channels", Run: m.checkActiveChannels},
+	)
+
+	return checks
+}
+
+// RunChecks последовательно выполняет проверки и возвращает их результаты.
+// При отмене ctx оставшиеся проверки не запускаются.
+func RunChecks(ctx context.Context, checks []Check) []types.CheckResult {
+	results := make([]types.CheckResult, 0, len(checks))
+	for _, check := range checks {
+		if ctx.Err() != nil {
+			break
+		}
+		results = append(results, check.Run(ctx))
+	}
+	return results
+}
+
+func (m *LinuxMonitor) checkSIPPeers(ctx context.Context) types.CheckResult {
+	peers, err := m.GetSIPPeers(ctx)
+	if err != nil {
+		return types.CheckResult{
+			Name:      "SIP Peers",
+			Status:    "error",
+			Message:   "sip show peers: " + err.Error(),
+			Timestamp: time.Now(),
+		}
+	}
+	online, total := CountPeers(peers)
+	result := types.CheckResult{
+		Name:      "SIP Peers",
+		Status:    "success",
+		Message:   fmt.Sprintf("%d online out of %d total", online, total),
+		Timestamp: time.Now(),
+	}
+	if online == 0 && total > 0 {
+		result.Status = "warning"
+		result.Message = fmt.Sprintf("No peers online (total: %d)", total)
+	}
+	return result
+}
+
+func (m *LinuxMonitor) checkActiveChannels(ctx context.Context) types.CheckResult {
+	count, _ := m.channelCounts(ctx)
+	result := types.CheckResult{
+		Name:      "Active Channels",
+		Status:    "success",
+		Message:   fmt.Sprintf("%d active channels", count),
+		Timestamp: time.Now(),
+	}
+	for _, rule := range m.currentAlertRules() {
+		if rule.Metric != types.MetricActiveCalls || !alerts.Breached(rule.Op, float64(count), rule.Threshold) {
+			continue
+		}
+		// Критическое правило важнее предупреждения
+		if result.Status == "error" {
+			continue
+		}
+		result.Status = alerts.StatusFor(rule.Severity)
+		result.Message = fmt.Sprintf("High channel count: %d (rule %s: %s %g)", count, rule.Name, rule.Op, rule.Threshold)
+	}
+	return result
+}
@@ -0,0 +1,220 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+// Процесс Asterisk ищется по pidfile в astrundir и проверяется по /proc.
+// Поиск по подстроке "asterisk" в ps находил и саму программу, и safe_asterisk,
+// и редакторы asterisk.conf, и удаленные консоли asterisk -rx.
+const (
+	pidfileName    = "asterisk.pid"
+	asteriskBinary = "asterisk"
+	wrapperName    = "safe_asterisk"
+
+	// consoleProbeTimeout - срок ответа на команду CLI, после которого
+	// запущенный процесс считается зависшим
+	consoleProbeTimeout = 5 * time.Second
+)
+
+// procInfo - сведения о процессе из /proc
+type procInfo struct {
+	pid       int
+	ppid      int
+	exe       string // пусто, если ссылка exe недоступна (чужой процесс без прав root)
+	args      []string
+	startTime uint64
+}
+
+// readProcInfo читает сведения о процессе pid
+func readProcInfo(pid int) (procInfo, error) {
+	dir := strconv.Itoa(pid)
+	data, err := readProcFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return procInfo{}, err
+	}
+	stat, err := parseProcStat(string(data))
+	if err != nil {
+		return procInfo{}, err
+	}
+	info := procInfo{pid: pid, ppid: stat.ppid, startTime: stat.startTime}
+
+	if data, err := readProcFile(filepath.Join(dir, "cmdline")); err == nil {
+		info.args = strings.FieldsFunc(string(data), func(r rune) bool { return r == 0 })
+	}
+	if exe, err := os.Readlink(filepath.Join(procRoot, dir, "exe")); err == nil {
+		// После обновления пакета работающий процесс ссылается на удаленный файл
+		info.exe = strings.TrimSuffix(exe, " (deleted)")
+	}
+	return info, nil
+}
+
+// name возвращает имя исполняемого файла процесса
+func (p procInfo) name() string {
+	if p.exe != "" {
+		return filepath.Base(p.exe)
+	}
+	if len(p.args) > 0 {
+		return filepath.Base(p.args[0])
+	}
+	return ""
+}
+
+// isDaemon сообщает, что процесс - сервер Asterisk, а не удаленная консоль
+// asterisk -r или -rx
+func (p procInfo) isDaemon() bool {
+	if p.name() != asteriskBinary || len(p.args) == 0 || filepath.Base(p.args[0]) != asteriskBinary {
+		return false
+	}
+	for _, arg := range p.args[1:] {
+		if strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && strings.ContainsAny(arg, "rRx") {
+			return false
+		}
+	}
+	return true
+}
+
+// isWrapper сообщает, что процесс - сценарий safe_asterisk
+func (p procInfo) isWrapper() bool {
+	if p.name() == wrapperName {
+		return true
+	}
+	// Сценарий запущен интерпретатором: sh /usr/sbin/safe_asterisk
+	return len(p.args) > 1 && filepath.Base(p.args[1]) == wrapperName
+}
+
+// readPidfile читает PID из pidfile
+func readPidfile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("%s: bad pid %q", path, strings.TrimSpace(string(data)))
+	}
+	return pid, nil
+}
+
+// scanProcesses ищет в /proc самый старый сервер Asterisk и процесс safe_asterisk.
+// Кандидаты отбираются по comm, чтобы не читать cmdline всех процессов;
+// safe_asterisk, запущенный через sh, называется именем интерпретатора.
+func scanProcesses() (daemon, wrapper procInfo, err error) {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return procInfo{}, procInfo{}, err
+	}
+
+	self := os.Getpid()
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		comm, err := readProcFile(filepath.Join(entry.Name(), "comm"))
+		if err != nil {
+			continue
+		}
+		if name := strings.TrimSpace(string(comm)); name != asteriskBinary && name != wrapperName && name != "sh" && name != "bash" {
+			continue
+		}
+
+		info, err := readProcInfo(pid)
+		switch {
+		case err != nil:
+		case info.isDaemon():
+			// Дочерние процессы System() до exec тоже называются asterisk
+			if daemon.pid == 0 || info.startTime < daemon.startTime {
+				daemon = info
+			}
+		case info.isWrapper():
+			if wrapper.pid == 0 {
+				wrapper = info
+			}
+		}
+	}
+	return daemon, wrapper, nil
+}
+
+// discoverProcess находит процесс Asterisk по pidfile, а если pidfile нет или он
+// устарел - среди процессов в /proc. Отзывчивость консоли не проверяется.
+func discoverProcess(pidfile string) types.AsteriskProcess {
+	process := types.AsteriskProcess{State: types.AsteriskNotRunning, Pidfile: pidfile}
+
+	pid, err := readPidfile(pidfile)
+	switch {
+	case err == nil:
+		if info, err := readProcInfo(pid); err == nil && info.isDaemon() {
+			process.State = types.AsteriskRunning
+			setProcess(&process, info)
+			return process
+		}
+		process.State = types.AsteriskStalePidfile
+		process.Detail = fmt.Sprintf("pidfile points to PID %d, which is not an asterisk process", pid)
+	case errors.Is(err, fs.ErrNotExist):
+		process.Detail = "pidfile not found"
+	default:
+		process.Detail = err.Error()
+	}
+
+	daemon, wrapper, err := scanProcesses()
+	switch {
+	case err != nil:
+		process.Detail += "; " + err.Error()
+	case daemon.pid != 0:
+		// Asterisk работает, но с другим astrundir или без pidfile
+		process.State = types.AsteriskRunning
+		process.Detail += fmt.Sprintf("; found PID %d in /proc, check run_dir", daemon.pid)
+		setProcess(&process, daemon)
+	case wrapper.pid != 0:
+		process.WrapperPID = wrapper.pid
+		process.Detail += fmt.Sprintf("; safe_asterisk (PID %d) is restarting asterisk", wrapper.pid)
+	}
+	return process
+}
+
+// setProcess переносит найденный процесс в результат и определяет safe_asterisk среди родителей
+func setProcess(process *types.AsteriskProcess, info procInfo) {
+	process.PID = info.pid
+	process.Exe = info.exe
+	process.Cmdline = strings.Join(info.args, " ")
+	if parent, err := readProcInfo(info.ppid); err == nil && parent.isWrapper() {
+		process.WrapperPID = parent.pid
+	}
+}
+
+// pidfilePath возвращает путь к pidfile Asterisk в настроенном astrundir
+func (m *LinuxMonitor) pidfilePath() string {
+	m.consoleMu.Lock()
+	defer m.consoleMu.Unlock()
+	return filepath.Join(m.runDir, pidfileName)
+}
+
+// DiscoverAsterisk находит процесс Asterisk и проверяет, что он отвечает на команды CLI
+func (m *LinuxMonitor) DiscoverAsterisk(ctx context.Context) types.AsteriskProcess {
+	process := discoverProcess(m.pidfilePath())
+	if process.State != types.AsteriskRunning {
+		return process
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, consoleProbeTimeout)
+	defer cancel()
+	if _, err := m.asteriskOutput(ctx, "core show version"); err != nil {
+		process.State = types.AsteriskUnresponsive
+		if process.Detail != "" {
+			process.Detail += "; "
+		}
+		process.Detail += err.Error()
+	}
+	return process
+}
@@ -0,0 +1,197 @@
+package monitor
+
+import (
+	"bufio"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+// dirScanInterval - как часто пересчитываются размеры каталогов Asterisk. Обход
+// каталога записей с сотнями тысяч файлов занимает заметное время, поэтому он
+// выполняется в фоне, а до его завершения возвращаются прошлые размеры.
+const dirScanInterval = 10 * time.Minute
+
+// SetDataDirs задает каталоги Asterisk (записи, логи, данные), для которых
+// контролируются файловые системы и размеры
+func (m *LinuxMonitor) SetDataDirs(dirs []string) {
+	m.dirMu.Lock()
+	defer m.dirMu.Unlock()
+	m.dataDirs = dirs
+}
+
+// GetDirUsage возвращает размеры каталогов Asterisk из последнего обхода и
+// запускает новый обход в фоне, если прошлый старше dirScanInterval
+func (m *LinuxMonitor) GetDirUsage() []types.DirUsage {
+	mounts := readMountPoints()
+
+	m.dirMu.Lock()
+	defer m.dirMu.Unlock()
+
+	now := time.Now()
+	var stale []string
+	dirs := make([]types.DirUsage, 0, len(m.dataDirs))
+	for _, path := range m.dataDirs {
+		usage, ok := m.dirUsage[path]
+		if !ok || now.Sub(usage.ScannedAt) >= dirScanInterval {
+			stale = append(stale, path)
+		}
+		usage.Path = path
+		usage.Mount = mountPointOf(mounts, path)
+		dirs = append(dirs, usage)
+	}
+
+	if len(stale) > 0 && !m.dirScanning {
+		m.dirScanning = true
+		go m.scanDirs(stale)
+	}
+	return dirs
+}
+
+// scanDirs обходит каталоги и сохраняет их размеры
+func (m *LinuxMonitor) scanDirs(paths []string) {
+	results := make(map[string]types.DirUsage, len(paths))
+	for _, path := range paths {
+		results[path] = scanDir(path)
+	}
+
+	m.dirMu.Lock()
+	defer m.dirMu.Unlock()
+	if m.dirUsage == nil {
+		m.dirUsage = make(map[string]types.DirUsage)
+	}
+	for path, usage := range results {
+		m.dirUsage[path] = usage
+	}
+	m.dirScanning = false
+}
+
+// scanDir считает суммарный размер и число файлов каталога, как du --apparent-size.
+// Недоступные подкаталоги пропускаются.
+func scanDir(path string) types.DirUsage {
+	usage := types.DirUsage{Path: path}
+	err := filepath.WalkDir(path, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() && name != path {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			usage.SizeBytes += uint64(info.Size())
+			usage.Files++
+		}
+		return nil
+	})
+	if err != nil {
+		usage.Error = err.Error()
+	}
+	usage.ScannedAt = time.Now()
+	return usage
+}
+
+// asteriskMounts добавляет к контролируемым точкам монтирования файловые
+// системы каталогов Asterisk и отмечает, какие каталоги лежат на каждой.
+// Каталог на файловой системе уже контролируемого пути приписывается ему.
+func (m *LinuxMonitor) asteriskMounts(paths []string) ([]string, map[string][]string) {
+	m.dirMu.Lock()
+	dataDirs := m.dataDirs
+	m.dirMu.Unlock()
+
+	mounts := readMountPoints()
+	byMount := make(map[string]string, len(paths))
+	for _, path := range paths {
+		mount := mountPointOf(mounts, path)
+		if _, ok := byMount[mount]; !ok {
+			byMount[mount] = path
+		}
+	}
+
+	dirs := make(map[string][]string)
+	for _, dir := range dataDirs {
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		mount := mountPointOf(mounts, dir)
+		path, ok := byMount[mount]
+		if !ok {
+			path = mount
+			byMount[mount] = path
+			paths = append(paths, path)
+		}
+		dirs[path] = append(dirs[path], dir)
+	}
+	return paths, dirs
+}
+
+// readMountPoints возвращает точки монтирования из /proc/self/mounts
+func readMountPoints() []string {
+	file, err := openProcFile("self/mounts")
+	if err != nil {
+		return []string{"/"}
+	}
+	defer file.Close()
+	return parseMountPoints(file)
+}
+
+// parseMountPoints разбирает /proc/self/mounts. Пробелы и другие служебные
+// символы в пути записаны восьмеричными escape-последовательностями (\040).
+func parseMountPoints(r io.Reader) []string {
+	var mounts []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		mounts = append(mounts, unescapeMountPath(fields[1]))
+	}
+	return mounts
+}
+
+func unescapeMountPath(path string) string {
+	if !strings.Contains(path, `\`) {
+		return path
+	}
+	var out strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' && i+3 < len(path) {
+			if code, err := strconv.ParseUint(path[i+1:i+4], 8, 8); err == nil {
+				out.WriteByte(byte(code))
+				i += 3
+				continue
+			}
+		}
+		out.WriteByte(path[i])
+	}
+	return out.String()
+}
+
+// mountPointOf возвращает точку монтирования, на которой лежит path: самую
+// длинную точку монтирования, которая является префиксом пути без символических ссылок
+func mountPointOf(mounts []string, path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	path = filepath.Clean(path)
+
+	best := "/"
+	for _, mount := range mounts {
+		if len(mount) <= len(best) {
+			continue
+		}
+		if path == mount || strings.HasPrefix(path, strings.TrimSuffix(mount, "/")+"/") {
+			best = mount
+		}
+	}
+	return best
+}
@@ -0,0 +1,351 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+// defaultCommandTimeout ограничивает внешние команды, для которых вызывающий
+// не задал срок, чтобы зависший asterisk -rx не блокировал сбор метрик
+const defaultCommandTimeout = 30 * time.Second
+
+// ErrCommandNotAllowed - команды Asterisk CLI нет в списке разрешенных
+var ErrCommandNotAllowed = errors.New("asterisk command not allowed")
+
+// asteriskCommands - команды Asterisk CLI, которые программа выполняет через asterisk -rx.
+// Другие команды не выполняются, даже если пришли из интерфейса.
+var asteriskCommands = map[string]bool{
+	"core show version":          true,
+	"core show uptime":           true,
+	"core show uptime seconds":   true,
+	"core show calls":            true,
+	"core show channels":         true,
+	"core show channels concise": true,
+	"core show channels count":   true,
+	"core show translation":      true,
+	"core show locks":            true,
+	"core show threads":          true,
+	"core show taskprocessors":   true,
+	"dialplan show":              true,
+	"module show":                true,
+	"sip show peers":             true,
+	"sip show registry":          true,
+	"sip show channelstats":      true,
+	"rtp show stats":             true,
+	"rtp show peers":             true,
+	"jitterbuffer show":          true,
+	"logger rotate":              true,
+	"logger show channels":       true,
+
+	// Переключение отладки
+	"sip set debug on":           true,
+	"sip set debug off":          true,
+	"rtp set debug on":           true,
+	"rtp set debug off":          true,
+	"rtcp set debug on":          true,
+	"rtcp set debug off":         true,
+	"core set debug 0":           true,
+	"core set debug 1":           true,
+	"core set debug 3":           true,
+	"jitterbuffer set debug on":  true,
+	"jitterbuffer set debug off": true,
+}
+
+// asteriskModuleCommands - команды CLI с именем модуля в аргументе
+var asteriskModuleCommands = []string{"module load ", "module unload ", "module reload "}
+
+// moduleNamePattern - допустимое имя модуля: только имя файла .so без пути
+var moduleNamePattern = regexp.MustCompile(`^[A-Za-z0-9_\-]+\.so$`)
+
+// Command - запуск внешней программы без оболочки. Аргументы передаются
+// программе как есть, поэтому пути и фильтры пользователя не интерпретируются.
+type Command struct {
+	Program string
+	Args    []string
+	// OKCodes - ненулевые коды завершения, которые не считаются ошибкой
+	// (grep без совпадений завершается с кодом 1)
+	OKCodes []int
+}
+
+// Cmd создает команду program с аргументами args
+func Cmd(program string, args ...string) Command {
+	return Command{Program: program, Args: args}
+}
+
+// AsteriskCLI возвращает команду asterisk -rx для разрешенной команды CLI
+func AsteriskCLI(command string) (Command, error) {
+	if !asteriskCommands[command] && !moduleCommand(command) {
+		return Command{}, fmt.Errorf("%w: %q", ErrCommandNotAllowed, command)
+	}
+	return Cmd("asterisk", "-rx", command), nil
+}
+
+// moduleCommand сообщает, что command - действие с модулем с допустимым именем
+func moduleCommand(command string) bool {
+	for _, prefix := range asteriskModuleCommands {
+		if name, ok := strings.CutPrefix(command, prefix); ok {
+			return moduleNamePattern.MatchString(name)
+		}
+	}
+	return false
+}
+
+// String возвращает команду для отображения, аргументы с пробелами в кавычках
+func (c Command) String() string {
+	parts := []string{c.Program}
+	for _, arg := range c.Args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n'\"\\$") {
+			arg = strconv.Quote(arg)
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// execResult - вывод, код завершения и длительность внешней команды
+type execResult struct {
+	stdout   []byte
+	stderr   []byte
+	exitCode int
+	duration time.Duration
+}
+
+// execute выполняет команду, stdout и stderr собираются раздельно. Ошибка
+// возвращается, если программу не удалось запустить, код завершения не входит
+// в OKCodes или ctx отменен. Команда завершается при отмене ctx или по
+// истечении defaultCommandTimeout, если ctx не задает срок.
+func execute(ctx context.Context, c Command) (execResult, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultCommandTimeout)
+		defer cancel()
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Program, c.Args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Команда запускается в своей группе процессов: при отмене завершаются и
+	// ее дочерние процессы, иначе они держат открытым stdout
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err := cmd.Run()
+	result := execResult{
+		stdout:   stdout.Bytes(),
+		stderr:   stderr.Bytes(),
+		exitCode: -1,
+		duration: time.Since(start),
+	}
+	if cmd.ProcessState != nil {
+		result.exitCode = cmd.ProcessState.ExitCode()
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() != nil:
+		return result, fmt.Errorf("%s: %w", c.Program, ctx.Err())
+	case errors.As(err, &exitErr) && slices.Contains(c.OKCodes, result.exitCode):
+		return result, nil
+	case err != nil:
+		return result, fmt.Errorf("%s: %w", c.Program, err)
+	}
+	return result, nil
+}
+
+// commandOutput выполняет программу name с аргументами args и возвращает ее stdout
+func commandOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	result, err := execute(ctx, Cmd(name, args...))
+	return result.stdout, err
+}
+
+// asteriskOutput выполняет разрешенную команду Asterisk CLI и возвращает ее вывод
+func (m *LinuxMonitor) asteriskOutput(ctx context.Context, command string) ([]byte, error) {
+	result, err := m.asteriskExecute(ctx, command)
+	return result.stdout, err
+}
+
+// asteriskExecute выполняет разрешенную команду Asterisk CLI через управляющий
+// сокет или, если он не используется, через asterisk -rx
+func (m *LinuxMonitor) asteriskExecute(ctx context.Context, command string) (execResult, error) {
+	cmd, err := AsteriskCLI(command)
+	if err != nil {
+		return execResult{exitCode: -1}, err
+	}
+
+	client := m.consoleClient()
+	if client == nil {
+		return execute(ctx, cmd)
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultCommandTimeout)
+		defer cancel()
+	}
+	start := time.Now()
+	output, err := client.Command(ctx, command)
+	result := execResult{stdout: []byte(output), duration: time.Since(start)}
+	if err != nil {
+		result.exitCode = -1
+	}
+	return result, err
+}
+
+// Run выполняет команду и возвращает ее результат: stdout в Message, stderr
+// в Error, код завершения и длительность. При ошибке статус "error".
+func (m *LinuxMonitor) Run(ctx context.Context, name string, cmd Command) types.CheckResult {
+	result, err := execute(ctx, cmd)
+	return newCheckResult(name, result, err)
+}
+
+// newCheckResult переносит результат команды в CheckResult
+func newCheckResult(name string, result execResult, err error) types.CheckResult {
+	check := types.CheckResult{
+		Name:      name,
+		Status:    "success",
+		Message:   strings.TrimSpace(string(result.stdout)),
+		Error:     strings.TrimSpace(string(result.stderr)),
+		ExitCode:  result.exitCode,
+		Duration:  result.duration,
+		Timestamp: time.Now(),
+	}
+	if err != nil {
+		check.Status = "error"
+		if check.Error == "" {
+			check.Error = err.Error()
+		} else {
+			check.Error = err.Error() + ": " + check.Error
+		}
+	}
+	return check
+}
+
+// Exec выполняет программу program с аргументами args
+func (m *LinuxMonitor) Exec(ctx context.Context, name, program string, args ...string) types.CheckResult {
+	return m.Run(ctx, name, Cmd(program, args...))
+}
+
+// AsteriskCommand выполняет команду Asterisk CLI из списка разрешенных
+func (m *LinuxMonitor) AsteriskCommand(ctx context.Context, name, command string) types.CheckResult {
+	result, err := m.asteriskExecute(ctx, command)
+	return newCheckResult(name, result, err)
+}
+
+// ValidatePath проверяет путь, введенный пользователем: путь должен быть
+// абсолютным, без управляющих символов и не корнем. Возвращает очищенный путь.
+func ValidatePath(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("path is empty")
+	}
+	if strings.ContainsFunc(path, func(r rune) bool { return r < ' ' || r == 0x7f }) {
+		return "", fmt.Errorf("path %q contains control characters", path)
+	}
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("path %q is not absolute", path)
+	}
+	clean := filepath.Clean(path)
+	if clean == "/" {
+		return "", errors.New("path must not be the root directory")
+	}
+	return clean, nil
+}
+
+// Обработка вывода команд вместо конвейеров оболочки
+
+// headLines возвращает первые n строк текста, как head -n
+func headLines(text string, n int) string {
+	lines := strings.Split(text, "\n")
+	if len(lines) > n {
+		lines = lines[:n]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// grepLines возвращает строки текста, подходящие под re, как grep -E
+func grepLines(text string, re *regexp.Regexp) []string {
+	var matched []string
+	for _, line := range strings.Split(text, "\n") {
+		if re.MatchString(line) {
+			matched = append(matched, line)
+		}
+	}
+	return matched
+}
+
+// countLines возвращает число непустых строк текста, как wc -l
+func countLines(text string) int {
+	count := 0
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// uncommented убирает строки конфигурации Asterisk, закомментированные ';' или '#'
+func uncommented(lines []string) []string {
+	var result []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, ";") || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		result = append(result, line)
+	}
+	return result
+}
+
+// filterOutput применяет filter к выводу команды. Вывод обрабатывается и при
+// ошибке: ping с потерями завершается с ненулевым кодом, но его сводка нужна.
+func filterOutput(result types.CheckResult, filter func(string) string) types.CheckResult {
+	if filter != nil {
+		result.Message = filter(result.Message)
+	}
+	return result
+}
+
+// firstLines возвращает обработку вывода, которая оставляет первые n строк
+func firstLines(n int) func(string) string {
+	return func(text string) string {
+		return headLines(text, n)
+	}
+}
+
+// matching возвращает обработку вывода, которая оставляет строки, подходящие
+// под pattern, или fallback, если таких строк нет
+func matching(pattern, fallback string) func(string) string {
+	re := regexp.MustCompile(pattern)
+	return func(text string) string {
+		if lines := grepLines(text, re); len(lines) > 0 {
+			return strings.Join(lines, "\n")
+		}
+		return fallback
+	}
+}
+
+// counting возвращает обработку вывода, которая заменяет вывод числом строк,
+// подходящих под pattern, как grep -c
+func counting(pattern string) func(string) string {
+	re := regexp.MustCompile(pattern)
+	return func(text string) string {
+		return strconv.Itoa(len(grepLines(text, re)))
+	}
+}
@@ -0,0 +1,137 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+// coredumpDir - каталог systemd-coredump, в нем лежат дампы всех программ
+const coredumpDir = "/var/lib/systemd/coredump"
+
+// SetDumpDirs задает каталоги, в которых ищутся дампы памяти Asterisk
+func (m *LinuxMonitor) SetDumpDirs(dirs []string) {
+	m.settingsMu.Lock()
+	defer m.settingsMu.Unlock()
+	m.dumpDirs = dirs
+}
+
+func (m *LinuxMonitor) currentDumpDirs() []string {
+	m.settingsMu.RLock()
+	defer m.settingsMu.RUnlock()
+	return m.dumpDirs
+}
+
+// LifecycleSnapshot возвращает состояние процесса, время запуска и перезагрузки
+// по core show uptime, результат systemd юнита и дампы памяти
+func (m *LinuxMonitor) LifecycleSnapshot(ctx context.Context) types.LifecycleSnapshot {
+	snapshot := types.LifecycleSnapshot{
+		Time:      time.Now(),
+		Process:   m.DiscoverAsterisk(ctx),
+		Unit:      unitStatus(ctx, "asterisk"),
+		CoreFiles: coreFiles(m.currentDumpDirs()),
+	}
+
+	if snapshot.Process.State == types.AsteriskRunning {
+		if output, err := m.asteriskOutput(ctx, "core show uptime seconds"); err == nil {
+			uptime, reload, err := parseUptimeSeconds(string(output))
+			if err == nil {
+				snapshot.StartedAt = snapshot.Time.Add(-uptime).Truncate(time.Second)
+				snapshot.ReloadedAt = snapshot.Time.Add(-reload).Truncate(time.Second)
+			}
+		}
+	}
+	return snapshot
+}
+
+// parseUptimeSeconds разбирает вывод core show uptime seconds:
+//
+//	System uptime: 86400
+//	Last reload: 3600
+func parseUptimeSeconds(output string) (uptime, reload time.Duration, err error) {
+	values := make(map[string]time.Duration)
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		values[strings.TrimSpace(key)] = time.Duration(seconds) * time.Second
+	}
+
+	uptime, ok := values["System uptime"]
+	if !ok {
+		return 0, 0, fmt.Errorf("core show uptime: unexpected output %q", headLines(output, 2))
+	}
+	// До первой перезагрузки Asterisk сообщает время запуска
+	reload, ok = values["Last reload"]
+	if !ok {
+		reload = uptime
+	}
+	return uptime, reload, nil
+}
+
+// unitStatus возвращает состояние и результат последнего запуска systemd юнита.
+// Без systemd поля остаются пустыми.
+func unitStatus(ctx context.Context, unit string) types.UnitStatus {
+	status := types.UnitStatus{NRestarts: -1}
+	output, err := commandOutput(ctx, "systemctl", "show", unit, "-p", "ActiveState", "-p", "Result", "-p", "NRestarts")
+	if err != nil {
+		return status
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, _ := strings.Cut(strings.TrimSpace(line), "=")
+		switch key {
+		case "ActiveState":
+			status.ActiveState = value
+		case "Result":
+			status.Result = value
+		case "NRestarts":
+			if n, err := strconv.Atoi(value); err == nil {
+				status.NRestarts = n
+			}
+		}
+	}
+	return status
+}
+
+// coreFiles возвращает дампы памяти в каталогах dirs: файлы core и core.*.
+// В каталоге systemd-coredump учитываются только дампы asterisk.
+func coreFiles(dirs []string) []types.CoreFile {
+	var files []types.CoreFile
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if name != "core" && !strings.HasPrefix(name, "core.") {
+				continue
+			}
+			if filepath.Clean(dir) == coredumpDir && !strings.Contains(name, "."+asteriskBinary+".") {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			files = append(files, types.CoreFile{
+				Path:    filepath.Join(dir, name),
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+			})
+		}
+	}
+	return files
+}
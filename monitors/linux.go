@@ -1,60 +1,165 @@
 package monitor
 
 import (
+    "asterisk-monitor/console"
     "asterisk-monitor/types"
+    "context"
     "fmt"
-    "os/exec"
+    "regexp"
     "strconv"
     "strings"
+    "sync"
     "time"
 )
 
-type LinuxMonitor struct{}
+type LinuxMonitor struct{
+    // Настройки меняются при сохранении и перезагрузке конфигурации,
+    // пока выполняются проверки
+    settingsMu  sync.RWMutex
+    alertRules  []types.AlertRule
+    mountPoints []string
+    dumpDirs    []string
+
+    moduleBaseline string // файл базовой линии модулей
+
+    consoleMu sync.Mutex
+    console   *console.Client // nil - команды CLI выполняются через asterisk -rx
+    runDir    string          // astrundir, в нем asterisk.ctl и asterisk.pid
+    ami       types.AsteriskConfig
+
+    cpuMu   sync.Mutex
+    prevCPU cpuTimes
+    lastCPU float64
+
+    procMu   sync.Mutex
+    prevProc processSample
+    lastProc types.ProcessMetrics
+
+    netMu     sync.Mutex
+    prevNet   networkSample
+    lastNet   map[string]types.InterfaceStats // скорости интерфейсов прошлого чтения
+    lastUDP   types.UDPStats
+    lastDrops float64
+
+    dirMu       sync.Mutex
+    dataDirs    []string                  // каталоги Asterisk для контроля места
+    dirUsage    map[string]types.DirUsage // размеры каталогов последнего обхода
+    dirScanning bool
+}
 
 func NewLinuxMonitor() *LinuxMonitor {
-    return &LinuxMonitor{}
+    return &LinuxMonitor{
+        console: console.New(console.SocketPath(console.DefaultRunDir)),
+        runDir:  console.DefaultRunDir,
+    }
 }
 
-// GetAsteriskStatus возвращает статус Asterisk
-func (m *LinuxMonitor) GetAsteriskStatus() string {
-    cmd := exec.Command("sh", "-c", "ps aux | grep -v grep | grep asterisk")
-    output, err := cmd.Output()
+// SetAlertRules задает правила оповещений, которые используются как пороги диагностики
+func (m *LinuxMonitor) SetAlertRules(rules []types.AlertRule) {
+    m.settingsMu.Lock()
+    defer m.settingsMu.Unlock()
+    m.alertRules = rules
+}
+
+func (m *LinuxMonitor) currentAlertRules() []types.AlertRule {
+    m.settingsMu.RLock()
+    defer m.settingsMu.RUnlock()
+    return m.alertRules
+}
+
+// Configure применяет настройки конфигурации, которые влияют на сбор метрик
+func (m *LinuxMonitor) Configure(cfg *types.Config) {
+    m.SetAlertRules(cfg.Alerts)
+    m.SetMountPoints(ParsePaths(cfg.Monitoring.MountPoints))
+    m.SetDumpDirs(ParsePaths(cfg.Asterisk.DumpDirs))
+    m.SetDataDirs(ParsePaths(cfg.Asterisk.DataDirs))
+    m.SetConsole(cfg.Asterisk.Transport, cfg.Asterisk.RunDir)
+    m.SetAMI(cfg.Asterisk)
+}
+
+// SetConsole выбирает способ выполнения команд Asterisk CLI: transport "exec"
+// запускает asterisk -rx на каждую команду, иначе используется управляющий
+// сокет в каталоге runDir. В runDir также ищется pidfile Asterisk.
+func (m *LinuxMonitor) SetConsole(transport, runDir string) {
+    m.consoleMu.Lock()
+    defer m.consoleMu.Unlock()
     
-    if err == nil && strings.Contains(string(output), "asterisk") {
-        return "running"
+    if runDir == "" {
+        runDir = console.DefaultRunDir
+    }
+    m.runDir = runDir
+    path := console.SocketPath(runDir)
+    if transport != "exec" && m.console != nil && m.console.Path() == path {
+        return
+    }
+    if m.console != nil {
+        m.console.Close()
+        m.console = nil
+    }
+    if transport != "exec" {
+        m.console = console.New(path)
     }
-    return "stopped"
 }
 
-// GetAsteriskPID возвращает PID процесса Asterisk
-func (m *LinuxMonitor) GetAsteriskPID() string {
-    // Получаем основной PID Asterisk (не safe_asterisk)
-    cmd := exec.Command("sh", "-c", "ps aux | grep asterisk | grep -v grep | grep -v safe_asterisk | awk '{print $2}' | head -1")
-    output, err := cmd.Output()
-    if err != nil {
-        return "N/A"
+// consoleClient возвращает клиент управляющего сокета или nil
+func (m *LinuxMonitor) consoleClient() *console.Client {
+    m.consoleMu.Lock()
+    defer m.consoleMu.Unlock()
+    return m.console
+}
+
+// ParsePaths разбирает список путей через запятую
+func ParsePaths(spec string) []string {
+    var paths []string
+    for _, path := range strings.Split(spec, ",") {
+        if path = strings.TrimSpace(path); path != "" {
+            paths = append(paths, path)
+        }
     }
-    pid := strings.TrimSpace(string(output))
-    if pid == "" {
+    return paths
+}
+
+// SetMountPoints задает файловые системы для контроля заполненности.
+// Первая точка монтирования используется как основная метрика disk_usage.
+func (m *LinuxMonitor) SetMountPoints(paths []string) {
+    m.settingsMu.Lock()
+    defer m.settingsMu.Unlock()
+    m.mountPoints = paths
+}
+
+// GetAsteriskStatus возвращает состояние процесса Asterisk: running, not running,
+// stale pidfile или console unresponsive
+func (m *LinuxMonitor) GetAsteriskStatus() string {
+    return m.DiscoverAsterisk(context.Background()).State
+}
+
+// GetAsteriskPID возвращает PID процесса Asterisk. Консоль не опрашивается,
+// поэтому PID зависшего процесса тоже возвращается.
+func (m *LinuxMonitor) GetAsteriskPID() string {
+    process := discoverProcess(m.pidfilePath())
+    if process.PID == 0 {
         return "N/A"
     }
-    return pid
+    return strconv.Itoa(process.PID)
 }
 
 // GetServiceStatus возвращает статус systemd сервиса
 func (m *LinuxMonitor) GetServiceStatus() string {
-    cmd := exec.Command("sh", "-c", "systemctl is-active asterisk 2>/dev/null || echo 'unknown'")
-    output, err := cmd.Output()
+    return m.serviceStatus(context.Background())
+}
+
+func (m *LinuxMonitor) serviceStatus(ctx context.Context) string {
+    // Для неактивного сервиса systemctl печатает состояние и завершается с ненулевым кодом
+    output, _ := commandOutput(ctx, "systemctl", "is-active", "asterisk")
     
-    if err != nil {
-        return "unknown"
+    if state := strings.TrimSpace(string(output)); state != "" {
+        return state
     }
-    return strings.TrimSpace(string(output))
+    return "unknown"
 }
 
 func (m *LinuxMonitor) GetSIPPeersDetail() string {
-    cmd := exec.Command("asterisk", "-rx", "sip show peers")
-    output, err := cmd.Output()
+    output, err := m.asteriskOutput(context.Background(), "sip show peers")
     
     if err != nil {
         return "Error getting SIP peers details"
@@ -68,148 +173,251 @@ func (m *LinuxMonitor) GetSIPPeersDetail() string {
     return string(output)
 }
 
-// GetSIPPeersCount возвращает количество онлайн и общее число SIP пиров
-func (m *LinuxMonitor) GetSIPPeersCount() (int, int) {
-    cmd := exec.Command("asterisk", "-rx", "sip show peers")
-    output, err := cmd.Output()
+// GetSIPPeers возвращает список SIP пиров с их статусом и задержкой
+func (m *LinuxMonitor) GetSIPPeers(ctx context.Context) ([]types.SIPPeer, error) {
+    output, err := m.asteriskOutput(ctx, "sip show peers")
     
     if err != nil {
-        return 0, 0
+        return nil, err
     }
     
-    outputStr := string(output)
+    return parseSIPPeers(string(output)), nil
+}
+
+// PeerOnline сообщает, доступен ли пир, так же, как итоговая строка
+// "sip show peers": доступны пиры OK и LAGGED, а без контроля доступности -
+// пиры с известным адресом. По нему считают дашборд, check peers и экспортер.
+func PeerOnline(peer types.SIPPeer) bool {
+    switch peer.Status {
+    case "OK", "LAGGED":
+        return true
+    case "Unmonitored":
+        return peer.Host != "(Unspecified)"
+    }
+    return false
+}
+
+// CountPeers возвращает число доступных (см. PeerOnline) и общее число пиров
+func CountPeers(peers []types.SIPPeer) (int, int) {
     online := 0
-    total := 0
-    
-    // Разбиваем вывод на строки
-    lines := strings.Split(outputStr, "\n")
+    for _, peer := range peers {
+        if PeerOnline(peer) {
+            online++
+        }
+    }
+    return online, len(peers)
+}
+
+// parseSIPPeers разбирает вывод "sip show peers"
+func parseSIPPeers(output string) []types.SIPPeer {
+    var peers []types.SIPPeer
     
-    for _, line := range lines {
+    for _, line := range strings.Split(output, "\n") {
         trimmed := strings.TrimSpace(line)
+        if trimmed == "" || strings.HasPrefix(trimmed, "Name/username") || strings.Contains(trimmed, "sip peers") {
+            continue
+        }
         
-        // Пропускаем заголовки и пустые строки
-        if strings.Contains(trimmed, "Name/username") || trimmed == "" {
+        match := peerStatusRe.FindStringSubmatch(trimmed)
+        if match == nil {
             continue
         }
         
-        // Ищем строку с итоговой статистикой
-        if strings.Contains(trimmed, "sip peers") {
-            // Парсим итоговую строку: "25 sip peers [Monitored: 7 online, 13 offline Unmonitored: 5 online, 0 offline]"
-            if strings.Contains(trimmed, "Monitored:") && strings.Contains(trimmed, "Unmonitored:") {
-                // Извлекаем числа из строки
-                monitoredOnline := extractNumberAfter(trimmed, "Monitored:", "online")
-                unmonitoredOnline := extractNumberAfter(trimmed, "Unmonitored:", "online")
-                online = monitoredOnline + unmonitoredOnline
-                
-                // Общее количество - первое число в строке
-                total = extractFirstNumber(trimmed)
-            }
-            break
+        fields := strings.Fields(trimmed)
+        if len(fields) < 2 {
+            continue
         }
         
-        // Если не нашли итоговую строку, считаем вручную
-        if strings.Contains(trimmed, "/") && len(strings.Fields(trimmed)) >= 6 {
-            total++
-            fields := strings.Fields(trimmed)
-            statusField := fields[len(fields)-2] // Предпоследнее поле - статус
-            if statusField == "OK" || statusField == "Unmonitored" {
-                online++
-            }
+        peer := types.SIPPeer{
+            Name:   strings.SplitN(fields[0], "/", 2)[0],
+            Host:   fields[1],
+            Status: match[1],
+        }
+        if match[2] != "" {
+            peer.Latency = match[2] + " ms"
         }
+        peers = append(peers, peer)
     }
     
-    return online, total
+    return peers
 }
 
-func extractNumberAfter(text, after, before string) int {
-    startIdx := strings.Index(text, after)
-    if startIdx == -1 {
-        return 0
-    }
-    
-    endIdx := strings.Index(text[startIdx:], before)
-    if endIdx == -1 {
-        return 0
-    }
+var peerStatusRe = regexp.MustCompile(`\b(OK|LAGGED|UNREACHABLE|UNKNOWN|Unmonitored)\b(?:\s+\((\d+) ms\))?`)
+
+// GetCallQuality возвращает RTP статистику активных SIP вызовов
+func (m *LinuxMonitor) GetCallQuality(ctx context.Context) ([]types.CallQuality, error) {
+    output, err := m.asteriskOutput(ctx, "sip show channelstats")
     
-    numberStr := strings.TrimSpace(text[startIdx+len(after) : startIdx+endIdx])
-    number, err := strconv.Atoi(numberStr)
     if err != nil {
-        return 0
+        return nil, err
     }
     
-    return number
+    return parseChannelStats(string(output)), nil
 }
 
-func extractFirstNumber(text string) int {
-    fields := strings.Fields(text)
-    if len(fields) > 0 {
-        number, err := strconv.Atoi(fields[0])
-        if err == nil {
-            return number
+var channelStatsRe = regexp.MustCompile(`^(\S+)\s+(\S+)\s+(\d+:\d+:\d+)\s+(\d+)\s+(\d+)\s+\(\s*([\d.]+)%\)\s+([\d.]+)\s+(\d+)\s+(\d+)\s+\(\s*([\d.]+)%\)\s+([\d.]+)`)
+
+// parseChannelStats разбирает вывод "sip show channelstats"
+func parseChannelStats(output string) []types.CallQuality {
+    var calls []types.CallQuality
+    
+    for _, line := range strings.Split(output, "\n") {
+        match := channelStatsRe.FindStringSubmatch(strings.TrimSpace(line))
+        if match == nil {
+            continue
         }
-    }
-    return 0
+        
+        call := types.CallQuality{
+            Peer:     match[1],
+            CallID:   match[2],
+            Duration: match[3],
+        }
+        call.RxPackets, _ = strconv.ParseInt(match[4], 10, 64)
+        call.RxLost, _ = strconv.ParseInt(match[5], 10, 64)
+        call.RxLossPct, _ = strconv.ParseFloat(match[6], 64)
+        call.RxJitter, _ = strconv.ParseFloat(match[7], 64)
+        call.TxPackets, _ = strconv.ParseInt(match[8], 10, 64)
+        call.TxLost, _ = strconv.ParseInt(match[9], 10, 64)
+        call.TxLossPct, _ = strconv.ParseFloat(match[10], 64)
+        call.TxJitter, _ = strconv.ParseFloat(match[11], 64)
+        calls = append(calls, call)
+    }
+    
+    return calls
 }
 
 // GetActiveCallsCount возвращает количество активных вызовов
 func (m *LinuxMonitor) GetActiveCallsCount() int {
-    cmd := exec.Command("asterisk", "-rx", "core show channels")
-    output, err := cmd.Output()
+    active, _ := m.channelCounts(context.Background())
+    return active
+}
+
+// GetCallsProcessed возвращает счетчик обработанных вызовов из "core show channels".
+// Счетчик растет с момента запуска Asterisk и сбрасывается при перезапуске.
+func (m *LinuxMonitor) GetCallsProcessed() int64 {
+    _, processed := m.channelCounts(context.Background())
+    return processed
+}
+
+// channelCounts возвращает число активных вызовов и счетчик обработанных
+// вызовов за один запуск "core show channels"
+func (m *LinuxMonitor) channelCounts(ctx context.Context) (int, int64) {
+    output, err := m.asteriskOutput(ctx, "core show channels")
     
     if err != nil {
-        return 0
+        return 0, 0
     }
     
-    lines := strings.Split(string(output), "\n")
-    for _, line := range lines {
-        if strings.Contains(line, "active channel") {
-            parts := strings.Fields(line)
-            if len(parts) > 0 {
-                count, _ := strconv.Atoi(parts[0])
-                return count
-            }
+    return parseChannelCounts(string(output))
+}
+
+// parseChannelCounts разбирает итоговые строки "core show channels":
+// "3 active channels" и "1534 calls processed"
+func parseChannelCounts(output string) (active int, processed int64) {
+    for _, line := range strings.Split(output, "\n") {
+        parts := strings.Fields(line)
+        if len(parts) == 0 {
+            continue
+        }
+        
+        switch {
+        case strings.Contains(line, "active channel"):
+            active, _ = strconv.Atoi(parts[0])
+        case strings.Contains(line, "calls processed") || strings.Contains(line, "call processed"):
+            processed, _ = strconv.ParseInt(parts[0], 10, 64)
         }
     }
     
-    return 0
+    return active, processed
 }
 
 // GetActiveChannels возвращает список активных каналов
-func (m *LinuxMonitor) GetActiveChannels() []types.ChannelInfo {
-    cmd := exec.Command("asterisk", "-rx", "core show channels")
-    output, err := cmd.Output()
+func (m *LinuxMonitor) GetActiveChannels(ctx context.Context) ([]types.ChannelInfo, error) {
+    output, err := m.asteriskOutput(ctx, "core show channels concise")
     
     if err != nil {
-        return []types.ChannelInfo{}
+        return nil, err
     }
     
+    return parseConciseChannels(string(output)), nil
+}
+
+// parseConciseChannels разбирает вывод "core show channels concise":
+// Channel!Context!Exten!Priority!State!Application!Data!CallerID!Accountcode!PeerAccount!AMAflags!Duration!BridgedTo!UniqueID
+func parseConciseChannels(output string) []types.ChannelInfo {
     var channels []types.ChannelInfo
-    lines := strings.Split(string(output), "\n")
     
-    for _, line := range lines {
-        if strings.Contains(line, "/") && !strings.Contains(line, "active channel") {
-            parts := strings.Fields(line)
-            if len(parts) >= 4 {
-                channel := types.ChannelInfo{
-                    Name:        parts[0],
-                    State:       parts[1],
-                    Duration:    parts[2],
-                    CallerID:    strings.Join(parts[3:], " "),
-                    Application: "unknown",
-                }
-                channels = append(channels, channel)
-            }
+    for _, line := range strings.Split(output, "\n") {
+        parts := strings.Split(strings.TrimSpace(line), "!")
+        if len(parts) < 12 {
+            continue
         }
+        
+        duration := parts[11]
+        if seconds, err := strconv.Atoi(duration); err == nil {
+            duration = fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds%3600/60, seconds%60)
+        }
+        
+        application := parts[5]
+        if parts[6] != "" {
+            application += "(" + parts[6] + ")"
+        }
+        
+        channels = append(channels, types.ChannelInfo{
+            Name:        parts[0],
+            State:       parts[4],
+            Duration:    duration,
+            CallerID:    parts[7],
+            Application: application,
+        })
     }
     
     return channels
 }
 
+// GetSIPRegistrations возвращает состояние исходящих регистраций SIP транков
+func (m *LinuxMonitor) GetSIPRegistrations(ctx context.Context) ([]types.SIPRegistration, error) {
+    output, err := m.asteriskOutput(ctx, "sip show registry")
+    
+    if err != nil {
+        return nil, err
+    }
+    
+    return parseSIPRegistry(string(output)), nil
+}
+
+var registryRe = regexp.MustCompile(`^(\S+)\s+([YN])\s+(\S+)\s+(\d+)\s+(.+?)(?:\s{2,}(.*))?$`)
+
+// parseSIPRegistry разбирает вывод "sip show registry"
+func parseSIPRegistry(output string) []types.SIPRegistration {
+    var registrations []types.SIPRegistration
+    
+    for _, line := range strings.Split(output, "\n") {
+        match := registryRe.FindStringSubmatch(strings.TrimSpace(line))
+        if match == nil {
+            continue
+        }
+        
+        refresh, _ := strconv.Atoi(match[4])
+        registrations = append(registrations, types.SIPRegistration{
+            Host:     match[1],
+            Username: match[3],
+            Refresh:  refresh,
+            State:    strings.TrimSpace(match[5]),
+            RegTime:  strings.TrimSpace(match[6]),
+        })
+    }
+    
+    return registrations
+}
+
 // GetAsteriskUptime возвращает время работы Asterisk
 func (m *LinuxMonitor) GetAsteriskUptime() string {
-    cmd := exec.Command("asterisk", "-rx", "core show uptime")
-    output, err := cmd.Output()
+    return m.asteriskUptime(context.Background())
+}
+
+func (m *LinuxMonitor) asteriskUptime(ctx context.Context) string {
+    output, err := m.asteriskOutput(ctx, "core show uptime")
     
     if err != nil {
         return "unknown"
@@ -225,47 +433,65 @@ func (m *LinuxMonitor) GetAsteriskUptime() string {
     return "unknown"
 }
 
-// GetSystemLoad возвращает нагрузку системы
+// GetSystemLoad возвращает нагрузку системы за 1, 5 и 15 минут
 func (m *LinuxMonitor) GetSystemLoad() string {
-    cmd := exec.Command("sh", "-c", "uptime | awk -F'load average:' '{print $2}'")
-    output, err := cmd.Output()
-    
+    load, err := m.GetLoadAverage()
     if err != nil {
         return "unknown"
     }
     
-    return strings.TrimSpace(string(output))
+    return fmt.Sprintf("%.2f, %.2f, %.2f", load[0], load[1], load[2])
 }
 
-// GetCPUUsage возвращает использование CPU
-func (m *LinuxMonitor) GetCPUUsage() float64 {
-    cmd := exec.Command("sh", "-c", "top -bn1 | grep 'Cpu(s)' | awk '{print $2}' | cut -d'%' -f1")
-    output, err := cmd.Output()
+// GetLoadAverage возвращает нагрузку системы из /proc/loadavg
+func (m *LinuxMonitor) GetLoadAverage() ([3]float64, error) {
+    data, err := readProcFile("loadavg")
+    if err != nil {
+        return [3]float64{}, err
+    }
     
+    return parseLoadAvg(string(data))
+}
+
+// GetCPUUsage возвращает загрузку CPU с момента предыдущего вызова по /proc/stat.
+// Первый вызов возвращает среднюю загрузку с момента загрузки системы.
+func (m *LinuxMonitor) GetCPUUsage() float64 {
+    file, err := openProcFile("stat")
     if err != nil {
         return 0
     }
+    defer file.Close()
     
-    usageStr := strings.TrimSpace(string(output))
-    usage, err := strconv.ParseFloat(usageStr, 64)
+    cur, err := parseCPUTimes(file)
     if err != nil {
         return 0
     }
     
-    return usage
+    m.cpuMu.Lock()
+    defer m.cpuMu.Unlock()
+    
+    if m.prevCPU.total == 0 || cur.total >= m.prevCPU.total+minCPUTicks || cur.total < m.prevCPU.total {
+        m.lastCPU = cpuUsagePercent(m.prevCPU, cur)
+        m.prevCPU = cur
+    }
+    
+    return m.lastCPU
 }
 
-// GetMemoryUsage возвращает использование памяти
+// GetMemoryUsage возвращает использование памяти по /proc/meminfo
 func (m *LinuxMonitor) GetMemoryUsage() float64 {
-    cmd := exec.Command("sh", "-c", "free | grep Mem | awk '{printf \"%.1f\", $3/$2 * 100.0}'")
-    output, err := cmd.Output()
+    file, err := openProcFile("meminfo")
+    if err != nil {
+        return 0
+    }
+    defer file.Close()
     
+    info, err := parseMemInfo(file)
     if err != nil {
         return 0
     }
     
-    usageStr := strings.TrimSpace(string(output))
-    usage, err := strconv.ParseFloat(usageStr, 64)
+    usage, err := memoryUsedPercent(info)
     if err != nil {
         return 0
     }
@@ -273,66 +499,66 @@ func (m *LinuxMonitor) GetMemoryUsage() float64 {
     return usage
 }
 
-// GetDiskUsage возвращает использование диска
+// GetDiskUsage возвращает заполненность основной точки монтирования
 func (m *LinuxMonitor) GetDiskUsage() float64 {
-    cmd := exec.Command("sh", "-c", "df / | awk 'NR==2 {print $5}' | sed 's/%//'")
-    output, err := cmd.Output()
-    
+    usage, err := statMount(m.MountPoints()[0])
     if err != nil {
         return 0
     }
     
-    usageStr := strings.TrimSpace(string(output))
-    usage, err := strconv.ParseFloat(usageStr, 64)
-    if err != nil {
-        return 0
-    }
-    
-    return usage
+    return usage.UsedPct
 }
 
-// ExecuteCommand выполняет команду Asterisk
-func (m *LinuxMonitor) ExecuteCommand(name, command string) types.CheckResult {
-    cmd := exec.Command("sh", "-c", command)
-    output, err := cmd.Output()
-    
-    if err != nil {
-        return types.CheckResult{
-            Name:      name,
-            Status:    "error",
-            Message:   fmt.Sprintf("Command failed: %s", command),
-            Error:     err.Error(),
-            Timestamp: time.Now(),
+// MountPoints возвращает контролируемые точки монтирования, по умолчанию "/"
+func (m *LinuxMonitor) MountPoints() []string {
+    m.settingsMu.RLock()
+    defer m.settingsMu.RUnlock()
+    if len(m.mountPoints) == 0 {
+        return []string{"/"}
+    }
+    return m.mountPoints
+}
+
+// GetMountUsage возвращает заполненность всех контролируемых точек монтирования
+// и файловых систем, на которых лежат каталоги Asterisk
+func (m *LinuxMonitor) GetMountUsage() []types.MountUsage {
+    var mounts []types.MountUsage
+    
+    paths, dirs := m.asteriskMounts(m.MountPoints())
+    for _, path := range paths {
+        usage, err := statMount(path)
+        if err != nil {
+            usage.Error = err.Error()
         }
+        usage.Dirs = dirs[path]
+        mounts = append(mounts, usage)
     }
     
-    return types.CheckResult{
-        Name:      name,
-        Status:    "success",
-        Message:   strings.TrimSpace(string(output)),
-        Timestamp: time.Now(),
-    }
+    return mounts
 }
 
+// asteriskMessagesLog - основной лог Asterisk
+const asteriskMessagesLog = "/var/log/asterisk/messages"
+
 // GetAsteriskLogs возвращает логи Asterisk
 func (m *LinuxMonitor) GetAsteriskLogs(lines int, level, filter string) string {
     if lines == 0 {
         lines = 50
     }
     
+    ctx := context.Background()
+    
     // Используем ротацию логов чтобы обновить файлы
-    rotateCmd := "asterisk -rx 'logger rotate' 2>/dev/null"
-    m.ExecuteCommand("Rotate Logs", rotateCmd)
+    m.AsteriskCommand(ctx, "Rotate Logs", "logger rotate")
     time.Sleep(100 * time.Millisecond)
     
     // Читаем логи из файла
-    cmd := fmt.Sprintf("tail -%d /var/log/asterisk/messages 2>/dev/null", lines)
-    result := m.ExecuteCommand("Logs", cmd)
+    count := strconv.Itoa(lines)
+    result := m.Exec(ctx, "Logs", "tail", "-n", count, asteriskMessagesLog)
     
-    // Если доступ запрещен, пробуем с sudo
-    if result.Status == "error" || strings.Contains(result.Message, "Permission denied") {
-        cmd = fmt.Sprintf("sudo tail -%d /var/log/asterisk/messages 2>/dev/null", lines)
-        result = m.ExecuteCommand("Logs Sudo", cmd)
+    // Если доступ запрещен, пробуем с sudo без запроса пароля
+    if result.Status == "error" || strings.Contains(result.Error, "Permission denied") {
+        result = m.Exec(ctx, "Logs Sudo", "sudo", "-n", "tail", "-n", count, asteriskMessagesLog)
     }
     
     // Если все еще ошибка, показываем информацию о доступных логах
@@ -370,20 +596,22 @@ func (m *LinuxMonitor) getLogsDebugInfo() string {
     var debug strings.Builder
     debug.WriteString("=== Logs Debug Information ===\n\n")
     
+    ctx := context.Background()
+    
     // Проверяем доступ к файлам логов
-    checks := []struct {
-        name string
-        cmd  string
-    }{
-        {"Messages File", "ls -la /var/log/asterisk/messages 2>&1 || echo 'Not found'"},
-        {"Full File", "ls -la /var/log/asterisk/full 2>&1 || echo 'Not found'"},
-        {"Asterisk Status", "asterisk -rx 'core show version' 2>&1"},
-        {"Logger Status", "asterisk -rx 'logger show channels' 2>&1"},
+    results := []types.CheckResult{
+        m.Exec(ctx, "Messages File", "ls", "-la", asteriskMessagesLog),
+        m.Exec(ctx, "Full File", "ls", "-la", "/var/log/asterisk/full"),
+        m.AsteriskCommand(ctx, "Asterisk Status", "core show version"),
+        m.AsteriskCommand(ctx, "Logger Status", "logger show channels"),
     }
     
-    for _, check := range checks {
-        result := m.ExecuteCommand(check.name, check.cmd)
-        debug.WriteString(fmt.Sprintf("● %s:\n%s\n\n", check.name, result.Message))
+    for _, result := range results {
+        output := strings.TrimSpace(result.Message + "\n" + result.Error)
+        if output == "" {
+            output = "Not found"
+        }
+        debug.WriteString(fmt.Sprintf("● %s:\n%s\n\n", result.Name, output))
     }
     
     debug.WriteString("Try these commands manually:\n")
@@ -420,41 +648,6 @@ func (m *LinuxMonitor) getFilterDebugInfo(rawLogs, level, filter string) string
     return debug.String()
 }
 
-func (m *LinuxMonitor) getSystemInfoInstead(lines int) string {
-    if lines == 0 {
-        lines = 20
-    }
-    
-    var output strings.Builder
-    output.WriteString("=== System Information (Logs unavailable) ===\n\n")
-    
-    // Получаем системную информацию через доступные команды
-    commands := []struct {
-        name string
-        cmd  string
-    }{
-        {"Uptime", "asterisk -rx 'core show uptime'"},
-        {"Version", "asterisk -rx 'core show version'"},
-        {"Channels", "asterisk -rx 'core show channels count'"},
-        {"Calls", "asterisk -rx 'core show calls'"},
-        {"SIP Peers", "asterisk -rx 'sip show peers' | head -10"},
-        {"System Load", "uptime"},
-    }
-    
-    for _, item := range commands {
-        result := m.ExecuteCommand(item.name, item.cmd)
-        if result.Status == "success" {
-            output.WriteString(fmt.Sprintf("● %s: %s\n", item.name, strings.TrimSpace(result.Message)))
-        }
-    }
-    
-    output.WriteString("\nTo access logs, run:\n")
-    output.WriteString("  sudo tail -f /var/log/asterisk/messages\n")
-    output.WriteString("Or check permissions on /var/log/asterisk/ directory\n")
-    
-    return output.String()
-}
-
 func (m *LinuxMonitor) filterLogsByLevel(logs, level string) string {
     levelLower := strings.ToLower(level)
     lines := strings.Split(logs, "\n")
@@ -535,25 +728,45 @@ func (m *LinuxMonitor) filterLogs(logs, filter string) string {
     return strings.Join(filtered, "\n")
 }
 
-// GetSystemMetrics возвращает полные системные метрики
-func (m *LinuxMonitor) GetSystemMetrics() types.SystemMetrics {
-    return types.SystemMetrics{
-        CPUUsage:     m.GetCPUUsage(),
-        MemoryUsage:  m.GetMemoryUsage(),
-        DiskUsage:    m.GetDiskUsage(),
-        ActiveCalls:  m.GetActiveCallsCount(),
-        OnlinePeers:  func() int { o, _ := m.GetSIPPeersCount(); return o }(),
-        TotalPeers:   func() int { _, t := m.GetSIPPeersCount(); return t }(),
-        Uptime:       m.GetAsteriskUptime(),
-        LoadAverage:  m.GetSystemLoad(),
-        AsteriskPID:  m.GetAsteriskPID(),
-        ServiceState: m.GetServiceStatus(),
+// GetSystemMetrics возвращает полные системные метрики. Недоступный Asterisk
+// не считается ошибкой, ошибка возвращается, только если истек срок ctx.
+// Число пиров не заполняется: его дает список пиров, см. CountPeers.
+func (m *LinuxMonitor) GetSystemMetrics(ctx context.Context) (types.SystemMetrics, error) {
+    activeCalls, callsProcessed := m.channelCounts(ctx)
+    metrics := types.SystemMetrics{
+        CPUUsage:       m.GetCPUUsage(),
+        MemoryUsage:    m.GetMemoryUsage(),
+        ActiveCalls:    activeCalls,
+        CallsProcessed: callsProcessed,
+        Uptime:         m.asteriskUptime(ctx),
+        AsteriskPID:    m.GetAsteriskPID(),
+        ServiceState:   m.serviceStatus(ctx),
+        Mounts:         m.GetMountUsage(),
+        Dirs:           m.GetDirUsage(),
+    }
+    
+    metrics.LoadAverage = "unknown"
+    if load, err := m.GetLoadAverage(); err == nil {
+        metrics.LoadAverage = fmt.Sprintf("%.2f, %.2f, %.2f", load[0], load[1], load[2])
+        metrics.Load1 = load[0]
+    }
+    
+    if pid, err := strconv.Atoi(metrics.AsteriskPID); err == nil {
+        if process, err := m.GetProcessMetrics(pid); err == nil {
+            metrics.Process = &process
+        }
+    }
+    
+    // Основная метрика диска - первая точка монтирования
+    if len(metrics.Mounts) > 0 {
+        metrics.DiskUsage = metrics.Mounts[0].UsedPct
     }
+    
+    return metrics, ctx.Err()
 }
 
 func (m *LinuxMonitor) GetRTPStats() string {
-    cmd := exec.Command("asterisk", "-rx", "rtp show stats")
-    output, err := cmd.Output()
+    output, err := m.asteriskOutput(context.Background(), "rtp show stats")
     if err != nil {
         return "RTP stats unavailable"
     }
@@ -561,8 +774,7 @@ func (m *LinuxMonitor) GetRTPStats() string {
 }
 
 func (m *LinuxMonitor) GetJitterBufferStats() string {
-    cmd := exec.Command("asterisk", "-rx", "jitterbuffer show")
-    output, err := cmd.Output()
+    output, err := m.asteriskOutput(context.Background(), "jitterbuffer show")
     if err != nil {
         return "Jitterbuffer stats unavailable"
     }
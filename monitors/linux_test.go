@@ -0,0 +1,35 @@
+package monitor
+
+import "testing"
+
+func TestParseChannelCounts(t *testing.T) {
+	tests := []struct {
+		name      string
+		output    string
+		active    int
+		processed int64
+	}{
+		{
+			name: "calls in progress",
+			output: "Channel              Location             State   Application(Data)\n" +
+				"SIP/100-00000001     s@default:1          Up      Dial(SIP/101)\n" +
+				"SIP/101-00000002     (None)               Up      AppDial((Outgoing Line))\n" +
+				"2 active channels\n1 active call\n1534 calls processed\n",
+			active:    2,
+			processed: 1534,
+		},
+		{
+			name:      "idle",
+			output:    "Channel              Location             State   Application(Data)\n0 active channels\n0 active calls\n1 call processed\n",
+			active:    0,
+			processed: 1,
+		},
+		{name: "empty", output: ""},
+	}
+	for _, tt := range tests {
+		active, processed := parseChannelCounts(tt.output)
+		if active != tt.active || processed != tt.processed {
+			t.Errorf("%s: got %d active, %d processed, want %d, %d", tt.name, active, processed, tt.active, tt.processed)
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package monitor
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 )
 
@@ -36,19 +38,18 @@ func (m *LinuxMonitor) GetProblemCallLogs(lines int) string {
 	}
 	
 	logFile := "/var/log/asterisk-monitor/problem-calls.log"
-	cmd := fmt.Sprintf("tail -%d %s 2>/dev/null || echo 'No problem call logs found'", lines, logFile)
-	
-	result := m.ExecuteCommand("Problem Logs", cmd)
+	result := m.Exec(context.Background(), "Problem Logs", "tail", "-n", strconv.Itoa(lines), logFile)
+	if result.Status != "success" {
+		return "No problem call logs found"
+	}
 	return result.Message
 }
 
 // ClearProblemCallLogs очищает лог проблемных вызовов
 func (m *LinuxMonitor) ClearProblemCallLogs() string {
 	logFile := "/var/log/asterisk-monitor/problem-calls.log"
-	cmd := fmt.Sprintf("echo '' > %s", logFile)
 	
-	result := m.ExecuteCommand("Clear Logs", cmd)
-	if result.Status == "success" {
+	if err := os.Truncate(logFile, 0); err == nil {
 		return "Problem call logs cleared successfully"
 	}
 	return "Failed to clear problem call logs"
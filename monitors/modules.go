@@ -0,0 +1,271 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+// moduleActions - действия с модулями, доступные из интерфейса
+var moduleActions = map[string]bool{
+	"load":   true,
+	"unload": true,
+	"reload": true,
+}
+
+// supportLevels - значения последнего столбца module show
+var supportLevels = map[string]bool{
+	"core":       true,
+	"extended":   true,
+	"deprecated": true,
+	"unknown":    true,
+}
+
+// SetModuleBaselineFile задает файл базовой линии модулей для проверки Modules
+func (m *LinuxMonitor) SetModuleBaselineFile(path string) {
+	m.moduleBaseline = path
+}
+
+// GetModules возвращает модули Asterisk по module show
+func (m *LinuxMonitor) GetModules(ctx context.Context) ([]types.AsteriskModule, error) {
+	output, err := m.asteriskOutput(ctx, "module show")
+	if err != nil {
+		return nil, err
+	}
+	return ParseModules(string(output)), nil
+}
+
+// ParseModules разбирает вывод module show:
+//
+//	Module                         Description                              Use Count  Status      Support Level
+//	chan_sip.so                    Session Initiation Protocol (SIP)        0          Not Running      extended
+//	226 modules loaded
+//
+// До Asterisk 12 столбцов Status и Support Level нет.
+func ParseModules(output string) []types.AsteriskModule {
+	var modules []types.AsteriskModule
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.HasSuffix(fields[0], ".so") {
+			continue
+		}
+
+		module := types.AsteriskModule{Name: fields[0]}
+		rest := fields[1:]
+		if n := len(rest); n > 0 && supportLevels[rest[n-1]] {
+			module.Support = rest[n-1]
+			rest = rest[:n-1]
+		}
+		if n := len(rest); n > 0 {
+			switch {
+			case n > 1 && rest[n-2] == "Not" && rest[n-1] == "Running":
+				module.Status = types.ModuleNotRunning
+				rest = rest[:n-2]
+			case rest[n-1] == types.ModuleRunning || rest[n-1] == types.ModuleDeclined || rest[n-1] == "Unknown":
+				module.Status = rest[n-1]
+				rest = rest[:n-1]
+			}
+		}
+		if n := len(rest); n > 0 {
+			if count, err := strconv.Atoi(rest[n-1]); err == nil {
+				module.UseCount = count
+				rest = rest[:n-1]
+			}
+		}
+		module.Description = strings.Join(rest, " ")
+		modules = append(modules, module)
+	}
+	return modules
+}
+
+// ModuleWorking сообщает, что модуль загружен и работает. Asterisk до 12
+// выводит только загруженные модули без состояния.
+func ModuleWorking(module types.AsteriskModule) bool {
+	return module.Status == "" || module.Status == types.ModuleRunning
+}
+
+// ModuleAction загружает, выгружает или перезагружает модуль name
+func (m *LinuxMonitor) ModuleAction(ctx context.Context, action, name string) types.CheckResult {
+	title := fmt.Sprintf("Module %s %s", action, name)
+	if !moduleActions[action] {
+		return types.CheckResult{Name: title, Status: "error", Error: "unknown module action " + action, Timestamp: time.Now()}
+	}
+
+	result := m.AsteriskCommand(ctx, title, "module "+action+" "+name)
+	// Asterisk сообщает об ошибке текстом, код завершения asterisk -rx нулевой
+	if result.Status == "success" && strings.Contains(result.Message, "Unable to") {
+		result.Status = "error"
+		result.Error = result.Message
+	}
+	return result
+}
+
+// ModuleBaseline возвращает базовую линию модулей этого сервера. ok равен
+// false, если она еще не сохранена.
+func (m *LinuxMonitor) ModuleBaseline() (baseline types.ModuleBaseline, ok bool, err error) {
+	baselines, err := readModuleBaselines(m.moduleBaseline)
+	if err != nil {
+		return baseline, false, err
+	}
+	baseline, ok = baselines[hostname()]
+	return baseline, ok, nil
+}
+
+// SaveModuleBaseline сохраняет работающие сейчас модули как базовую линию этого
+// сервера. Базовые линии других серверов в том же файле сохраняются.
+func (m *LinuxMonitor) SaveModuleBaseline(ctx context.Context) (types.ModuleBaseline, error) {
+	modules, err := m.GetModules(ctx)
+	if err != nil {
+		return types.ModuleBaseline{}, err
+	}
+	if len(modules) == 0 {
+		return types.ModuleBaseline{}, fmt.Errorf("module show returned no modules")
+	}
+
+	baseline := types.ModuleBaseline{Host: hostname(), SavedAt: time.Now()}
+	for _, module := range modules {
+		if ModuleWorking(module) {
+			baseline.Modules = append(baseline.Modules, module.Name)
+		}
+	}
+	sort.Strings(baseline.Modules)
+
+	baselines, err := readModuleBaselines(m.moduleBaseline)
+	if err != nil {
+		return baseline, err
+	}
+	baselines[baseline.Host] = baseline
+
+	data, err := json.MarshalIndent(baselines, "", "  ")
+	if err != nil {
+		return baseline, err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.moduleBaseline), 0755); err != nil {
+		return baseline, err
+	}
+	return baseline, os.WriteFile(m.moduleBaseline, data, 0644)
+}
+
+// readModuleBaselines читает базовые линии модулей всех серверов по имени хоста
+func readModuleBaselines(path string) (map[string]types.ModuleBaseline, error) {
+	baselines := make(map[string]types.ModuleBaseline)
+	if path == "" {
+		return baselines, fmt.Errorf("module baseline file is not configured")
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return baselines, nil
+	}
+	if err != nil {
+		return baselines, err
+	}
+	if err := json.Unmarshal(data, &baselines); err != nil {
+		return baselines, fmt.Errorf("%s: %w", path, err)
+	}
+	return baselines, nil
+}
+
+// hostname возвращает имя сервера, по которому выбирается базовая линия
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "localhost"
+	}
+	return name
+}
+
+// CompareModules сравнивает модули с базовой линией
+func CompareModules(baseline types.ModuleBaseline, modules []types.AsteriskModule) types.ModuleDrift {
+	var drift types.ModuleDrift
+
+	loaded := make(map[string]types.AsteriskModule, len(modules))
+	for _, module := range modules {
+		loaded[module.Name] = module
+	}
+	expected := make(map[string]bool, len(baseline.Modules))
+	for _, name := range baseline.Modules {
+		expected[name] = true
+		module, ok := loaded[name]
+		switch {
+		case !ok:
+			drift.Missing = append(drift.Missing, name)
+		case !ModuleWorking(module):
+			drift.Failed = append(drift.Failed, name)
+		}
+	}
+	for _, module := range modules {
+		if !expected[module.Name] && ModuleWorking(module) {
+			drift.Added = append(drift.Added, module.Name)
+		}
+	}
+
+	sort.Strings(drift.Missing)
+	sort.Strings(drift.Failed)
+	sort.Strings(drift.Added)
+	return drift
+}
+
+// checkModules сравнивает модули Asterisk с базовой линией сервера
+func (m *LinuxMonitor) checkModules(ctx context.Context) types.CheckResult {
+	result := types.CheckResult{Name: "Modules", Status: "success", Timestamp: time.Now()}
+
+	modules, err := m.GetModules(ctx)
+	if err != nil {
+		result.Status = "error"
+		result.Message = "module show failed"
+		result.Error = err.Error()
+		return result
+	}
+	running := 0
+	for _, module := range modules {
+		if ModuleWorking(module) {
+			running++
+		}
+	}
+	summary := fmt.Sprintf("%d modules, %d running", len(modules), running)
+
+	baseline, ok, err := m.ModuleBaseline()
+	if err != nil {
+		result.Status = "warning"
+		result.Message = summary
+		result.Error = err.Error()
+		return result
+	}
+	if !ok {
+		result.Status = "warning"
+		result.Message = summary + "; no module baseline saved (Internals > Modules, 'b')"
+		return result
+	}
+
+	drift := CompareModules(baseline, modules)
+	var problems []string
+	if len(drift.Missing) > 0 {
+		problems = append(problems, "missing: "+strings.Join(drift.Missing, ", "))
+	}
+	if len(drift.Failed) > 0 {
+		problems = append(problems, "failed: "+strings.Join(drift.Failed, ", "))
+	}
+	if len(drift.Added) > 0 {
+		problems = append(problems, "added: "+strings.Join(drift.Added, ", "))
+	}
+
+	switch {
+	case len(drift.Missing) > 0 || len(drift.Failed) > 0:
+		result.Status = "error"
+	case len(drift.Added) > 0:
+		result.Status = "warning"
+	default:
+		problems = append(problems, fmt.Sprintf("matches baseline of %s", baseline.SavedAt.Format(time.DateOnly)))
+	}
+	result.Message = summary + "; " + strings.Join(problems, "; ")
+	return result
+}
@@ -0,0 +1,462 @@
+package monitor
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+// minNetworkInterval - минимальный интервал между чтениями для пересчета скоростей.
+// Сеть читают и коллектор, и режим отладки, частые вызовы возвращают прошлые скорости.
+const minNetworkInterval = time.Second
+
+// topSocketNotes - сколько сокетов с наибольшей очередью попадает в текст оповещения
+const topSocketNotes = 3
+
+// networkSample - предыдущее чтение счетчиков сети для вычисления скоростей
+type networkSample struct {
+	at         time.Time
+	interfaces map[string]types.InterfaceStats
+	udp        types.UDPStats
+	drops      map[uint64]uint64 // отбрасывания по inode сокета Asterisk
+}
+
+// GetNetworkHealth возвращает счетчики интерфейсов, ошибки UDP ядра и очереди
+// UDP сокетов Asterisk. Прерывистый звук чаще всего вызван переполнением
+// буфера приема сокета (RcvbufErrors), которое не видно ни в Asterisk, ни в ping.
+func (m *LinuxMonitor) GetNetworkHealth() types.NetworkHealth {
+	health := types.NetworkHealth{Time: time.Now()}
+
+	file, err := openProcFile("net/dev")
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	health.Interfaces, err = parseNetDev(file)
+	file.Close()
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+
+	if file, err := openProcFile("net/snmp"); err == nil {
+		health.UDP, err = parseUDPSnmp(file)
+		file.Close()
+		if err != nil {
+			health.Error = err.Error()
+		}
+	}
+	// IPv6 может быть отключен, тогда snmp6 нет
+	if file, err := openProcFile("net/snmp6"); err == nil {
+		addUDPSnmp6(&health.UDP, file)
+		file.Close()
+	}
+
+	health.RmemDefault = readSysctl("net/core/rmem_default")
+	health.RmemMax = readSysctl("net/core/rmem_max")
+	health.Sockets, health.SocketsError = m.asteriskUDPSockets()
+
+	m.rateNetwork(&health)
+	return health
+}
+
+// rateNetwork вычисляет скорости с предыдущего чтения и запоминает текущее
+func (m *LinuxMonitor) rateNetwork(health *types.NetworkHealth) {
+	cur := networkSample{
+		at:         health.Time,
+		interfaces: make(map[string]types.InterfaceStats, len(health.Interfaces)),
+		udp:        health.UDP,
+		drops:      make(map[uint64]uint64, len(health.Sockets)),
+	}
+	for _, iface := range health.Interfaces {
+		cur.interfaces[iface.Name] = iface
+	}
+	for _, socket := range health.Sockets {
+		cur.drops[socket.Inode] = socket.Drops
+	}
+
+	m.netMu.Lock()
+	defer m.netMu.Unlock()
+
+	prev := m.prevNet
+	elapsed := cur.at.Sub(prev.at)
+	switch {
+	case prev.at.IsZero():
+		m.prevNet = cur
+		return
+	case elapsed < minNetworkInterval:
+		// Слишком частый вызов дает шумную оценку, возвращаем прошлые скорости
+		last := m.lastNet
+		for i := range health.Interfaces {
+			if rated, ok := last[health.Interfaces[i].Name]; ok {
+				copyRates(&health.Interfaces[i], rated)
+			}
+		}
+		health.UDP.InErrorsPerSec = m.lastUDP.InErrorsPerSec
+		health.UDP.RcvbufErrorsPerSec = m.lastUDP.RcvbufErrorsPerSec
+		health.UDP.SndbufErrorsPerSec = m.lastUDP.SndbufErrorsPerSec
+		health.DropsPerSec = m.lastDrops
+		health.Rated = last != nil
+		return
+	}
+
+	seconds := elapsed.Seconds()
+	last := make(map[string]types.InterfaceStats, len(health.Interfaces))
+	for i := range health.Interfaces {
+		iface := &health.Interfaces[i]
+		if before, ok := prev.interfaces[iface.Name]; ok {
+			iface.RxBytesPerSec = rate(before.RxBytes, iface.RxBytes, seconds)
+			iface.TxBytesPerSec = rate(before.TxBytes, iface.TxBytes, seconds)
+			iface.RxPacketsPerSec = rate(before.RxPackets, iface.RxPackets, seconds)
+			iface.TxPacketsPerSec = rate(before.TxPackets, iface.TxPackets, seconds)
+			iface.RxErrorsPerSec = rate(before.RxErrors, iface.RxErrors, seconds)
+			iface.RxDropsPerSec = rate(before.RxDrops, iface.RxDrops, seconds)
+			iface.TxErrorsPerSec = rate(before.TxErrors, iface.TxErrors, seconds)
+			iface.TxDropsPerSec = rate(before.TxDrops, iface.TxDrops, seconds)
+		}
+		last[iface.Name] = *iface
+	}
+
+	udp := &health.UDP
+	udp.InErrorsPerSec = rate(prev.udp.InErrors, udp.InErrors, seconds)
+	udp.RcvbufErrorsPerSec = rate(prev.udp.RcvbufErrors, udp.RcvbufErrors, seconds)
+	udp.SndbufErrorsPerSec = rate(prev.udp.SndbufErrors, udp.SndbufErrors, seconds)
+
+	// Сокеты RTP создаются и закрываются с каждым вызовом, учитываются только
+	// сокеты, которые были и при прошлом чтении
+	var drops uint64
+	for inode, count := range cur.drops {
+		if before, ok := prev.drops[inode]; ok && count > before {
+			drops += count - before
+		}
+	}
+	health.DropsPerSec = float64(drops) / seconds
+	health.Rated = true
+
+	m.prevNet = cur
+	m.lastNet = last
+	m.lastUDP = *udp
+	m.lastDrops = health.DropsPerSec
+}
+
+// copyRates переносит скорости из прошлого чтения интерфейса
+func copyRates(iface *types.InterfaceStats, rated types.InterfaceStats) {
+	iface.RxBytesPerSec = rated.RxBytesPerSec
+	iface.TxBytesPerSec = rated.TxBytesPerSec
+	iface.RxPacketsPerSec = rated.RxPacketsPerSec
+	iface.TxPacketsPerSec = rated.TxPacketsPerSec
+	iface.RxErrorsPerSec = rated.RxErrorsPerSec
+	iface.RxDropsPerSec = rated.RxDropsPerSec
+	iface.TxErrorsPerSec = rated.TxErrorsPerSec
+	iface.TxDropsPerSec = rated.TxDropsPerSec
+}
+
+// rate возвращает прирост счетчика в секунду. Сброс счетчика (перезагрузка
+// драйвера, пересоздание интерфейса) дает 0.
+func rate(prev, cur uint64, seconds float64) float64 {
+	if cur < prev || seconds <= 0 {
+		return 0
+	}
+	return float64(cur-prev) / seconds
+}
+
+// parseNetDev разбирает /proc/net/dev:
+//
+//	Inter-|   Receive                                                |  Transmit
+//	 face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+//	  eth0: 1234567    8910    0    3    0     0          0         0  7654321    1098    0    0    0     0       0          0
+func parseNetDev(r io.Reader) ([]types.InterfaceStats, error) {
+	var interfaces []types.InterfaceStats
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		name, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) < 16 {
+			continue
+		}
+
+		values := make([]uint64, 16)
+		for i := range values {
+			value, err := strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("/proc/net/dev: bad counter %q", fields[i])
+			}
+			values[i] = value
+		}
+		interfaces = append(interfaces, types.InterfaceStats{
+			Name:      strings.TrimSpace(name),
+			RxBytes:   values[0],
+			RxPackets: values[1],
+			RxErrors:  values[2],
+			RxDrops:   values[3],
+			TxBytes:   values[8],
+			TxPackets: values[9],
+			TxErrors:  values[10],
+			TxDrops:   values[11],
+		})
+	}
+	return interfaces, scanner.Err()
+}
+
+// parseUDPSnmp разбирает счетчики UDP из /proc/net/snmp. Каждый протокол
+// занимает две строки: имена счетчиков и значения.
+//
+//	Udp: InDatagrams NoPorts InErrors OutDatagrams RcvbufErrors SndbufErrors InCsumErrors
+//	Udp: 2145872 1203 57 2150112 57 0 0
+func parseUDPSnmp(r io.Reader) (types.UDPStats, error) {
+	var udp types.UDPStats
+	var header []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "Udp:" {
+			continue
+		}
+		if header == nil {
+			header = fields[1:]
+			continue
+		}
+
+		counters := make(map[string]uint64, len(header))
+		for i, name := range header {
+			if i+1 < len(fields) {
+				counters[name], _ = strconv.ParseUint(fields[i+1], 10, 64)
+			}
+		}
+		addUDPCounters(&udp, counters)
+		return udp, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return udp, err
+	}
+	return udp, fmt.Errorf("/proc/net/snmp: Udp counters not found")
+}
+
+// addUDPSnmp6 добавляет счетчики UDP для IPv6 из /proc/net/snmp6, где каждый
+// счетчик записан отдельной строкой: "Udp6InErrors 12"
+func addUDPSnmp6(udp *types.UDPStats, r io.Reader) {
+	counters := make(map[string]uint64)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || !strings.HasPrefix(fields[0], "Udp6") {
+			continue
+		}
+		counters[strings.TrimPrefix(fields[0], "Udp6")], _ = strconv.ParseUint(fields[1], 10, 64)
+	}
+	addUDPCounters(udp, counters)
+}
+
+func addUDPCounters(udp *types.UDPStats, counters map[string]uint64) {
+	udp.InDatagrams += counters["InDatagrams"]
+	udp.OutDatagrams += counters["OutDatagrams"]
+	udp.NoPorts += counters["NoPorts"]
+	udp.InErrors += counters["InErrors"]
+	udp.RcvbufErrors += counters["RcvbufErrors"]
+	udp.SndbufErrors += counters["SndbufErrors"]
+}
+
+// readSysctl возвращает числовой параметр ядра из /proc/sys или 0
+func readSysctl(name string) uint64 {
+	data, err := readProcFile(filepath.Join("sys", name))
+	if err != nil {
+		return 0
+	}
+	value, _ := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	return value
+}
+
+// asteriskUDPSockets возвращает UDP сокеты процесса Asterisk по убыванию
+// очереди приема. Вторым значением возвращается причина, по которой сокеты
+// определить не удалось.
+func (m *LinuxMonitor) asteriskUDPSockets() ([]types.UDPSocket, string) {
+	process := discoverProcess(m.pidfilePath())
+	if process.PID == 0 {
+		return nil, "asterisk process not found"
+	}
+
+	inodes, err := socketInodes(process.PID)
+	if err != nil {
+		// Каталог fd доступен только владельцу процесса и root
+		return nil, fmt.Sprintf("cannot read sockets of PID %d: %v", process.PID, err)
+	}
+
+	var sockets []types.UDPSocket
+	for _, name := range []string{"net/udp", "net/udp6"} {
+		file, err := openProcFile(name)
+		if err != nil {
+			continue
+		}
+		all, err := parseUDPSockets(file)
+		file.Close()
+		if err != nil {
+			return nil, err.Error()
+		}
+		for _, socket := range all {
+			if inodes[socket.Inode] {
+				sockets = append(sockets, socket)
+			}
+		}
+	}
+
+	sort.SliceStable(sockets, func(i, j int) bool {
+		if sockets[i].RxQueue != sockets[j].RxQueue {
+			return sockets[i].RxQueue > sockets[j].RxQueue
+		}
+		return sockets[i].Port < sockets[j].Port
+	})
+	return sockets, ""
+}
+
+// socketInodes возвращает inode сокетов, открытых процессом pid
+func socketInodes(pid int) (map[uint64]bool, error) {
+	dir := filepath.Join(procRoot, strconv.Itoa(pid), "fd")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	inodes := make(map[uint64]bool)
+	for _, entry := range entries {
+		target, err := os.Readlink(filepath.Join(dir, entry.Name()))
+		if err != nil || !strings.HasPrefix(target, "socket:[") {
+			continue
+		}
+		if inode, err := strconv.ParseUint(strings.Trim(target[len("socket:"):], "[]"), 10, 64); err == nil {
+			inodes[inode] = true
+		}
+	}
+	return inodes, nil
+}
+
+// parseUDPSockets разбирает /proc/net/udp и /proc/net/udp6:
+//
+//	sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops
+//	 0: 00000000:13C4 00000000:0000 07 00000000:00000000 00:00000000 00000000   997        0 31245 2 0000000000000000 0
+func parseUDPSockets(r io.Reader) ([]types.UDPSocket, error) {
+	var sockets []types.UDPSocket
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || !strings.HasSuffix(fields[0], ":") {
+			continue
+		}
+
+		ip, port, err := parseSocketAddress(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		txQueue, rxQueue, _ := strings.Cut(fields[4], ":")
+		socket := types.UDPSocket{
+			Local: net.JoinHostPort(ip.String(), strconv.Itoa(port)),
+			Port:  port,
+		}
+		socket.TxQueue, _ = strconv.ParseUint(txQueue, 16, 64)
+		socket.RxQueue, _ = strconv.ParseUint(rxQueue, 16, 64)
+		socket.Inode, _ = strconv.ParseUint(fields[9], 10, 64)
+		if len(fields) > 12 {
+			socket.Drops, _ = strconv.ParseUint(fields[12], 10, 64)
+		}
+		sockets = append(sockets, socket)
+	}
+	return sockets, scanner.Err()
+}
+
+// parseSocketAddress разбирает адрес вида 0100007F:13C4. Ядро выводит адрес
+// 32-битными словами в порядке байт машины, порт - числом.
+func parseSocketAddress(text string) (net.IP, int, error) {
+	addr, portText, ok := strings.Cut(text, ":")
+	raw, err := hex.DecodeString(addr)
+	if !ok || err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return nil, 0, fmt.Errorf("bad socket address %q", text)
+	}
+	port, err := strconv.ParseUint(portText, 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("bad socket port %q", text)
+	}
+
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		binary.NativeEndian.PutUint32(ip[i:], binary.BigEndian.Uint32(raw[i:]))
+	}
+	return ip, int(port), nil
+}
+
+// NetworkSamples преобразует состояние сети в измерения. Скорости появляются
+// со второго чтения, петлевой интерфейс не учитывается.
+func NetworkSamples(health types.NetworkHealth, ts time.Time) []types.Sample {
+	if health.Error != "" || !health.Rated {
+		return nil
+	}
+
+	var samples []types.Sample
+	for _, iface := range health.Interfaces {
+		if iface.Name == "lo" {
+			continue
+		}
+		labels := map[string]string{"interface": iface.Name}
+		for _, value := range []struct {
+			metric string
+			value  float64
+		}{
+			{types.MetricNetRxBytes, iface.RxBytesPerSec},
+			{types.MetricNetTxBytes, iface.TxBytesPerSec},
+			{types.MetricNetRxErrors, iface.RxErrorsPerSec},
+			{types.MetricNetRxDrops, iface.RxDropsPerSec},
+			{types.MetricNetTxErrors, iface.TxErrorsPerSec},
+			{types.MetricNetTxDrops, iface.TxDropsPerSec},
+		} {
+			samples = append(samples, types.Sample{Metric: value.metric, Labels: labels, Value: value.value, Timestamp: ts})
+		}
+	}
+
+	udp := health.UDP
+	samples = append(samples,
+		types.Sample{
+			Metric:    types.MetricUDPInErrors,
+			Value:     udp.InErrorsPerSec,
+			Timestamp: ts,
+			Note:      fmt.Sprintf("UDP InErrors %d total", udp.InErrors),
+		},
+		types.Sample{
+			Metric:    types.MetricUDPRcvbufErrors,
+			Value:     udp.RcvbufErrorsPerSec,
+			Timestamp: ts,
+			Note:      fmt.Sprintf("UDP RcvbufErrors %d total, rmem_default %d, rmem_max %d", udp.RcvbufErrors, health.RmemDefault, health.RmemMax),
+		},
+	)
+
+	if health.SocketsError != "" {
+		return samples
+	}
+	var rxQueue uint64
+	var notes []string
+	for i, socket := range health.Sockets {
+		rxQueue = max(rxQueue, socket.RxQueue)
+		if i < topSocketNotes && socket.RxQueue > 0 {
+			notes = append(notes, fmt.Sprintf("%s %d bytes", socket.Local, socket.RxQueue))
+		}
+	}
+	note := fmt.Sprintf("%d asterisk UDP sockets, no receive backlog", len(health.Sockets))
+	if len(notes) > 0 {
+		note = fmt.Sprintf("%d asterisk UDP sockets, largest receive queues: %s", len(health.Sockets), strings.Join(notes, ", "))
+	}
+	return append(samples,
+		types.Sample{Metric: types.MetricAsteriskUDPRxQueue, Value: float64(rxQueue), Timestamp: ts, Note: note},
+		types.Sample{Metric: types.MetricAsteriskUDPDrops, Value: health.DropsPerSec, Timestamp: ts, Note: note},
+	)
+}
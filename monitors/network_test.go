@@ -0,0 +1,220 @@
+package monitor
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+// requireLittleEndian пропускает тест на машинах с обратным порядком байт:
+// адреса в образцах /proc/net записаны машиной x86
+func requireLittleEndian(t *testing.T) {
+	t.Helper()
+	if binary.NativeEndian.Uint16([]byte{1, 0}) != 1 {
+		t.Skip("sample /proc/net files are from a little-endian machine")
+	}
+}
+
+func TestParseNetDev(t *testing.T) {
+	interfaces, err := parseNetDev(openTestdata(t, "proc/net/dev"))
+	if err != nil {
+		t.Fatalf("parseNetDev: %v", err)
+	}
+	if len(interfaces) != 2 {
+		t.Fatalf("got %d interfaces, want 2: %+v", len(interfaces), interfaces)
+	}
+
+	want := types.InterfaceStats{
+		Name:      "eth0",
+		RxBytes:   987654321,
+		RxPackets: 1234567,
+		RxErrors:  2,
+		RxDrops:   15,
+		TxBytes:   123456789,
+		TxPackets: 654321,
+		TxErrors:  0,
+		TxDrops:   3,
+	}
+	if interfaces[0].Name != "lo" || interfaces[1] != want {
+		t.Errorf("got %+v, want lo and %+v", interfaces, want)
+	}
+}
+
+func TestParseNetDevMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr string
+	}{
+		{name: "headers only", input: "Inter-|   Receive\n face |bytes    packets\n", want: 0},
+		{name: "short line skipped", input: "  eth1: 1 2 3\n  eth0: 1 2 3 4 5 6 7 8 9 10 11 12 13 14 15 16\n", want: 1},
+		{name: "bad counter", input: "  eth0: 1 2 3 4 5 6 7 8 9 x 11 12 13 14 15 16\n", wantErr: `bad counter "x"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseNetDev(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseNetDev: %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("got %d interfaces, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseUDPSnmp(t *testing.T) {
+	udp, err := parseUDPSnmp(openTestdata(t, "proc/net/snmp"))
+	if err != nil {
+		t.Fatalf("parseUDPSnmp: %v", err)
+	}
+	// Счетчики UdpLite не смешиваются с Udp
+	want := types.UDPStats{
+		InDatagrams:  2145872,
+		OutDatagrams: 2150112,
+		NoPorts:      1203,
+		InErrors:     57,
+		RcvbufErrors: 57,
+	}
+	if udp != want {
+		t.Errorf("got %+v, want %+v", udp, want)
+	}
+
+	addUDPSnmp6(&udp, openTestdata(t, "proc/net/snmp6"))
+	want = types.UDPStats{
+		InDatagrams:  2146872,
+		OutDatagrams: 2151012,
+		NoPorts:      1206,
+		InErrors:     62,
+		RcvbufErrors: 62,
+		SndbufErrors: 1,
+	}
+	if udp != want {
+		t.Errorf("with snmp6 got %+v, want %+v", udp, want)
+	}
+}
+
+func TestParseUDPSnmpMalformed(t *testing.T) {
+	if _, err := parseUDPSnmp(strings.NewReader("Ip: Forwarding\nIp: 2\n")); err == nil {
+		t.Error("no error without Udp counters")
+	}
+	if _, err := parseUDPSnmp(strings.NewReader("Udp: InDatagrams InErrors\n")); err == nil {
+		t.Error("no error without Udp values line")
+	}
+
+	// Строка значений короче заголовка, нечисловые значения дают 0
+	udp, err := parseUDPSnmp(strings.NewReader("Udp: InDatagrams InErrors RcvbufErrors\nUdp: 10 x\n"))
+	if err != nil {
+		t.Fatalf("parseUDPSnmp: %v", err)
+	}
+	if udp != (types.UDPStats{InDatagrams: 10}) {
+		t.Errorf("got %+v", udp)
+	}
+}
+
+func TestParseUDPSockets(t *testing.T) {
+	requireLittleEndian(t)
+
+	sockets, err := parseUDPSockets(openTestdata(t, "proc/net/udp"))
+	if err != nil {
+		t.Fatalf("parseUDPSockets: %v", err)
+	}
+	want := []types.UDPSocket{
+		{Local: "0.0.0.0:5060", Port: 5060, Inode: 31245},
+		{Local: "127.0.0.1:53", Port: 53, Inode: 20001},
+		{Local: "0.0.0.0:10000", Port: 10000, RxQueue: 128000, Drops: 41, Inode: 31300},
+		{Local: "10.0.0.1:50000", Port: 50000, Inode: 40000},
+	}
+	if len(sockets) != len(want) {
+		t.Fatalf("got %d sockets, want %d: %+v", len(sockets), len(want), sockets)
+	}
+	for i := range want {
+		if sockets[i] != want[i] {
+			t.Errorf("socket %d = %+v, want %+v", i, sockets[i], want[i])
+		}
+	}
+
+	if _, err := parseUDPSockets(strings.NewReader("  0: ZZZZ:13C4 00000000:0000 07 00000000:00000000 00:00000000 00000000 0 0 1 2\n")); err == nil {
+		t.Error("no error for a bad address")
+	}
+}
+
+func TestParseSocketAddress(t *testing.T) {
+	requireLittleEndian(t)
+
+	tests := []struct {
+		input string
+		ip    string
+		port  int
+		ok    bool
+	}{
+		{"0100007F:13C4", "127.0.0.1", 5060, true},
+		{"0100000A:C350", "10.0.0.1", 50000, true},
+		{"00000000000000000000000001000000:13C4", "::1", 5060, true},
+		{"0000000000000000FFFF00000100007F:0035", "127.0.0.1", 53, true},
+		{"0100007F", "", 0, false},
+		{"01007F:0035", "", 0, false},
+		{"0100007F:GGGG", "", 0, false},
+		{"0100007F:10000", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		ip, port, err := parseSocketAddress(tt.input)
+		if !tt.ok {
+			if err == nil {
+				t.Errorf("%s: got %v:%d, want error", tt.input, ip, port)
+			}
+			continue
+		}
+		if err != nil || ip.String() != tt.ip || port != tt.port {
+			t.Errorf("%s: got %v:%d, %v, want %s:%d", tt.input, ip, port, err, tt.ip, tt.port)
+		}
+	}
+}
+
+func TestRateNetwork(t *testing.T) {
+	m := &LinuxMonitor{}
+	start := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	read := func(at time.Time, rx uint64, rcvbuf uint64, drops uint64) types.NetworkHealth {
+		health := types.NetworkHealth{
+			Time:       at,
+			Interfaces: []types.InterfaceStats{{Name: "eth0", RxBytes: rx}},
+			UDP:        types.UDPStats{RcvbufErrors: rcvbuf},
+			Sockets:    []types.UDPSocket{{Inode: 31300, Drops: drops}},
+		}
+		m.rateNetwork(&health)
+		return health
+	}
+
+	if first := read(start, 1000, 10, 5); first.Rated {
+		t.Error("first reading is rated")
+	}
+
+	second := read(start.Add(10*time.Second), 21000, 30, 25)
+	if !second.Rated || second.Interfaces[0].RxBytesPerSec != 2000 || second.UDP.RcvbufErrorsPerSec != 2 || second.DropsPerSec != 2 {
+		t.Errorf("second reading: %+v", second)
+	}
+
+	// Слишком частое чтение возвращает прошлые скорости
+	early := read(start.Add(10*time.Second+100*time.Millisecond), 99999, 99, 99)
+	if early.Interfaces[0].RxBytesPerSec != 2000 || early.UDP.RcvbufErrorsPerSec != 2 {
+		t.Errorf("early reading: %+v", early)
+	}
+
+	// Сброс счетчиков (пересоздание интерфейса) не дает отрицательных скоростей
+	reset := read(start.Add(20*time.Second), 500, 0, 0)
+	if reset.Interfaces[0].RxBytesPerSec != 0 || reset.UDP.RcvbufErrorsPerSec != 0 || reset.DropsPerSec != 0 {
+		t.Errorf("reading after counter reset: %+v", reset)
+	}
+}
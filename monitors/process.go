@@ -0,0 +1,227 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+// userHZ - частота тиков, в которых /proc/<pid>/stat отдает процессорное время.
+// На Linux для пользовательских программ она всегда 100.
+const userHZ = 100
+
+// minProcessInterval - минимальный интервал между чтениями для пересчета скоростей
+const minProcessInterval = time.Second
+
+// procStat - поля /proc/<pid>/stat, нужные для метрик процесса
+type procStat struct {
+	ppid      int
+	utime     uint64
+	stime     uint64
+	threads   int
+	startTime uint64 // в тиках с момента загрузки
+}
+
+// processSample - предыдущее чтение счетчиков процесса для вычисления скоростей
+type processSample struct {
+	pid         int
+	ticks       uint64
+	ctxSwitches uint64
+	at          time.Time
+}
+
+// parseProcStat разбирает /proc/<pid>/stat. Имя процесса в скобках может
+// содержать пробелы, поэтому поля отсчитываются от последней скобки.
+func parseProcStat(data string) (procStat, error) {
+	end := strings.LastIndex(data, ")")
+	if end < 0 {
+		return procStat{}, fmt.Errorf("stat: unexpected format")
+	}
+
+	// Первое поле после имени - state (3-е поле по man proc)
+	fields := strings.Fields(data[end+1:])
+	if len(fields) < 20 {
+		return procStat{}, fmt.Errorf("stat: too few fields")
+	}
+
+	var stat procStat
+	var err error
+	if stat.ppid, err = strconv.Atoi(fields[1]); err != nil {
+		return procStat{}, fmt.Errorf("stat: bad ppid %q", fields[1])
+	}
+	if stat.utime, err = strconv.ParseUint(fields[11], 10, 64); err != nil {
+		return procStat{}, fmt.Errorf("stat: bad utime %q", fields[11])
+	}
+	if stat.stime, err = strconv.ParseUint(fields[12], 10, 64); err != nil {
+		return procStat{}, fmt.Errorf("stat: bad stime %q", fields[12])
+	}
+	if stat.threads, err = strconv.Atoi(fields[17]); err != nil {
+		return procStat{}, fmt.Errorf("stat: bad num_threads %q", fields[17])
+	}
+	if stat.startTime, err = strconv.ParseUint(fields[19], 10, 64); err != nil {
+		return procStat{}, fmt.Errorf("stat: bad starttime %q", fields[19])
+	}
+	return stat, nil
+}
+
+// parseProcStatus разбирает /proc/<pid>/status в пары ключ - значение
+func parseProcStatus(r io.Reader) (map[string]string, error) {
+	status := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if ok {
+			status[key] = strings.TrimSpace(value)
+		}
+	}
+	return status, scanner.Err()
+}
+
+// statusUint возвращает числовое значение из status, единицы измерения отбрасываются
+func statusUint(status map[string]string, key string) uint64 {
+	fields := strings.Fields(status[key])
+	if len(fields) == 0 {
+		return 0
+	}
+	value, _ := strconv.ParseUint(fields[0], 10, 64)
+	return value
+}
+
+// parseOpenFilesLimit возвращает мягкий лимит "Max open files" из /proc/<pid>/limits.
+// Для unlimited возвращается 0.
+func parseOpenFilesLimit(r io.Reader) (uint64, error) {
+	const prefix = "Max open files"
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		fields := strings.Fields(line[len(prefix):])
+		if len(fields) == 0 {
+			break
+		}
+		if fields[0] == "unlimited" {
+			return 0, nil
+		}
+		return strconv.ParseUint(fields[0], 10, 64)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("limits: %q not found", prefix)
+}
+
+// parseUptime возвращает время работы системы из /proc/uptime
+func parseUptime(data string) (time.Duration, error) {
+	fields := strings.Fields(data)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("/proc/uptime: empty")
+	}
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("/proc/uptime: bad value %q", fields[0])
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// GetProcessMetrics возвращает использование ресурсов процессом pid.
+// Загрузка CPU и частота переключений контекста считаются с предыдущего вызова,
+// при первом вызове - в среднем с момента запуска процесса.
+func (m *LinuxMonitor) GetProcessMetrics(pid int) (types.ProcessMetrics, error) {
+	dir := strconv.Itoa(pid)
+	metrics := types.ProcessMetrics{PID: pid, OpenFDs: -1}
+
+	data, err := readProcFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return metrics, err
+	}
+	stat, err := parseProcStat(string(data))
+	if err != nil {
+		return metrics, err
+	}
+
+	file, err := openProcFile(filepath.Join(dir, "status"))
+	if err != nil {
+		return metrics, err
+	}
+	status, err := parseProcStatus(file)
+	file.Close()
+	if err != nil {
+		return metrics, err
+	}
+
+	metrics.Threads = stat.threads
+	metrics.RSSBytes = statusUint(status, "VmRSS") * 1024
+	metrics.VoluntaryCtxSwitches = statusUint(status, "voluntary_ctxt_switches")
+	metrics.NonvoluntaryCtxSwitches = statusUint(status, "nonvoluntary_ctxt_switches")
+
+	// Каталог fd доступен только владельцу процесса и root
+	if entries, err := os.ReadDir(filepath.Join(procRoot, dir, "fd")); err == nil {
+		metrics.OpenFDs = len(entries)
+	}
+	if file, err := openProcFile(filepath.Join(dir, "limits")); err == nil {
+		metrics.MaxFDs, _ = parseOpenFilesLimit(file)
+		file.Close()
+	}
+	if metrics.OpenFDs >= 0 && metrics.MaxFDs > 0 {
+		metrics.FDUsagePct = float64(metrics.OpenFDs) / float64(metrics.MaxFDs) * 100
+	}
+
+	now := time.Now()
+	cur := processSample{
+		pid:         pid,
+		ticks:       stat.utime + stat.stime,
+		ctxSwitches: metrics.VoluntaryCtxSwitches + metrics.NonvoluntaryCtxSwitches,
+		at:          now,
+	}
+
+	m.procMu.Lock()
+	defer m.procMu.Unlock()
+
+	prev := m.prevProc
+	switch {
+	case prev.pid == pid && now.Sub(prev.at) < minProcessInterval:
+		// Слишком частый вызов дает шумную оценку, возвращаем прошлую
+		metrics.CPUPercent = m.lastProc.CPUPercent
+		metrics.CtxSwitchesPerSec = m.lastProc.CtxSwitchesPerSec
+		return metrics, nil
+	case prev.pid == pid && cur.ticks >= prev.ticks && cur.ctxSwitches >= prev.ctxSwitches:
+		elapsed := now.Sub(prev.at).Seconds()
+		metrics.CPUPercent = float64(cur.ticks-prev.ticks) / userHZ / elapsed * 100
+		metrics.CtxSwitchesPerSec = float64(cur.ctxSwitches-prev.ctxSwitches) / elapsed
+	default:
+		metrics.CPUPercent, metrics.CtxSwitchesPerSec = averageSinceStart(stat, cur)
+	}
+
+	m.prevProc = cur
+	m.lastProc = metrics
+	return metrics, nil
+}
+
+// averageSinceStart возвращает средние загрузку CPU и частоту переключений
+// контекста с момента запуска процесса
+func averageSinceStart(stat procStat, cur processSample) (float64, float64) {
+	data, err := readProcFile("uptime")
+	if err != nil {
+		return 0, 0
+	}
+	uptime, err := parseUptime(string(data))
+	if err != nil {
+		return 0, 0
+	}
+
+	age := uptime.Seconds() - float64(stat.startTime)/userHZ
+	if age <= 0 {
+		return 0, 0
+	}
+	return float64(cur.ticks) / userHZ / age * 100, float64(cur.ctxSwitches) / age
+}
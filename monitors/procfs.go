@@ -0,0 +1,161 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"asterisk-monitor/types"
+)
+
+// procRoot - корень procfs, в тестах можно подставить каталог с образцами файлов
+var procRoot = "/proc"
+
+// minCPUTicks - минимальный прирост счетчиков /proc/stat, при котором
+// загрузка пересчитывается. При частых вызовах возвращается прошлое значение.
+const minCPUTicks = 50
+
+// cpuTimes - суммарные счетчики процессорного времени из строки "cpu" в /proc/stat
+type cpuTimes struct {
+	idle  uint64
+	total uint64
+}
+
+// parseCPUTimes разбирает /proc/stat. Простоем считаются idle и iowait,
+// guest уже учтен в user и в сумму не входит.
+func parseCPUTimes(r io.Reader) (cpuTimes, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+
+		var times cpuTimes
+		for i, field := range fields[1:] {
+			if i >= 8 { // guest, guest_nice
+				break
+			}
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return cpuTimes{}, fmt.Errorf("/proc/stat: bad cpu field %q", field)
+			}
+			times.total += value
+			if i == 3 || i == 4 { // idle, iowait
+				times.idle += value
+			}
+		}
+		return times, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return cpuTimes{}, err
+	}
+	return cpuTimes{}, fmt.Errorf("/proc/stat: cpu line not found")
+}
+
+// cpuUsagePercent вычисляет загрузку CPU между двумя чтениями /proc/stat
+func cpuUsagePercent(prev, cur cpuTimes) float64 {
+	total := cur.total - prev.total
+	if cur.total <= prev.total || total == 0 {
+		return 0
+	}
+	busy := total - (cur.idle - prev.idle)
+	return float64(busy) / float64(total) * 100
+}
+
+// parseMemInfo разбирает /proc/meminfo, значения возвращаются в килобайтах
+func parseMemInfo(r io.Reader) (map[string]uint64, error) {
+	info := make(map[string]uint64)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		info[key] = value
+	}
+	return info, scanner.Err()
+}
+
+// memoryUsedPercent возвращает долю занятой памяти по MemAvailable.
+// Старые ядра без MemAvailable оцениваются по MemFree, Buffers и Cached.
+func memoryUsedPercent(info map[string]uint64) (float64, error) {
+	total := info["MemTotal"]
+	if total == 0 {
+		return 0, fmt.Errorf("/proc/meminfo: MemTotal not found")
+	}
+
+	available, ok := info["MemAvailable"]
+	if !ok {
+		available = info["MemFree"] + info["Buffers"] + info["Cached"]
+	}
+	if available > total {
+		available = total
+	}
+	return float64(total-available) / float64(total) * 100, nil
+}
+
+// parseLoadAvg разбирает первые три поля /proc/loadavg
+func parseLoadAvg(data string) ([3]float64, error) {
+	var load [3]float64
+
+	fields := strings.Fields(data)
+	if len(fields) < 3 {
+		return load, fmt.Errorf("/proc/loadavg: unexpected format %q", strings.TrimSpace(data))
+	}
+	for i := range load {
+		value, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return load, fmt.Errorf("/proc/loadavg: bad value %q", fields[i])
+		}
+		load[i] = value
+	}
+	return load, nil
+}
+
+// statMount возвращает заполненность файловой системы, как ее считает df:
+// занятое место относится к месту, доступному непривилегированным пользователям
+func statMount(path string) (types.MountUsage, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return types.MountUsage{Path: path}, err
+	}
+
+	blockSize := uint64(st.Bsize)
+	total := uint64(st.Blocks) * blockSize
+	free := uint64(st.Bfree) * blockSize
+	avail := uint64(st.Bavail) * blockSize
+	used := total - free
+
+	usage := types.MountUsage{
+		Path:       path,
+		TotalBytes: total,
+		UsedBytes:  used,
+		AvailBytes: avail,
+	}
+	if used+avail > 0 {
+		usage.UsedPct = float64(used) / float64(used+avail) * 100
+	}
+	return usage, nil
+}
+
+func readProcFile(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(procRoot, name))
+}
+
+func openProcFile(name string) (*os.File, error) {
+	return os.Open(filepath.Join(procRoot, name))
+}
@@ -0,0 +1,262 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useProcRoot подменяет корень procfs на время теста
+func useProcRoot(t *testing.T, dir string) {
+	t.Helper()
+	old := procRoot
+	procRoot = dir
+	t.Cleanup(func() { procRoot = old })
+}
+
+func openTestdata(t *testing.T, name string) *os.File {
+	t.Helper()
+	file, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("open testdata: %v", err)
+	}
+	t.Cleanup(func() { file.Close() })
+	return file
+}
+
+func TestParseCPUTimes(t *testing.T) {
+	sample, err := os.ReadFile("testdata/proc/stat")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    cpuTimes
+		wantErr string
+	}{
+		{
+			name:  "sample",
+			input: string(sample),
+			want:  cpuTimes{idle: 46828483 + 16683, total: 60377929},
+		},
+		{
+			name:  "guest excluded",
+			input: "cpu  100 0 50 800 50 0 0 0 30 5\n",
+			want:  cpuTimes{idle: 850, total: 1000},
+		},
+		{
+			// Ядра до 2.6 выводят только user, nice, system и idle
+			name:  "old kernel",
+			input: "cpu  100 0 50 850\n",
+			want:  cpuTimes{idle: 850, total: 1000},
+		},
+		{
+			name:  "per-cpu lines ignored",
+			input: "cpu0 1 2 3 4 5\ncpu  10 0 10 80 0\n",
+			want:  cpuTimes{idle: 80, total: 100},
+		},
+		{
+			name:    "bad field",
+			input:   "cpu  100 x 50 800\n",
+			wantErr: `bad cpu field "x"`,
+		},
+		{
+			name:    "short line",
+			input:   "cpu  100 0\nintr 1 2 3\n",
+			wantErr: "cpu line not found",
+		},
+		{
+			name:    "empty",
+			input:   "",
+			wantErr: "cpu line not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCPUTimes(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCPUTimes: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCPUUsagePercent(t *testing.T) {
+	tests := []struct {
+		name      string
+		prev, cur cpuTimes
+		want      float64
+	}{
+		{"since boot", cpuTimes{}, cpuTimes{idle: 750, total: 1000}, 25},
+		{"interval", cpuTimes{idle: 750, total: 1000}, cpuTimes{idle: 1500, total: 2000}, 25},
+		{"idle", cpuTimes{idle: 750, total: 1000}, cpuTimes{idle: 1750, total: 2000}, 0},
+		{"no ticks", cpuTimes{idle: 750, total: 1000}, cpuTimes{idle: 750, total: 1000}, 0},
+		// Счетчики переполнились или были сброшены: разность не имеет смысла
+		{"counter wrap", cpuTimes{idle: 750, total: 1000}, cpuTimes{idle: 10, total: 40}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cpuUsagePercent(tt.prev, tt.cur); got != tt.want {
+				t.Errorf("cpuUsagePercent = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCPUUsageCounterWrap(t *testing.T) {
+	dir := t.TempDir()
+	useProcRoot(t, dir)
+	m := &LinuxMonitor{}
+
+	steps := []struct {
+		stat string
+		want float64
+	}{
+		{"cpu  200 0 50 750 0\n", 25},   // первое чтение - загрузка с момента загрузки
+		{"cpu  210 0 50 760 0\n", 25},   // меньше minCPUTicks - прошлое значение
+		{"cpu  10 0 10 30 0\n", 0},      // счетчики сброшены
+		{"cpu  60 0 10 130 0\n", 33.33}, // отсчет от сброшенных значений
+	}
+	for i, step := range steps {
+		if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(step.stat), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got := m.GetCPUUsage()
+		if got < step.want-0.01 || got > step.want+0.01 {
+			t.Errorf("step %d: GetCPUUsage = %.2f, want %.2f", i, got, step.want)
+		}
+	}
+}
+
+func TestParseMemInfo(t *testing.T) {
+	info, err := parseMemInfo(openTestdata(t, "proc/meminfo"))
+	if err != nil {
+		t.Fatalf("parseMemInfo: %v", err)
+	}
+	want := map[string]uint64{
+		"MemTotal":        8048960,
+		"MemAvailable":    4024480,
+		"Cached":          2048000,
+		"HugePages_Total": 0, // без единиц измерения
+	}
+	for key, value := range want {
+		if got, ok := info[key]; !ok || got != value {
+			t.Errorf("%s = %d (present %v), want %d", key, got, ok, value)
+		}
+	}
+
+	info, err = parseMemInfo(strings.NewReader("MemTotal: 1000 kB\ngarbage\nBroken: x kB\nEmpty:\nMemFree: 400 kB\n"))
+	if err != nil {
+		t.Fatalf("parseMemInfo: %v", err)
+	}
+	if len(info) != 2 || info["MemTotal"] != 1000 || info["MemFree"] != 400 {
+		t.Errorf("malformed lines not skipped: %v", info)
+	}
+}
+
+func TestMemoryUsedPercent(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		{name: "MemAvailable", file: "proc/meminfo", want: 50},
+		{name: "old kernel without MemAvailable", file: "proc/meminfo-2.6.32", want: 50},
+		{name: "available above total", input: "MemTotal: 1000 kB\nMemAvailable: 1200 kB\n", want: 0},
+		{name: "no MemTotal", input: "MemFree: 1000 kB\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var info map[string]uint64
+			var err error
+			if tt.file != "" {
+				info, err = parseMemInfo(openTestdata(t, tt.file))
+			} else {
+				info, err = parseMemInfo(strings.NewReader(tt.input))
+			}
+			if err != nil {
+				t.Fatalf("parseMemInfo: %v", err)
+			}
+
+			got, err := memoryUsedPercent(info)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("memoryUsedPercent = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("memoryUsedPercent: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("memoryUsedPercent = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLoadAvg(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    [3]float64
+		wantErr string
+	}{
+		{name: "sample", input: "0.52 0.58 0.59 2/1234 56789\n", want: [3]float64{0.52, 0.58, 0.59}},
+		{name: "only averages", input: "1.00 2.50 3.75", want: [3]float64{1, 2.5, 3.75}},
+		{name: "too few fields", input: "0.52 0.58\n", wantErr: "unexpected format"},
+		{name: "bad value", input: "0.52 abc 0.59 2/1234 56789\n", wantErr: `bad value "abc"`},
+		{name: "empty", input: "", wantErr: "unexpected format"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLoadAvg(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseLoadAvg: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcfsSampleFiles(t *testing.T) {
+	useProcRoot(t, filepath.Join("testdata", "proc"))
+	m := &LinuxMonitor{}
+
+	if got := m.GetMemoryUsage(); got != 50 {
+		t.Errorf("GetMemoryUsage = %v, want 50", got)
+	}
+	if got := m.GetSystemLoad(); got != "0.52, 0.58, 0.59" {
+		t.Errorf("GetSystemLoad = %q", got)
+	}
+	want := float64(60377929-46845166) / 60377929 * 100
+	if got := m.GetCPUUsage(); got != want {
+		t.Errorf("GetCPUUsage = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,217 @@
+package monitor
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+// Метрики каждого источника измерений. Если источник не ответил, его метрики
+// неизвестны: они не сохраняются, а оповещения по ним остаются как были.
+var (
+	SystemMetricNames = []string{
+		types.MetricCPUUsage, types.MetricMemoryUsage, types.MetricDiskUsage, types.MetricActiveCalls,
+		types.MetricCallsProcessed, types.MetricAsteriskUp, types.MetricLoad1,
+		types.MetricProcessCPU, types.MetricProcessRSS, types.MetricProcessThreads, types.MetricProcessCtxSwitches,
+		types.MetricProcessFDs, types.MetricProcessFDUsage, types.MetricMountUsage, types.MetricDirSize,
+	}
+	PeerMetricNames = []string{
+		types.MetricPeersOnline, types.MetricPeersTotal, types.MetricPeerUp, types.MetricPeerLatency,
+	}
+	CallQualityMetricNames = []string{
+		types.MetricCallRxLoss, types.MetricCallTxLoss, types.MetricCallRxJitter, types.MetricCallTxJitter,
+	}
+	TaskprocessorMetricNames = []string{
+		types.MetricTaskprocessorMaxQueue, types.MetricTaskprocessorsOverHighWater,
+	}
+	NetworkMetricNames = []string{
+		types.MetricNetRxBytes, types.MetricNetTxBytes, types.MetricNetRxErrors, types.MetricNetRxDrops,
+		types.MetricNetTxErrors, types.MetricNetTxDrops, types.MetricUDPInErrors, types.MetricUDPRcvbufErrors,
+		types.MetricAsteriskUDPRxQueue, types.MetricAsteriskUDPDrops,
+	}
+)
+
+// Readings - показания источников за один цикл сбора. Ошибка источника
+// означает, что его показаний нет, а не что они нулевые.
+type Readings struct {
+	System            types.SystemMetrics
+	SystemErr         error
+	Peers             []types.SIPPeer
+	PeersErr          error
+	CallQuality       []types.CallQuality
+	CallQualityErr    error
+	Taskprocessors    types.TaskprocessorReport
+	TaskprocessorsErr error
+	Network           types.NetworkHealth
+	NetworkErr        error
+}
+
+// Samples возвращает измерения источников без ошибок и имена метрик
+// источников с ошибкой. Такие метрики не попадают в измерения, чтобы
+// пропавшие ряды не снимали оповещения и не записывались нулями в историю.
+func (r Readings) Samples(ts time.Time) ([]types.Sample, []string) {
+	var samples []types.Sample
+	var unknown []string
+
+	if r.SystemErr != nil {
+		unknown = append(unknown, SystemMetricNames...)
+	} else {
+		samples = append(samples, SystemSamples(r.System, ts)...)
+	}
+	if r.PeersErr != nil {
+		unknown = append(unknown, PeerMetricNames...)
+	} else {
+		samples = append(samples, PeerSamples(r.Peers, ts)...)
+	}
+	if r.CallQualityErr != nil {
+		unknown = append(unknown, CallQualityMetricNames...)
+	} else {
+		samples = append(samples, CallQualitySamples(r.CallQuality, ts)...)
+	}
+	if r.TaskprocessorsErr != nil || r.Taskprocessors.Error != "" {
+		unknown = append(unknown, TaskprocessorMetricNames...)
+	} else {
+		samples = append(samples, TaskprocessorSamples(r.Taskprocessors, ts)...)
+	}
+	if r.NetworkErr != nil || r.Network.Error != "" {
+		unknown = append(unknown, NetworkMetricNames...)
+	} else {
+		samples = append(samples, NetworkSamples(r.Network, ts)...)
+	}
+
+	return samples, unknown
+}
+
+// SystemSamples преобразует системные метрики в набор измерений для хранилища
+func SystemSamples(metrics types.SystemMetrics, ts time.Time) []types.Sample {
+	up := 0.0
+	if metrics.ServiceState == "active" {
+		up = 1
+	}
+
+	samples := []types.Sample{
+		{Metric: types.MetricCPUUsage, Value: metrics.CPUUsage, Timestamp: ts},
+		{Metric: types.MetricMemoryUsage, Value: metrics.MemoryUsage, Timestamp: ts},
+		{Metric: types.MetricDiskUsage, Value: metrics.DiskUsage, Timestamp: ts},
+		{Metric: types.MetricActiveCalls, Value: float64(metrics.ActiveCalls), Timestamp: ts},
+		{Metric: types.MetricCallsProcessed, Value: float64(metrics.CallsProcessed), Timestamp: ts},
+		{Metric: types.MetricAsteriskUp, Value: up, Timestamp: ts},
+		{Metric: types.MetricLoad1, Value: metrics.Load1, Timestamp: ts},
+	}
+
+	if p := metrics.Process; p != nil {
+		samples = append(samples,
+			types.Sample{Metric: types.MetricProcessCPU, Value: p.CPUPercent, Timestamp: ts},
+			types.Sample{Metric: types.MetricProcessRSS, Value: float64(p.RSSBytes) / (1024 * 1024), Timestamp: ts},
+			types.Sample{Metric: types.MetricProcessThreads, Value: float64(p.Threads), Timestamp: ts},
+			types.Sample{Metric: types.MetricProcessCtxSwitches, Value: p.CtxSwitchesPerSec, Timestamp: ts},
+		)
+		if p.OpenFDs >= 0 {
+			samples = append(samples, types.Sample{Metric: types.MetricProcessFDs, Value: float64(p.OpenFDs), Timestamp: ts})
+		}
+		if p.MaxFDs > 0 && p.OpenFDs >= 0 {
+			samples = append(samples, types.Sample{Metric: types.MetricProcessFDUsage, Value: p.FDUsagePct, Timestamp: ts})
+		}
+	}
+
+	for _, mount := range metrics.Mounts {
+		if mount.Error != "" {
+			continue
+		}
+		samples = append(samples, types.Sample{
+			Metric:    types.MetricMountUsage,
+			Labels:    map[string]string{"mount": mount.Path},
+			Value:     mount.UsedPct,
+			Timestamp: ts,
+		})
+	}
+
+	// Размер каталога известен только после первого обхода
+	for _, dir := range metrics.Dirs {
+		if dir.Error != "" || dir.ScannedAt.IsZero() {
+			continue
+		}
+		samples = append(samples, types.Sample{
+			Metric:    types.MetricDirSize,
+			Labels:    map[string]string{"dir": dir.Path},
+			Value:     float64(dir.SizeBytes) / (1024 * 1024),
+			Timestamp: ts,
+		})
+	}
+
+	return samples
+}
+
+// PeerSamples преобразует статусы SIP пиров в число доступных пиров и
+// измерения доступности и задержки каждого пира
+func PeerSamples(peers []types.SIPPeer, ts time.Time) []types.Sample {
+	online, total := CountPeers(peers)
+	samples := []types.Sample{
+		{Metric: types.MetricPeersOnline, Value: float64(online), Timestamp: ts},
+		{Metric: types.MetricPeersTotal, Value: float64(total), Timestamp: ts},
+	}
+
+	for _, peer := range peers {
+		labels := map[string]string{"peer": peer.Name}
+
+		samples = append(samples, types.Sample{
+			Metric:    types.MetricPeerUp,
+			Labels:    labels,
+			Value:     PeerUpValue(peer),
+			Timestamp: ts,
+		})
+
+		if latency, ok := PeerLatencyMs(peer); ok {
+			samples = append(samples, types.Sample{
+				Metric:    types.MetricPeerLatency,
+				Labels:    labels,
+				Value:     latency,
+				Timestamp: ts,
+			})
+		}
+	}
+
+	return samples
+}
+
+// CallQualitySamples преобразует RTP статистику вызовов в измерения потерь и джиттера
+func CallQualitySamples(calls []types.CallQuality, ts time.Time) []types.Sample {
+	var samples []types.Sample
+
+	for _, call := range calls {
+		labels := map[string]string{"peer": call.Peer, "call_id": call.CallID}
+
+		samples = append(samples,
+			types.Sample{Metric: types.MetricCallRxLoss, Labels: labels, Value: call.RxLossPct, Timestamp: ts},
+			types.Sample{Metric: types.MetricCallTxLoss, Labels: labels, Value: call.TxLossPct, Timestamp: ts},
+			types.Sample{Metric: types.MetricCallRxJitter, Labels: labels, Value: call.RxJitter, Timestamp: ts},
+			types.Sample{Metric: types.MetricCallTxJitter, Labels: labels, Value: call.TxJitter, Timestamp: ts},
+		)
+	}
+
+	return samples
+}
+
+// PeerLatencyMs возвращает задержку пира в миллисекундах, если она известна
+func PeerLatencyMs(peer types.SIPPeer) (float64, bool) {
+	latency := strings.TrimSpace(strings.TrimSuffix(peer.Latency, "ms"))
+	if latency == "" {
+		return 0, false
+	}
+
+	value, err := strconv.ParseFloat(latency, 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
+
+// PeerUpValue возвращает 1 для доступного пира (см. PeerOnline) и 0 для недоступного
+func PeerUpValue(peer types.SIPPeer) float64 {
+	if PeerOnline(peer) {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,99 @@
+package monitor
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+func TestReadingsSamples(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	readings := Readings{
+		System: types.SystemMetrics{CPUUsage: 12, ServiceState: "active"},
+		Peers: []types.SIPPeer{
+			{Name: "100", Host: "10.0.0.5", Status: "OK", Latency: "12 ms"},
+			{Name: "101", Host: "(Unspecified)", Status: "UNREACHABLE"},
+		},
+		CallQualityErr: errors.New("sip show channelstats: timeout"),
+		Taskprocessors: types.TaskprocessorReport{Error: "taskprocessors unavailable"},
+	}
+
+	samples, unknown := readings.Samples(now)
+
+	got := make(map[string]int)
+	for _, sample := range samples {
+		got[sample.Metric]++
+	}
+	for metric, want := range map[string]int{
+		types.MetricCPUUsage:    1,
+		types.MetricPeersOnline: 1,
+		types.MetricPeersTotal:  1,
+		types.MetricPeerUp:      2,
+		types.MetricPeerLatency: 1,
+		types.MetricCallRxLoss:  0,
+	} {
+		if got[metric] != want {
+			t.Errorf("%s: %d samples, want %d", metric, got[metric], want)
+		}
+	}
+
+	isUnknown := make(map[string]bool)
+	for _, metric := range unknown {
+		isUnknown[metric] = true
+	}
+	for _, metric := range append(CallQualityMetricNames, TaskprocessorMetricNames...) {
+		if !isUnknown[metric] {
+			t.Errorf("%s not reported unknown", metric)
+		}
+	}
+	if isUnknown[types.MetricPeerUp] || isUnknown[types.MetricCPUUsage] {
+		t.Errorf("metrics of answered sources reported unknown: %v", unknown)
+	}
+
+	// Без ответа источника пиров нет ни нулевого числа пиров, ни рядов пиров
+	readings = Readings{PeersErr: errors.New("asterisk is not running")}
+	samples, unknown = readings.Samples(now)
+	for _, sample := range samples {
+		if sample.Metric == types.MetricPeersOnline || sample.Metric == types.MetricPeerUp {
+			t.Errorf("sample %+v from a failed peers source", sample)
+		}
+	}
+	if len(unknown) != len(PeerMetricNames) {
+		t.Errorf("unknown = %v, want %v", unknown, PeerMetricNames)
+	}
+}
+
+func TestPeerOnline(t *testing.T) {
+	tests := []struct {
+		peer types.SIPPeer
+		want bool
+	}{
+		{types.SIPPeer{Host: "10.0.0.5", Status: "OK"}, true},
+		{types.SIPPeer{Host: "10.0.0.5", Status: "LAGGED"}, true},
+		{types.SIPPeer{Host: "10.0.0.5", Status: "Unmonitored"}, true},
+		// Незарегистрированный пир без контроля доступности не считается доступным
+		{types.SIPPeer{Host: "(Unspecified)", Status: "Unmonitored"}, false},
+		{types.SIPPeer{Host: "10.0.0.5", Status: "UNREACHABLE"}, false},
+		{types.SIPPeer{Host: "(Unspecified)", Status: "UNKNOWN"}, false},
+	}
+
+	var peers []types.SIPPeer
+	wantOnline := 0
+	for _, tt := range tests {
+		if got := PeerOnline(tt.peer); got != tt.want {
+			t.Errorf("PeerOnline(%+v) = %v, want %v", tt.peer, got, tt.want)
+		}
+		if got := PeerUpValue(tt.peer) == 1; got != tt.want {
+			t.Errorf("PeerUpValue(%+v) disagrees with PeerOnline", tt.peer)
+		}
+		peers = append(peers, tt.peer)
+		if tt.want {
+			wantOnline++
+		}
+	}
+	if online, total := CountPeers(peers); online != wantOnline || total != len(tests) {
+		t.Errorf("CountPeers = %d/%d, want %d/%d", online, total, wantOnline, len(tests))
+	}
+}
@@ -0,0 +1,250 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+// SecurityChecks возвращает проверки быстрого (full=false) или полного сканирования безопасности
+func (m *LinuxMonitor) SecurityChecks(full bool) []Check {
+	// ps без подходящих процессов завершается с кодом 1, grep без совпадений - тоже
+	processUser := Command{Program: "ps", Args: []string{"-C", "asterisk", "-o", "user="}, OKCodes: []int{1}}
+	grepConfig := func(args ...string) Command {
+		return Command{Program: "grep", Args: args, OKCodes: []int{1}}
+	}
+
+	firewall := m.firstSuccessCheck("Firewall Status", "No firewall detected",
+		Cmd("systemctl", "is-active", "ufw"),
+		Cmd("systemctl", "is-active", "firewalld"),
+	)
+
+	if !full {
+		return []Check{
+			m.listenerCheck("Open SIP Ports", "No SIP ports listening", types.ListenerSIP, types.ListenerSIPTLS, types.ListenerIAX2),
+			m.listenerCheck("Open AMI Port", "AMI port not listening", types.ListenerAMI),
+			m.listenerCheck("Open ARI/HTTP Port", "ARI/HTTP server not listening", types.ListenerHTTP),
+			m.securityCheck("Fail2Ban Status", Cmd("systemctl", "is-active", "fail2ban"), nil),
+			firewall,
+			m.securityCheck("Asterisk Process User", processUser, firstLines(1)),
+		}
+	}
+
+	return []Check{
+		// Network Security: слушающие сокеты Asterisk по /proc/net, включая UDP
+		m.listenerCheck("SIP Port Exposure", "No SIP ports listening", types.ListenerSIP, types.ListenerSIPTLS),
+		m.listenerCheck("IAX2 Port Exposure", "IAX2 port not listening", types.ListenerIAX2),
+		m.listenerCheck("AMI Port Exposure", "AMI port not listening", types.ListenerAMI),
+		m.listenerCheck("ARI/HTTP Port Exposure", "ARI/HTTP server not listening", types.ListenerHTTP),
+		m.listenerCheck("RTP Port Range", "No RTP sockets open", types.ListenerRTP),
+
+		// Service Security
+		m.securityCheck("Fail2Ban Status", Cmd("systemctl", "is-active", "fail2ban"), nil),
+		firewall,
+		m.firstSuccessCheck("SELinux Status", "SELinux not available", Cmd("getenforce")),
+
+		// File Permissions
+		m.securityCheck("Asterisk Config Permissions", Cmd("find", "/etc/asterisk", "-type", "f", "-perm", "/o+rw"), counting(`\S`)),
+		m.securityCheck("Asterisk Directory Permissions", Cmd("find", "/etc/asterisk", "-type", "d", "-perm", "/o+rwx"), counting(`\S`)),
+		m.securityCheck("Asterisk File Ownership", Cmd("find", "/etc/asterisk", "!", "-user", "asterisk", "-type", "f"), counting(`\S`)),
+
+		// Process Security
+		m.securityCheck("Asterisk Process User", processUser, firstLines(1)),
+		m.securityCheck("Asterisk Running as Root", processUser, counting(`^\s*root\s*$`)),
+
+		// SSL/TLS Security
+		{Name: "SSL Certificate Check", Run: m.checkExpiringCertificates},
+		m.securityCheck("TLS Configuration", grepConfig("-r", "-h", "--include=*.conf", "tls", "/etc/asterisk"), configLines("", 0, true)),
+
+		// Authentication Security
+		m.securityCheck("Default Passwords Check", grepConfig("password", "/etc/asterisk/sip.conf"), configLines("", 5, false)),
+		m.securityCheck("AMI Authentication", grepConfig("-e", "secret", "-e", "password", "/etc/asterisk/manager.conf"), configLines("", 3, false)),
+
+		// Logging Security
+		m.securityCheck("Log File Permissions", Cmd("ls", "-la", "/var/log/asterisk/"), firstLines(5)),
+		m.securityCheck("Debug Mode Check", grepConfig("debug", "/etc/asterisk/logger.conf"), configLines("off", 0, true)),
+	}
+}
+
+// securityCheck выполняет команду и оценивает результат AnalyzeSecurityResult
+func (m *LinuxMonitor) securityCheck(name string, cmd Command, filter func(string) string) Check {
+	check := m.commandCheck(name, cmd, filter)
+	run := check.Run
+	check.Run = func(ctx context.Context) types.CheckResult {
+		result := run(ctx)
+		AnalyzeSecurityResult(&result)
+		return result
+	}
+	return check
+}
+
+// firstSuccessCheck выполняет команды по очереди до первой успешной и сообщает
+// ее вывод. Если ни одна не успешна, результат - fallback.
+func (m *LinuxMonitor) firstSuccessCheck(name, fallback string, cmds ...Command) Check {
+	return Check{
+		Name: name,
+		Run: func(ctx context.Context) types.CheckResult {
+			for _, cmd := range cmds {
+				if result := m.Run(ctx, name, cmd); result.Status == "success" {
+					AnalyzeSecurityResult(&result)
+					return result
+				}
+			}
+			result := types.CheckResult{Name: name, Message: fallback, Timestamp: time.Now()}
+			AnalyzeSecurityResult(&result)
+			return result
+		},
+	}
+}
+
+// checkExpiringCertificates считает сертификаты Asterisk, которые истекают в ближайшие сутки
+func (m *LinuxMonitor) checkExpiringCertificates(ctx context.Context) types.CheckResult {
+	result := types.CheckResult{Name: "SSL Certificate Check", Timestamp: time.Now()}
+
+	certs, err := m.GetCertificates(DefaultCertDir)
+	switch {
+	case err != nil && !errors.Is(err, fs.ErrNotExist):
+		result.Status = "error"
+		result.Error = err.Error()
+		return result
+	case len(certs) == 0:
+		result.Message = "No SSL certificates found"
+	default:
+		expiring := 0
+		for _, cert := range certs {
+			if time.Until(cert.NotAfter) < 24*time.Hour {
+				expiring++
+			}
+		}
+		result.Message = strconv.Itoa(expiring)
+	}
+
+	AnalyzeSecurityResult(&result)
+	return result
+}
+
+// configLines возвращает обработку вывода grep по конфигурации Asterisk:
+// закомментированные строки и строки, содержащие exclude, отбрасываются.
+// Остается не больше limit строк (0 - все) или их число, если count.
+func configLines(exclude string, limit int, count bool) func(string) string {
+	return func(text string) string {
+		var lines []string
+		for _, line := range uncommented(strings.Split(text, "\n")) {
+			if exclude == "" || !strings.Contains(line, exclude) {
+				lines = append(lines, line)
+			}
+		}
+		if count {
+			return strconv.Itoa(len(lines))
+		}
+		if limit > 0 && len(lines) > limit {
+			lines = lines[:limit]
+		}
+		return strings.Join(lines, "\n")
+	}
+}
+
+// AnalyzeSecurityResult устанавливает статус результата проверки безопасности
+func AnalyzeSecurityResult(result *types.CheckResult) {
+	// Анализируем результат и устанавливаем соответствующий статус
+	switch result.Name {
+	case "Fail2Ban Status":
+		if result.Message != "active" {
+			result.Status = "warning"
+			result.Message += " ⚠️  Fail2Ban not active"
+		} else {
+			result.Status = "success"
+		}
+
+	case "Firewall Status":
+		if strings.Contains(result.Message, "inactive") || strings.Contains(result.Message, "No firewall") {
+			result.Status = "warning"
+			result.Message += " ⚠️  Firewall not active"
+		} else if strings.Contains(result.Message, "active") {
+			result.Status = "success"
+		}
+
+	case "Asterisk Config Permissions":
+		if result.Message != "0" && strings.TrimSpace(result.Message) != "" {
+			result.Status = "error"
+			result.Message += " ❌ World-writable config files found"
+		} else {
+			result.Status = "success"
+			result.Message = "Config permissions are secure"
+		}
+
+	case "Asterisk Process User":
+		if result.Message == "root" {
+			result.Status = "warning"
+			result.Message += " ⚠️  Running as root - not recommended"
+		} else if strings.TrimSpace(result.Message) != "" {
+			result.Status = "success"
+			result.Message += " ✓ Running as non-root user"
+		}
+
+	case "Asterisk Running as Root":
+		if result.Message != "0" && strings.TrimSpace(result.Message) != "" {
+			result.Status = "error"
+			result.Message += " ❌ Asterisk should not run as root"
+		} else {
+			result.Status = "success"
+			result.Message = "Asterisk not running as root"
+		}
+
+	case "SSL Certificate Check":
+		if result.Message != "No SSL certificates found" && result.Message != "0" && strings.TrimSpace(result.Message) != "" {
+			result.Status = "warning"
+			result.Message += " ⚠️  SSL certificates expiring soon"
+		} else {
+			result.Status = "success"
+			if result.Message == "No SSL certificates found" {
+				result.Message = "No SSL certificates configured"
+			} else {
+				result.Message = "SSL certificates are valid"
+			}
+		}
+
+	case "Default Passwords Check":
+		if strings.TrimSpace(result.Message) != "" {
+			result.Status = "warning"
+			result.Message += " ⚠️  Check for default passwords"
+		} else {
+			result.Status = "success"
+			result.Message = "No obvious default passwords found"
+		}
+	}
+
+	// Если статус еще не установлен, устанавливаем по умолчанию
+	if result.Status == "" {
+		if result.Error != "" {
+			result.Status = "error"
+		} else {
+			result.Status = "success"
+		}
+	}
+}
+
+// SecurityScore возвращает долю успешных проверок безопасности в процентах
+func SecurityScore(results []types.CheckResult) int {
+	total := 0
+	success := 0
+	for _, result := range results {
+		switch result.Status {
+		case "success":
+			success++
+			total++
+		case "warning", "error":
+			total++
+		}
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return success * 100 / total
+}
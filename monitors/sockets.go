@@ -0,0 +1,463 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+// asteriskConfDir - каталог конфигурации Asterisk, из которого читаются порты
+var asteriskConfDir = "/etc/asterisk"
+
+// Состояния сокетов в /proc/net: TCP_LISTEN у слушающего TCP сокета, TCP_CLOSE
+// у UDP сокета без удаленного адреса (connect не вызывался)
+const (
+	tcpListen  = 0x0A
+	udpUnbound = 0x07
+)
+
+// asteriskPorts - порты служб Asterisk по протоколу и диапазон RTP
+type asteriskPorts struct {
+	tcp      map[int]string
+	udp      map[int]string
+	rtpStart int
+	rtpEnd   int
+}
+
+// role возвращает назначение порта Asterisk или пустую строку
+func (p asteriskPorts) role(proto string, port int) string {
+	if strings.HasPrefix(proto, "tcp") {
+		return p.tcp[port]
+	}
+	if role, ok := p.udp[port]; ok {
+		return role
+	}
+	if port >= p.rtpStart && port <= p.rtpEnd {
+		return types.ListenerRTP
+	}
+	return ""
+}
+
+// GetListeningSockets возвращает слушающие сокеты из /proc/net/{tcp,tcp6,udp,udp6}
+// с процессами-владельцами. Сокеты Asterisk определяются по PID процесса и
+// получают назначение по портам из конфигурации Asterisk.
+func (m *LinuxMonitor) GetListeningSockets() ([]types.ListeningSocket, error) {
+	var sockets []types.ListeningSocket
+	for _, proto := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		file, err := openProcFile(filepath.Join("net", proto))
+		if err != nil {
+			// IPv6 может быть отключен
+			if strings.HasSuffix(proto, "6") {
+				continue
+			}
+			return nil, err
+		}
+		found, err := parseListeningSockets(file, proto)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("/proc/net/%s: %w", proto, err)
+		}
+		sockets = append(sockets, found...)
+	}
+
+	owners := socketOwners()
+	asteriskPID := discoverProcess(m.pidfilePath()).PID
+	ports := readAsteriskPorts()
+	for i := range sockets {
+		socket := &sockets[i]
+		if owner, ok := owners[socket.Inode]; ok {
+			socket.PID, socket.Process = owner.pid, owner.name
+		}
+		role := ports.role(socket.Proto, socket.Port)
+		switch {
+		case socket.PID != 0 && (socket.PID == asteriskPID || socket.Process == asteriskBinary):
+			socket.Asterisk = true
+			socket.Role = role
+		case socket.PID == 0 && role != "":
+			// Без root чужие каталоги fd недоступны, сокет относится к Asterisk по порту
+			socket.Asterisk = true
+			socket.Role = role
+		}
+	}
+
+	sort.SliceStable(sockets, func(i, j int) bool {
+		if sockets[i].Port != sockets[j].Port {
+			return sockets[i].Port < sockets[j].Port
+		}
+		return sockets[i].Proto < sockets[j].Proto
+	})
+	return sockets, nil
+}
+
+// parseListeningSockets разбирает /proc/net/tcp, tcp6, udp или udp6 и оставляет
+// слушающие TCP и несвязанные UDP сокеты
+func parseListeningSockets(r io.Reader, proto string) ([]types.ListeningSocket, error) {
+	want := int64(tcpListen)
+	if strings.HasPrefix(proto, "udp") {
+		want = udpUnbound
+	}
+
+	var sockets []types.ListeningSocket
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || !strings.HasSuffix(fields[0], ":") {
+			continue
+		}
+		if state, err := strconv.ParseInt(fields[3], 16, 32); err != nil || state != want {
+			continue
+		}
+
+		ip, port, err := parseSocketAddress(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		inode, _ := strconv.ParseUint(fields[9], 10, 64)
+		sockets = append(sockets, types.ListeningSocket{
+			Proto:    proto,
+			Address:  ip.String(),
+			Port:     port,
+			Inode:    inode,
+			Exposure: exposure(ip),
+		})
+	}
+	return sockets, scanner.Err()
+}
+
+// exposure определяет, откуда доступен сокет, привязанный к адресу ip
+func exposure(ip net.IP) string {
+	switch {
+	case ip.IsUnspecified():
+		return types.ExposureAll
+	case ip.IsLoopback():
+		return types.ExposureLoopback
+	case ip.IsPrivate() || ip.IsLinkLocalUnicast():
+		return types.ExposureLAN
+	default:
+		return types.ExposurePublic
+	}
+}
+
+// socketOwner - процесс, которому принадлежит сокет
+type socketOwner struct {
+	pid  int
+	name string
+}
+
+// socketOwners сопоставляет inode сокетов с процессами по ссылкам /proc/<pid>/fd.
+// Процессы, каталог fd которых недоступен, пропускаются.
+func socketOwners() map[uint64]socketOwner {
+	owners := make(map[uint64]socketOwner)
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return owners
+	}
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		inodes, err := socketInodes(pid)
+		if err != nil || len(inodes) == 0 {
+			continue
+		}
+		comm, _ := readProcFile(filepath.Join(entry.Name(), "comm"))
+		owner := socketOwner{pid: pid, name: strings.TrimSpace(string(comm))}
+		for inode := range inodes {
+			if _, ok := owners[inode]; !ok {
+				owners[inode] = owner
+			}
+		}
+	}
+	return owners
+}
+
+// confSection - секция файла конфигурации Asterisk
+type confSection struct {
+	name   string
+	values map[string]string
+}
+
+// readAsteriskConf читает секции файла конфигурации Asterisk. Шаблоны
+// "[name](template)" и "=>" поддерживаются, #include не раскрывается,
+// из повторяющихся ключей секции остается последний.
+func readAsteriskConf(name string) ([]confSection, error) {
+	file, err := os.Open(filepath.Join(asteriskConfDir, name))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var sections []confSection
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), ";")
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "["):
+			if end := strings.Index(line, "]"); end > 0 {
+				sections = append(sections, confSection{name: line[1:end], values: make(map[string]string)})
+			}
+			continue
+		case len(sections) == 0:
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimPrefix(value, ">")
+		sections[len(sections)-1].values[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	return sections, scanner.Err()
+}
+
+// confGeneral возвращает значения секции [general] файла name или nil
+func confGeneral(name string) map[string]string {
+	sections, _ := readAsteriskConf(name)
+	for _, section := range sections {
+		if section.name == "general" {
+			return section.values
+		}
+	}
+	return nil
+}
+
+// confPort возвращает порт из значения "порт" или "адрес:порт"
+func confPort(value string) (int, bool) {
+	if _, port, err := net.SplitHostPort(value); err == nil {
+		value = port
+	}
+	port, err := strconv.Atoi(value)
+	return port, err == nil && port > 0 && port < 65536
+}
+
+// readAsteriskPorts определяет порты служб Asterisk по sip.conf, pjsip.conf,
+// iax.conf, manager.conf, http.conf и rtp.conf. Стандартные порты
+// учитываются всегда: файлы могут быть недоступны без прав root.
+func readAsteriskPorts() asteriskPorts {
+	ports := asteriskPorts{
+		tcp: map[int]string{
+			5060: types.ListenerSIP,
+			5061: types.ListenerSIPTLS,
+			5038: types.ListenerAMI,
+			5039: types.ListenerAMI,
+			8088: types.ListenerHTTP,
+			8089: types.ListenerHTTP,
+		},
+		udp: map[int]string{
+			5060: types.ListenerSIP,
+			4569: types.ListenerIAX2,
+		},
+		rtpStart: 10000,
+		rtpEnd:   20000,
+	}
+
+	if sip := confGeneral("sip.conf"); sip != nil {
+		if port, ok := confPort(sip["bindport"]); ok {
+			ports.udp[port] = types.ListenerSIP
+			ports.tcp[port] = types.ListenerSIP
+		}
+		if port, ok := confPort(sip["udpbindaddr"]); ok {
+			ports.udp[port] = types.ListenerSIP
+		}
+		if port, ok := confPort(sip["tcpbindaddr"]); ok {
+			ports.tcp[port] = types.ListenerSIP
+		}
+		if port, ok := confPort(sip["tlsbindaddr"]); ok {
+			ports.tcp[port] = types.ListenerSIPTLS
+		}
+	}
+
+	// Транспорты PJSIP - секции type=transport, bind=адрес[:порт]
+	transports, _ := readAsteriskConf("pjsip.conf")
+	for _, section := range transports {
+		if section.values["type"] != "transport" {
+			continue
+		}
+		protocol := strings.ToLower(section.values["protocol"])
+		port, ok := confPort(section.values["bind"])
+		switch {
+		case protocol == "tls":
+			ports.tcp[portOr(port, ok, 5061)] = types.ListenerSIPTLS
+		case protocol == "tcp":
+			ports.tcp[portOr(port, ok, 5060)] = types.ListenerSIP
+		case protocol == "" || protocol == "udp":
+			ports.udp[portOr(port, ok, 5060)] = types.ListenerSIP
+		}
+	}
+
+	if iax := confGeneral("iax.conf"); iax != nil {
+		if port, ok := confPort(iax["bindport"]); ok {
+			ports.udp[port] = types.ListenerIAX2
+		}
+	}
+	if manager := confGeneral("manager.conf"); manager != nil {
+		if port, ok := confPort(manager["port"]); ok {
+			ports.tcp[port] = types.ListenerAMI
+		}
+		if port, ok := confPort(manager["tlsbindport"]); ok {
+			ports.tcp[port] = types.ListenerAMI
+		}
+	}
+	if http := confGeneral("http.conf"); http != nil {
+		if port, ok := confPort(http["bindport"]); ok {
+			ports.tcp[port] = types.ListenerHTTP
+		}
+		if port, ok := confPort(http["tlsbindaddr"]); ok {
+			ports.tcp[port] = types.ListenerHTTP
+		}
+	}
+	if rtp := confGeneral("rtp.conf"); rtp != nil {
+		start, okStart := confPort(rtp["rtpstart"])
+		end, okEnd := confPort(rtp["rtpend"])
+		if okStart && okEnd && start <= end {
+			ports.rtpStart, ports.rtpEnd = start, end
+		}
+	}
+	return ports
+}
+
+// portOr возвращает port, если он задан, иначе порт по умолчанию
+func portOr(port int, ok bool, fallback int) int {
+	if ok {
+		return port
+	}
+	return fallback
+}
+
+// Строгость оценки доступности: служба управления (AMI, ARI) не должна быть
+// доступна извне, SIP и IAX2 снаружи доступны по назначению, но требуют
+// межсетевого экрана и fail2ban, RTP всегда слушает все интерфейсы.
+var exposureStatus = map[string]map[string]string{
+	types.ListenerAMI:    {types.ExposureAll: "error", types.ExposurePublic: "error", types.ExposureLAN: "warning"},
+	types.ListenerHTTP:   {types.ExposureAll: "error", types.ExposurePublic: "error", types.ExposureLAN: "warning"},
+	types.ListenerSIP:    {types.ExposureAll: "warning", types.ExposurePublic: "warning"},
+	types.ListenerSIPTLS: {types.ExposureAll: "warning", types.ExposurePublic: "warning"},
+	types.ListenerIAX2:   {types.ExposureAll: "warning", types.ExposurePublic: "warning"},
+}
+
+// statusRank упорядочивает статусы проверок по тяжести
+var statusRank = map[string]int{"success": 0, "warning": 1, "error": 2}
+
+// exposureText описывает доступность для сообщения проверки
+var exposureText = map[string]string{
+	types.ExposureLoopback: "loopback only",
+	types.ExposureLAN:      "LAN",
+	types.ExposurePublic:   "public address",
+	types.ExposureAll:      "all interfaces",
+}
+
+// listenerCheck возвращает проверку безопасности слушающих сокетов Asterisk
+// с назначениями roles. absent - сообщение, если таких сокетов нет.
+func (m *LinuxMonitor) listenerCheck(name, absent string, roles ...string) Check {
+	return Check{
+		Name: name,
+		Run: func(ctx context.Context) types.CheckResult {
+			result := types.CheckResult{Name: name, Status: "success", Timestamp: time.Now()}
+			sockets, err := m.GetListeningSockets()
+			if err != nil {
+				result.Status = "error"
+				result.Message = "Socket inventory failed"
+				result.Error = err.Error()
+				return result
+			}
+
+			lines, status := describeListeners(sockets, roles)
+			if len(lines) == 0 {
+				result.Message = absent
+				return result
+			}
+			result.Status = status
+			result.Message = strings.Join(lines, "\n")
+			switch status {
+			case "error":
+				result.Message += "\n❌ Management interface reachable from outside - SECURITY RISK!"
+			case "warning":
+				result.Message += "\n⚠️  Exposed beyond loopback, restrict with bind address or firewall"
+			}
+			return result
+		},
+	}
+}
+
+// describeListeners описывает сокеты Asterisk с назначениями roles и возвращает
+// наихудший статус. Сокеты RTP сводятся в одну строку на адрес.
+func describeListeners(sockets []types.ListeningSocket, roles []string) ([]string, string) {
+	wanted := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		wanted[role] = true
+	}
+
+	var lines []string
+	status := "success"
+	rtp := make(map[string]int)
+	var rtpKeys []string
+	for _, socket := range sockets {
+		if !socket.Asterisk || !wanted[socket.Role] {
+			continue
+		}
+		if socket.Role == types.ListenerRTP {
+			key := socket.Proto + " " + socket.Address + " (" + exposureText[socket.Exposure] + ")"
+			if rtp[key] == 0 {
+				rtpKeys = append(rtpKeys, key)
+			}
+			rtp[key]++
+			continue
+		}
+
+		socketStatus := exposureStatus[socket.Role][socket.Exposure]
+		if socketStatus == "" {
+			socketStatus = "success"
+		}
+		if statusRank[socketStatus] > statusRank[status] {
+			status = socketStatus
+		}
+
+		owner := "owner unknown"
+		if socket.PID != 0 {
+			owner = fmt.Sprintf("%s[%d]", socket.Process, socket.PID)
+		}
+		lines = append(lines, fmt.Sprintf("%s %s %s - %s, %s",
+			socket.Role, socket.Proto, net.JoinHostPort(socket.Address, strconv.Itoa(socket.Port)), exposureText[socket.Exposure], owner))
+	}
+	for _, key := range rtpKeys {
+		lines = append(lines, fmt.Sprintf("RTP %s: %d sockets", key, rtp[key]))
+	}
+	return lines, status
+}
+
+// checkPorts сообщает, на каких адресах Asterisk принимает SIP и AMI
+func (m *LinuxMonitor) checkPorts(ctx context.Context) types.CheckResult {
+	result := types.CheckResult{Name: "Ports", Status: "success", Timestamp: time.Now()}
+	sockets, err := m.GetListeningSockets()
+	if err != nil {
+		result.Status = "error"
+		result.Message = "Socket inventory failed"
+		result.Error = err.Error()
+		return result
+	}
+
+	lines, _ := describeListeners(sockets, []string{types.ListenerSIP, types.ListenerSIPTLS, types.ListenerAMI})
+	if len(lines) == 0 {
+		result.Status = "warning"
+		result.Message = "No SIP/AMI ports found"
+		return result
+	}
+	result.Message = strings.Join(lines, "\n")
+	return result
+}
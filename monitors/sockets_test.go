@@ -0,0 +1,257 @@
+package monitor
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"asterisk-monitor/types"
+)
+
+// fakeProc создает procfs с образцами /proc/net и процессами: Asterisk с PID
+// asteriskPID и sshd с PID 777. Сокеты процессов задаются inode, pidfile
+// Asterisk записывается в возвращаемый каталог.
+func fakeProc(t *testing.T, asteriskInodes, sshdInodes []uint64) (runDir string) {
+	t.Helper()
+	const asteriskPID = 4321
+
+	root := t.TempDir()
+	useProcRoot(t, root)
+
+	write := func(name, content string) {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		data, err := os.ReadFile(filepath.Join("testdata", "proc", "net", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		write(filepath.Join("net", name), string(data))
+	}
+
+	process := func(pid int, comm, cmdline string, inodes []uint64) {
+		dir := strconv.Itoa(pid)
+		write(filepath.Join(dir, "comm"), comm+"\n")
+		write(filepath.Join(dir, "cmdline"), cmdline)
+		write(filepath.Join(dir, "stat"), dir+" ("+comm+") S 1 "+dir+" "+dir+" 0 -1 4194560 5000 0 0 0 150 60 0 0 20 0 42 0 123456 1000000 5000")
+		fdDir := filepath.Join(root, dir, "fd")
+		if err := os.MkdirAll(fdDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for i, inode := range inodes {
+			target := "socket:[" + strconv.FormatUint(inode, 10) + "]"
+			if err := os.Symlink(target, filepath.Join(fdDir, strconv.Itoa(i+3))); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	process(asteriskPID, "asterisk", "/usr/sbin/asterisk\x00-f\x00-U\x00asterisk\x00", asteriskInodes)
+	process(777, "sshd", "/usr/sbin/sshd\x00-D\x00", sshdInodes)
+
+	runDir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(runDir, pidfileName), []byte(strconv.Itoa(asteriskPID)+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return runDir
+}
+
+// useAsteriskConf подменяет каталог конфигурации Asterisk на время теста
+func useAsteriskConf(t *testing.T, dir string) {
+	t.Helper()
+	old := asteriskConfDir
+	asteriskConfDir = dir
+	t.Cleanup(func() { asteriskConfDir = old })
+}
+
+func TestParseListeningSockets(t *testing.T) {
+	requireLittleEndian(t)
+
+	tests := []struct {
+		proto string
+		want  []types.ListeningSocket
+	}{
+		{"tcp", []types.ListeningSocket{
+			{Proto: "tcp", Address: "0.0.0.0", Port: 5060, Inode: 31250, Exposure: types.ExposureAll},
+			{Proto: "tcp", Address: "127.0.0.1", Port: 5038, Inode: 31251, Exposure: types.ExposureLoopback},
+			{Proto: "tcp", Address: "0.0.0.0", Port: 22, Inode: 15000, Exposure: types.ExposureAll},
+		}},
+		{"tcp6", []types.ListeningSocket{
+			{Proto: "tcp6", Address: "::", Port: 8088, Inode: 31252, Exposure: types.ExposureAll},
+		}},
+		// Подключенный UDP сокет (состояние 01) не слушает
+		{"udp", []types.ListeningSocket{
+			{Proto: "udp", Address: "0.0.0.0", Port: 5060, Inode: 31245, Exposure: types.ExposureAll},
+			{Proto: "udp", Address: "127.0.0.1", Port: 53, Inode: 20001, Exposure: types.ExposureLoopback},
+			{Proto: "udp", Address: "0.0.0.0", Port: 10000, Inode: 31300, Exposure: types.ExposureAll},
+		}},
+		{"udp6", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.proto, func(t *testing.T) {
+			got, err := parseListeningSockets(openTestdata(t, filepath.Join("proc", "net", tt.proto)), tt.proto)
+			if err != nil {
+				t.Fatalf("parseListeningSockets: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d sockets, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("socket %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseListeningSocketsMalformed(t *testing.T) {
+	input := "  sl  local_address rem_address   st\n" +
+		"   0: 00000000:13C4 00000000:0000 0A\n" + // слишком мало полей
+		"   1: 00000000:13C4 00000000:0000 ZZ 00000000:00000000 00:00000000 00000000 0 0 1\n" + // неизвестное состояние
+		"   2: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000 0 0 2\n"
+	got, err := parseListeningSockets(strings.NewReader(input), "tcp")
+	if err != nil {
+		t.Fatalf("parseListeningSockets: %v", err)
+	}
+	if len(got) != 1 || got[0].Port != 22 {
+		t.Errorf("got %+v, want only port 22", got)
+	}
+
+	bad := "   0: 0000:13C4 00000000:0000 0A 00000000:00000000 00:00000000 00000000 0 0 1\n"
+	if _, err := parseListeningSockets(strings.NewReader(bad), "tcp"); err == nil {
+		t.Error("no error for a bad address")
+	}
+}
+
+func TestExposure(t *testing.T) {
+	tests := map[string]string{
+		"0.0.0.0":     types.ExposureAll,
+		"::":          types.ExposureAll,
+		"127.0.0.1":   types.ExposureLoopback,
+		"::1":         types.ExposureLoopback,
+		"10.1.2.3":    types.ExposureLAN,
+		"192.168.1.1": types.ExposureLAN,
+		"fe80::1":     types.ExposureLAN,
+		"203.0.113.5": types.ExposurePublic,
+	}
+	for address, want := range tests {
+		if got := exposure(net.ParseIP(address)); got != want {
+			t.Errorf("exposure(%s) = %s, want %s", address, got, want)
+		}
+	}
+}
+
+func TestReadAsteriskPorts(t *testing.T) {
+	useAsteriskConf(t, filepath.Join("testdata", "asterisk"))
+	ports := readAsteriskPorts()
+
+	tests := []struct {
+		proto string
+		port  int
+		want  string
+	}{
+		{"udp", 5070, types.ListenerSIP}, // bindport из sip.conf
+		{"tcp6", 5070, types.ListenerSIP},
+		{"udp", 5060, types.ListenerSIP}, // стандартный порт учитывается всегда
+		{"tcp", 5038, types.ListenerAMI},
+		{"udp6", 4569, types.ListenerIAX2},
+		{"udp", 10050, types.ListenerRTP}, // диапазон из rtp.conf
+		{"udp", 10101, ""},
+		{"tcp", 10050, ""},
+		{"tcp", 22, ""},
+	}
+	for _, tt := range tests {
+		if got := ports.role(tt.proto, tt.port); got != tt.want {
+			t.Errorf("role(%s, %d) = %q, want %q", tt.proto, tt.port, got, tt.want)
+		}
+	}
+}
+
+func TestGetListeningSockets(t *testing.T) {
+	requireLittleEndian(t)
+	useAsteriskConf(t, filepath.Join("testdata", "asterisk"))
+	runDir := fakeProc(t, []uint64{31245, 31250, 31251, 31252}, []uint64{15000})
+	m := &LinuxMonitor{runDir: runDir}
+
+	sockets, err := m.GetListeningSockets()
+	if err != nil {
+		t.Fatalf("GetListeningSockets: %v", err)
+	}
+
+	type key struct {
+		proto string
+		port  int
+	}
+	got := make(map[key]types.ListeningSocket)
+	for _, socket := range sockets {
+		got[key{socket.Proto, socket.Port}] = socket
+	}
+
+	tests := []struct {
+		key      key
+		pid      int
+		process  string
+		asterisk bool
+		role     string
+	}{
+		{key{"udp", 5060}, 4321, "asterisk", true, types.ListenerSIP},
+		{key{"tcp", 5060}, 4321, "asterisk", true, types.ListenerSIP},
+		{key{"tcp", 5038}, 4321, "asterisk", true, types.ListenerAMI},
+		{key{"tcp6", 8088}, 4321, "asterisk", true, types.ListenerHTTP},
+		{key{"tcp", 22}, 777, "sshd", false, ""},
+		// Владелец неизвестен: сокет на порту Asterisk относится к Asterisk
+		{key{"udp", 10000}, 0, "", true, types.ListenerRTP},
+		{key{"udp", 53}, 0, "", false, ""},
+	}
+	if len(sockets) != len(tests) {
+		t.Errorf("got %d sockets, want %d: %+v", len(sockets), len(tests), sockets)
+	}
+	for _, tt := range tests {
+		socket, ok := got[tt.key]
+		if !ok {
+			t.Errorf("%v not found", tt.key)
+			continue
+		}
+		if socket.PID != tt.pid || socket.Process != tt.process || socket.Asterisk != tt.asterisk || socket.Role != tt.role {
+			t.Errorf("%v = %+v, want pid %d %q asterisk %v role %q", tt.key, socket, tt.pid, tt.process, tt.asterisk, tt.role)
+		}
+	}
+
+	for i := 1; i < len(sockets); i++ {
+		if sockets[i-1].Port > sockets[i].Port {
+			t.Errorf("sockets not sorted by port: %d before %d", sockets[i-1].Port, sockets[i].Port)
+		}
+	}
+}
+
+func TestAsteriskUDPSockets(t *testing.T) {
+	requireLittleEndian(t)
+	runDir := fakeProc(t, []uint64{31245, 31300}, nil)
+	m := &LinuxMonitor{runDir: runDir}
+
+	sockets, reason := m.asteriskUDPSockets()
+	if reason != "" {
+		t.Fatalf("asteriskUDPSockets: %s", reason)
+	}
+	// Сокеты других процессов не попадают, первым идет сокет с очередью
+	if len(sockets) != 2 || sockets[0].Inode != 31300 || sockets[1].Inode != 31245 {
+		t.Errorf("got %+v, want inodes 31300, 31245", sockets)
+	}
+
+	// Без pidfile процесс ищется в /proc, где Asterisk нет
+	useProcRoot(t, t.TempDir())
+	m = &LinuxMonitor{runDir: t.TempDir()}
+	if _, reason := m.asteriskUDPSockets(); reason == "" {
+		t.Error("no reason without an asterisk process")
+	}
+}
@@ -0,0 +1,168 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+// GetTaskprocessors возвращает очереди задач по core show taskprocessors и
+// потоки по core show threads. Перегрузка Asterisk раньше всего заметна по
+// росту очередей задач.
+func (m *LinuxMonitor) GetTaskprocessors(ctx context.Context) types.TaskprocessorReport {
+	report := types.TaskprocessorReport{Time: time.Now(), Threads: -1}
+
+	output, err := m.asteriskOutput(ctx, "core show taskprocessors")
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	report.Processors = ParseTaskprocessors(string(output))
+
+	if output, err := m.asteriskOutput(ctx, "core show threads"); err == nil {
+		report.Threads, report.ThreadGroups = ParseThreads(string(output))
+	}
+	return report
+}
+
+// ParseTaskprocessors разбирает вывод core show taskprocessors. С Asterisk 13.10:
+//
+//	Processor                            Processed   In Queue  Max Depth  Low water High water
+//	stasis/m:manager:core-00000006           52317          0         12        450        500
+//
+// В ранних версиях столбцов уровней нет, строки обрамлены рамкой из '+' и '-'.
+func ParseTaskprocessors(output string) []types.Taskprocessor {
+	var processors []types.Taskprocessor
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || strings.HasPrefix(fields[0], "+") || fields[0] == "Processor" {
+			continue
+		}
+
+		// Имя - все до числовых столбцов: пять в новом формате, три в старом
+		numeric := 0
+		for i := len(fields) - 1; i > 0 && numeric < 5; i-- {
+			if _, err := strconv.ParseInt(fields[i], 10, 64); err != nil {
+				break
+			}
+			numeric++
+		}
+		if numeric == 4 {
+			numeric = 3
+		}
+		if numeric < 3 {
+			continue
+		}
+
+		values := make([]int64, 5)
+		start := len(fields) - numeric
+		for i := 0; i < numeric; i++ {
+			values[i], _ = strconv.ParseInt(fields[start+i], 10, 64)
+		}
+		processors = append(processors, types.Taskprocessor{
+			Name:      strings.Join(fields[:start], " "),
+			Processed: values[0],
+			InQueue:   int(values[1]),
+			MaxDepth:  int(values[2]),
+			LowWater:  int(values[3]),
+			HighWater: int(values[4]),
+		})
+	}
+	return processors
+}
+
+// ParseThreads разбирает вывод core show threads и возвращает число потоков и
+// потоки, сгруппированные по функции запуска, самые многочисленные первыми:
+//
+//	0x7f1c6c85c700 13712 tps_processing_function started at [  202] taskprocessor.c default_listener_start()
+//	197 threads listed.
+//
+// В ранних версиях LWP потока не выводится.
+func ParseThreads(output string) (int, []types.ThreadGroup) {
+	total := -1
+	counts := make(map[string]int)
+	listed := 0
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[1] == "threads" && fields[2] == "listed." {
+			if n, err := strconv.Atoi(fields[0]); err == nil {
+				total = n
+			}
+			continue
+		}
+		if len(fields) < 2 || !strings.HasPrefix(fields[0], "0x") {
+			continue
+		}
+
+		function := fields[1]
+		if _, err := strconv.Atoi(function); err == nil && len(fields) > 2 {
+			function = fields[2]
+		}
+		counts[function]++
+		listed++
+	}
+	if total < 0 && listed > 0 {
+		total = listed
+	}
+
+	groups := make([]types.ThreadGroup, 0, len(counts))
+	for function, count := range counts {
+		groups = append(groups, types.ThreadGroup{Function: function, Count: count})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Function < groups[j].Function
+	})
+	return total, groups
+}
+
+// OverHighWater сообщает, что очередь достигла уровня high water. Asterisk в
+// этот момент включает ограничение нагрузки (alert) для очереди.
+func OverHighWater(tp types.Taskprocessor) bool {
+	return tp.HighWater > 0 && tp.InQueue >= tp.HighWater
+}
+
+// TaskprocessorSamples преобразует очереди задач в измерения наибольшей очереди
+// и числа очередей выше high water. Имена очередей попадают в текст оповещения.
+func TaskprocessorSamples(report types.TaskprocessorReport, ts time.Time) []types.Sample {
+	if report.Error != "" || len(report.Processors) == 0 {
+		return nil
+	}
+
+	largest := report.Processors[0]
+	var over []string
+	for _, tp := range report.Processors {
+		if tp.InQueue > largest.InQueue {
+			largest = tp
+		}
+		if OverHighWater(tp) {
+			over = append(over, fmt.Sprintf("%s %d/%d", tp.Name, tp.InQueue, tp.HighWater))
+		}
+	}
+
+	count := len(over)
+	note := "no queue above high water"
+	if count > 0 {
+		if len(over) > 5 {
+			over = append(over[:5], fmt.Sprintf("and %d more", len(over)-5))
+		}
+		note = "over high water: " + strings.Join(over, ", ")
+	}
+
+	return []types.Sample{
+		{
+			Metric:    types.MetricTaskprocessorMaxQueue,
+			Value:     float64(largest.InQueue),
+			Timestamp: ts,
+			Note:      fmt.Sprintf("largest queue %s: %d (max depth %d)", largest.Name, largest.InQueue, largest.MaxDepth),
+		},
+		{Metric: types.MetricTaskprocessorsOverHighWater, Value: float64(count), Timestamp: ts, Note: note},
+	}
+}
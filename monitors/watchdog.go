@@ -0,0 +1,201 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"asterisk-monitor/console"
+	"asterisk-monitor/types"
+)
+
+// SetAMI задает адрес и учетные данные AMI для проверки отзывчивости
+func (m *LinuxMonitor) SetAMI(cfg types.AsteriskConfig) {
+	m.consoleMu.Lock()
+	defer m.consoleMu.Unlock()
+	m.ami = cfg
+}
+
+// ProbeAsterisk проверяет, что Asterisk отвечает на команду CLI и, если ami,
+// на вход в AMI. Каждая проверка ограничена timeout. Зависшим считается
+// процесс, который не ответил ни на одну из проверок.
+func (m *LinuxMonitor) ProbeAsterisk(ctx context.Context, timeout time.Duration, ami bool) types.HealthProbe {
+	probe := types.HealthProbe{
+		Time:    time.Now(),
+		State:   types.HealthOK,
+		Process: discoverProcess(m.pidfilePath()),
+	}
+	if probe.Process.State != types.AsteriskRunning {
+		probe.State = types.HealthGone
+		return probe
+	}
+
+	var err error
+	if probe.CLILatency, err = m.probeCLI(ctx, timeout); err != nil {
+		probe.CLIError = err.Error()
+	}
+	if ami {
+		probe.AMIChecked = true
+		if probe.AMILatency, err = m.probeAMI(ctx, timeout); err != nil {
+			probe.AMIError = err.Error()
+		}
+	}
+
+	if probe.CLIError != "" && (!ami || probe.AMIError != "") {
+		probe.State = types.HealthUnresponsive
+		probe.Process.State = types.AsteriskUnresponsive
+	}
+	return probe
+}
+
+// probeCLI выполняет core show version по отдельному соединению: общее
+// соединение может быть занято командой, которая уже зависла
+func (m *LinuxMonitor) probeCLI(ctx context.Context, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	_, elapsed, err := m.freshCommand(ctx, "core show version")
+	return elapsed, err
+}
+
+// freshCommand выполняет разрешенную команду CLI по новому соединению с
+// управляющим сокетом или через asterisk -rx
+func (m *LinuxMonitor) freshCommand(ctx context.Context, command string) (string, time.Duration, error) {
+	cmd, err := AsteriskCLI(command)
+	if err != nil {
+		return "", 0, err
+	}
+
+	shared := m.consoleClient()
+	if shared == nil {
+		result, err := execute(ctx, cmd)
+		return string(result.stdout), result.duration, err
+	}
+
+	client := console.New(shared.Path())
+	defer client.Close()
+
+	start := time.Now()
+	output, err := client.Command(ctx, command)
+	return output, time.Since(start), err
+}
+
+// probeAMI подключается к AMI и отправляет Login. Любой ответ, в том числе
+// отказ в доступе, означает, что менеджер AMI не завис.
+func (m *LinuxMonitor) probeAMI(ctx context.Context, timeout time.Duration) (time.Duration, error) {
+	m.consoleMu.Lock()
+	cfg := m.ami
+	m.consoleMu.Unlock()
+
+	host := cfg.Host
+	if host == "" {
+		host = "localhost"
+	}
+	port := cfg.AMIPort
+	if port == "" {
+		port = "5038"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return 0, fmt.Errorf("ami: %w", err)
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	reader := bufio.NewReader(conn)
+	banner, err := reader.ReadString('\n')
+	if err != nil {
+		return time.Since(start), fmt.Errorf("ami banner: %w", err)
+	}
+	if !strings.HasPrefix(banner, "Asterisk Call Manager") {
+		return time.Since(start), fmt.Errorf("ami: unexpected banner %q", strings.TrimSpace(banner))
+	}
+
+	login := fmt.Sprintf("Action: Login\r\nUsername: %s\r\nSecret: %s\r\nEvents: off\r\n\r\n", cfg.Username, cfg.Password)
+	if _, err := conn.Write([]byte(login)); err != nil {
+		return time.Since(start), fmt.Errorf("ami login: %w", err)
+	}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return time.Since(start), fmt.Errorf("ami login: %w", err)
+		}
+		if strings.HasPrefix(line, "Response:") {
+			elapsed := time.Since(start)
+			conn.Write([]byte("Action: Logoff\r\n\r\n"))
+			return elapsed, nil
+		}
+	}
+}
+
+// CaptureHangReport снимает состояние зависшего процесса pid: core show locks
+// (есть только в сборках с DEBUG_THREADS) и состояние, wchan и стек ядра каждого
+// потока из /proc. Стеки ядра доступны только root.
+func (m *LinuxMonitor) CaptureHangReport(ctx context.Context, pid int, timeout time.Duration) string {
+	var report strings.Builder
+	fmt.Fprintf(&report, "Asterisk hang report, PID %d, %s\n\n", pid, time.Now().Format(time.RFC3339))
+
+	report.WriteString("== core show locks ==\n")
+	lockCtx, cancel := context.WithTimeout(ctx, timeout)
+	locks, _, err := m.freshCommand(lockCtx, "core show locks")
+	cancel()
+	if err != nil {
+		fmt.Fprintf(&report, "error: %v\n", err)
+	}
+	report.WriteString(strings.TrimSpace(locks) + "\n\n")
+
+	report.WriteString("== threads ==\n")
+	taskDir := filepath.Join(procRoot, strconv.Itoa(pid), "task")
+	entries, err := os.ReadDir(taskDir)
+	if err != nil {
+		fmt.Fprintf(&report, "error: %v\n", err)
+		return report.String()
+	}
+
+	states := make(map[string]int)
+	for _, entry := range entries {
+		dir := filepath.Join(strconv.Itoa(pid), "task", entry.Name())
+		comm, _ := readProcFile(filepath.Join(dir, "comm"))
+		wchan, _ := readProcFile(filepath.Join(dir, "wchan"))
+		state := "?"
+		if data, err := readProcFile(filepath.Join(dir, "stat")); err == nil {
+			if end := strings.LastIndex(string(data), ")"); end >= 0 {
+				if fields := strings.Fields(string(data)[end+1:]); len(fields) > 0 {
+					state = fields[0]
+				}
+			}
+		}
+		states[state]++
+
+		fmt.Fprintf(&report, "\n-- thread %s (%s) state %s wchan %s\n",
+			entry.Name(), strings.TrimSpace(string(comm)), state, strings.TrimSpace(string(wchan)))
+		if stack, err := readProcFile(filepath.Join(dir, "stack")); err == nil {
+			report.Write(stack)
+		}
+	}
+
+	var summary []string
+	for state, count := range states {
+		summary = append(summary, fmt.Sprintf("%s=%d", state, count))
+	}
+	fmt.Fprintf(&report, "\n== thread states: %s ==\n", strings.Join(summary, " "))
+	return report.String()
+}
+
+// RestartUnit перезапускает systemd юнит
+func (m *LinuxMonitor) RestartUnit(ctx context.Context, unit string) types.CheckResult {
+	return m.Exec(ctx, "Restart "+unit, "systemctl", "restart", "--", unit)
+}
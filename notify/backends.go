@@ -0,0 +1,209 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+// DefaultTelegramAPI - адрес Telegram Bot API
+const DefaultTelegramAPI = "https://api.telegram.org"
+
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// webhook отправляет JSON с оповещениями на произвольный URL
+type webhook struct {
+	name string
+	url  string
+	r    *renderer
+}
+
+func newWebhook(cfg types.NotifierConfig, r *renderer) (Notifier, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("notifier %q: url is required", cfg.Name)
+	}
+	return &webhook{name: cfg.Name, url: cfg.URL, r: r}, nil
+}
+
+func (w *webhook) Name() string { return w.name }
+
+func (w *webhook) Send(ctx context.Context, n Notification) error {
+	subject, body, err := w.r.render(n)
+	if err != nil {
+		return err
+	}
+
+	payload := struct {
+		Notification
+		Subject string `json:"subject"`
+		Text    string `json:"text"`
+	}{n, subject, body}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, w.url, data)
+}
+
+// telegram отправляет сообщение через Telegram Bot API
+type telegram struct {
+	name   string
+	api    string
+	token  string
+	chatID string
+	r      *renderer
+}
+
+func newTelegram(cfg types.NotifierConfig, r *renderer) (Notifier, error) {
+	if cfg.Token == "" || cfg.ChatID == "" {
+		return nil, fmt.Errorf("notifier %q: token and chat_id are required", cfg.Name)
+	}
+
+	api := cfg.URL
+	if api == "" {
+		api = DefaultTelegramAPI
+	}
+	return &telegram{
+		name:   cfg.Name,
+		api:    strings.TrimSuffix(api, "/"),
+		token:  cfg.Token,
+		chatID: cfg.ChatID,
+		r:      r,
+	}, nil
+}
+
+func (t *telegram) Name() string { return t.name }
+
+func (t *telegram) Send(ctx context.Context, n Notification) error {
+	subject, body, err := t.r.render(n)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"chat_id":                  t.chatID,
+		"text":                     subject + "\n\n" + body,
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+	err = postJSON(ctx, t.api+"/bot"+t.token+"/sendMessage", data)
+	return redactToken(err, t.token)
+}
+
+// redactToken убирает токен бота из ошибки запроса. *url.Error содержит полный
+// адрес /bot<token>/sendMessage, а ошибки отправки попадают в лог и интерфейс.
+func redactToken(err error, token string) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+	return fmt.Errorf("%s %s: %w", urlErr.Op, strings.ReplaceAll(urlErr.URL, token, "<token>"), urlErr.Err)
+}
+
+// postJSON отправляет JSON и проверяет код ответа
+func postJSON(ctx context.Context, url string, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// smtpNotifier отправляет письмо через SMTP сервер (STARTTLS, если сервер его поддерживает)
+type smtpNotifier struct {
+	name     string
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+	to       []string
+	r        *renderer
+}
+
+func newSMTP(cfg types.NotifierConfig, r *renderer) (Notifier, error) {
+	to := splitList(cfg.To)
+	if cfg.Host == "" || cfg.From == "" || len(to) == 0 {
+		return nil, fmt.Errorf("notifier %q: host, from and to are required", cfg.Name)
+	}
+
+	port := cfg.Port
+	if port == 0 {
+		port = 25
+	}
+	return &smtpNotifier{
+		name:     cfg.Name,
+		addr:     net.JoinHostPort(cfg.Host, strconv.Itoa(port)),
+		host:     cfg.Host,
+		username: cfg.Username,
+		password: cfg.Password,
+		from:     cfg.From,
+		to:       to,
+		r:        r,
+	}, nil
+}
+
+func (s *smtpNotifier) Name() string { return s.name }
+
+func (s *smtpNotifier) Send(ctx context.Context, n Notification) error {
+	subject, body, err := s.r.render(n)
+	if err != nil {
+		return err
+	}
+
+	var msg strings.Builder
+	msg.WriteString("From: " + s.from + "\r\n")
+	msg.WriteString("To: " + strings.Join(s.to, ", ") + "\r\n")
+	msg.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	msg.WriteString("Date: " + n.Time.Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n") + "\r\n")
+
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+
+	// net/smtp не поддерживает контекст, поэтому отправка выполняется в горутине
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- smtp.SendMail(s.addr, auth, s.from, s.to, []byte(msg.String()))
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
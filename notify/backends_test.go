@@ -0,0 +1,257 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"asterisk-monitor/alerts"
+	"asterisk-monitor/types"
+)
+
+func testAlertNotification() Notification {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	return NewNotification([]alerts.Alert{{
+		Rule:     "asterisk_down",
+		Severity: alerts.SeverityCritical,
+		State:    alerts.StateFiring,
+		Message:  "asterisk_down: asterisk_up = 0 (== 0)",
+		FiredAt:  now,
+	}}, now)
+}
+
+func newTestNotifier(t *testing.T, cfg types.NotifierConfig) Notifier {
+	t.Helper()
+	notifier, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New(%+v): %v", cfg, err)
+	}
+	return notifier
+}
+
+func TestWebhookSend(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	notifier := newTestNotifier(t, types.NotifierConfig{Name: "hook", Type: "webhook", URL: server.URL})
+	if err := notifier.Send(context.Background(), testAlertNotification()); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if got["status"] != "FIRING" {
+		t.Errorf("status = %v, want FIRING", got["status"])
+	}
+	if subject, _ := got["subject"].(string); !strings.Contains(subject, "1 firing") {
+		t.Errorf("subject = %q, want it to count firing alerts", subject)
+	}
+	if text, _ := got["text"].(string); !strings.Contains(text, "asterisk_down") {
+		t.Errorf("text = %q, want the alert message", text)
+	}
+}
+
+func TestWebhookErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream unavailable", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	notifier := newTestNotifier(t, types.NotifierConfig{Name: "hook", Type: "webhook", URL: server.URL})
+	err := notifier.Send(context.Background(), testAlertNotification())
+	if err == nil {
+		t.Fatal("Send succeeded on 502")
+	}
+	for _, want := range []string{"502", "upstream unavailable"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+}
+
+func TestTelegramSend(t *testing.T) {
+	const token = "123456:secret-token"
+
+	var path string
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		io.WriteString(w, `{"ok":true}`)
+	}))
+	defer server.Close()
+
+	notifier := newTestNotifier(t, types.NotifierConfig{
+		Name: "tg", Type: "telegram", URL: server.URL + "/", Token: token, ChatID: "-100500",
+	})
+	if err := notifier.Send(context.Background(), testAlertNotification()); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if want := "/bot" + token + "/sendMessage"; path != want {
+		t.Errorf("path = %q, want %q", path, want)
+	}
+	if got["chat_id"] != "-100500" {
+		t.Errorf("chat_id = %v, want -100500", got["chat_id"])
+	}
+	if text, _ := got["text"].(string); !strings.HasPrefix(text, "[FIRING]") {
+		t.Errorf("text = %q, want subject first", text)
+	}
+}
+
+func TestTelegramErrorRedactsToken(t *testing.T) {
+	const token = "123456:secret-token"
+
+	// Закрытый сервер: запрос завершается ошибкой *url.Error с полным адресом
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	notifier := newTestNotifier(t, types.NotifierConfig{
+		Name: "tg", Type: "telegram", URL: server.URL, Token: token, ChatID: "1",
+	})
+	err := notifier.Send(context.Background(), testAlertNotification())
+	if err == nil {
+		t.Fatal("Send succeeded against a closed server")
+	}
+	if strings.Contains(err.Error(), token) {
+		t.Errorf("error exposes the bot token: %v", err)
+	}
+	if !strings.Contains(err.Error(), "/bot<token>/sendMessage") {
+		t.Errorf("error %q lacks the redacted URL", err)
+	}
+}
+
+// smtpStub - SMTP сервер без STARTTLS и AUTH, который принимает одно письмо
+type smtpStub struct {
+	addr     string
+	from     string
+	to       []string
+	messages chan string
+}
+
+func startSMTPStub(t *testing.T) *smtpStub {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	stub := &smtpStub{addr: listener.Addr().String(), messages: make(chan string, 1)}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		stub.serve(textproto.NewConn(conn))
+	}()
+	return stub
+}
+
+func (s *smtpStub) serve(c *textproto.Conn) {
+	c.PrintfLine("220 stub ESMTP")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			c.PrintfLine("250 stub")
+		case "MAIL":
+			s.from = arg
+			c.PrintfLine("250 OK")
+		case "RCPT":
+			s.to = append(s.to, arg)
+			c.PrintfLine("250 OK")
+		case "DATA":
+			c.PrintfLine("354 end with .")
+			data, err := c.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.messages <- string(data)
+			c.PrintfLine("250 queued")
+		case "QUIT":
+			c.PrintfLine("221 bye")
+			return
+		default:
+			c.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func TestSMTPSend(t *testing.T) {
+	stub := startSMTPStub(t)
+	host, port, _ := net.SplitHostPort(stub.addr)
+	portNumber, _ := strconv.Atoi(port)
+
+	notifier := newTestNotifier(t, types.NotifierConfig{
+		Name: "mail", Type: "smtp", Host: host, Port: portNumber,
+		From: "monitor@example.com", To: "ops@example.com, oncall@example.com",
+	})
+	if err := notifier.Send(context.Background(), testAlertNotification()); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	message := <-stub.messages
+	for _, want := range []string{
+		"From: monitor@example.com",
+		"To: ops@example.com, oncall@example.com",
+		"Subject: [FIRING] Asterisk Monitor",
+		"asterisk_down: asterisk_up = 0",
+	} {
+		if !strings.Contains(message, want) {
+			t.Errorf("message lacks %q:\n%s", want, message)
+		}
+	}
+	if len(stub.to) != 2 {
+		t.Errorf("recipients = %v, want 2", stub.to)
+	}
+}
+
+func TestSMTPSendCanceled(t *testing.T) {
+	// Сервер принимает соединение, но не отвечает: отправку прерывает контекст
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	notifier := newTestNotifier(t, types.NotifierConfig{
+		Name: "mail", Type: "smtp", Host: host, Port: portNumber, From: "a@example.com", To: "b@example.com",
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := notifier.Send(ctx, testAlertNotification()); err != context.DeadlineExceeded {
+		t.Errorf("Send = %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,215 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"asterisk-monitor/alerts"
+	"asterisk-monitor/types"
+)
+
+// route - канал уведомлений с фильтром по важности и ограничением частоты
+type route struct {
+	notifier   Notifier
+	severities map[string]bool
+	sent       []time.Time // время отправки сообщений за последний час
+}
+
+// Dispatcher группирует изменения оповещений, подавляет повторы
+// и рассылает сообщения по каналам с учетом важности
+type Dispatcher struct {
+	mu       sync.Mutex
+	settings types.NotificationsConfig
+	routes   []*route
+	pending  []alerts.Alert
+	lastSent map[string]time.Time // ключ оповещения и состояние -> время последней отправки
+	onError  func(notifier string, err error)
+}
+
+// NewDispatcher создает рассылку по конфигурации
+func NewDispatcher(cfg *types.Config) (*Dispatcher, error) {
+	d := &Dispatcher{
+		lastSent: make(map[string]time.Time),
+		onError: func(notifier string, err error) {
+			log.Printf("notifier %s: %v", notifier, err)
+		},
+	}
+	return d, d.Configure(cfg)
+}
+
+// SetErrorHandler задает обработчик ошибок отправки
+func (d *Dispatcher) SetErrorHandler(handler func(notifier string, err error)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onError = handler
+}
+
+// Configure заменяет каналы уведомлений. Некорректные и выключенные каналы пропускаются.
+func (d *Dispatcher) Configure(cfg *types.Config) error {
+	var errs []error
+	var routes []*route
+
+	for _, nc := range cfg.Notifiers {
+		if !nc.Enabled {
+			continue
+		}
+
+		notifier, err := New(nc)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		severities := make(map[string]bool)
+		for _, severity := range splitList(nc.Severities) {
+			severities[severity] = true
+		}
+		if len(severities) == 0 {
+			severities[alerts.SeverityWarning] = true
+			severities[alerts.SeverityCritical] = true
+		}
+		routes = append(routes, &route{notifier: notifier, severities: severities})
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.settings = cfg.Notifications
+	d.routes = routes
+
+	return errors.Join(errs...)
+}
+
+// Notifiers возвращает имена активных каналов
+func (d *Dispatcher) Notifiers() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var names []string
+	for _, r := range d.routes {
+		names = append(names, r.notifier.Name())
+	}
+	return names
+}
+
+// Enqueue добавляет изменения оповещений в очередь. Повторы одного
+// оповещения в том же состоянии в пределах repeat_interval отбрасываются.
+func (d *Dispatcher) Enqueue(changed []alerts.Alert, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	repeat := time.Duration(d.settings.RepeatInterval) * time.Second
+	for _, alert := range changed {
+		key := alert.Key() + "/" + alert.State
+		if last, ok := d.lastSent[key]; ok && now.Sub(last) < repeat {
+			continue
+		}
+		d.lastSent[key] = now
+		d.pending = append(d.pending, alert)
+	}
+
+	// Старые отметки о повторах больше не нужны
+	for key, last := range d.lastSent {
+		if now.Sub(last) >= repeat {
+			delete(d.lastSent, key)
+		}
+	}
+}
+
+// Flush рассылает накопленные изменения одним сообщением на канал
+func (d *Dispatcher) Flush(ctx context.Context, now time.Time) {
+	d.mu.Lock()
+	pending := d.pending
+	d.pending = nil
+	routes := d.routes
+	limit := d.settings.RateLimit
+	onError := d.onError
+	d.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	for _, r := range routes {
+		var routed []alerts.Alert
+		for _, alert := range pending {
+			if r.severities[alert.Severity] {
+				routed = append(routed, alert)
+			}
+		}
+		if len(routed) == 0 {
+			continue
+		}
+
+		if !d.allow(r, limit, now) {
+			onError(r.notifier.Name(), fmt.Errorf("rate limit of %d messages per hour reached, %d alerts dropped", limit, len(routed)))
+			continue
+		}
+
+		if err := r.notifier.Send(ctx, NewNotification(routed, now)); err != nil {
+			onError(r.notifier.Name(), err)
+		}
+	}
+}
+
+// allow проверяет и учитывает ограничение числа сообщений в час
+func (d *Dispatcher) allow(r *route, limit int, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	recent := r.sent[:0]
+	for _, t := range r.sent {
+		if now.Sub(t) < time.Hour {
+			recent = append(recent, t)
+		}
+	}
+	r.sent = recent
+
+	if limit > 0 && len(r.sent) >= limit {
+		return false
+	}
+	r.sent = append(r.sent, now)
+	return true
+}
+
+// SendTest отправляет пробное сообщение во все каналы или в канал с именем name
+func (d *Dispatcher) SendTest(ctx context.Context, name string) map[string]error {
+	d.mu.Lock()
+	routes := d.routes
+	d.mu.Unlock()
+
+	results := make(map[string]error)
+	for _, r := range routes {
+		if name != "" && r.notifier.Name() != name {
+			continue
+		}
+		results[r.notifier.Name()] = r.notifier.Send(ctx, TestNotification(time.Now()))
+	}
+	return results
+}
+
+// Run рассылает накопленные изменения каждые group_wait секунд до отмены контекста
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		d.mu.Lock()
+		wait := time.Duration(d.settings.GroupWait) * time.Second
+		d.mu.Unlock()
+		if wait <= 0 {
+			wait = time.Second
+		}
+
+		select {
+		case <-ctx.Done():
+			// Отправляем то, что успело накопиться
+			flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			d.Flush(flushCtx, time.Now())
+			cancel()
+			return
+		case <-time.After(wait):
+			d.Flush(ctx, time.Now())
+		}
+	}
+}
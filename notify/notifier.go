@@ -0,0 +1,144 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"asterisk-monitor/alerts"
+	"asterisk-monitor/types"
+)
+
+// Шаблоны сообщений по умолчанию
+const (
+	DefaultSubjectTemplate = `[{{.Status}}] Asterisk Monitor {{.Server}}: {{len .Firing}} firing, {{len .Resolved}} resolved`
+	DefaultBodyTemplate    = `{{if .Test}}This is a test notification from Asterisk Monitor on {{.Server}}.
+{{end}}{{range .Firing}}[FIRING {{.Severity}}] {{.Message}} since {{.FiredAt.Format "2006-01-02 15:04:05"}}
+{{end}}{{range .Resolved}}[RESOLVED] {{.Message}} at {{.ResolvedAt.Format "2006-01-02 15:04:05"}}
+{{end}}`
+)
+
+// Notification - сгруппированное сообщение об изменении оповещений
+type Notification struct {
+	Server   string         `json:"server"`
+	Status   string         `json:"status"` // FIRING, RESOLVED, TEST
+	Time     time.Time      `json:"time"`
+	Test     bool           `json:"test"`
+	Firing   []alerts.Alert `json:"firing"`
+	Resolved []alerts.Alert `json:"resolved"`
+}
+
+// NewNotification группирует изменения оповещений в одно сообщение
+func NewNotification(changed []alerts.Alert, now time.Time) Notification {
+	hostname, _ := os.Hostname()
+	n := Notification{Server: hostname, Time: now, Firing: []alerts.Alert{}, Resolved: []alerts.Alert{}}
+
+	for _, alert := range changed {
+		if alert.State == alerts.StateResolved {
+			n.Resolved = append(n.Resolved, alert)
+		} else {
+			n.Firing = append(n.Firing, alert)
+		}
+	}
+
+	n.Status = "RESOLVED"
+	if len(n.Firing) > 0 {
+		n.Status = "FIRING"
+	}
+	return n
+}
+
+// TestNotification возвращает пробное сообщение с одним выдуманным оповещением
+func TestNotification(now time.Time) Notification {
+	n := NewNotification([]alerts.Alert{{
+		Rule:      "test",
+		Metric:    "test",
+		Severity:  alerts.SeverityWarning,
+		State:     alerts.StateFiring,
+		Message:   "test: this is a test alert",
+		StartedAt: now,
+		FiredAt:   now,
+	}}, now)
+	n.Status = "TEST"
+	n.Test = true
+	return n
+}
+
+// Notifier - канал доставки уведомлений
+type Notifier interface {
+	Name() string
+	Send(ctx context.Context, n Notification) error
+}
+
+// New создает канал уведомлений по конфигурации
+func New(cfg types.NotifierConfig) (Notifier, error) {
+	r, err := newRenderer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.Type {
+	case "webhook":
+		return newWebhook(cfg, r)
+	case "smtp":
+		return newSMTP(cfg, r)
+	case "telegram":
+		return newTelegram(cfg, r)
+	}
+	return nil, fmt.Errorf("notifier %q: unsupported type %q", cfg.Name, cfg.Type)
+}
+
+// renderer формирует тему и текст сообщения по шаблонам канала
+type renderer struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+func newRenderer(cfg types.NotifierConfig) (*renderer, error) {
+	subjectText := cfg.SubjectTemplate
+	if subjectText == "" {
+		subjectText = DefaultSubjectTemplate
+	}
+	bodyText := cfg.BodyTemplate
+	if bodyText == "" {
+		bodyText = DefaultBodyTemplate
+	}
+	// В ini файле перевод строки в шаблоне задается как \n
+	bodyText = strings.ReplaceAll(bodyText, `\n`, "\n")
+
+	subject, err := template.New("subject").Parse(subjectText)
+	if err != nil {
+		return nil, fmt.Errorf("notifier %q: subject template: %w", cfg.Name, err)
+	}
+	body, err := template.New("body").Parse(bodyText)
+	if err != nil {
+		return nil, fmt.Errorf("notifier %q: body template: %w", cfg.Name, err)
+	}
+	return &renderer{subject: subject, body: body}, nil
+}
+
+func (r *renderer) render(n Notification) (string, string, error) {
+	var subject, body bytes.Buffer
+	if err := r.subject.Execute(&subject, n); err != nil {
+		return "", "", err
+	}
+	if err := r.body.Execute(&body, n); err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(subject.String()), strings.TrimSpace(body.String()), nil
+}
+
+// splitList разбирает список значений через запятую
+func splitList(spec string) []string {
+	var values []string
+	for _, value := range strings.Split(spec, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
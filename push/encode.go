@@ -0,0 +1,149 @@
+package push
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Форматы отправки
+const (
+	FormatInflux = "influx"
+	FormatStatsD = "statsd"
+)
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxTagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	statsdNameReplacer       = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", " ", "_", "\n", "_")
+	statsdTagReplacer        = strings.NewReplacer(":", "_", "|", "_", ",", "_", "#", "_", " ", "_", "\n", "_")
+)
+
+// encodeInflux кодирует точки в InfluxDB line protocol, по строке на точку
+func encodeInflux(points []Point, prefix string, tags map[string]string) []string {
+	lines := make([]string, 0, len(points))
+
+	for _, p := range points {
+		var b strings.Builder
+		b.WriteString(influxMeasurementEscaper.Replace(joinName(prefix, p.Name, "_")))
+
+		merged := mergeTags(tags, p.Tags)
+		for _, key := range sortedKeys(merged) {
+			if merged[key] == "" {
+				continue
+			}
+			b.WriteByte(',')
+			b.WriteString(influxTagEscaper.Replace(key))
+			b.WriteByte('=')
+			b.WriteString(influxTagEscaper.Replace(merged[key]))
+		}
+
+		b.WriteByte(' ')
+		for i, key := range sortedFieldKeys(p.Fields) {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(influxTagEscaper.Replace(key))
+			b.WriteByte('=')
+			b.WriteString(strconv.FormatFloat(p.Fields[key], 'f', -1, 64))
+		}
+
+		b.WriteByte(' ')
+		b.WriteString(strconv.FormatInt(p.Time.UnixNano(), 10))
+		lines = append(lines, b.String())
+	}
+
+	return lines
+}
+
+// encodeStatsD кодирует точки в StatsD с тегами в формате DogStatsD (|#key:value).
+// Значения отправляются как gauge, каждое событие дополнительно увеличивает
+// счетчик, а длительности событий (поля с суффиксом _s) уходят как timing в мс.
+func encodeStatsD(points []Point, prefix string, tags map[string]string) []string {
+	var lines []string
+
+	for _, p := range points {
+		name := statsdNameReplacer.Replace(joinName(prefix, p.Name, "."))
+		suffix := statsdTags(mergeTags(tags, p.Tags))
+
+		if p.Event {
+			lines = append(lines, name+".count:1|c"+suffix)
+		}
+
+		for _, key := range sortedFieldKeys(p.Fields) {
+			field, value, kind := key, p.Fields[key], "|g"
+			if seconds, ok := strings.CutSuffix(key, "_s"); ok && p.Event {
+				field, value, kind = seconds+"_ms", value*1000, "|ms"
+			}
+			formatted := strconv.FormatFloat(value, 'f', -1, 64)
+			lines = append(lines, name+"."+statsdNameReplacer.Replace(field)+":"+formatted+kind+suffix)
+		}
+	}
+
+	return lines
+}
+
+func statsdTags(tags map[string]string) string {
+	var parts []string
+	for _, key := range sortedKeys(tags) {
+		if tags[key] == "" {
+			continue
+		}
+		parts = append(parts, statsdTagReplacer.Replace(key)+":"+statsdTagReplacer.Replace(tags[key]))
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+	return "|#" + strings.Join(parts, ",")
+}
+
+func joinName(prefix, name, sep string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + sep + name
+}
+
+// mergeTags объединяет глобальные теги с тегами точки, теги точки имеют приоритет
+func mergeTags(global, local map[string]string) map[string]string {
+	merged := make(map[string]string, len(global)+len(local))
+	for key, value := range global {
+		merged[key] = value
+	}
+	for key, value := range local {
+		merged[key] = value
+	}
+	return merged
+}
+
+// ParseTags разбирает список тегов вида "key=value,key2=value2"
+func ParseTags(spec string) map[string]string {
+	tags := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			continue
+		}
+		tags[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return tags
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedFieldKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
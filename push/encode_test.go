@@ -0,0 +1,114 @@
+package push
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var testTime = time.Unix(1760788800, 5)
+
+func TestEncodeInflux(t *testing.T) {
+	tests := []struct {
+		name   string
+		point  Point
+		prefix string
+		tags   map[string]string
+		want   string
+	}{
+		{
+			name:   "prefix and sorted tags and fields",
+			point:  Point{Name: "system", Fields: map[string]float64{"up": 1, "cpu_usage": 12.5}, Time: testTime},
+			prefix: "asterisk",
+			tags:   map[string]string{"host": "pbx1", "dc": "msk"},
+			want:   "asterisk_system,dc=msk,host=pbx1 cpu_usage=12.5,up=1 1760788800000000005",
+		},
+		{
+			name:  "escaping",
+			point: Point{Name: "disk usage,all", Tags: map[string]string{"mount": "/var/lib my,disk=1"}, Fields: map[string]float64{"used pct": 42}, Time: testTime},
+			want:  `disk\ usage\,all,mount=/var/lib\ my\,disk\=1 used\ pct=42 1760788800000000005`,
+		},
+		{
+			name:  "point tags override global, empty tags skipped",
+			point: Point{Name: "peer", Tags: map[string]string{"host": "pbx2", "peer_host": ""}, Fields: map[string]float64{"up": 0}, Time: testTime},
+			tags:  map[string]string{"host": "pbx1"},
+			want:  "peer,host=pbx2 up=0 1760788800000000005",
+		},
+		{
+			name:  "float formatting",
+			point: Point{Name: "process", Fields: map[string]float64{"rss_bytes": 123456789, "ratio": 0.000125}, Time: testTime},
+			want:  "process ratio=0.000125,rss_bytes=123456789 1760788800000000005",
+		},
+	}
+	for _, tt := range tests {
+		lines := encodeInflux([]Point{tt.point}, tt.prefix, tt.tags)
+		if len(lines) != 1 || lines[0] != tt.want {
+			t.Errorf("%s:\n got %q\nwant %q", tt.name, lines, tt.want)
+		}
+	}
+}
+
+func TestEncodeStatsD(t *testing.T) {
+	tests := []struct {
+		name   string
+		point  Point
+		prefix string
+		tags   map[string]string
+		want   []string
+	}{
+		{
+			name:   "gauges with DogStatsD tags",
+			point:  Point{Name: "system", Fields: map[string]float64{"up": 1, "cpu_usage": 12.5}},
+			prefix: "asterisk",
+			tags:   map[string]string{"host": "pbx1", "dc": ""},
+			want: []string{
+				"asterisk.system.cpu_usage:12.5|g|#host:pbx1",
+				"asterisk.system.up:1|g|#host:pbx1",
+			},
+		},
+		{
+			name:  "no tags",
+			point: Point{Name: "channels", Fields: map[string]float64{"count": 3}},
+			want:  []string{"channels.count:3|g"},
+		},
+		{
+			name:  "reserved characters in names and tags",
+			point: Point{Name: "disk usage", Tags: map[string]string{"mount": "/var|lib:#1,2"}, Fields: map[string]float64{"used@pct": 42}},
+			want:  []string{"disk_usage.used_pct:42|g|#mount:/var_lib__1_2"},
+		},
+		{
+			name: "event counter and timing",
+			point: Point{
+				Name:   "call_end",
+				Tags:   map[string]string{"peer": "trunk1"},
+				Fields: map[string]float64{"duration_s": 65, "rx_loss_pct": 1.5},
+				Event:  true,
+			},
+			prefix: "asterisk",
+			want: []string{
+				"asterisk.call_end.count:1|c|#peer:trunk1",
+				"asterisk.call_end.duration_ms:65000|ms|#peer:trunk1",
+				"asterisk.call_end.rx_loss_pct:1.5|g|#peer:trunk1",
+			},
+		},
+		{
+			// Поле с суффиксом _s у обычной точки остается gauge
+			name:  "seconds field of a gauge point",
+			point: Point{Name: "process", Fields: map[string]float64{"uptime_s": 30}},
+			want:  []string{"process.uptime_s:30|g"},
+		},
+	}
+	for _, tt := range tests {
+		got := encodeStatsD([]Point{tt.point}, tt.prefix, tt.tags)
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s:\n got %q\nwant %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	got := ParseTags(" host = pbx1 ,dc=msk,broken,=empty,")
+	if len(got) != 2 || got["host"] != "pbx1" || got["dc"] != "msk" {
+		t.Errorf("ParseTags = %v, want host and dc", got)
+	}
+}
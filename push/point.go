@@ -0,0 +1,187 @@
+package push
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	monitor "asterisk-monitor/monitors"
+	"asterisk-monitor/types"
+)
+
+// Point - одно измерение для отправки во внешнюю систему метрик
+type Point struct {
+	Name   string
+	Tags   map[string]string
+	Fields map[string]float64
+	Time   time.Time
+	// Event - разовое событие (например, завершение вызова), а не текущее значение
+	Event bool
+}
+
+// SystemPoints возвращает измерение системных метрик
+func SystemPoints(metrics types.SystemMetrics, ts time.Time) []Point {
+	up := 0.0
+	if metrics.ServiceState == "active" {
+		up = 1
+	}
+
+	points := []Point{{
+		Name: "system",
+		Fields: map[string]float64{
+			"cpu_usage":    metrics.CPUUsage,
+			"memory_usage": metrics.MemoryUsage,
+			"disk_usage":   metrics.DiskUsage,
+			"active_calls": float64(metrics.ActiveCalls),
+			"peers_online": float64(metrics.OnlinePeers),
+			"peers_total":  float64(metrics.TotalPeers),
+			"up":           up,
+			"load1":        metrics.Load1,
+		},
+		Time: ts,
+	}}
+
+	if p := metrics.Process; p != nil {
+		fields := map[string]float64{
+			"cpu_percent":          p.CPUPercent,
+			"rss_bytes":            float64(p.RSSBytes),
+			"threads":              float64(p.Threads),
+			"ctx_switches_per_sec": p.CtxSwitchesPerSec,
+		}
+		if p.OpenFDs >= 0 {
+			fields["open_fds"] = float64(p.OpenFDs)
+		}
+		points = append(points, Point{Name: "process", Fields: fields, Time: ts})
+	}
+
+	for _, mount := range metrics.Mounts {
+		if mount.Error != "" {
+			continue
+		}
+		points = append(points, Point{
+			Name:   "disk",
+			Tags:   map[string]string{"mount": mount.Path},
+			Fields: map[string]float64{"used_pct": mount.UsedPct, "avail_bytes": float64(mount.AvailBytes)},
+			Time:   ts,
+		})
+	}
+
+	return points
+}
+
+// PeerPoints возвращает измерения доступности и задержки SIP пиров. Статус
+// передается полем up, а не тегом: текст статуса создавал бы новый ряд на
+// каждое его значение
+func PeerPoints(peers []types.SIPPeer, ts time.Time) []Point {
+	var points []Point
+
+	for _, peer := range peers {
+		fields := map[string]float64{"up": monitor.PeerUpValue(peer)}
+		if latency, ok := monitor.PeerLatencyMs(peer); ok {
+			fields["latency_ms"] = latency
+		}
+
+		points = append(points, Point{
+			Name:   "peer",
+			Tags:   map[string]string{"peer": peer.Name, "peer_host": peer.Host},
+			Fields: fields,
+			Time:   ts,
+		})
+	}
+
+	return points
+}
+
+// ChannelPoints возвращает количество каналов по состояниям
+func ChannelPoints(channels []types.ChannelInfo, ts time.Time) []Point {
+	counts := make(map[string]int)
+	for _, channel := range channels {
+		counts[channel.State]++
+	}
+
+	points := []Point{{
+		Name:   "channels",
+		Tags:   map[string]string{"state": "all"},
+		Fields: map[string]float64{"count": float64(len(channels))},
+		Time:   ts,
+	}}
+	for state, count := range counts {
+		points = append(points, Point{
+			Name:   "channels",
+			Tags:   map[string]string{"state": state},
+			Fields: map[string]float64{"count": float64(count)},
+			Time:   ts,
+		})
+	}
+
+	return points
+}
+
+// CallEndPoints возвращает события завершения вызовов с итоговым качеством RTP
+func CallEndPoints(calls []types.CallQuality, ts time.Time) []Point {
+	var points []Point
+
+	for _, call := range calls {
+		points = append(points, Point{
+			Name: "call_end",
+			Tags: map[string]string{"peer": call.Peer},
+			Fields: map[string]float64{
+				"duration_s":  float64(durationSeconds(call.Duration)),
+				"rx_packets":  float64(call.RxPackets),
+				"rx_lost":     float64(call.RxLost),
+				"rx_loss_pct": call.RxLossPct,
+				"rx_jitter":   call.RxJitter,
+				"tx_packets":  float64(call.TxPackets),
+				"tx_lost":     float64(call.TxLost),
+				"tx_loss_pct": call.TxLossPct,
+				"tx_jitter":   call.TxJitter,
+			},
+			Time:  ts,
+			Event: true,
+		})
+	}
+
+	return points
+}
+
+// CallTracker отслеживает активные вызовы между опросами, чтобы определить завершенные
+type CallTracker struct {
+	active map[string]types.CallQuality
+}
+
+// NewCallTracker создает пустой трекер вызовов
+func NewCallTracker() *CallTracker {
+	return &CallTracker{active: make(map[string]types.CallQuality)}
+}
+
+// Observe запоминает текущие вызовы и возвращает последнюю статистику
+// вызовов, которые пропали с прошлого опроса
+func (t *CallTracker) Observe(calls []types.CallQuality) []types.CallQuality {
+	current := make(map[string]types.CallQuality, len(calls))
+	for _, call := range calls {
+		current[call.CallID] = call
+	}
+
+	var ended []types.CallQuality
+	for id, call := range t.active {
+		if _, ok := current[id]; !ok {
+			ended = append(ended, call)
+		}
+	}
+
+	t.active = current
+	return ended
+}
+
+// durationSeconds переводит длительность HH:MM:SS в секунды
+func durationSeconds(duration string) int {
+	total := 0
+	for _, part := range strings.Split(duration, ":") {
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return 0
+		}
+		total = total*60 + value
+	}
+	return total
+}
@@ -0,0 +1,57 @@
+package push
+
+import (
+	"testing"
+
+	"asterisk-monitor/types"
+)
+
+func TestPeerPoints(t *testing.T) {
+	peers := []types.SIPPeer{
+		{Name: "trunk1", Host: "10.0.0.1", Status: "OK", Latency: "12 ms"},
+		{Name: "100", Host: "(Unspecified)", Status: "UNREACHABLE"},
+	}
+
+	points := PeerPoints(peers, testTime)
+	if len(points) != 2 {
+		t.Fatalf("got %d points, want 2", len(points))
+	}
+	for _, p := range points {
+		// Текст статуса не должен попадать в теги и порождать новые ряды
+		if _, ok := p.Tags["status"]; ok {
+			t.Errorf("%s: status tag %q", p.Tags["peer"], p.Tags["status"])
+		}
+	}
+
+	if up := points[0].Fields["up"]; up != 1 {
+		t.Errorf("trunk1 up = %v, want 1", up)
+	}
+	if latency, ok := points[0].Fields["latency_ms"]; !ok || latency != 12 {
+		t.Errorf("trunk1 latency_ms = %v, %v, want 12", latency, ok)
+	}
+	if up := points[1].Fields["up"]; up != 0 {
+		t.Errorf("100 up = %v, want 0", up)
+	}
+	if _, ok := points[1].Fields["latency_ms"]; ok {
+		t.Error("latency for an unreachable peer")
+	}
+}
+
+func TestCallTrackerReportsEndedCalls(t *testing.T) {
+	tracker := NewCallTracker()
+	first := types.CallQuality{CallID: "a", Peer: "trunk1", Duration: "00:01:05"}
+	second := types.CallQuality{CallID: "b", Peer: "100"}
+
+	if ended := tracker.Observe([]types.CallQuality{first, second}); len(ended) != 0 {
+		t.Fatalf("ended = %+v on the first poll", ended)
+	}
+	ended := tracker.Observe([]types.CallQuality{second})
+	if len(ended) != 1 || ended[0].CallID != "a" {
+		t.Fatalf("ended = %+v, want call a", ended)
+	}
+
+	points := CallEndPoints(ended, testTime)
+	if len(points) != 1 || !points[0].Event || points[0].Fields["duration_s"] != 65 {
+		t.Errorf("points = %+v, want a call_end event of 65 s", points)
+	}
+}
@@ -0,0 +1,109 @@
+package push
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"asterisk-monitor/types"
+)
+
+// Sink накапливает точки и отправляет их пачками. Точки, которые не удалось
+// отправить, остаются в буфере ограниченного размера до следующей попытки.
+type Sink struct {
+	mu         sync.Mutex
+	format     string
+	prefix     string
+	tags       map[string]string
+	transport  transport
+	batchSize  int
+	bufferSize int
+	buffer     []Point
+	dropped    int
+}
+
+// New создает получатель по секции [push] конфигурации
+func New(cfg types.PushConfig) (*Sink, error) {
+	if cfg.Format != FormatInflux && cfg.Format != FormatStatsD {
+		return nil, fmt.Errorf("unsupported push format %q", cfg.Format)
+	}
+
+	t, err := newTransport(cfg.Endpoint, cfg.Token)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := t.(*udpTransport); !ok && cfg.Format == FormatStatsD {
+		return nil, fmt.Errorf("statsd format requires an udp:// endpoint")
+	}
+
+	s := &Sink{
+		format:     cfg.Format,
+		prefix:     cfg.Prefix,
+		tags:       ParseTags(cfg.Tags),
+		transport:  t,
+		batchSize:  cfg.BatchSize,
+		bufferSize: cfg.BufferSize,
+	}
+	if s.batchSize <= 0 {
+		s.batchSize = 500
+	}
+	if s.bufferSize < s.batchSize {
+		s.bufferSize = s.batchSize
+	}
+	return s, nil
+}
+
+// Add добавляет точки в буфер. При переполнении отбрасываются самые старые.
+func (s *Sink) Add(points ...Point) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buffer = append(s.buffer, points...)
+	if overflow := len(s.buffer) - s.bufferSize; overflow > 0 {
+		s.buffer = append(s.buffer[:0:0], s.buffer[overflow:]...)
+		s.dropped += overflow
+	}
+}
+
+// Flush отправляет буфер пачками по batchSize точек. При ошибке
+// неотправленные точки остаются в буфере.
+func (s *Sink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.buffer) > 0 {
+		n := s.batchSize
+		if n > len(s.buffer) {
+			n = len(s.buffer)
+		}
+
+		if err := s.transport.Send(ctx, s.encode(s.buffer[:n])); err != nil {
+			return err
+		}
+		s.buffer = s.buffer[n:]
+	}
+
+	s.buffer = nil
+	return nil
+}
+
+// Pending возвращает количество неотправленных точек
+func (s *Sink) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buffer)
+}
+
+// Dropped возвращает количество точек, отброшенных из-за переполнения буфера
+func (s *Sink) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+func (s *Sink) encode(points []Point) []string {
+	if s.format == FormatStatsD {
+		return encodeStatsD(points, s.prefix, s.tags)
+	}
+	return encodeInflux(points, s.prefix, s.tags)
+}
@@ -0,0 +1,116 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxDatagram - максимальный размер UDP пакета, чтобы не превышать типичный MTU
+const maxDatagram = 1400
+
+// transport доставляет закодированные строки получателю
+type transport interface {
+	Send(ctx context.Context, lines []string) error
+}
+
+// newTransport выбирает транспорт по схеме адреса: udp://host:port или http(s)://...
+func newTransport(endpoint, token string) (transport, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+
+	switch u.Scheme {
+	case "udp":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid endpoint %q: missing host", endpoint)
+		}
+		return &udpTransport{addr: u.Host}, nil
+	case "http", "https":
+		return &httpTransport{
+			url:    endpoint,
+			token:  token,
+			client: &http.Client{Timeout: 10 * time.Second},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported endpoint scheme %q", u.Scheme)
+}
+
+type udpTransport struct {
+	addr string
+}
+
+// Send отправляет строки пакетами, не разрывая строки между пакетами
+func (t *udpTransport) Send(ctx context.Context, lines []string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", t.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetWriteDeadline(deadline)
+	}
+
+	var packet bytes.Buffer
+	flush := func() error {
+		if packet.Len() == 0 {
+			return nil
+		}
+		_, err := conn.Write(packet.Bytes())
+		packet.Reset()
+		return err
+	}
+
+	for _, line := range lines {
+		if packet.Len() > 0 && packet.Len()+len(line)+1 > maxDatagram {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		packet.WriteString(line)
+		packet.WriteByte('\n')
+	}
+
+	return flush()
+}
+
+type httpTransport struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// Send отправляет строки одним POST запросом (InfluxDB /write или /api/v2/write)
+func (t *httpTransport) Send(ctx context.Context, lines []string) error {
+	body := strings.Join(lines, "\n") + "\n"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if t.token != "" {
+		req.Header.Set("Authorization", "Token "+t.token)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("push endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
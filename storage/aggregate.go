@@ -0,0 +1,38 @@
+package storage
+
+import "asterisk-monitor/types"
+
+// Aggregate - сводка по набору измерений
+type Aggregate struct {
+	Count int
+	Min   float64
+	Max   float64
+	Avg   float64
+	Last  float64
+}
+
+// Summarize вычисляет минимум, максимум, среднее и последнее значение
+func Summarize(samples []types.Sample) Aggregate {
+	var agg Aggregate
+	if len(samples) == 0 {
+		return agg
+	}
+
+	sum := 0.0
+	agg.Min = samples[0].Value
+	agg.Max = samples[0].Value
+	for _, sample := range samples {
+		if sample.Value < agg.Min {
+			agg.Min = sample.Value
+		}
+		if sample.Value > agg.Max {
+			agg.Max = sample.Value
+		}
+		sum += sample.Value
+	}
+
+	agg.Count = len(samples)
+	agg.Avg = sum / float64(len(samples))
+	agg.Last = samples[len(samples)-1].Value
+	return agg
+}
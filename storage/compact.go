@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+const (
+	// Сырые данные хранятся за сегодня и вчера, более старые прореживаются
+	rawKeepDays = 1
+
+	// Шаг прореживания старых данных
+	downsampleStep = 5 * time.Minute
+)
+
+// Compact прореживает старые сырые сегменты до 5-минутных средних
+// и удаляет сегменты старше срока хранения. Без блокировки писателя
+// ничего не делает.
+func (s *Store) Compact(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.compactLocked(now)
+}
+
+func (s *Store) compactLocked(now time.Time) error {
+	s.lastCompact = now
+	if !s.Writer() {
+		return nil
+	}
+
+	segments, err := s.segments()
+	if err != nil {
+		return err
+	}
+
+	today := truncateDay(now)
+	rawCutoff := today.AddDate(0, 0, -rawKeepDays)
+
+	for _, seg := range segments {
+		if s.retention > 0 && seg.day.Before(today.AddDate(0, 0, -s.retention)) {
+			if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+
+		if seg.downsampled || !seg.day.Before(rawCutoff) {
+			continue
+		}
+
+		if err := s.downsample(seg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// downsampledSample - запись сегмента средних: среднее значение и число
+// сырых точек в интервале, чтобы опоздавшие данные объединялись с учетом веса
+type downsampledSample struct {
+	types.Sample
+	Count int `json:"c,omitempty"`
+}
+
+// downsample заменяет сырой сегмент сегментом 5-минутных средних
+func (s *Store) downsample(seg segment) error {
+	day := seg.day.Format(segmentDateLayout)
+	if s.currentDay == day && s.current != nil {
+		s.current.Close()
+		s.current = nil
+		s.currentDay = ""
+	}
+
+	type bucket struct {
+		sample types.Sample
+		sum    float64
+		count  int
+	}
+	buckets := make(map[string]*bucket)
+
+	add := func(sample types.Sample, count int) {
+		start := sample.Timestamp.Truncate(downsampleStep)
+		key := SeriesKey(sample.Metric, sample.Labels) + "@" + start.Format(time.RFC3339)
+
+		b, ok := buckets[key]
+		if !ok {
+			b = &bucket{sample: sample}
+			b.sample.Timestamp = start
+			buckets[key] = b
+		}
+		b.sum += sample.Value * float64(count)
+		b.count += count
+	}
+
+	// Если сегмент средних уже существует (например, после опоздавшей записи), объединяем.
+	// Средние без числа точек записаны старой версией и считаются одной точкой.
+	target := filepath.Join(s.dir, day+downsampleSuffix)
+	err := readSegment(target, func(sample downsampledSample) {
+		add(sample.Sample, max(sample.Count, 1))
+	})
+	if err != nil {
+		return err
+	}
+	err = readSegment(seg.path, func(sample types.Sample) {
+		add(sample, 1)
+	})
+	if err != nil {
+		return err
+	}
+
+	result := make([]downsampledSample, 0, len(buckets))
+	for _, b := range buckets {
+		b.sample.Value = b.sum / float64(b.count)
+		result = append(result, downsampledSample{Sample: b.sample, Count: b.count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Timestamp.Before(result[j].Timestamp)
+	})
+
+	tmp := target + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	for _, sample := range result {
+		if err := encoder.Encode(sample); err != nil {
+			file.Close()
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, target); err != nil {
+		return err
+	}
+	return os.Remove(seg.path)
+}
+
+// SeriesKey возвращает уникальный ключ ряда: имя метрики и отсортированные метки
+func SeriesKey(metric string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return metric
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+labels[k])
+	}
+	return metric + "{" + strings.Join(parts, ",") + "}"
+}
@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"os"
+	"sync"
+	"syscall"
+)
+
+// WriterLock выбирает единственного писателя общего файла, когда с ним работают
+// и интерфейс, и фоновый режим. Блокировка flock держится до завершения процесса
+// и снимается ядром даже после сбоя, поэтому второй процесс может перехватить
+// запись, повторив Acquire.
+type WriterLock struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// NewWriterLock создает блокировку на файле path. Файл создается при первом Acquire.
+func NewWriterLock(path string) *WriterLock {
+	return &WriterLock{path: path}
+}
+
+// Acquire пытается получить блокировку без ожидания и сообщает, удерживает ли
+// ее этот процесс. Повторный вызов у владельца сразу возвращает true.
+func (l *WriterLock) Acquire() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file != nil {
+		return true
+	}
+
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return false
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		return false
+	}
+	l.file = file
+	return true
+}
+
+// Release снимает блокировку, если она удерживается
+func (l *WriterLock) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return
+	}
+	// Закрытие файла снимает flock
+	l.file.Close()
+	l.file = nil
+}
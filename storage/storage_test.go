@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+var testNow = time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local)
+
+func openTestStore(t *testing.T, dir string, retentionDays int) *Store {
+	t.Helper()
+	s, err := Open(dir, retentionDays)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func appendSamples(t *testing.T, s *Store, samples ...types.Sample) {
+	t.Helper()
+	if err := s.Append(samples...); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+}
+
+func queryValues(t *testing.T, s *Store, from, to time.Time) []types.Sample {
+	t.Helper()
+	samples, err := s.Query(types.MetricCPUUsage, from, to)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	return samples
+}
+
+func cpuSample(value float64, at time.Time) types.Sample {
+	return types.Sample{Metric: types.MetricCPUUsage, Value: value, Timestamp: at}
+}
+
+func TestCompactMergesLatePointsByWeight(t *testing.T) {
+	s := openTestStore(t, t.TempDir(), 0)
+	bucket := testNow.AddDate(0, 0, -3).Truncate(downsampleStep)
+
+	appendSamples(t, s, cpuSample(1, bucket), cpuSample(2, bucket.Add(time.Minute)), cpuSample(3, bucket.Add(2*time.Minute)))
+	if err := s.Compact(testNow); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	// Опоздавшая точка того же интервала объединяется с уже прореженными
+	appendSamples(t, s, cpuSample(10, bucket.Add(3*time.Minute)))
+	if err := s.Compact(testNow); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	got := queryValues(t, s, bucket.Add(-time.Hour), bucket.Add(time.Hour))
+	if len(got) != 1 || got[0].Value != 4 || !got[0].Timestamp.Equal(bucket) {
+		t.Fatalf("got %+v, want one average 4 at %v", got, bucket)
+	}
+
+	raw := filepath.Join(s.Dir(), bucket.Format(segmentDateLayout)+rawSuffix)
+	if _, err := os.Stat(raw); !os.IsNotExist(err) {
+		t.Errorf("raw segment not removed after compaction: %v", err)
+	}
+}
+
+func TestCompactLegacySegmentWithoutCount(t *testing.T) {
+	dir := t.TempDir()
+	day := testNow.AddDate(0, 0, -3)
+	bucket := day.Truncate(downsampleStep)
+
+	// Сегмент средних без числа точек считается одной точкой на интервал
+	legacy := `{"m":"cpu_usage","v":2,"t":"` + bucket.Format(time.RFC3339) + `"}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, day.Format(segmentDateLayout)+downsampleSuffix), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := openTestStore(t, dir, 0)
+	appendSamples(t, s, cpuSample(4, bucket.Add(time.Minute)))
+	if err := s.Compact(testNow); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	got := queryValues(t, s, bucket, bucket.Add(time.Hour))
+	if len(got) != 1 || got[0].Value != 3 {
+		t.Fatalf("got %+v, want one average 3", got)
+	}
+}
+
+func TestCompactRetention(t *testing.T) {
+	s := openTestStore(t, t.TempDir(), 3)
+
+	appendSamples(t, s,
+		cpuSample(1, testNow.AddDate(0, 0, -5)),
+		cpuSample(2, testNow.AddDate(0, 0, -3)),
+		cpuSample(3, testNow.AddDate(0, 0, -2)),
+		cpuSample(4, testNow),
+	)
+	if err := s.Compact(testNow); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	segments, err := s.segments()
+	if err != nil {
+		t.Fatalf("segments: %v", err)
+	}
+	var names []string
+	for _, seg := range segments {
+		names = append(names, filepath.Base(seg.path))
+	}
+	want := []string{
+		testNow.AddDate(0, 0, -3).Format(segmentDateLayout) + downsampleSuffix,
+		testNow.AddDate(0, 0, -2).Format(segmentDateLayout) + downsampleSuffix,
+		testNow.Format(segmentDateLayout) + rawSuffix,
+	}
+	if len(names) != len(want) {
+		t.Fatalf("segments = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("segment %d = %s, want %s", i, names[i], want[i])
+		}
+	}
+
+	got := queryValues(t, s, testNow.AddDate(0, 0, -10), testNow)
+	if len(got) != 3 || got[0].Value != 2 {
+		t.Errorf("got %+v, want values 2, 3, 4", got)
+	}
+}
+
+func TestQueryAcrossSegments(t *testing.T) {
+	s := openTestStore(t, t.TempDir(), 0)
+	old := testNow.AddDate(0, 0, -3).Truncate(downsampleStep)
+
+	appendSamples(t, s,
+		cpuSample(10, old),
+		cpuSample(20, old.Add(time.Minute)),
+		types.Sample{Metric: types.MetricMemoryUsage, Value: 50, Timestamp: old},
+		cpuSample(7, testNow.Add(-time.Minute)),
+		cpuSample(5, testNow.Add(-2*time.Minute)),
+		cpuSample(9, testNow.Add(time.Minute)),
+	)
+	if err := s.Compact(testNow); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	got := queryValues(t, s, old.Add(-time.Hour), testNow)
+	want := []types.Sample{
+		cpuSample(15, old), // 5-минутное среднее
+		cpuSample(5, testNow.Add(-2*time.Minute)),
+		cpuSample(7, testNow.Add(-time.Minute)),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].Value != want[i].Value || !got[i].Timestamp.Equal(want[i].Timestamp) {
+			t.Errorf("sample %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// Интервал внутри дня не захватывает прореженные данные
+	if got := queryValues(t, s, testNow.Add(-time.Hour), testNow.Add(time.Hour)); len(got) != 3 {
+		t.Errorf("today: got %+v, want 3 samples", got)
+	}
+}
+
+func TestSingleWriter(t *testing.T) {
+	dir := t.TempDir()
+	writer := openTestStore(t, dir, 0)
+	reader := openTestStore(t, dir, 0)
+
+	if !writer.Writer() || reader.Writer() {
+		t.Fatalf("writer %v, reader %v, want only the first store to write", writer.Writer(), reader.Writer())
+	}
+
+	appendSamples(t, writer, cpuSample(1, testNow))
+	appendSamples(t, reader, cpuSample(2, testNow))
+
+	// Читатель видит данные писателя, но свои не записывает
+	got := queryValues(t, reader, testNow.Add(-time.Hour), testNow.Add(time.Hour))
+	if len(got) != 1 || got[0].Value != 1 {
+		t.Fatalf("got %+v, want only the writer's sample", got)
+	}
+
+	// После завершения писателя запись перехватывает другой процесс
+	writer.Close()
+	appendSamples(t, reader, cpuSample(3, testNow.Add(time.Minute)))
+	if got := queryValues(t, reader, testNow.Add(-time.Hour), testNow.Add(time.Hour)); len(got) != 2 {
+		t.Errorf("got %+v, want the reader to take over writing", got)
+	}
+}
@@ -0,0 +1,274 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+const (
+	// Формат даты в имени сегмента: 2006-01-02.raw.seg
+	segmentDateLayout = "2006-01-02"
+
+	rawSuffix        = ".raw.seg"
+	downsampleSuffix = ".5m.seg"
+
+	// Файл блокировки писателя в каталоге хранилища
+	lockFile = "store.lock"
+
+	// Как часто Append запускает компакцию
+	compactEvery = time.Hour
+)
+
+// Store - встраиваемое хранилище временных рядов.
+// Данные пишутся в append-only сегменты по одному файлу на день
+// в формате JSON lines. Старые сегменты прореживаются до 5-минутных
+// средних и удаляются по истечении срока хранения.
+//
+// Интерфейс и фоновый режим могут работать с одним каталогом. Пишет и
+// прореживает сегменты только владелец блокировки каталога, у остальных
+// процессов хранилище доступно только для чтения: иначе каждое измерение
+// записывалось бы дважды, а компакции мешали бы друг другу.
+type Store struct {
+	mu          sync.Mutex
+	dir         string
+	retention   int
+	lock        *WriterLock
+	current     *os.File
+	currentDay  string
+	lastCompact time.Time
+}
+
+// Open открывает (или создает) хранилище в каталоге dir.
+// retentionDays <= 0 отключает удаление старых данных.
+func Open(dir string, retentionDays int) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	s := &Store{
+		dir:       dir,
+		retention: retentionDays,
+		lock:      NewWriterLock(filepath.Join(dir, lockFile)),
+	}
+
+	if err := s.Compact(time.Now()); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Writer сообщает, что этот процесс пишет в хранилище. Если писатель
+// завершился, блокировку получает процесс, который вызовет Writer следующим.
+func (s *Store) Writer() bool {
+	return s.lock.Acquire()
+}
+
+// Dir возвращает каталог хранилища
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// SetRetention изменяет срок хранения данных в днях
+func (s *Store) SetRetention(days int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retention = days
+}
+
+// Append дописывает измерения в сегмент текущего дня. Процесс без блокировки
+// писателя измерения не сохраняет: их записывает владелец блокировки.
+func (s *Store) Append(samples ...types.Sample) error {
+	if len(samples) == 0 || !s.Writer() {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sample := range samples {
+		if sample.Timestamp.IsZero() {
+			sample.Timestamp = time.Now()
+		}
+
+		file, err := s.segmentFor(sample.Timestamp)
+		if err != nil {
+			return err
+		}
+
+		line, err := json.Marshal(sample)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+
+	if time.Since(s.lastCompact) > compactEvery {
+		return s.compactLocked(time.Now())
+	}
+	return nil
+}
+
+// Query возвращает измерения метрики в интервале [from, to], упорядоченные по времени
+func (s *Store) Query(metric string, from, to time.Time) ([]types.Sample, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []types.Sample
+	fromDay := truncateDay(from)
+	for _, seg := range segments {
+		if seg.day.Before(fromDay) || seg.day.After(to) {
+			continue
+		}
+
+		err := readSegment(seg.path, func(sample types.Sample) {
+			if sample.Metric != metric {
+				return
+			}
+			if sample.Timestamp.Before(from) || sample.Timestamp.After(to) {
+				return
+			}
+			result = append(result, sample)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp.Before(result[j].Timestamp)
+	})
+	return result, nil
+}
+
+// Close закрывает текущий сегмент и снимает блокировку писателя
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.lock.Release()
+
+	if s.current == nil {
+		return nil
+	}
+	err := s.current.Close()
+	s.current = nil
+	s.currentDay = ""
+	return err
+}
+
+// segmentFor возвращает открытый сегмент для дня метки времени
+func (s *Store) segmentFor(ts time.Time) (*os.File, error) {
+	day := ts.Format(segmentDateLayout)
+	if s.current != nil && s.currentDay == day {
+		return s.current, nil
+	}
+
+	if s.current != nil {
+		s.current.Close()
+		s.current = nil
+	}
+
+	path := filepath.Join(s.dir, day+rawSuffix)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	s.current = file
+	s.currentDay = day
+	return file, nil
+}
+
+type segment struct {
+	path        string
+	day         time.Time
+	downsampled bool
+}
+
+// segments возвращает список сегментов хранилища, отсортированный по дате
+func (s *Store) segments() ([]segment, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []segment
+	for _, entry := range entries {
+		name := entry.Name()
+
+		var suffix string
+		switch {
+		case strings.HasSuffix(name, rawSuffix):
+			suffix = rawSuffix
+		case strings.HasSuffix(name, downsampleSuffix):
+			suffix = downsampleSuffix
+		default:
+			continue
+		}
+
+		day, err := time.ParseInLocation(segmentDateLayout, strings.TrimSuffix(name, suffix), time.Local)
+		if err != nil {
+			continue
+		}
+
+		result = append(result, segment{
+			path:        filepath.Join(s.dir, name),
+			day:         day,
+			downsampled: suffix == downsampleSuffix,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].day.Before(result[j].day)
+	})
+	return result, nil
+}
+
+// readSegment читает сегмент построчно, пропуская поврежденные строки
+// (например, недописанную строку после аварийного завершения)
+func readSegment[T any](path string, fn func(T)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var sample T
+		if err := json.Unmarshal(scanner.Bytes(), &sample); err != nil {
+			continue
+		}
+		fn(sample)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read segment %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+func truncateDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
@@ -3,11 +3,13 @@ package types
 import "time"
 
 type CheckResult struct {
-    Name      string    `json:"name"`
-    Status    string    `json:"status"` // success, warning, error
-    Message   string    `json:"message"`
-    Error     string    `json:"error,omitempty"`
-    Timestamp time.Time `json:"timestamp"`
+    Name      string        `json:"name"`
+    Status    string        `json:"status"` // success, warning, error
+    Message   string        `json:"message"`
+    Error     string        `json:"error,omitempty"`     // stderr команды или описание ошибки
+    ExitCode  int           `json:"exit_code,omitempty"` // код завершения команды, -1 - не запускалась
+    Duration  time.Duration `json:"duration,omitempty"`  // длительность команды
+    Timestamp time.Time     `json:"timestamp"`
 }
 
 type ChannelInfo struct {
@@ -18,6 +20,14 @@ type ChannelInfo struct {
     Application string `json:"application"`
 }
 
+type SIPRegistration struct {
+    Host     string `json:"host"`
+    Username string `json:"username"`
+    Refresh  int    `json:"refresh"`
+    State    string `json:"state"`
+    RegTime  string `json:"reg_time"`
+}
+
 type SIPPeer struct {
     Name     string `json:"name"`
     Host     string `json:"host"`
@@ -27,16 +37,116 @@ type SIPPeer struct {
 }
 
 type SystemMetrics struct {
-    CPUUsage     float64 `json:"cpu_usage"`
-    MemoryUsage  float64 `json:"memory_usage"`
-    DiskUsage    float64 `json:"disk_usage"`
-    ActiveCalls  int     `json:"active_calls"`
-    TotalPeers   int     `json:"total_peers"`
-    OnlinePeers  int     `json:"online_peers"`
-    Uptime       string  `json:"uptime"`
-    LoadAverage  string  `json:"load_average"`
-    AsteriskPID  string  `json:"asterisk_pid"`
-    ServiceState string  `json:"service_state"`
+    CPUUsage       float64 `json:"cpu_usage"`
+    MemoryUsage    float64 `json:"memory_usage"`
+    DiskUsage      float64 `json:"disk_usage"`
+    ActiveCalls    int     `json:"active_calls"`
+    CallsProcessed int64   `json:"calls_processed"`
+    TotalPeers     int     `json:"total_peers"`
+    OnlinePeers    int     `json:"online_peers"`
+    Uptime         string  `json:"uptime"`
+    LoadAverage    string  `json:"load_average"`
+    Load1          float64 `json:"load1"`
+    AsteriskPID    string  `json:"asterisk_pid"`
+    ServiceState   string  `json:"service_state"`
+
+    Mounts  []MountUsage    `json:"mounts,omitempty"`
+    Dirs    []DirUsage      `json:"dirs,omitempty"`    // каталоги Asterisk, размеры обновляются в фоне
+    Process *ProcessMetrics `json:"process,omitempty"` // nil, если процесс Asterisk не найден
+}
+
+// Состояния процесса Asterisk
+const (
+    AsteriskRunning      = "running"
+    AsteriskNotRunning   = "not running"
+    AsteriskStalePidfile = "stale pidfile"        // pidfile указывает на процесс, которого нет или который не Asterisk
+    AsteriskUnresponsive = "console unresponsive" // процесс есть, но не отвечает на команды CLI
+)
+
+// AsteriskProcess содержит результат поиска процесса Asterisk
+type AsteriskProcess struct {
+    State      string `json:"state"`
+    PID        int    `json:"pid,omitempty"`
+    Exe        string `json:"exe,omitempty"`
+    Cmdline    string `json:"cmdline,omitempty"`
+    WrapperPID int    `json:"wrapper_pid,omitempty"` // safe_asterisk, который перезапускает Asterisk
+    Pidfile    string `json:"pidfile"`
+    Detail     string `json:"detail,omitempty"` // пояснение к состоянию
+}
+
+// UnitStatus содержит состояние systemd юнита Asterisk
+type UnitStatus struct {
+    ActiveState string `json:"active_state"`
+    Result      string `json:"result"`     // success, exit-code, signal, core-dump, watchdog, timeout
+    NRestarts   int    `json:"n_restarts"` // автоматических перезапусков, -1 - неизвестно
+}
+
+// CoreFile описывает файл дампа памяти
+type CoreFile struct {
+    Path    string    `json:"path"`
+    Size    int64     `json:"size"`
+    ModTime time.Time `json:"mod_time"`
+}
+
+// LifecycleSnapshot - состояние Asterisk в момент опроса, по последовательности
+// снимков строится хронология запусков, перезагрузок и сбоев
+type LifecycleSnapshot struct {
+    Time       time.Time       `json:"time"`
+    Process    AsteriskProcess `json:"process"`
+    StartedAt  time.Time       `json:"started_at,omitempty"`  // по core show uptime, нулевое, если консоль не ответила
+    ReloadedAt time.Time       `json:"reloaded_at,omitempty"` // время последней перезагрузки конфигурации
+    Unit       UnitStatus      `json:"unit"`
+    CoreFiles  []CoreFile      `json:"core_files,omitempty"`
+}
+
+// ProcessMetrics содержит использование ресурсов основным процессом Asterisk
+type ProcessMetrics struct {
+    PID                     int     `json:"pid"`
+    CPUPercent              float64 `json:"cpu_percent"` // может превышать 100 на нескольких ядрах
+    RSSBytes                uint64  `json:"rss_bytes"`
+    Threads                 int     `json:"threads"`
+    OpenFDs                 int     `json:"open_fds"` // -1, если каталог fd недоступен
+    MaxFDs                  uint64  `json:"max_fds"`  // мягкий лимит, 0 - без ограничения
+    FDUsagePct              float64 `json:"fd_usage_pct"`
+    VoluntaryCtxSwitches    uint64  `json:"voluntary_ctx_switches"`
+    NonvoluntaryCtxSwitches uint64  `json:"nonvoluntary_ctx_switches"`
+    CtxSwitchesPerSec       float64 `json:"ctx_switches_per_sec"`
+}
+
+// MountUsage содержит заполненность одной файловой системы
+type MountUsage struct {
+    Path       string   `json:"path"`
+    TotalBytes uint64   `json:"total_bytes"`
+    UsedBytes  uint64   `json:"used_bytes"`
+    AvailBytes uint64   `json:"avail_bytes"`
+    UsedPct    float64  `json:"used_pct"`
+    Dirs       []string `json:"dirs,omitempty"` // каталоги Asterisk на этой файловой системе
+    Error      string   `json:"error,omitempty"`
+}
+
+// DirUsage содержит размер каталога Asterisk (записи, логи, данные)
+type DirUsage struct {
+    Path      string    `json:"path"`
+    Mount     string    `json:"mount"` // точка монтирования, на которой лежит каталог
+    SizeBytes uint64    `json:"size_bytes"`
+    Files     int       `json:"files"`
+    ScannedAt time.Time `json:"scanned_at"` // нулевое, пока каталог ни разу не обойден
+    Error     string    `json:"error,omitempty"`
+}
+
+// BackupInfo описывает архив резервной копии
+type BackupInfo struct {
+    Path     string    `json:"path"`
+    Size     int64     `json:"size"`
+    Modified time.Time `json:"modified"`
+}
+
+// CertInfo описывает TLS сертификат и срок его действия
+type CertInfo struct {
+    Path     string    `json:"path"`
+    Subject  string    `json:"subject"`
+    NotAfter time.Time `json:"not_after"`
+    DaysLeft int       `json:"days_left"`
 }
 
 // AsteriskConfig содержит настройки подключения к Asterisk
@@ -45,13 +155,18 @@ type AsteriskConfig struct {
     AMIPort  string `ini:"ami_port" json:"ami_port"`
     Username string `ini:"username" json:"username"`
     Password string `ini:"password" json:"password"`
+    RunDir    string `ini:"run_dir" json:"run_dir"`     // astrundir из asterisk.conf, в нем asterisk.ctl
+    Transport string `ini:"transport" json:"transport"` // socket - управляющий сокет, exec - asterisk -rx на каждую команду
+    DumpDirs  string `ini:"dump_dirs" json:"dump_dirs"` // каталоги дампов памяти через запятую
+    DataDirs  string `ini:"data_dirs" json:"data_dirs"` // каталоги записей, логов и данных для контроля места через запятую
 }
 
 // MonitoringConfig содержит настройки мониторинга
 type MonitoringConfig struct {
-    RefreshInterval int  `ini:"refresh_interval" json:"refresh_interval"`
-    EnableAlerts    bool `ini:"enable_alerts" json:"enable_alerts"`
-    LogRetention    int  `ini:"log_retention" json:"log_retention"`
+    RefreshInterval int    `ini:"refresh_interval" json:"refresh_interval"`
+    EnableAlerts    bool   `ini:"enable_alerts" json:"enable_alerts"`
+    LogRetention    int    `ini:"log_retention" json:"log_retention"`
+    MountPoints     string `ini:"mount_points" json:"mount_points"` // точки монтирования через запятую, первая - основная
 }
 
 // SecurityConfig содержит настройки безопасности
@@ -61,8 +176,376 @@ type SecurityConfig struct {
     CheckSSL       bool `ini:"check_ssl" json:"check_ssl"`
 }
 
+// ExporterConfig содержит настройки Prometheus экспортера
+type ExporterConfig struct {
+    Listen         string `ini:"listen" json:"listen"`
+    Interval       int    `ini:"interval" json:"interval"`
+    ChecksInterval int    `ini:"checks_interval" json:"checks_interval"`
+}
+
+// DaemonConfig содержит расписание фонового режима (интервалы в секундах, 0 - отключено)
+type DaemonConfig struct {
+    MetricsInterval    int    `ini:"metrics_interval" json:"metrics_interval"`
+    QuickCheckInterval int    `ini:"quick_check_interval" json:"quick_check_interval"`
+    FullCheckInterval  int    `ini:"full_check_interval" json:"full_check_interval"`
+    SecurityInterval   int    `ini:"security_interval" json:"security_interval"`
+    FullSecurityScan   bool   `ini:"full_security_scan" json:"full_security_scan"`
+    StateFile          string `ini:"state_file" json:"state_file"`
+}
+
+// PushConfig содержит настройки отправки метрик в InfluxDB или StatsD
+type PushConfig struct {
+    Enabled    bool   `ini:"enabled" json:"enabled"`
+    Format     string `ini:"format" json:"format"`     // influx, statsd
+    Endpoint   string `ini:"endpoint" json:"endpoint"` // udp://host:port, http(s)://host/write?...
+    Token      string `ini:"token" json:"token"`
+    Prefix     string `ini:"prefix" json:"prefix"`
+    Tags       string `ini:"tags" json:"tags"` // key=value,key2=value2
+    Interval   int    `ini:"interval" json:"interval"`
+    BatchSize  int    `ini:"batch_size" json:"batch_size"`
+    BufferSize int    `ini:"buffer_size" json:"buffer_size"`
+}
+
+// AlertRule описывает правило оповещения (секция [alert.<name>] в config.ini)
+type AlertRule struct {
+    Name       string  `ini:"-" json:"name"`
+    Metric     string  `ini:"metric" json:"metric"`
+    Op         string  `ini:"op" json:"op"` // >, >=, <, <=, ==, !=
+    Threshold  float64 `ini:"threshold" json:"threshold"`
+    For        int     `ini:"for" json:"for"`               // секунды до срабатывания
+    Hysteresis float64 `ini:"hysteresis" json:"hysteresis"` // отступ от порога для снятия
+    Severity   string  `ini:"severity" json:"severity"`     // warning, critical
+    Labels     string  `ini:"labels" json:"labels"`         // peer=trunk1,server=pbx1
+}
+
+// NotificationsConfig содержит общие настройки отправки оповещений (интервалы в секундах)
+type NotificationsConfig struct {
+    GroupWait      int `ini:"group_wait" json:"group_wait"`           // ожидание для группировки оповещений
+    RepeatInterval int `ini:"repeat_interval" json:"repeat_interval"` // подавление повторов одного оповещения
+    RateLimit      int `ini:"rate_limit" json:"rate_limit"`           // максимум сообщений в час на канал, 0 - без ограничения
+}
+
+// AnomalyConfig содержит настройки обнаружения аномалий объема вызовов
+// по базовой линии, изученной для каждого часа недели, и утечек памяти Asterisk
+type AnomalyConfig struct {
+    Enabled        bool    `ini:"enabled" json:"enabled"`
+    LearnWeeks     int     `ini:"learn_weeks" json:"learn_weeks"`         // сколько недель истории использовать
+    MinSamples     int     `ini:"min_samples" json:"min_samples"`         // минимум 5-минутных интервалов в часе недели
+    MinExpected    float64 `ini:"min_expected" json:"min_expected"`       // ожидаемый уровень, ниже которого ноль не считается аномалией
+    LeakWindow     int     `ini:"leak_window" json:"leak_window"`         // окно оценки роста памяти Asterisk, часы
+    ForecastWindow int     `ini:"forecast_window" json:"forecast_window"` // окно оценки роста занятого места на дисках, часы
+}
+
+// WatchdogConfig содержит настройки сторожа зависаний Asterisk (интервалы в секундах)
+type WatchdogConfig struct {
+    Enabled         bool   `ini:"enabled" json:"enabled"`
+    Interval        int    `ini:"interval" json:"interval"`
+    ProbeTimeout    int    `ini:"probe_timeout" json:"probe_timeout"`
+    ProbeAMI        bool   `ini:"probe_ami" json:"probe_ami"`
+    Failures        int    `ini:"failures" json:"failures"`                   // неудачных проверок подряд до восстановления
+    Action          string `ini:"action" json:"action"`                       // none - только журнал, restart - перезапуск юнита
+    Unit            string `ini:"unit" json:"unit"`
+    RestartWhenGone bool   `ini:"restart_when_gone" json:"restart_when_gone"` // запускать юнит, если процесса нет
+    MaxAttempts     int    `ini:"max_attempts" json:"max_attempts"`           // попыток за attempt_window
+    AttemptWindow   int    `ini:"attempt_window" json:"attempt_window"`
+    Cooldown        int    `ini:"cooldown" json:"cooldown"`                   // пауза после попытки восстановления
+    Capture         bool   `ini:"capture" json:"capture"`                     // снимать core show locks и стеки потоков
+}
+
+// Результаты проверки отзывчивости Asterisk
+const (
+    HealthOK           = "ok"
+    HealthUnresponsive = "unresponsive" // процесс есть, но не отвечает
+    HealthGone         = "gone"         // процесса нет
+)
+
+// HealthProbe содержит результат проверки отзывчивости Asterisk
+type HealthProbe struct {
+    Time       time.Time       `json:"time"`
+    State      string          `json:"state"`
+    Process    AsteriskProcess `json:"process"`
+    CLILatency time.Duration   `json:"cli_latency"`
+    CLIError   string          `json:"cli_error,omitempty"`
+    AMIChecked bool            `json:"ami_checked"`
+    AMILatency time.Duration   `json:"ami_latency"`
+    AMIError   string          `json:"ami_error,omitempty"`
+}
+
+// Taskprocessor - очередь задач Asterisk из core show taskprocessors.
+// До Asterisk 13.10 уровней нет, LowWater и HighWater равны 0.
+type Taskprocessor struct {
+    Name      string `json:"name"`
+    Processed int64  `json:"processed"`
+    InQueue   int    `json:"in_queue"`
+    MaxDepth  int    `json:"max_depth"`
+    LowWater  int    `json:"low_water"`
+    HighWater int    `json:"high_water"`
+}
+
+// ThreadGroup - потоки Asterisk, запущенные одной функцией
+type ThreadGroup struct {
+    Function string `json:"function"`
+    Count    int    `json:"count"`
+}
+
+// TaskprocessorReport содержит очереди задач и потоки Asterisk
+type TaskprocessorReport struct {
+    Time         time.Time       `json:"time"`
+    Processors   []Taskprocessor `json:"processors"`
+    Threads      int             `json:"threads"` // по core show threads, -1 если неизвестно
+    ThreadGroups []ThreadGroup   `json:"thread_groups"`
+    Error        string          `json:"error,omitempty"`
+}
+
+// Состояния модуля в module show. Asterisk до 12 состояние не выводит.
+const (
+    ModuleRunning    = "Running"
+    ModuleNotRunning = "Not Running"
+    ModuleDeclined   = "Declined"
+)
+
+// AsteriskModule - модуль Asterisk из module show
+type AsteriskModule struct {
+    Name        string `json:"name"`
+    Description string `json:"description"`
+    UseCount    int    `json:"use_count"`
+    Status      string `json:"status"`
+    Support     string `json:"support"` // core, extended, deprecated
+}
+
+// ModuleBaseline - модули, которые должны работать на сервере Host
+type ModuleBaseline struct {
+    Host    string    `json:"host"`
+    SavedAt time.Time `json:"saved_at"`
+    Modules []string  `json:"modules"`
+}
+
+// ModuleDrift - расхождения модулей Asterisk с базовой линией
+type ModuleDrift struct {
+    Missing []string `json:"missing"` // есть в базовой линии, но не загружены
+    Failed  []string `json:"failed"`  // есть в базовой линии, загружены, но не работают
+    Added   []string `json:"added"`   // работают, но нет в базовой линии
+}
+
+// InterfaceStats - счетчики сетевого интерфейса из /proc/net/dev и их
+// скорости в секунду с предыдущего чтения
+type InterfaceStats struct {
+    Name      string `json:"name"`
+    RxBytes   uint64 `json:"rx_bytes"`
+    RxPackets uint64 `json:"rx_packets"`
+    RxErrors  uint64 `json:"rx_errors"`
+    RxDrops   uint64 `json:"rx_drops"`
+    TxBytes   uint64 `json:"tx_bytes"`
+    TxPackets uint64 `json:"tx_packets"`
+    TxErrors  uint64 `json:"tx_errors"`
+    TxDrops   uint64 `json:"tx_drops"`
+
+    RxBytesPerSec   float64 `json:"rx_bytes_per_sec"`
+    TxBytesPerSec   float64 `json:"tx_bytes_per_sec"`
+    RxPacketsPerSec float64 `json:"rx_packets_per_sec"`
+    TxPacketsPerSec float64 `json:"tx_packets_per_sec"`
+    RxErrorsPerSec  float64 `json:"rx_errors_per_sec"`
+    RxDropsPerSec   float64 `json:"rx_drops_per_sec"`
+    TxErrorsPerSec  float64 `json:"tx_errors_per_sec"`
+    TxDropsPerSec   float64 `json:"tx_drops_per_sec"`
+}
+
+// UDPStats - счетчики UDP ядра из /proc/net/snmp и /proc/net/snmp6 (IPv4 и
+// IPv6 вместе) и скорости ошибок в секунду с предыдущего чтения
+type UDPStats struct {
+    InDatagrams  uint64 `json:"in_datagrams"`
+    OutDatagrams uint64 `json:"out_datagrams"`
+    NoPorts      uint64 `json:"no_ports"`
+    InErrors     uint64 `json:"in_errors"`
+    RcvbufErrors uint64 `json:"rcvbuf_errors"` // датаграммы, не поместившиеся в буфер приема сокета
+    SndbufErrors uint64 `json:"sndbuf_errors"`
+
+    InErrorsPerSec     float64 `json:"in_errors_per_sec"`
+    RcvbufErrorsPerSec float64 `json:"rcvbuf_errors_per_sec"`
+    SndbufErrorsPerSec float64 `json:"sndbuf_errors_per_sec"`
+}
+
+// UDPSocket - UDP сокет Asterisk из /proc/net/udp или /proc/net/udp6
+type UDPSocket struct {
+    Local   string `json:"local"` // адрес:порт
+    Port    int    `json:"port"`
+    RxQueue uint64 `json:"rx_queue"` // байт ожидают чтения Asterisk
+    TxQueue uint64 `json:"tx_queue"`
+    Drops   uint64 `json:"drops"` // датаграмм отброшено с момента создания сокета
+    Inode   uint64 `json:"inode"`
+}
+
+// NetworkHealth содержит состояние сети, важное для качества RTP
+type NetworkHealth struct {
+    Time         time.Time        `json:"time"`
+    Rated        bool             `json:"rated"` // скорости посчитаны, при первом чтении их нет
+    Interfaces   []InterfaceStats `json:"interfaces"`
+    UDP          UDPStats         `json:"udp"`
+    Sockets      []UDPSocket      `json:"sockets"`       // по убыванию очереди приема
+    DropsPerSec  float64          `json:"drops_per_sec"` // отбрасывания на сокетах Asterisk
+    RmemDefault  uint64           `json:"rmem_default"`  // net.core.rmem_default, 0 если неизвестно
+    RmemMax      uint64           `json:"rmem_max"`      // net.core.rmem_max, 0 если неизвестно
+    SocketsError string           `json:"sockets_error,omitempty"`
+    Error        string           `json:"error,omitempty"`
+}
+
+// Назначения слушающих сокетов Asterisk
+const (
+    ListenerSIP    = "SIP"
+    ListenerSIPTLS = "SIP TLS"
+    ListenerAMI    = "AMI"
+    ListenerHTTP   = "ARI/HTTP"
+    ListenerIAX2   = "IAX2"
+    ListenerRTP    = "RTP"
+)
+
+// Доступность слушающего сокета по адресу, к которому он привязан
+const (
+    ExposureLoopback = "loopback" // только с этого сервера
+    ExposureLAN      = "lan"      // частный или link-local адрес
+    ExposurePublic   = "public"   // конкретный внешний адрес
+    ExposureAll      = "all"      // все интерфейсы, 0.0.0.0 или ::
+)
+
+// ListeningSocket - слушающий TCP или несвязанный UDP сокет из /proc/net
+type ListeningSocket struct {
+    Proto    string `json:"proto"` // tcp, tcp6, udp, udp6
+    Address  string `json:"address"`
+    Port     int    `json:"port"`
+    Inode    uint64 `json:"inode"`
+    PID      int    `json:"pid,omitempty"` // 0, если владелец неизвестен (нет прав на /proc/<pid>/fd)
+    Process  string `json:"process,omitempty"`
+    Asterisk bool   `json:"asterisk"`       // сокет процесса Asterisk или порт Asterisk у неизвестного владельца
+    Role     string `json:"role,omitempty"` // Listener*, только для сокетов Asterisk
+    Exposure string `json:"exposure"`
+}
+
+// RefreshConfig задает интервалы автообновления вкладок в секундах:
+// 0 - refresh_interval, отрицательное значение отключает автообновление вкладки
+type RefreshConfig struct {
+    Dashboard   int `ini:"dashboard" json:"dashboard"`
+    Channels    int `ini:"channels" json:"channels"`
+    Diagnostics int `ini:"diagnostics" json:"diagnostics"`
+}
+
+// NotifierConfig описывает канал уведомлений (секция [notifier.<name>] в config.ini)
+type NotifierConfig struct {
+    Name            string `ini:"-" json:"name"`
+    Type            string `ini:"type" json:"type"` // webhook, smtp, telegram
+    Enabled         bool   `ini:"enabled" json:"enabled"`
+    Severities      string `ini:"severities" json:"severities"` // warning,critical
+    SubjectTemplate string `ini:"subject_template" json:"subject_template"`
+    BodyTemplate    string `ini:"body_template" json:"body_template"`
+    URL             string `ini:"url" json:"url"` // webhook URL или адрес Telegram Bot API
+    Host            string `ini:"host" json:"host"`
+    Port            int    `ini:"port" json:"port"`
+    Username        string `ini:"username" json:"username"`
+    Password        string `ini:"password" json:"password"`
+    From            string `ini:"from" json:"from"`
+    To              string `ini:"to" json:"to"` // адреса через запятую
+    Token           string `ini:"token" json:"token"`
+    ChatID          string `ini:"chat_id" json:"chat_id"`
+}
+
 type Config struct {
-    Asterisk   AsteriskConfig   `ini:"asterisk" json:"asterisk"`
-    Monitoring MonitoringConfig `ini:"monitoring" json:"monitoring"`
-    Security   SecurityConfig   `ini:"security" json:"security"`
-}
\ No newline at end of file
+    Asterisk      AsteriskConfig      `ini:"asterisk" json:"asterisk"`
+    Monitoring    MonitoringConfig    `ini:"monitoring" json:"monitoring"`
+    Security      SecurityConfig      `ini:"security" json:"security"`
+    Exporter      ExporterConfig      `ini:"exporter" json:"exporter"`
+    Daemon        DaemonConfig        `ini:"daemon" json:"daemon"`
+    Push          PushConfig          `ini:"push" json:"push"`
+    Notifications NotificationsConfig `ini:"notifications" json:"notifications"`
+    Anomaly       AnomalyConfig       `ini:"anomaly" json:"anomaly"`
+    Refresh       RefreshConfig       `ini:"refresh" json:"refresh"`
+    Watchdog      WatchdogConfig      `ini:"watchdog" json:"watchdog"`
+    Alerts        []AlertRule         `ini:"-" json:"alerts"`
+    Notifiers     []NotifierConfig    `ini:"-" json:"notifiers"`
+}
+
+// CallQuality содержит RTP статистику одного SIP вызова
+type CallQuality struct {
+    Peer      string  `json:"peer"`
+    CallID    string  `json:"call_id"`
+    Duration  string  `json:"duration"`
+    RxPackets int64   `json:"rx_packets"`
+    RxLost    int64   `json:"rx_lost"`
+    RxLossPct float64 `json:"rx_loss_pct"`
+    RxJitter  float64 `json:"rx_jitter"`
+    TxPackets int64   `json:"tx_packets"`
+    TxLost    int64   `json:"tx_lost"`
+    TxLossPct float64 `json:"tx_loss_pct"`
+    TxJitter  float64 `json:"tx_jitter"`
+}
+
+// Sample - одно измерение временного ряда
+type Sample struct {
+    Metric    string            `json:"m"`
+    Labels    map[string]string `json:"l,omitempty"`
+    Value     float64           `json:"v"`
+    Timestamp time.Time         `json:"t"`
+    Note      string            `json:"n,omitempty"` // пояснение для текста оповещения, не входит в ряд
+}
+
+// Имена метрик, сохраняемых в хранилище временных рядов
+const (
+    MetricCPUUsage     = "cpu_usage"
+    MetricMemoryUsage  = "memory_usage"
+    MetricDiskUsage    = "disk_usage"
+    MetricMountUsage   = "mount_usage_pct"
+    MetricLoad1        = "load1"
+    MetricActiveCalls  = "active_calls"
+    MetricPeersOnline  = "peers_online"
+    MetricPeersTotal   = "peers_total"
+    MetricAsteriskUp   = "asterisk_up"
+    MetricPeerUp       = "peer_up"
+    MetricPeerLatency  = "peer_latency_ms"
+    MetricCallRxLoss   = "call_rx_loss_pct"
+    MetricCallTxLoss   = "call_tx_loss_pct"
+    MetricCallRxJitter = "call_rx_jitter"
+    MetricCallTxJitter = "call_tx_jitter"
+
+    // Счетчик обработанных вызовов Asterisk, по его приращению считаются попытки вызовов
+    MetricCallsProcessed = "calls_processed"
+
+    // Ресурсы основного процесса Asterisk
+    MetricProcessCPU         = "asterisk_cpu_pct"
+    MetricProcessRSS         = "asterisk_rss_mb"
+    MetricProcessThreads     = "asterisk_threads"
+    MetricProcessFDs         = "asterisk_open_fds"
+    MetricProcessFDUsage     = "asterisk_fd_usage_pct"
+    MetricProcessCtxSwitches = "asterisk_ctx_switches_per_sec"
+
+    // Очереди задач Asterisk: наибольшая очередь и число очередей выше high water
+    MetricTaskprocessorMaxQueue       = "asterisk_taskprocessor_max_queue"
+    MetricTaskprocessorsOverHighWater = "asterisk_taskprocessors_over_high_water"
+
+    // Сеть: скорости по интерфейсам (метка interface), ошибки UDP ядра и сокеты Asterisk
+    MetricNetRxBytes         = "net_rx_bytes_per_sec"
+    MetricNetTxBytes         = "net_tx_bytes_per_sec"
+    MetricNetRxErrors        = "net_rx_errors_per_sec"
+    MetricNetRxDrops         = "net_rx_drops_per_sec"
+    MetricNetTxErrors        = "net_tx_errors_per_sec"
+    MetricNetTxDrops         = "net_tx_drops_per_sec"
+    MetricUDPInErrors        = "udp_in_errors_per_sec"
+    MetricUDPRcvbufErrors    = "udp_rcvbuf_errors_per_sec"
+    MetricAsteriskUDPRxQueue = "asterisk_udp_rx_queue_bytes"
+    MetricAsteriskUDPDrops   = "asterisk_udp_drops_per_sec"
+
+    // Размер каталога Asterisk (метка dir)
+    MetricDirSize = "asterisk_dir_size_mb"
+)
+
+// Метрики обнаружения аномалий. Не сохраняются, вычисляются по истории.
+const (
+    MetricCallVolumeScore = "call_volume_zscore"
+    MetricCallVolumeZero  = "call_volume_unexpected_zero"
+    MetricRSSGrowth       = "asterisk_rss_growth_mb_per_hour"
+    MetricThreadGrowth    = "asterisk_threads_growth_per_hour"
+
+    // Прогноз заполнения дисков по росту mount_usage_pct и asterisk_dir_size_mb
+    MetricMountDaysUntilFull = "mount_days_until_full"
+    MetricDirGrowth          = "asterisk_dir_growth_mb_per_day"
+
+    // Незапланированных перезапусков Asterisk за последний час, вычисляется по хронологии
+    MetricUnexpectedRestarts = "asterisk_unexpected_restarts"
+)
@@ -0,0 +1,422 @@
+package ui
+
+import (
+	"asterisk-monitor/alerts"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Режимы ввода на вкладке оповещений
+const (
+	alertsModeList    = ""
+	alertsModeAck     = "ack"
+	alertsModeSilence = "silence"
+)
+
+// testNotificationMsg - результат отправки пробного уведомления
+type testNotificationMsg struct {
+	results map[string]error
+}
+
+type AlertsModel struct {
+	alerts   AlertManager
+	notifier TestNotifier
+	viewport viewport.Model
+	input    textinput.Model
+	mode     string
+	selected string // ключ выбранного оповещения, список пересортировывается при обновлении
+	message  string
+	ready    bool
+}
+
+func NewAlertsModel(manager AlertManager, notifier TestNotifier) AlertsModel {
+	input := textinput.New()
+	input.CharLimit = 200
+
+	return AlertsModel{
+		alerts:   manager,
+		notifier: notifier,
+		viewport: viewport.New(80, 20),
+		input:    input,
+	}
+}
+
+func (m AlertsModel) Init() tea.Cmd {
+	return nil
+}
+
+// Typing сообщает, что вкладка ожидает ввод текста и горячие клавиши не должны срабатывать
+func (m AlertsModel) Typing() bool {
+	return m.mode != alertsModeList
+}
+
+func (m AlertsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.mode != alertsModeList {
+			return m.updateInput(msg)
+		}
+
+		switch msg.String() {
+		case "up", "k":
+			m.moveSelection(-1)
+		case "down", "j":
+			m.moveSelection(1)
+		case "a", "A":
+			if _, ok := m.selectedAlert(); ok {
+				m.startInput(alertsModeAck, "Acknowledgement note")
+				return m, textinput.Blink
+			}
+			m.message = warningStyle.Render("No alert selected")
+		case "s", "S":
+			if _, ok := m.selectedAlert(); ok {
+				m.startInput(alertsModeSilence, "Duration and comment, e.g. 2h planned maintenance")
+				return m, textinput.Blink
+			}
+			m.message = warningStyle.Render("No alert selected")
+		case "u", "U":
+			m.unsilenceSelected()
+		case "t", "T":
+			if m.notifier == nil {
+				m.message = warningStyle.Render("Notifications are not configured")
+				break
+			}
+			m.message = infoStyle.Render("Sending test notification...")
+			m.updateContent()
+			return m, m.sendTest()
+		case "q", "Q", "ctrl+c":
+			return m, tea.Quit
+		}
+		m.updateContent()
+	case testNotificationMsg:
+		m.message = formatTestResults(msg.results)
+		m.updateContent()
+	case AlertsUpdatedMsg:
+		m.updateContent()
+	case tea.WindowSizeMsg:
+		if !m.ready {
+			m.viewport = viewport.New(msg.Width, msg.Height-4)
+			m.viewport.Style = lipgloss.NewStyle().
+				BorderStyle(lipgloss.RoundedBorder()).
+				BorderForeground(lipgloss.Color("62"))
+			m.ready = true
+		} else {
+			m.viewport.Width = msg.Width
+			m.viewport.Height = msg.Height - 4
+		}
+		m.updateContent()
+	}
+
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m AlertsModel) View() string {
+	if !m.ready {
+		return "Initializing..."
+	}
+
+	view := m.viewport.View() + "\n"
+	if m.mode != alertsModeList {
+		view += m.input.View() + "\n"
+	}
+	return view + m.footer()
+}
+
+func (m *AlertsModel) startInput(mode, placeholder string) {
+	m.mode = mode
+	m.input.Reset()
+	m.input.Placeholder = placeholder
+	m.input.Focus()
+}
+
+func (m AlertsModel) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.mode = alertsModeList
+		m.input.Blur()
+		m.message = ""
+		m.updateContent()
+		return m, nil
+	case "enter":
+		switch m.mode {
+		case alertsModeAck:
+			m.acknowledgeSelected(strings.TrimSpace(m.input.Value()))
+		case alertsModeSilence:
+			m.silenceSelected(strings.TrimSpace(m.input.Value()))
+		}
+		m.mode = alertsModeList
+		m.input.Blur()
+		m.updateContent()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// selectedIndex возвращает позицию выбранного оповещения в active. Если оно
+// снято, выбирается первое, чтобы действие не ушло на оповещение, которого
+// пользователь не видел выбранным.
+func (m *AlertsModel) selectedIndex(active []alerts.Alert) int {
+	for i, alert := range active {
+		if alert.Key() == m.selected {
+			return i
+		}
+	}
+	if len(active) > 0 {
+		m.selected = active[0].Key()
+		return 0
+	}
+	m.selected = ""
+	return -1
+}
+
+func (m *AlertsModel) moveSelection(delta int) {
+	active := m.alerts.Active()
+	i := m.selectedIndex(active) + delta
+	if i >= 0 && i < len(active) {
+		m.selected = active[i].Key()
+	}
+}
+
+// selectedAlert возвращает выбранное оповещение по ключу, а не по позиции
+func (m *AlertsModel) selectedAlert() (alerts.Alert, bool) {
+	for _, alert := range m.alerts.Active() {
+		if alert.Key() == m.selected {
+			return alert, true
+		}
+	}
+	return alerts.Alert{}, false
+}
+
+func (m *AlertsModel) acknowledgeSelected(note string) {
+	alert, ok := m.selectedAlert()
+	if !ok {
+		m.message = warningStyle.Render("Selected alert is no longer active")
+		return
+	}
+
+	if err := m.alerts.Acknowledge(alert.Key(), note, time.Now()); err != nil {
+		m.message = errorStyle.Render("Acknowledge failed: " + err.Error())
+		return
+	}
+	m.message = successStyle.Render("Acknowledged: " + alert.Rule)
+}
+
+// silenceSelected разбирает ввод вида "2h комментарий" и создает тишину для выбранного оповещения
+func (m *AlertsModel) silenceSelected(spec string) {
+	alert, ok := m.selectedAlert()
+	if !ok {
+		m.message = warningStyle.Render("Selected alert is no longer active")
+		return
+	}
+
+	durationText, comment, _ := strings.Cut(spec, " ")
+	duration, err := time.ParseDuration(durationText)
+	if err != nil {
+		m.message = errorStyle.Render(fmt.Sprintf("Invalid duration %q, use e.g. 30m or 2h", durationText))
+		return
+	}
+
+	silence, err := m.alerts.AddSilence(m.alerts.MatchersFor(alert), duration, strings.TrimSpace(comment), time.Now())
+	if err != nil {
+		m.message = errorStyle.Render("Silence failed: " + err.Error())
+		return
+	}
+	m.message = successStyle.Render(fmt.Sprintf("Silenced %s until %s", alert.Rule, formatAlertTime(silence.Until)))
+}
+
+func (m *AlertsModel) unsilenceSelected() {
+	alert, ok := m.selectedAlert()
+	if !ok {
+		m.message = warningStyle.Render("No alert selected")
+		return
+	}
+
+	silence, ok := m.alerts.SilenceFor(alert, time.Now())
+	if !ok {
+		m.message = warningStyle.Render("Selected alert is not silenced")
+		return
+	}
+	m.alerts.RemoveSilence(silence.ID, time.Now())
+	m.message = successStyle.Render("Silence removed: " + formatMatchers(silence.Matchers))
+}
+
+func (m *AlertsModel) sendTest() tea.Cmd {
+	notifier := m.notifier
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		return testNotificationMsg{results: notifier.SendTest(ctx, "")}
+	}
+}
+
+func formatTestResults(results map[string]error) string {
+	if len(results) == 0 {
+		return warningStyle.Render("No enabled notifiers configured")
+	}
+
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var parts []string
+	for _, name := range names {
+		if err := results[name]; err != nil {
+			parts = append(parts, errorStyle.Render(name+": "+err.Error()))
+		} else {
+			parts = append(parts, successStyle.Render(name+": sent"))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func (m *AlertsModel) updateContent() {
+	var content strings.Builder
+
+	content.WriteString(TitleStyle.Render("🚨 Alerts"))
+	content.WriteString("\n\n")
+
+	if !m.alerts.Enabled() {
+		content.WriteString(warningStyle.Render("Alerts are disabled (enable_alerts = false)"))
+		content.WriteString("\n\n")
+	}
+
+	content.WriteString(m.renderActive())
+	content.WriteString("\n\n")
+	content.WriteString(m.renderSilences())
+	content.WriteString("\n\n")
+	content.WriteString(m.renderHistory())
+
+	if m.message != "" {
+		content.WriteString("\n\n" + m.message)
+	}
+
+	m.viewport.SetContent(content.String())
+}
+
+func (m *AlertsModel) renderActive() string {
+	active := m.alerts.Active()
+	selected := m.selectedIndex(active)
+	if len(active) == 0 {
+		return borderStyle.Render("Active Alerts:\n" + successStyle.Render("No active alerts"))
+	}
+
+	var rows [][]string
+	for i, alert := range active {
+		cursor := " "
+		if i == selected {
+			cursor = "▶"
+		}
+
+		var notes []string
+		if alert.Acknowledged {
+			note := "ACK " + formatAlertTime(alert.AckAt)
+			if alert.AckNote != "" {
+				note += ": " + alert.AckNote
+			}
+			notes = append(notes, note)
+		}
+		if alert.Silenced {
+			notes = append(notes, "SILENCED")
+		}
+
+		rows = append(rows, []string{
+			cursor,
+			strings.ToUpper(alert.State),
+			alert.Severity,
+			formatAlertTime(alert.StartedAt),
+			formatAlertTime(alert.LastSeen),
+			TruncateString(alert.Message, 60),
+			TruncateString(strings.Join(notes, ", "), 40),
+		})
+	}
+
+	return "Active Alerts:\n" + FormatTable([]string{" ", "STATE", "SEVERITY", "FIRST SEEN", "LAST SEEN", "ALERT", "NOTES"}, rows)
+}
+
+func (m *AlertsModel) renderSilences() string {
+	silences := m.alerts.Silences(time.Now())
+	if len(silences) == 0 {
+		return borderStyle.Render("Silences:\nNo active silences")
+	}
+
+	var rows [][]string
+	for _, silence := range silences {
+		rows = append(rows, []string{
+			formatMatchers(silence.Matchers),
+			formatAlertTime(silence.Until),
+			TruncateString(silence.Comment, 40),
+		})
+	}
+	return "Silences:\n" + FormatTable([]string{"MATCHERS", "UNTIL", "COMMENT"}, rows)
+}
+
+func (m *AlertsModel) renderHistory() string {
+	history := m.alerts.History()
+	if len(history) == 0 {
+		return borderStyle.Render("History:\nNo resolved alerts")
+	}
+
+	var rows [][]string
+	for i, alert := range history {
+		if i >= 20 { // Show only last 20 resolved alerts
+			break
+		}
+		rows = append(rows, []string{
+			alert.Severity,
+			formatAlertTime(alert.StartedAt),
+			formatAlertTime(alert.LastSeen),
+			formatAlertTime(alert.ResolvedAt),
+			TruncateString(alert.Message, 60),
+			TruncateString(alert.AckNote, 30),
+		})
+	}
+	return "History:\n" + FormatTable([]string{"SEVERITY", "FIRST SEEN", "LAST SEEN", "RESOLVED", "ALERT", "ACK NOTE"}, rows)
+}
+
+// formatAlertTime форматирует время с датой, чтобы при пересменке было видно ночные события
+func formatAlertTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("01-02 15:04:05")
+}
+
+func formatMatchers(matchers map[string]string) string {
+	keys := make([]string, 0, len(matchers))
+	for key := range matchers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+"="+matchers[key])
+	}
+	return strings.Join(parts, ",")
+}
+
+func (m *AlertsModel) footer() string {
+	help := "↑/↓ select | 'a' acknowledge | 's' silence | 'u' unsilence | 't' test notification | 'q' quit"
+	if m.mode != alertsModeList {
+		help = "ENTER to confirm | ESC to cancel"
+	}
+	return lipgloss.NewStyle().
+		Foreground(colorGray).
+		Render(help)
+}
@@ -1,11 +1,14 @@
 package ui
 
 import (
+	monitor "asterisk-monitor/monitors"
 	"asterisk-monitor/types"
+	"context"
 	"fmt"
+	"path/filepath"
 	"strings"
-	"time"
 
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
@@ -20,6 +23,8 @@ type BackupModel struct {
 	results      []types.CheckResult
 	backupsList  string
 	ready        bool
+	task         taskRunner
+	notice       string
 }
 
 func NewBackupModel(mon MonitorInterface) BackupModel {
@@ -42,6 +47,7 @@ func NewBackupModel(mon MonitorInterface) BackupModel {
 		restoreInput: restore,
 		results:      []types.CheckResult{},
 		backupsList:  "",
+		task:         newTaskRunner(),
 		ready:        true, // Сразу готов
 	}
 }
@@ -59,17 +65,26 @@ func (m BackupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "b", "B":
-			m.createBackup()
-			return m, nil
+			if m.task.Running() {
+				return m, nil
+			}
+			return m, m.createBackup()
 		case "r", "R":
-			if m.restoreInput.Value() != "" {
-				m.restoreBackup()
+			if m.task.Running() || m.restoreInput.Value() == "" {
+				return m, nil
 			}
-			return m, nil
+			return m, m.restoreBackup()
 		case "l", "L":
 			m.listBackups()
 			return m, nil
+		case "esc":
+			m.cancelTask()
+			return m, nil
 		case "c", "C":
+			if m.task.Running() {
+				return m, nil
+			}
+			m.notice = ""
 			m.results = []types.CheckResult{}
 			m.backupsList = ""
 			m.updateContent()
@@ -98,6 +113,27 @@ func (m BackupModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.viewport.Height = msg.Height - 8
 		}
 		m.updateContent()
+	case CancelTasksMsg:
+		m.cancelTask()
+		return m, nil
+	case taskProgressMsg:
+		if !m.task.Owns(msg.id) {
+			return m, nil
+		}
+		m.results = append(m.results, msg.result)
+		m.updateContent()
+		return m, msg.next
+	case taskDoneMsg:
+		if !m.task.Finish(msg.id) {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.notice = taskError("Operation", msg.err)
+		}
+		m.listBackups() // Обновляем список бэкапов
+		return m, nil
+	case spinner.TickMsg:
+		return m, m.task.Tick(msg)
 	}
 
 	m.backupInput, _ = m.backupInput.Update(msg)
@@ -115,194 +151,68 @@ func (m BackupModel) View() string {
 	var controls strings.Builder
 	controls.WriteString("Backup Path: " + m.backupInput.View() + "\n")
 	controls.WriteString("Restore File: " + m.restoreInput.View() + "\n\n")
-	controls.WriteString("Press TAB to switch fields | ")
-	controls.WriteString("B: Backup | R: Restore | L: List | C: Clear | Q: Quit")
+	switch {
+	case m.task.Running():
+		controls.WriteString(m.task.Status())
+	case m.notice != "":
+		controls.WriteString(warningStyle.Render(m.notice))
+	default:
+		controls.WriteString("Press TAB to switch fields | ")
+		controls.WriteString("B: Backup | R: Restore | L: List | C: Clear | Q: Quit")
+	}
 
 	return controls.String() + "\n" + m.viewport.View()
 }
 
-func (m *BackupModel) createBackup() {
-	backupPath := m.backupInput.Value()
-	if backupPath == "" {
-		backupPath = "/tmp/asterisk-backups"
-	}
-
-	timestamp := time.Now().Format("2006-01-02-150405")
-	backupFile := fmt.Sprintf("%s/asterisk-backup-%s.tar.gz", backupPath, timestamp)
-	backupDir := fmt.Sprintf("/tmp/asterisk-backup-%s", timestamp)
-
-	// Добавляем сообщение о начале бэкапа
-	m.results = append(m.results, types.CheckResult{
-		Name:      "Backup Started",
-		Status:    "info",
-		Message:   fmt.Sprintf("Creating backup to: %s", backupFile),
-		Timestamp: time.Now(),
+// createBackup запускает создание бэкапа в фоне, шаги приходят через taskProgressMsg
+func (m *BackupModel) createBackup() tea.Cmd {
+	m.notice = ""
+	mon, backupPath := m.monitor, m.backupInput.Value()
+	return m.task.Start("Creating backup", backupTimeout, func(ctx context.Context, progress func(types.CheckResult)) (any, error) {
+		mon.CreateBackup(ctx, backupPath, progress)
+		return nil, nil
 	})
-	m.updateContent()
-
-	commands := []string{
-		fmt.Sprintf("mkdir -p %s", backupPath),
-		fmt.Sprintf("mkdir -p %s", backupDir),
-		"cp -r /etc/asterisk/ " + backupDir + "/ 2>/dev/null || echo 'No /etc/asterisk access'",
-		"cp -r /var/lib/asterisk/ " + backupDir + "/ 2>/dev/null || echo 'No /var/lib/asterisk access'",
-		"cp -r /var/spool/asterisk/ " + backupDir + "/ 2>/dev/null || echo 'No /var/spool/asterisk access'",
-		"cp -r /var/log/asterisk/ " + backupDir + "/ 2>/dev/null || echo 'No /var/log/asterisk access'",
-		fmt.Sprintf("tar -czf %s -C %s . 2>/dev/null || echo 'Tar failed'", backupFile, backupDir),
-		fmt.Sprintf("rm -rf %s", backupDir),
-		fmt.Sprintf("chmod 644 %s 2>/dev/null || true", backupFile),
-	}
-
-	// Выполняем команды синхронно
-	for i, cmd := range commands {
-		result := m.monitor.ExecuteCommand(fmt.Sprintf("Backup Step %d", i+1), cmd)
-		m.results = append(m.results, result)
-		m.updateContent()
-		time.Sleep(500 * time.Millisecond) // Задержка для визуального эффекта
-
-		// Если ошибка, прерываем
-		if result.Status == "error" {
-			// Cleanup on error
-			m.monitor.ExecuteCommand("Cleanup", fmt.Sprintf("rm -rf %s %s", backupDir, backupFile))
-			return
-		}
-	}
-
-	// Verify backup
-	verifyCmd := fmt.Sprintf("test -f %s && tar -tzf %s | wc -l || echo '0'", backupFile, backupFile)
-	verifyResult := m.monitor.ExecuteCommand("Verify Backup", verifyCmd)
-	
-	if verifyResult.Status == "success" && verifyResult.Message != "0" {
-		fileCount := strings.TrimSpace(verifyResult.Message)
-		m.results = append(m.results, types.CheckResult{
-			Name:      "Backup Completed",
-			Status:    "success",
-			Message:   fmt.Sprintf("Backup created successfully: %s (%s files)", backupFile, fileCount),
-			Timestamp: time.Now(),
-		})
-	} else {
-		m.results = append(m.results, types.CheckResult{
-			Name:      "Backup Completed",
-			Status:    "warning",
-			Message:   fmt.Sprintf("Backup created but verification failed: %s", backupFile),
-			Timestamp: time.Now(),
-		})
-	}
-
-	m.updateContent()
-	m.listBackups() // Обновляем список бэкапов
 }
 
-func (m *BackupModel) restoreBackup() {
-	backupFile := m.restoreInput.Value()
-	if backupFile == "" {
-		m.results = append(m.results, types.CheckResult{
-			Name:      "Restore Error",
-			Status:    "error",
-			Message:   "No backup file specified",
-			Timestamp: time.Now(),
-		})
-		m.updateContent()
-		return
-	}
-
-	// Добавляем сообщение о начале восстановления
-	m.results = append(m.results, types.CheckResult{
-		Name:      "Restore Started",
-		Status:    "info",
-		Message:   fmt.Sprintf("Starting restore from: %s", backupFile),
-		Timestamp: time.Now(),
+// restoreBackup запускает восстановление в фоне. После остановки Asterisk
+// восстановление доводится до конца и отмена только перестает показывать шаги.
+func (m *BackupModel) restoreBackup() tea.Cmd {
+	m.notice = ""
+	mon, backupFile := m.monitor, m.restoreInput.Value()
+	return m.task.Start("Restoring backup", backupTimeout, func(ctx context.Context, progress func(types.CheckResult)) (any, error) {
+		mon.RestoreBackup(ctx, backupFile, progress)
+		return nil, nil
 	})
-	m.updateContent()
-
-	// Check if backup file exists
-	checkCmd := fmt.Sprintf("test -f %s && echo 'exists' || echo 'not found'", backupFile)
-	checkResult := m.monitor.ExecuteCommand("Check Backup", checkCmd)
-	if !strings.Contains(checkResult.Message, "exists") {
-		m.results = append(m.results, types.CheckResult{
-			Name:      "Restore Error",
-			Status:    "error",
-			Message:   fmt.Sprintf("Backup file not found: %s", backupFile),
-			Timestamp: time.Now(),
-		})
-		m.updateContent()
-		return
-	}
-
-	// Create restore directory
-	restoreDir := fmt.Sprintf("/tmp/asterisk-restore-%d", time.Now().Unix())
-
-	commands := []string{
-		fmt.Sprintf("mkdir -p %s", restoreDir),
-		fmt.Sprintf("tar -xzf %s -C %s", backupFile, restoreDir),
-
-		// Stop Asterisk before restore
-		"systemctl stop asterisk",
-
-		// Backup current configuration
-		fmt.Sprintf("cp -r /etc/asterisk /etc/asterisk.backup.%d", time.Now().Unix()),
-
-		// Restore files
-		fmt.Sprintf("cp -r %s/etc/asterisk/* /etc/asterisk/ 2>/dev/null || echo 'No config files to restore'", restoreDir),
-		fmt.Sprintf("cp -r %s/var/lib/asterisk/* /var/lib/asterisk/ 2>/dev/null || echo 'No lib files to restore'", restoreDir),
-		fmt.Sprintf("cp -r %s/var/spool/asterisk/* /var/spool/asterisk/ 2>/dev/null || echo 'No spool files to restore'", restoreDir),
-
-		// Fix permissions
-		"chown -R asterisk:asterisk /etc/asterisk/ 2>/dev/null || true",
-		"chown -R asterisk:asterisk /var/lib/asterisk/ 2>/dev/null || true",
-		"chown -R asterisk:asterisk /var/spool/asterisk/ 2>/dev/null || true",
-
-		// Start Asterisk
-		"systemctl start asterisk",
-
-		// Cleanup
-		fmt.Sprintf("rm -rf %s", restoreDir),
-	}
+}
 
-	// Выполняем команды синхронно
-	for i, cmd := range commands {
-		result := m.monitor.ExecuteCommand(fmt.Sprintf("Restore Step %d", i+1), cmd)
-		m.results = append(m.results, result)
-		m.updateContent()
-		time.Sleep(500 * time.Millisecond)
-
-		// Если ошибка, пытаемся восстановить
-		if result.Status == "error" {
-			// Emergency restore
-			m.monitor.ExecuteCommand("Emergency Restore",
-				fmt.Sprintf("cp -r /etc/asterisk.backup.%d/* /etc/asterisk/ && systemctl start asterisk", time.Now().Unix()))
-			return
-		}
+func (m *BackupModel) cancelTask() {
+	if m.task.Cancel() {
+		m.notice = "Operation cancelled; a restore that already stopped Asterisk continues in the background"
 	}
-
-	m.results = append(m.results, types.CheckResult{
-		Name:      "Restore Completed",
-		Status:    "success",
-		Message:   fmt.Sprintf("Backup restored successfully from: %s", backupFile),
-		Timestamp: time.Now(),
-	})
-	m.updateContent()
 }
 
 func (m *BackupModel) listBackups() {
 	backupPath := m.backupInput.Value()
 	if backupPath == "" {
-		backupPath = "/tmp/asterisk-backups"
+		backupPath = monitor.DefaultBackupPath
 	}
 
-	cmd := fmt.Sprintf("ls -la %s/asterisk-backup-*.tar.gz 2>/dev/null | head -20 || echo 'No backups found'", backupPath)
-	result := m.monitor.ExecuteCommand("List Backups", cmd)
-
-	if result.Status == "success" && !strings.Contains(result.Message, "No backups found") {
-		// Get backup sizes
-		sizeCmd := fmt.Sprintf("du -h %s/asterisk-backup-*.tar.gz 2>/dev/null | sort -hr || echo 'No size info'", backupPath)
-		sizeResult := m.monitor.ExecuteCommand("Backup Sizes", sizeCmd)
-
-		m.backupsList = "Available Backups:\n" + result.Message
-		if sizeResult.Status == "success" {
-			m.backupsList += "\n\nSizes:\n" + sizeResult.Message
-		}
-	} else {
+	backups, err := m.monitor.ListBackups(backupPath)
+	switch {
+	case err != nil:
+		m.backupsList = fmt.Sprintf("Failed to list backups in %s: %v", backupPath, err)
+	case len(backups) == 0:
 		m.backupsList = "No backups found in " + backupPath
+	default:
+		var rows [][]string
+		for _, backup := range backups {
+			rows = append(rows, []string{
+				filepath.Base(backup.Path),
+				monitor.FormatBytes(backup.Size),
+				backup.Modified.Format("2006-01-02 15:04:05"),
+			})
+		}
+		m.backupsList = "Available Backups:\n" + FormatTable([]string{"File", "Size", "Modified"}, rows)
 	}
 
 	m.updateContent()
@@ -357,6 +267,9 @@ func (m *BackupModel) renderResults() string {
 		timestamp := FormatTimestamp(result.Timestamp)
 		builder.WriteString(fmt.Sprintf("%s [%s] %s: %s\n",
 			statusIcon, timestamp, result.Name, result.Message))
+		if result.Status != "success" && result.Error != "" {
+			builder.WriteString("   " + FormatCommandError(result) + "\n")
+		}
 	}
 
 	return borderStyle.Render(builder.String())
@@ -383,26 +296,3 @@ Important Notes:
 
 	return borderStyle.Render(info)
 }
-
-// Helper function to get backup statistics
-func (m *BackupModel) getBackupStats() string {
-	backupPath := m.backupInput.Value()
-	if backupPath == "" {
-		backupPath = "/tmp/asterisk-backups"
-	}
-
-	statsCmd := fmt.Sprintf(`
-        count=$(find %s -name "asterisk-backup-*.tar.gz" -type f 2>/dev/null | wc -l)
-        total_size=$(du -ch %s/asterisk-backup-*.tar.gz 2>/dev/null | grep total | cut -f1)
-        latest=$(ls -t %s/asterisk-backup-*.tar.gz 2>/dev/null | head -1)
-        if [ -n "$latest" ]; then
-            latest_date=$(stat -c %%y "$latest" 2>/dev/null | cut -d' ' -f1)
-        else
-            latest_date="N/A"
-        fi
-        echo "Backups: $count | Total Size: $total_size | Latest: $latest_date"
-    `, backupPath, backupPath, backupPath)
-
-	result := m.monitor.ExecuteCommand("Backup Stats", statsCmd)
-	return result.Message
-}
\ No newline at end of file
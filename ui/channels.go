@@ -1,40 +1,50 @@
 package ui
 
 import (
+	"asterisk-monitor/collector"
 	"asterisk-monitor/types"
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
 type ChannelsModel struct {
-	monitor  MonitorInterface
+	cache    *collector.Collector
 	viewport viewport.Model
 	channels []types.ChannelInfo
 	ready    bool
+	task     taskRunner
+	notice   string
+	updated  time.Time
+	refresh  refresher
 }
 
-func NewChannelsModel(mon MonitorInterface) ChannelsModel {
+// NewChannelsModel создает вкладку каналов, список читается из кэша коллектора.
+// Интервал автообновления берется из cfg.
+func NewChannelsModel(cache *collector.Collector, cfg ConfigGetter) ChannelsModel {
 	vp := viewport.New(80, 20)
 	vp.Style = lipgloss.NewStyle().
 		BorderStyle(lipgloss.RoundedBorder()).
 		BorderForeground(lipgloss.Color("62"))
 	
 	return ChannelsModel{
-		monitor:  mon,
+		cache:    cache,
 		viewport: vp,
 		channels: []types.ChannelInfo{},
+		task:     newTaskRunner(),
+		refresh:  newRefresher("channels", cfg),
 		ready:    true, // Сразу готов
 	}
 }
 
 func (m ChannelsModel) Init() tea.Cmd {
-	m.loadChannels()
-	m.updateContent()
-	return nil
+	return requestLoad
 }
 
 func (m ChannelsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -44,8 +54,14 @@ func (m ChannelsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "r", "R":
-			m.loadChannels()
-			m.updateContent()
+			return m, m.loadChannels(0)
+		case "p", "P":
+			m.refresh.TogglePause()
+			return m, nil
+		case "esc":
+			if m.task.Cancel() {
+				m.notice = taskError("Loading channels", context.Canceled)
+			}
 			return m, nil
 		case "q", "Q", "ctrl+c":
 			return m, tea.Quit
@@ -62,6 +78,33 @@ func (m ChannelsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.viewport.Height = msg.Height - 2
 		}
 		m.updateContent()
+	case loadMsg:
+		return m, tea.Batch(m.loadChannels(-1), m.refresh.Start())
+	case CollectorUpdatedMsg:
+		if msg.Source == collector.SourceChannels && !m.refresh.Paused() {
+			m.syncFromCache()
+		}
+	case refreshTickMsg:
+		due, next := m.refresh.Update(msg)
+		if due {
+			return m, tea.Batch(next, m.loadChannels(m.refresh.Interval()))
+		}
+		return m, next
+	case CancelTasksMsg:
+		m.task.Cancel()
+		m.refresh.Stop()
+		return m, nil
+	case taskDoneMsg:
+		if !m.task.Finish(msg.id) {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.notice = taskError("Loading channels", msg.err)
+		}
+		m.syncFromCache()
+		return m, nil
+	case spinner.TickMsg:
+		return m, m.task.Tick(msg)
 	}
 
 	m.viewport, cmd = m.viewport.Update(msg)
@@ -76,8 +119,28 @@ func (m ChannelsModel) View() string {
 	return m.viewport.View() + "\n" + m.footer()
 }
 
-func (m *ChannelsModel) loadChannels() {
-	m.channels = m.monitor.GetActiveChannels()
+// loadChannels показывает список из кэша и обновляет его в фоне, если список
+// старше maxAge: при открытии вкладки - старше TTL источника (maxAge < 0),
+// при автообновлении - старше интервала вкладки, по 'r' - всегда
+func (m *ChannelsModel) loadChannels(maxAge time.Duration) tea.Cmd {
+	if m.task.Running() {
+		return nil
+	}
+
+	m.notice = ""
+	m.syncFromCache()
+	cache := m.cache
+	return m.task.Start("Loading channels", fetchTimeout, func(ctx context.Context, progress func(types.CheckResult)) (any, error) {
+		return nil, refreshSources(ctx, cache, maxAge, collector.SourceChannels)
+	})
+}
+
+// syncFromCache переносит в модель последний список каналов
+func (m *ChannelsModel) syncFromCache() {
+	channels, status := collector.Cached[[]types.ChannelInfo](m.cache, collector.SourceChannels)
+	m.channels = channels
+	m.updated = status.UpdatedAt
+	m.updateContent()
 }
 
 func (m *ChannelsModel) updateContent() {
@@ -116,8 +179,14 @@ func (m *ChannelsModel) renderChannels() string {
 }
 
 func (m *ChannelsModel) footer() string {
+	if m.task.Running() {
+		return m.task.Status()
+	}
+	if m.notice != "" {
+		return warningStyle.Render(m.notice)
+	}
 	count := len(m.channels)
 	return lipgloss.NewStyle().
 		Foreground(colorGray).
-		Render(fmt.Sprintf("Active channels: %d | Press 'r' to refresh | 'q' to quit", count))
+		Render(fmt.Sprintf("Active channels: %d | Updated: %s | %s | Press 'r' to refresh | 'q' to quit", count, FormatTimestamp(m.updated), m.refresh.Status()))
 }
\ No newline at end of file
@@ -1,30 +1,145 @@
 package ui
 
 import (
+    "context"
+    "fmt"
     "strings"
     "time"
 
     "github.com/charmbracelet/lipgloss"
 
+	"asterisk-monitor/alerts"
+	"asterisk-monitor/lifecycle"
+	monitor "asterisk-monitor/monitors"
 	"asterisk-monitor/types"
+	"asterisk-monitor/watchdog"
 )
 
 type MonitorInterface interface {
     GetAsteriskStatus() string
     GetAsteriskPID() string
     GetServiceStatus() string
-    GetSIPPeersCount() (int, int)
     GetSIPPeersDetail() string
+    GetSIPPeers(ctx context.Context) ([]types.SIPPeer, error)
+    GetCallQuality(ctx context.Context) ([]types.CallQuality, error)
     GetActiveCallsCount() int
-    GetActiveChannels() []types.ChannelInfo
+    GetActiveChannels(ctx context.Context) ([]types.ChannelInfo, error)
     GetAsteriskUptime() string
     GetSystemLoad() string
     GetCPUUsage() float64
     GetMemoryUsage() float64
     GetDiskUsage() float64
-    ExecuteCommand(name, command string) types.CheckResult
+    Exec(ctx context.Context, name, program string, args ...string) types.CheckResult
+    AsteriskCommand(ctx context.Context, name, command string) types.CheckResult
     GetAsteriskLogs(lines int, level, filter string) string
-    GetSystemMetrics() types.SystemMetrics
+    GetSystemMetrics(ctx context.Context) (types.SystemMetrics, error)
+    DiagnosticChecks(full bool) []monitor.Check
+    SecurityChecks(full bool) []monitor.Check
+    CreateBackup(ctx context.Context, backupPath string, progress func(types.CheckResult)) (string, []types.CheckResult)
+    RestoreBackup(ctx context.Context, backupFile string, progress func(types.CheckResult)) []types.CheckResult
+    ListBackups(backupPath string) ([]types.BackupInfo, error)
+    ProbeAsterisk(ctx context.Context, timeout time.Duration, ami bool) types.HealthProbe
+    GetModules(ctx context.Context) ([]types.AsteriskModule, error)
+    ModuleAction(ctx context.Context, action, name string) types.CheckResult
+    ModuleBaseline() (types.ModuleBaseline, bool, error)
+    SaveModuleBaseline(ctx context.Context) (types.ModuleBaseline, error)
+    GetNetworkHealth() types.NetworkHealth
+}
+
+// MetricsStore определяет интерфейс хранилища временных рядов
+type MetricsStore interface {
+    Append(samples ...types.Sample) error
+    Query(metric string, from, to time.Time) ([]types.Sample, error)
+}
+
+// AlertSource определяет источник текущих оповещений для отображения во всех видах
+type AlertSource interface {
+    Active() []alerts.Alert
+    History() []alerts.Alert
+    Enabled() bool
+}
+
+// AlertManager дополняет источник оповещений подтверждением и тишинами
+type AlertManager interface {
+    AlertSource
+    Acknowledge(key, note string, now time.Time) error
+    AddSilence(matchers map[string]string, duration time.Duration, comment string, now time.Time) (alerts.Silence, error)
+    RemoveSilence(id string, now time.Time) bool
+    Silences(now time.Time) []alerts.Silence
+    SilenceFor(alert alerts.Alert, now time.Time) (alerts.Silence, bool)
+    MatchersFor(alert alerts.Alert) map[string]string
+}
+
+// TestNotifier отправляет пробное уведомление во все каналы или в канал name
+type TestNotifier interface {
+    SendTest(ctx context.Context, name string) map[string]error
+}
+
+// RestartHistory возвращает хронологию запусков, перезапусков и сбоев Asterisk
+type RestartHistory interface {
+    Events(since time.Time) ([]lifecycle.Event, error)
+}
+
+// RecoveryLog возвращает журнал сторожа зависаний Asterisk
+type RecoveryLog interface {
+    Entries(since time.Time) ([]watchdog.Entry, error)
+}
+
+// AlertsUpdatedMsg отправляется в программу, когда изменилось состояние оповещений
+type AlertsUpdatedMsg struct{}
+
+// CollectorUpdatedMsg отправляется в программу после обновления источника коллектора
+type CollectorUpdatedMsg struct {
+    Source string
+}
+
+// NoticeMsg передает дашборду служебное сообщение (например, ошибку отправки уведомления)
+type NoticeMsg struct {
+    Text string
+}
+
+// FormatAlert форматирует оповещение одной строкой
+func FormatAlert(alert alerts.Alert) string {
+    icon := "⚠️ "
+    style := warningStyle
+    if alert.Severity == alerts.SeverityCritical {
+        icon = "🚨"
+        style = errorStyle
+    }
+    
+    state := strings.ToUpper(alert.State)
+    since := alert.StartedAt
+    if alert.State == alerts.StateFiring {
+        since = alert.FiredAt
+    }
+    if alert.State == alerts.StateResolved {
+        icon = "✅"
+        style = successStyle
+        since = alert.ResolvedAt
+    }
+    
+    return fmt.Sprintf("%s %s %s - %s", icon, style.Render(state), FormatTimestamp(since), alert.Message)
+}
+
+// AlertBanner возвращает строку со сводкой активных оповещений или пустую строку
+func AlertBanner(source AlertSource) string {
+    if source == nil || !source.Enabled() {
+        return ""
+    }
+    
+    // Подтвержденные и заглушенные оповещения в сводку не попадают
+    var firing []alerts.Alert
+    for _, alert := range source.Active() {
+        if alert.State == alerts.StateFiring && !alert.Acknowledged && !alert.Silenced {
+            firing = append(firing, alert)
+        }
+    }
+    if len(firing) == 0 {
+        return ""
+    }
+    
+    // Первое оповещение - самое важное (критические и новые первыми)
+    return FormatAlert(firing[0]) + InfoStyle.Render(fmt.Sprintf(" (firing: %d, press 9 for details)", len(firing)))
 }
 
 var (
@@ -89,11 +204,11 @@ func ErrorStyle() lipgloss.Style {
 
 func FormatStatus(status string) string {
 	switch status {
-	case "running", "active", "success":
+	case "running", "active", "success", types.HealthOK:
 		return successStyle.Render("● " + status)
-	case "stopped", "inactive", "failed", "error":
+	case "stopped", "inactive", "failed", "error", types.AsteriskNotRunning, types.AsteriskStalePidfile, types.HealthGone, types.HealthUnresponsive:
 		return errorStyle.Render("● " + status)
-	case "warning":
+	case "warning", types.AsteriskUnresponsive:
 		return warningStyle.Render("● " + status)
 	default:
 		return infoStyle.Render("● " + status)
@@ -238,6 +353,25 @@ func FormatTimestamp(t time.Time) string {
 	return t.Format("15:04:05")
 }
 
+// FormatCommandError возвращает строку с ошибкой команды, ее кодом завершения
+// и длительностью или пустую строку, если ошибки нет
+func FormatCommandError(result types.CheckResult) string {
+	if result.Error == "" {
+		return ""
+	}
+	var details []string
+	if result.ExitCode > 0 {
+		details = append(details, fmt.Sprintf("exit %d", result.ExitCode))
+	}
+	if result.Duration > 0 {
+		details = append(details, result.Duration.Round(time.Millisecond).String())
+	}
+	if len(details) == 0 {
+		return "Error: " + result.Error
+	}
+	return fmt.Sprintf("Error: %s (%s)", result.Error, strings.Join(details, ", "))
+}
+
 func TruncateString(s string, maxLen int) string {
     if len(s) <= maxLen {
         return s
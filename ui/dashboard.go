@@ -1,49 +1,103 @@
 package ui
 
 import (
+	"asterisk-monitor/anomaly"
+	"asterisk-monitor/collector"
+	monitor "asterisk-monitor/monitors"
+	"asterisk-monitor/storage"
 	"asterisk-monitor/types"
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
 type DashboardModel struct {
-	monitor    MonitorInterface
+	cache      *collector.Collector
+	cfg        ConfigGetter
+	store      MetricsStore
 	viewport   viewport.Model
 	metrics    types.SystemMetrics
+	asterisk   types.AsteriskProcess
 	lastUpdate time.Time
-	alerts     []string
+	alerts     AlertSource
+	notices    []string
 	ready      bool
+	task       taskRunner
+	refresh    refresher
 }
 
-func NewDashboardModel(mon MonitorInterface) DashboardModel {
+// dashboardSources - источники коллектора, из которых рисуется дашборд
+var dashboardSources = []string{collector.SourceSystem, collector.SourceStatus, collector.SourcePeers}
+
+// NewDashboardModel создает дашборд. Данные читаются из кэша коллектора cache,
+// сохранение истории в store выполняет тот, кто запускает коллектор.
+// Интервал автообновления берется из cfg.
+func NewDashboardModel(cache *collector.Collector, cfg ConfigGetter, store MetricsStore, alertSource AlertSource) DashboardModel {
 	vp := viewport.New(80, 20)
 	return DashboardModel{
-		monitor:    mon,
-		viewport:   vp,
-		metrics:    mon.GetSystemMetrics(),
-		lastUpdate: time.Now(),
-		alerts:     []string{},
+		cache:    cache,
+		cfg:      cfg,
+		store:    store,
+		viewport: vp,
+		asterisk: types.AsteriskProcess{State: "unknown"},
+		alerts:   alertSource,
+		notices:  []string{},
+		task:     newTaskRunner(),
+		refresh:  newRefresher("dashboard", cfg),
 	}
 }
 
 func (m DashboardModel) Init() tea.Cmd {
-	return nil
+	return requestLoad
 }
 
 func (m DashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
+	case AlertsUpdatedMsg:
+		if m.ready {
+			m.updateContent()
+		}
+	case NoticeMsg:
+		m.addNotice(msg.Text)
+		if m.ready {
+			m.updateContent()
+		}
+	case CollectorUpdatedMsg:
+		// На паузе дашборд показывает снимок, пока пользователь его не обновит
+		if m.refresh.Paused() {
+			break
+		}
+		m.syncFromCache()
+		if m.ready {
+			m.updateContent()
+		}
+	case refreshTickMsg:
+		due, next := m.refresh.Update(msg)
+		if due {
+			return m, tea.Batch(next, m.refreshData(m.refresh.Interval()))
+		}
+		return m, next
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "r", "R":
-			m.refreshData()
+			return m, m.refreshData(0)
+		case "p", "P":
+			m.refresh.TogglePause()
+			return m, nil
+		case "esc":
+			if m.task.Cancel() && m.ready {
+				m.updateContent()
+			}
+			return m, nil
 		case "q", "Q", "ctrl+c":
 			return m, tea.Quit
 		}
@@ -59,6 +113,26 @@ func (m DashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.viewport.Height = msg.Height - 2
 		}
 		m.updateContent()
+	case loadMsg:
+		return m, tea.Batch(m.refreshData(-1), m.refresh.Start())
+	case CancelTasksMsg:
+		m.task.Cancel()
+		m.refresh.Stop()
+		return m, nil
+	case taskDoneMsg:
+		if !m.task.Finish(msg.id) {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.addNotice(taskError("Refresh", msg.err))
+		}
+		m.syncFromCache()
+		if m.ready {
+			m.updateContent()
+		}
+		return m, nil
+	case spinner.TickMsg:
+		return m, m.task.Tick(msg)
 	}
 
 	m.viewport, cmd = m.viewport.Update(msg)
@@ -73,17 +147,34 @@ func (m DashboardModel) View() string {
 	return m.viewport.View() + "\n" + m.footer()
 }
 
-func (m *DashboardModel) refreshData() {
-	m.metrics = m.monitor.GetSystemMetrics()
-	m.lastUpdate = time.Now()
-	m.updateContent()
+// refreshData обновляет источники дашборда в фоне. Значения не старше maxAge
+// берутся из кэша: при открытии вкладки - в пределах TTL источников (maxAge < 0),
+// при автообновлении - в пределах интервала вкладки, по 'r' источники опрашиваются заново.
+func (m *DashboardModel) refreshData(maxAge time.Duration) tea.Cmd {
+	if m.task.Running() {
+		return nil
+	}
 
-	// Check for alerts
-	if m.metrics.ActiveCalls > 10 {
-		m.addAlert(fmt.Sprintf("High call volume: %d active calls", m.metrics.ActiveCalls))
+	m.syncFromCache()
+	if m.ready {
+		m.updateContent()
 	}
-	if m.metrics.CPUUsage > 80 {
-		m.addAlert(fmt.Sprintf("High CPU usage: %.1f%%", m.metrics.CPUUsage))
+	cache := m.cache
+	return m.task.Start("Refreshing", fetchTimeout, func(ctx context.Context, progress func(types.CheckResult)) (any, error) {
+		return nil, refreshSources(ctx, cache, maxAge, dashboardSources...)
+	})
+}
+
+// syncFromCache переносит в модель последние значения источников дашборда
+func (m *DashboardModel) syncFromCache() {
+	var status collector.Status
+	m.metrics, status = collector.Cached[types.SystemMetrics](m.cache, collector.SourceSystem)
+	m.lastUpdate = status.UpdatedAt
+	peers, _ := collector.Cached[[]types.SIPPeer](m.cache, collector.SourcePeers)
+	m.metrics.OnlinePeers, m.metrics.TotalPeers = monitor.CountPeers(peers)
+
+	if asterisk, _ := collector.Cached[types.AsteriskProcess](m.cache, collector.SourceStatus); asterisk.State != "" {
+		m.asterisk = asterisk
 	}
 }
 
@@ -102,31 +193,57 @@ func (m *DashboardModel) updateContent() {
 	content.WriteString(m.renderMetrics())
 	content.WriteString("\n\n")
 
+	// Asterisk Process
+	content.WriteString(m.renderProcess())
+	content.WriteString("\n\n")
+
 	// SIP Peers
 	content.WriteString(m.renderSIPPeers())
 	content.WriteString("\n\n")
 
-	// Recent Alerts
-	if len(m.alerts) > 0 {
+	// History
+	if m.store != nil {
+		content.WriteString(m.renderHistory())
+		content.WriteString("\n\n")
+	}
+
+	// Alerts
+	if m.alerts != nil && m.alerts.Enabled() {
 		content.WriteString(m.renderAlerts())
 		content.WriteString("\n\n")
 	}
 
+	// Notices
+	if len(m.notices) > 0 {
+		content.WriteString(m.renderNotices())
+		content.WriteString("\n\n")
+	}
+
+	// Data sources
+	content.WriteString(m.renderSources())
+	content.WriteString("\n\n")
+
 	m.viewport.SetContent(content.String())
 }
 
 func (m *DashboardModel) renderSystemStatus() string {
-	status := m.monitor.GetAsteriskStatus()
 	serviceStatus := m.metrics.ServiceState
 
-	return borderStyle.Render(
-		"System Status:\n" +
-			FormatStatus("Asterisk Process: "+status) + "\n" +
-			FormatStatus("Systemd Service: "+serviceStatus) + "\n" +
-			FormatMetric("PID", m.metrics.AsteriskPID) + "\n" +
-			FormatMetric("Uptime", m.metrics.Uptime) + "\n" +
-			FormatMetric("Load Average", m.metrics.LoadAverage),
-	)
+	var content strings.Builder
+	content.WriteString("System Status:\n")
+	content.WriteString("Asterisk Process: " + FormatStatus(m.asterisk.State) + "\n")
+	content.WriteString("Systemd Service: " + FormatStatus(serviceStatus) + "\n")
+	content.WriteString(FormatMetric("PID", m.metrics.AsteriskPID) + "\n")
+	if m.asterisk.WrapperPID != 0 {
+		content.WriteString(FormatMetric("Wrapper", fmt.Sprintf("safe_asterisk (PID %d)", m.asterisk.WrapperPID)) + "\n")
+	}
+	if m.asterisk.Detail != "" {
+		content.WriteString(FormatMetric("Detail", TruncateString(m.asterisk.Detail, 60)) + "\n")
+	}
+	content.WriteString(FormatMetric("Uptime", m.metrics.Uptime) + "\n")
+	content.WriteString(FormatMetric("Load Average", m.metrics.LoadAverage))
+
+	return borderStyle.Render(content.String())
 }
 
 func (m *DashboardModel) renderMetrics() string {
@@ -149,15 +266,112 @@ func (m *DashboardModel) renderMetrics() string {
 			ProgressBar(20, m.metrics.CPUUsage) + "\n" +
 			FormatMetric("Memory Usage", fmt.Sprintf("%.1f%%", m.metrics.MemoryUsage)) + " " +
 			ProgressBar(20, m.metrics.MemoryUsage) + "\n" +
-			FormatMetric("Disk Usage", fmt.Sprintf("%.1f%%", m.metrics.DiskUsage)) + " " +
-			ProgressBar(20, m.metrics.DiskUsage) + "\n" +
+			m.renderDiskUsage() +
 			FormatMetric("Active Calls", callsStr) + "\n" +
 			FormatMetric("SIP Peers", peersStatus),
 	)
 }
 
+// renderDiskUsage показывает заполненность каждой контролируемой точки монтирования,
+// каталоги Asterisk на ней и прогноз заполнения по истории
+func (m *DashboardModel) renderDiskUsage() string {
+	if len(m.metrics.Mounts) <= 1 && len(m.metrics.Dirs) == 0 {
+		return FormatMetric("Disk Usage", fmt.Sprintf("%.1f%%", m.metrics.DiskUsage)) + " " +
+			ProgressBar(20, m.metrics.DiskUsage) + "\n"
+	}
+
+	sizes := make(map[string]string, len(m.metrics.Dirs))
+	for _, dir := range m.metrics.Dirs {
+		switch {
+		case dir.Error != "":
+			sizes[dir.Path] = "unavailable"
+		case dir.ScannedAt.IsZero():
+			sizes[dir.Path] = "scanning"
+		default:
+			sizes[dir.Path] = monitor.FormatBytes(int64(dir.SizeBytes))
+		}
+	}
+
+	forecasts := m.diskForecasts()
+	var lines strings.Builder
+	for _, mount := range m.metrics.Mounts {
+		label := "Disk " + mount.Path
+		if mount.Error != "" {
+			lines.WriteString(FormatMetric(label, errorStyle.Render("unavailable")) + "\n")
+			continue
+		}
+		line := FormatMetric(label, fmt.Sprintf("%.1f%%", mount.UsedPct)) + " " + ProgressBar(20, mount.UsedPct)
+		if days, ok := forecasts[mount.Path]; ok && days < anomaly.MaxForecastDays {
+			forecast := fmt.Sprintf(" full in ~%.1f days", days)
+			if days < 7 {
+				forecast = errorStyle.Render(forecast)
+			} else if days < 30 {
+				forecast = warningStyle.Render(forecast)
+			}
+			line += forecast
+		}
+		lines.WriteString(line + "\n")
+		for _, dir := range mount.Dirs {
+			lines.WriteString("  " + FormatMetric(dir, sizes[dir]) + "\n")
+		}
+	}
+	return lines.String()
+}
+
+// diskForecasts оценивает по истории mount_usage_pct, через сколько дней
+// заполнится каждая файловая система. Без истории прогноза нет.
+func (m *DashboardModel) diskForecasts() map[string]float64 {
+	if m.store == nil {
+		return nil
+	}
+	window := anomaly.ForecastWindow(m.cfg.Get().Anomaly)
+	to := time.Now()
+	samples, err := m.store.Query(types.MetricMountUsage, to.Add(-window), to)
+	if err != nil {
+		return nil
+	}
+
+	byMount := make(map[string][]types.Sample)
+	for _, sample := range samples {
+		mount := sample.Labels["mount"]
+		byMount[mount] = append(byMount[mount], sample)
+	}
+	forecasts := make(map[string]float64, len(byMount))
+	for mount, series := range byMount {
+		if days, ok := anomaly.DaysUntilFull(series, window); ok {
+			forecasts[mount] = days
+		}
+	}
+	return forecasts
+}
+
+// renderProcess показывает ресурсы основного процесса Asterisk
+func (m *DashboardModel) renderProcess() string {
+	p := m.metrics.Process
+	if p == nil {
+		return borderStyle.Render("Asterisk Process:\n" + warningStyle.Render("Process not found"))
+	}
+
+	fds := "n/a (no access to /proc/" + strconv.Itoa(p.PID) + "/fd)"
+	if p.OpenFDs >= 0 {
+		fds = strconv.Itoa(p.OpenFDs)
+		if p.MaxFDs > 0 {
+			fds = fmt.Sprintf("%d/%d %s", p.OpenFDs, p.MaxFDs, ProgressBar(20, p.FDUsagePct))
+		}
+	}
+
+	return borderStyle.Render(
+		"Asterisk Process:\n" +
+			FormatMetric("CPU", fmt.Sprintf("%.1f%%", p.CPUPercent)) + "\n" +
+			FormatMetric("RSS", fmt.Sprintf("%.1f MB", float64(p.RSSBytes)/(1024*1024))) + "\n" +
+			FormatMetric("Threads", strconv.Itoa(p.Threads)) + "\n" +
+			FormatMetric("Open Files", fds) + "\n" +
+			FormatMetric("Context Switches", fmt.Sprintf("%.0f/s", p.CtxSwitchesPerSec)),
+	)
+}
+
 func (m *DashboardModel) renderSIPPeers() string {
-	online, total := m.monitor.GetSIPPeersCount()
+	online, total := m.metrics.OnlinePeers, m.metrics.TotalPeers
 	status := "Healthy"
 	style := successStyle
 
@@ -176,31 +390,124 @@ func (m *DashboardModel) renderSIPPeers() string {
 	)
 }
 
+func (m *DashboardModel) renderHistory() string {
+	to := time.Now()
+	from := to.Add(-time.Hour)
+
+	var history strings.Builder
+	history.WriteString("History (last hour):\n")
+
+	rows := []struct {
+		label  string
+		metric string
+		format string
+	}{
+		{"CPU Usage", types.MetricCPUUsage, "%.1f%%"},
+		{"Memory Usage", types.MetricMemoryUsage, "%.1f%%"},
+		{"Asterisk CPU", types.MetricProcessCPU, "%.1f%%"},
+		{"Asterisk RSS", types.MetricProcessRSS, "%.1f MB"},
+		{"Active Calls", types.MetricActiveCalls, "%.0f"},
+		{"Online Peers", types.MetricPeersOnline, "%.0f"},
+	}
+
+	for _, row := range rows {
+		samples, err := m.store.Query(row.metric, from, to)
+		if err != nil {
+			history.WriteString(FormatMetric(row.label, "error: "+err.Error()) + "\n")
+			continue
+		}
+		if len(samples) == 0 {
+			history.WriteString(FormatMetric(row.label, "no data") + "\n")
+			continue
+		}
+
+		agg := storage.Summarize(samples)
+		history.WriteString(FormatMetric(row.label, fmt.Sprintf("min "+row.format+" | avg "+row.format+" | max "+row.format,
+			agg.Min, agg.Avg, agg.Max)) + "\n")
+	}
+
+	return borderStyle.Render(strings.TrimSuffix(history.String(), "\n"))
+}
+
 func (m *DashboardModel) renderAlerts() string {
 	var alertsStr strings.Builder
-	alertsStr.WriteString("Recent Alerts:\n")
+	alertsStr.WriteString("Alerts:\n")
 
-	for i, alert := range m.alerts {
-		if i >= 5 { // Show only last 5 alerts
+	active := m.alerts.Active()
+	if len(active) == 0 {
+		alertsStr.WriteString(successStyle.Render("No active alerts") + "\n")
+	}
+	for _, alert := range active {
+		alertsStr.WriteString(FormatAlert(alert) + "\n")
+	}
+
+	// Show only last 5 resolved alerts
+	for i, alert := range m.alerts.History() {
+		if i >= 5 {
 			break
 		}
-		alertsStr.WriteString("⚠️  " + alert + "\n")
+		alertsStr.WriteString(FormatAlert(alert) + "\n")
 	}
 
-	return borderStyle.Render(alertsStr.String())
+	return borderStyle.Render(strings.TrimSuffix(alertsStr.String(), "\n"))
 }
 
-func (m *DashboardModel) addAlert(alert string) {
+func (m *DashboardModel) renderNotices() string {
+	var noticesStr strings.Builder
+	noticesStr.WriteString("Notices:\n")
+
+	for i, notice := range m.notices {
+		if i >= 5 { // Show only last 5 notices
+			break
+		}
+		noticesStr.WriteString("⚠️  " + notice + "\n")
+	}
+
+	return borderStyle.Render(noticesStr.String())
+}
+
+func (m *DashboardModel) addNotice(notice string) {
 	timestamp := FormatTimestamp(time.Now())
-	m.alerts = append([]string{timestamp + " - " + alert}, m.alerts...)
-	if len(m.alerts) > 10 {
-		m.alerts = m.alerts[:10]
+	m.notices = append([]string{timestamp + " - " + notice}, m.notices...)
+	if len(m.notices) > 10 {
+		m.notices = m.notices[:10]
+	}
+}
+
+// renderSources показывает возраст и ошибки источников коллектора
+func (m *DashboardModel) renderSources() string {
+	now := time.Now()
+	var rows [][]string
+	for _, status := range m.cache.Statuses() {
+		updated := "never"
+		if !status.UpdatedAt.IsZero() {
+			updated = status.Age(now).Truncate(time.Second).String() + " ago"
+		}
+		state := "ok"
+		switch {
+		case status.Fetching:
+			state = "updating"
+		case status.Err != nil:
+			state = "error: " + TruncateString(status.Err.Error(), 40)
+		case status.UpdatedAt.IsZero():
+			state = "pending"
+		}
+		rows = append(rows, []string{
+			status.Name,
+			updated,
+			status.Duration.Round(time.Millisecond).String(),
+			state,
+		})
 	}
+	return "Data Sources:\n" + FormatTable([]string{"Source", "Updated", "Took", "State"}, rows)
 }
 
 func (m *DashboardModel) footer() string {
+	if m.task.Running() {
+		return m.task.Status()
+	}
 	return lipgloss.NewStyle().
 		Foreground(colorGray).
-		Render(fmt.Sprintf("Last update: %s | Press 'r' to refresh | 'q' to quit",
-			FormatTimestamp(m.lastUpdate)))
+		Render(fmt.Sprintf("Last update: %s | %s | Press 'r' to refresh | 'q' to quit",
+			FormatTimestamp(m.lastUpdate), m.refresh.Status()))
 }
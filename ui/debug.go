@@ -1,15 +1,20 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+
+	monitor "asterisk-monitor/monitors"
+	"asterisk-monitor/types"
 )
 
 type DebugModel struct {
@@ -24,6 +29,9 @@ type DebugModel struct {
 	logFile      string
 	problemCalls []string
 	ready        bool
+	session      int // номер сеанса отладки, результаты прежних сеансов отбрасываются
+	task         taskRunner
+	poll         taskRunner
 }
 
 func NewDebugModel(mon MonitorInterface) DebugModel {
@@ -47,13 +55,15 @@ func NewDebugModel(mon MonitorInterface) DebugModel {
 		isLogging:    false,
 		logFile:      filepath.Join(logDir, "problem-calls.log"),
 		problemCalls: []string{},
+		task:         newTaskRunner(),
+		poll:         newTaskRunner(),
 		ready:        true,
 	}
 }
 
 func (m DebugModel) Init() tea.Cmd {
-	m.updateContent()
-	return nil
+	// После возврата на вкладку сбор возобновляется
+	return requestLoad
 }
 
 func (m DebugModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -63,14 +73,11 @@ func (m DebugModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "s", "S":
-			m.startDebug()
-			return m, nil
+			return m, m.startDebug()
 		case "x", "X":
-			m.stopDebug()
-			return m, nil
+			return m, m.stopDebug()
 		case "a", "A":
-			m.startAudioDebug()
-			return m, nil
+			return m, m.startAudioDebug()
 		case "l", "L":
 			m.toggleLogging()
 			return m, nil
@@ -86,10 +93,12 @@ func (m DebugModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.showProblemCalls()
 			return m, nil
 		case "r", "R":
-			m.refreshDebug()
+			return m, m.refreshDebug()
+		case "esc":
+			m.poll.Cancel()
 			return m, nil
 		case "q", "Q", "ctrl+c":
-			m.stopDebug()
+			m.disableDebugNow()
 			return m, tea.Quit
 		}
 	case tea.WindowSizeMsg:
@@ -104,46 +113,40 @@ func (m DebugModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.viewport.Height = msg.Height - 6
 		}
 		m.updateContent()
-	case debugUpdateMsg:
-		if m.isRunning {
-			newLogs := string(msg)
-			if m.filter != "" {
-				newLogs = m.filterDebugLogs(newLogs)
-			}
-			if strings.TrimSpace(newLogs) != "" {
-				m.debugLogs = newLogs + "\n" + m.debugLogs
-
-				// Если включено логирование, записываем проблемные события
-				if m.isLogging {
-					m.logProblemEvents(newLogs)
-				}
-
-				// Ограничиваем размер логов
-				lines := strings.Split(m.debugLogs, "\n")
-				if len(lines) > 100 {
-					m.debugLogs = strings.Join(lines[:100], "\n")
-				}
+	case loadMsg:
+		return m, m.refreshDebug()
+	case CancelTasksMsg:
+		// Отладка в Asterisk остается включенной, прекращается только сбор
+		m.poll.Cancel()
+		m.task.Cancel()
+		return m, nil
+	case debugPollMsg:
+		if msg.session != m.session {
+			return m, nil
+		}
+		return m, m.refreshDebug()
+	case taskDoneMsg:
+		switch {
+		case m.task.Finish(msg.id):
+			if msg.err != nil {
+				m.debugLogs = taskError("Debug command", msg.err) + "\n" + m.debugLogs
 				m.updateContent()
 			}
-			// Продолжаем обновление если все еще запущено
-			if m.isRunning {
-				return m, tea.Tick(2*time.Second, func(t time.Time) tea.Msg {
-					return m.getDebugLogs()
-				})
+			// Первый сбор через секунду, чтобы Asterisk успел начать вывод
+			return m, m.schedulePoll(time.Second)
+		case m.poll.Finish(msg.id):
+			if msg.err == nil {
+				m.applySnapshot(msg.value.(debugSnapshot))
 			}
-		}
-	case audioStatsMsg:
-		if m.isRunning && m.debugMode == "audio" {
-			m.audioStats = string(msg)
-			m.updateContent()
-
-			// Продолжаем сбор аудиостатистики
-			if m.isRunning {
-				return m, tea.Tick(3*time.Second, func(t time.Time) tea.Msg {
-					return m.getAudioStats()
-				})
+			interval := 2 * time.Second
+			if m.debugMode == "audio" {
+				interval = 3 * time.Second
 			}
+			return m, m.schedulePoll(interval)
 		}
+		return m, nil
+	case spinner.TickMsg:
+		return m, tea.Batch(m.task.Tick(msg), m.poll.Tick(msg))
 	}
 
 	m.viewport, cmd = m.viewport.Update(msg)
@@ -155,33 +158,68 @@ func (m DebugModel) View() string {
 		return "Initializing debug..."
 	}
 
-	return m.viewport.View() + "\n" + m.footer()
+	status := m.task.Status()
+	if status == "" {
+		status = m.poll.Status()
+	}
+	if status != "" {
+		status += "\n"
+	}
+
+	return m.viewport.View() + "\n" + status + m.footer()
 }
 
-// Messages
-type debugUpdateMsg string
-type audioStatsMsg string
+// Консоль Asterisk читается debugCaptureTime, из нее берется не больше debugLogLines строк
+const (
+	debugCaptureTime = 5 * time.Second
+	debugLogLines    = 20
+)
 
-// Debug functions
-func (m *DebugModel) startDebug() {
-	if m.isRunning {
-		return
+// debugPollMsg запускает очередной сбор отладочного вывода сессии session
+type debugPollMsg struct {
+	session int
+}
+
+// debugSnapshot - результат одного сбора отладочного вывода
+type debugSnapshot struct {
+	logs       string
+	audioStats string
+}
+
+var (
+	debugOnCommands = []string{
+		"sip set debug on",
+		"rtp set debug on",
+		"core set debug 1",
 	}
 
-	m.isRunning = true
-	m.debugMode = "basic"
+	audioDebugOnCommands = []string{
+		"sip set debug on",
+		"rtp set debug on",
+		"rtcp set debug on",
+		"core set debug 3",
+		"jitterbuffer set debug on",
+	}
 
-	// Включаем базовые дебаг режимы
-	commands := []string{
-		"asterisk -rx 'sip set debug on'",
-		"asterisk -rx 'rtp set debug on'",
-		"asterisk -rx 'core set debug 1'",
+	debugOffCommands = []string{
+		"sip set debug off",
+		"rtp set debug off",
+		"rtcp set debug off",
+		"core set debug 0",
+		"jitterbuffer set debug off",
 	}
+)
 
-	for _, cmd := range commands {
-		m.monitor.ExecuteCommand("Enable Debug", cmd)
+// Debug functions
+func (m *DebugModel) startDebug() tea.Cmd {
+	if m.isRunning {
+		return nil
 	}
 
+	m.isRunning = true
+	m.debugMode = "basic"
+	m.session++
+
 	m.debugLogs = "=== BASIC DEBUG MODE STARTED ===\n"
 	m.debugLogs += "SIP Debug: ON\n"
 	m.debugLogs += "RTP Debug: ON\n"
@@ -192,34 +230,22 @@ func (m *DebugModel) startDebug() {
 
 	m.updateContent()
 
-	// Запускаем получение логов
-	go func() {
-		time.Sleep(1 * time.Second)
-		m.getDebugLogs()
-	}()
+	// Включаем базовые дебаг режимы, сбор логов начнется после выполнения команд
+	return m.runDebugCommands("Enabling debug", "Enable Debug", debugOnCommands)
 }
 
-func (m *DebugModel) startAudioDebug() {
+func (m *DebugModel) startAudioDebug() tea.Cmd {
+	// Выключение прежнего режима и включение аудио выполняются одной задачей
+	var commands []string
 	if m.isRunning {
-		m.stopDebug()
-		time.Sleep(1 * time.Second)
+		commands = append(commands, debugOffCommands...)
 	}
+	commands = append(commands, audioDebugOnCommands...)
 
+	m.poll.Cancel()
 	m.isRunning = true
 	m.debugMode = "audio"
-
-	// Включаем расширенные дебаг режимы для аудио проблем
-	commands := []string{
-		"asterisk -rx 'sip set debug on'",
-		"asterisk -rx 'rtp set debug on'",
-		"asterisk -rx 'rtcp set debug on'",
-		"asterisk -rx 'core set debug 3'",
-		"asterisk -rx 'jitterbuffer set debug on'",
-	}
-
-	for _, cmd := range commands {
-		m.monitor.ExecuteCommand("Enable Audio Debug", cmd)
-	}
+	m.session++
 
 	m.debugLogs = "=== AUDIO DEBUG MODE STARTED ===\n"
 	m.debugLogs += "🔊 Focus: Audio Quality Issues\n"
@@ -235,37 +261,63 @@ func (m *DebugModel) startAudioDebug() {
 
 	m.updateContent()
 
-	// Запускаем получение логов и статистики
-	go func() {
-		time.Sleep(1 * time.Second)
-		m.getDebugLogs()
-		m.getAudioStats()
-	}()
+	return m.runDebugCommands("Enabling audio debug", "Enable Audio Debug", commands)
 }
 
-func (m *DebugModel) stopDebug() {
+func (m *DebugModel) stopDebug() tea.Cmd {
 	if !m.isRunning {
-		return
+		return nil
 	}
 
 	m.isRunning = false
+	m.session++
+	m.poll.Cancel()
+
+	m.debugLogs += "\n=== DEBUG MODE STOPPED ===\n"
+	m.audioStats = ""
+	m.updateContent()
 
 	// Выключаем все дебаг режимы
-	commands := []string{
-		"asterisk -rx 'sip set debug off'",
-		"asterisk -rx 'rtp set debug off'",
-		"asterisk -rx 'rtcp set debug off'",
-		"asterisk -rx 'core set debug 0'",
-		"asterisk -rx 'jitterbuffer set debug off'",
-	}
+	return m.runDebugCommands("Disabling debug", "Disable Debug", debugOffCommands)
+}
 
-	for _, cmd := range commands {
-		m.monitor.ExecuteCommand("Disable Debug", cmd)
+// runDebugCommands выполняет команды переключения отладки в фоне. Команды
+// доводятся до конца и после отмены, иначе Asterisk остался бы в полуотладочном режиме.
+func (m *DebugModel) runDebugCommands(title, name string, commands []string) tea.Cmd {
+	mon := m.monitor
+	return m.task.Start(title, commandTimeout*time.Duration(len(commands)), func(ctx context.Context, progress func(types.CheckResult)) (any, error) {
+		ctx = context.WithoutCancel(ctx)
+		for _, command := range commands {
+			cmdCtx, cancel := context.WithTimeout(ctx, commandTimeout)
+			mon.AsteriskCommand(cmdCtx, name, command)
+			cancel()
+		}
+		return nil, nil
+	})
+}
+
+// disableDebugNow выключает отладку перед выходом из программы
+func (m *DebugModel) disableDebugNow() {
+	if !m.isRunning {
+		return
 	}
+	m.isRunning = false
+	for _, command := range debugOffCommands {
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		m.monitor.AsteriskCommand(ctx, "Disable Debug", command)
+		cancel()
+	}
+}
 
-	m.debugLogs += "\n=== DEBUG MODE STOPPED ===\n"
-	m.audioStats = ""
-	m.updateContent()
+// schedulePoll планирует следующий сбор отладочного вывода текущей сессии
+func (m *DebugModel) schedulePoll(delay time.Duration) tea.Cmd {
+	if !m.isRunning {
+		return nil
+	}
+	session := m.session
+	return tea.Tick(delay, func(time.Time) tea.Msg {
+		return debugPollMsg{session: session}
+	})
 }
 
 func (m *DebugModel) toggleLogging() {
@@ -283,13 +335,51 @@ func (m *DebugModel) toggleLogging() {
 	m.updateContent()
 }
 
-func (m *DebugModel) refreshDebug() {
-	if m.isRunning {
-		m.getDebugLogs()
-		if m.debugMode == "audio" {
-			m.getAudioStats()
+// refreshDebug запускает сбор отладочного вывода, если отладка включена
+// и предыдущий сбор и команды переключения завершены
+func (m *DebugModel) refreshDebug() tea.Cmd {
+	if !m.isRunning || m.poll.Running() || m.task.Running() {
+		return nil
+	}
+
+	mon, filter, audio := m.monitor, m.filter, m.debugMode == "audio"
+	return m.poll.Start("Collecting debug output", fetchTimeout, func(ctx context.Context, progress func(types.CheckResult)) (any, error) {
+		snapshot := debugSnapshot{logs: collectDebugLogs(ctx, mon, filter)}
+		if audio {
+			snapshot.audioStats = collectAudioStats(ctx, mon)
 		}
+		return snapshot, nil
+	})
+}
+
+// applySnapshot добавляет собранный вывод к журналу вкладки
+func (m *DebugModel) applySnapshot(snapshot debugSnapshot) {
+	if m.debugMode == "audio" && snapshot.audioStats != "" {
+		m.audioStats = snapshot.audioStats
+	}
+
+	newLogs := snapshot.logs
+	if m.filter != "" {
+		newLogs = m.filterDebugLogs(newLogs)
+	}
+	if strings.TrimSpace(newLogs) == "" {
+		m.updateContent()
+		return
+	}
+
+	m.debugLogs = newLogs + "\n" + m.debugLogs
+
+	// Если включено логирование, записываем проблемные события
+	if m.isLogging {
+		m.logProblemEvents(newLogs)
 	}
+
+	// Ограничиваем размер логов
+	lines := strings.Split(m.debugLogs, "\n")
+	if len(lines) > 100 {
+		m.debugLogs = strings.Join(lines[:100], "\n")
+	}
+	m.updateContent()
 }
 
 func (m *DebugModel) showProblemCalls() {
@@ -375,73 +465,131 @@ func (m *DebugModel) writeToLogFile(content string) {
 	file.WriteString(content)
 }
 
-func (m *DebugModel) getDebugLogs() tea.Msg {
-	if !m.isRunning {
-		return nil
+// collectDebugLogs читает отладочный вывод консоли Asterisk в течение
+// debugCaptureTime и оставляет строки, содержащие одно из слов фильтра через '|'
+func collectDebugLogs(ctx context.Context, mon MonitorInterface, filter string) string {
+	// Консоль не завершается сама, поэтому ее остановка по сроку - не ошибка
+	captureCtx, cancel := context.WithTimeout(ctx, debugCaptureTime)
+	defer cancel()
+	result := mon.Exec(captureCtx, "Debug Logs", "asterisk", "-rvvv")
+
+	keywords := strings.Split(filter, "|")
+	var lines []string
+	for _, line := range strings.Split(result.Message+"\n"+result.Error, "\n") {
+		if strings.TrimSpace(line) != "" && containsAny(line, keywords) {
+			lines = append(lines, line)
+			if len(lines) == debugLogLines {
+				break
+			}
+		}
 	}
 
-	// Получаем логи с фильтрацией проблем
-	cmd := fmt.Sprintf(
-		"timeout 5 asterisk -rvvv 2>&1 | grep -E '%s' | head -20 || echo 'No debug output'",
-		m.filter,
-	)
-
-	result := m.monitor.ExecuteCommand("Debug Logs", cmd)
-
-	if result.Status == "success" && strings.TrimSpace(result.Message) != "" {
-		return debugUpdateMsg(result.Message)
+	if len(lines) > 0 {
+		return strings.Join(lines, "\n")
 	}
-
-	return debugUpdateMsg("... waiting for debug events ...")
+	return "... waiting for debug events ..."
 }
 
-func (m *DebugModel) getAudioStats() tea.Msg {
-	if !m.isRunning || m.debugMode != "audio" {
-		return nil
-	}
-
+// collectAudioStats собирает статистику RTP, кодеков и сети для аудио-отладки
+func collectAudioStats(ctx context.Context, mon MonitorInterface) string {
 	// Собираем расширенную статистику по аудио проблемам
-	commands := []string{
+	sections := []struct {
+		title  string
+		result func() types.CheckResult
+		filter func(string) string
+	}{
 		// Статистика RTP
-		"asterisk -rx 'rtp show stats' 2>/dev/null | head -10",
+		{"📊 RTP Statistics:", func() types.CheckResult { return mon.AsteriskCommand(ctx, "AudioStat0", "rtp show stats") }, firstLines(10)},
 		// Активные RTP сессии
-		"asterisk -rx 'rtp show peers' 2>/dev/null | head -10",
+		{"🔗 RTP Sessions:", func() types.CheckResult { return mon.AsteriskCommand(ctx, "AudioStat1", "rtp show peers") }, firstLines(10)},
 		// Проблемы с кодеками
-		"asterisk -rx 'core show translation' 2>/dev/null | grep -E '(ulaw|alaw|gsm|g729)'",
+		{"🎵 Codec Status:", func() types.CheckResult { return mon.AsteriskCommand(ctx, "AudioStat2", "core show translation") }, linesWith("ulaw", "alaw", "gsm", "g729")},
 		// Статус джиттер-буферов
-		"asterisk -rx 'jitterbuffer show' 2>/dev/null | head -5",
-		// Сетевые проблемы
-		"ping -c 2 8.8.8.8 2>/dev/null | grep 'packet loss' || echo 'Network check failed'",
-		// Нагрузка системы
-		"top -bn1 | grep 'Cpu(s)' | awk '{print $2}' | cut -d'%' -f1",
+		{"📈 Jitter Buffers:", func() types.CheckResult { return mon.AsteriskCommand(ctx, "AudioStat3", "jitterbuffer show") }, firstLines(5)},
 	}
 
 	var stats strings.Builder
 	stats.WriteString("=== AUDIO QUALITY STATS ===\n\n")
 
-	for i, cmd := range commands {
-		result := m.monitor.ExecuteCommand(fmt.Sprintf("AudioStat%d", i), cmd)
-		if result.Status == "success" && strings.TrimSpace(result.Message) != "" {
-			switch i {
-			case 0:
-				stats.WriteString("📊 RTP Statistics:\n")
-			case 1:
-				stats.WriteString("\n🔗 RTP Sessions:\n")
-			case 2:
-				stats.WriteString("\n🎵 Codec Status:\n")
-			case 3:
-				stats.WriteString("\n📈 Jitter Buffers:\n")
-			case 4:
-				stats.WriteString("\n🌐 Network:\n")
-			case 5:
-				stats.WriteString(fmt.Sprintf("\n💻 CPU Load: %s%%\n", result.Message))
-				continue
+	for i, section := range sections {
+		if ctx.Err() != nil {
+			break
+		}
+		output := section.filter(section.result().Message)
+		if output == "" {
+			continue
+		}
+		if i > 0 {
+			stats.WriteString("\n")
+		}
+		stats.WriteString(section.title + "\n" + output + "\n")
+	}
+
+	// Потери в ядре: ошибки интерфейсов и переполнение буферов UDP сокетов
+	stats.WriteString("\n🌐 Network:\n" + formatNetworkHealth(mon.GetNetworkHealth()))
+
+	// Нагрузка системы
+	stats.WriteString(fmt.Sprintf("\n💻 CPU Load: %.1f%%\n", mon.GetCPUUsage()))
+
+	return stats.String()
+}
+
+// formatNetworkHealth выводит интерфейсы, счетчики UDP ядра и очереди UDP
+// сокетов Asterisk. Скорости появляются со второго опроса.
+func formatNetworkHealth(health types.NetworkHealth) string {
+	if health.Error != "" {
+		return errorStyle.Render(health.Error) + "\n"
+	}
+
+	var out strings.Builder
+	for _, iface := range health.Interfaces {
+		if iface.Name == "lo" {
+			continue
+		}
+		line := fmt.Sprintf("%-10s rx %s/s %.0f pps  tx %s/s %.0f pps  errors %d/%d  drops %d/%d",
+			iface.Name,
+			monitor.FormatBytes(int64(iface.RxBytesPerSec)), iface.RxPacketsPerSec,
+			monitor.FormatBytes(int64(iface.TxBytesPerSec)), iface.TxPacketsPerSec,
+			iface.RxErrors, iface.TxErrors, iface.RxDrops, iface.TxDrops)
+		if iface.RxErrorsPerSec+iface.RxDropsPerSec+iface.TxErrorsPerSec+iface.TxDropsPerSec > 0 {
+			line = warningStyle.Render(line + "  (growing)")
+		}
+		out.WriteString(line + "\n")
+	}
+
+	udp := health.UDP
+	line := fmt.Sprintf("UDP        in errors %d (%.1f/s)  receive buffer errors %d (%.1f/s)  send buffer errors %d",
+		udp.InErrors, udp.InErrorsPerSec, udp.RcvbufErrors, udp.RcvbufErrorsPerSec, udp.SndbufErrors)
+	if udp.RcvbufErrorsPerSec > 0 {
+		line = errorStyle.Render(line) + fmt.Sprintf("\n           kernel drops RTP: raise net.core.rmem_default (%d) / rmem_max (%d)", health.RmemDefault, health.RmemMax)
+	}
+	out.WriteString(line + "\n")
+
+	switch {
+	case health.SocketsError != "":
+		out.WriteString("Asterisk sockets: " + warningStyle.Render(health.SocketsError) + "\n")
+	case len(health.Sockets) == 0:
+		out.WriteString("Asterisk sockets: no UDP sockets\n")
+	default:
+		out.WriteString(fmt.Sprintf("Asterisk sockets: %d UDP, drops %.1f/s\n", len(health.Sockets), health.DropsPerSec))
+		for i, socket := range health.Sockets {
+			if i == 5 {
+				out.WriteString(fmt.Sprintf("  ... %d more\n", len(health.Sockets)-5))
+				break
+			}
+			line := fmt.Sprintf("  %-28s rx queue %s  tx queue %s  drops %d",
+				socket.Local, monitor.FormatBytes(int64(socket.RxQueue)), monitor.FormatBytes(int64(socket.TxQueue)), socket.Drops)
+			if socket.RxQueue > 0 {
+				line = warningStyle.Render(line)
 			}
-			stats.WriteString(result.Message + "\n")
+			out.WriteString(line + "\n")
 		}
 	}
 
-	return audioStatsMsg(stats.String())
+	if !health.Rated {
+		out.WriteString(lipgloss.NewStyle().Foreground(colorGray).Render("Rates appear after the next refresh") + "\n")
+	}
+	return out.String()
 }
 
 func (m *DebugModel) filterDebugLogs(logs string) string {
@@ -492,6 +640,30 @@ func (m *DebugModel) highlightAudioProblems(line string) string {
 	return line
 }
 
+// firstLines возвращает обработку вывода, которая оставляет первые n строк
+func firstLines(n int) func(string) string {
+	return func(text string) string {
+		lines := strings.Split(strings.TrimSpace(text), "\n")
+		if len(lines) > n {
+			lines = lines[:n]
+		}
+		return strings.Join(lines, "\n")
+	}
+}
+
+// linesWith возвращает обработку вывода, которая оставляет строки с одним из слов keywords
+func linesWith(keywords ...string) func(string) string {
+	return func(text string) string {
+		var lines []string
+		for _, line := range strings.Split(text, "\n") {
+			if containsAny(line, keywords) {
+				lines = append(lines, line)
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+}
+
 func containsAny(text string, keywords []string) bool {
 	textUpper := strings.ToUpper(text)
 	for _, keyword := range keywords {
@@ -565,7 +737,8 @@ Common Audio Issues Detected:
 • RTP Packet Loss & Jitter
 • Jitter Buffer Performance  
 • Codec Compatibility
-• Network Latency & Stability
+• Interface Errors & Drops
+• UDP Receive Buffer Overflows
 • System Resource Usage
 
 ⚡ Commands:
@@ -1,11 +1,13 @@
 package ui
 
 import (
+	monitor "asterisk-monitor/monitors"
 	"asterisk-monitor/types"
+	"context"
 	"fmt"
 	"strings"
-	"time"
 
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -16,9 +18,16 @@ type DiagnosticsModel struct {
 	viewport viewport.Model
 	results  []types.CheckResult
 	ready    bool
+	task     taskRunner
+	total    int
+	notice   string
+	full     bool // последним запускалась полная диагностика
+	refresh  refresher
 }
 
-func NewDiagnosticsModel(mon MonitorInterface) DiagnosticsModel {
+// NewDiagnosticsModel создает вкладку диагностики. Интервал автоматического
+// повтора последней диагностики берется из cfg.
+func NewDiagnosticsModel(mon MonitorInterface, cfg ConfigGetter) DiagnosticsModel {
 	vp := viewport.New(80, 20)
 	vp.Style = lipgloss.NewStyle().
 		BorderStyle(lipgloss.RoundedBorder()).
@@ -28,13 +37,14 @@ func NewDiagnosticsModel(mon MonitorInterface) DiagnosticsModel {
 		monitor:  mon,
 		viewport: vp,
 		results:  []types.CheckResult{},
+		task:     newTaskRunner(),
+		refresh:  newRefresher("diagnostics", cfg),
 		ready:    true, // Сразу готов к работе
 	}
 }
 
 func (m DiagnosticsModel) Init() tea.Cmd {
-	m.updateContent()
-	return nil
+	return requestLoad
 }
 
 func (m DiagnosticsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -44,15 +54,26 @@ func (m DiagnosticsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "r", "R":
-			m.runQuickDiagnostics()
-			return m, nil
+			return m, m.runQuickDiagnostics()
 		case "f", "F":
-			m.runFullDiagnostics()
-			return m, nil
+			return m, m.runFullDiagnostics()
 		case "c", "C":
+			if m.task.Running() {
+				return m, nil
+			}
 			m.results = []types.CheckResult{}
+			m.notice = ""
 			m.updateContent()
 			return m, nil
+		case "p", "P":
+			m.refresh.TogglePause()
+			return m, nil
+		case "esc":
+			if m.task.Cancel() {
+				m.notice = taskError("Diagnostics", context.Canceled)
+				m.updateContent()
+			}
+			return m, nil
 		case "q", "Q", "ctrl+c":
 			return m, tea.Quit
 		}
@@ -68,6 +89,44 @@ func (m DiagnosticsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.viewport.Height = msg.Height - 4
 		}
 		m.updateContent()
+	case loadMsg:
+		m.updateContent()
+		return m, m.refresh.Start()
+	case refreshTickMsg:
+		due, next := m.refresh.Update(msg)
+		// Повтор пропускается, пока предыдущая диагностика не завершилась
+		if due && !m.task.Running() {
+			if m.full {
+				return m, tea.Batch(next, m.runFullDiagnostics())
+			}
+			return m, tea.Batch(next, m.runQuickDiagnostics())
+		}
+		return m, next
+	case CancelTasksMsg:
+		m.refresh.Stop()
+		if m.task.Cancel() {
+			m.notice = taskError("Diagnostics", context.Canceled)
+			m.updateContent()
+		}
+		return m, nil
+	case taskProgressMsg:
+		if !m.task.Owns(msg.id) {
+			return m, nil
+		}
+		m.results = append(m.results, msg.result)
+		m.updateContent()
+		return m, msg.next
+	case taskDoneMsg:
+		if !m.task.Finish(msg.id) {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.notice = taskError("Diagnostics", msg.err)
+		}
+		m.updateContent()
+		return m, nil
+	case spinner.TickMsg:
+		return m, m.task.Tick(msg)
 	}
 
 	m.viewport, cmd = m.viewport.Update(msg)
@@ -79,117 +138,37 @@ func (m DiagnosticsModel) View() string {
 		return "\nInitializing diagnostics..."
 	}
 
-	header := TitleStyle.Render("🔍 Asterisk Diagnostics") + "\n\n"
+	header := TitleStyle.Render("🔍 Asterisk Diagnostics") + "\n"
+	if m.task.Running() {
+		header += fmt.Sprintf("%s %s\n", m.task.Status(), InfoStyle.Render(fmt.Sprintf("[%d/%d]", len(m.results), m.total)))
+	} else if m.notice != "" {
+		header += warningStyle.Render(m.notice) + "\n"
+	} else {
+		header += "\n"
+	}
 	footer := "\n" + m.footer()
 	
 	return header + m.viewport.View() + footer
 }
 
-func (m *DiagnosticsModel) runQuickDiagnostics() {
-	m.results = []types.CheckResult{}
-	m.updateContent()
-
-	// Просто выполняем проверки синхронно
-	checks := []struct {
-		name string
-		cmd  string
-	}{
-		{"Service Status", "systemctl is-active asterisk"},
-		{"Asterisk Process", "ps aux | grep -v grep | grep asterisk | head -1"},
-		{"Version Info", "asterisk -rx 'core show version' | head -1"},
-	}
-
-	for _, check := range checks {
-		result := m.monitor.ExecuteCommand(check.name, check.cmd)
-		m.results = append(m.results, result)
-		m.updateContent()
-		time.Sleep(300 * time.Millisecond)
-	}
-
-	// Добавляем SIP и каналы
-	online, total := m.monitor.GetSIPPeersCount()
-	sipResult := types.CheckResult{
-		Name:      "SIP Peers",
-		Status:    "success",
-		Message:   fmt.Sprintf("%d online out of %d total", online, total),
-		Timestamp: time.Now(),
-	}
-	if online == 0 && total > 0 {
-		sipResult.Status = "warning"
-		sipResult.Message = fmt.Sprintf("No peers online (total: %d)", total)
-	}
-	m.results = append(m.results, sipResult)
-	m.updateContent()
+func (m *DiagnosticsModel) runQuickDiagnostics() tea.Cmd {
+	m.full = false
+	return m.runChecks("Running quick diagnostics", m.monitor.DiagnosticChecks(false))
+}
 
-	count := m.monitor.GetActiveCallsCount()
-	channelsResult := types.CheckResult{
-		Name:      "Active Channels",
-		Status:    "success",
-		Message:   fmt.Sprintf("%d active channels", count),
-		Timestamp: time.Now(),
-	}
-	if count > 10 {
-		channelsResult.Status = "warning"
-		channelsResult.Message = fmt.Sprintf("High channel count: %d", count)
-	}
-	m.results = append(m.results, channelsResult)
-	m.updateContent()
+func (m *DiagnosticsModel) runFullDiagnostics() tea.Cmd {
+	m.full = true
+	return m.runChecks("Running full diagnostics", m.monitor.DiagnosticChecks(true))
 }
 
-func (m *DiagnosticsModel) runFullDiagnostics() {
+// runChecks запускает проверки в фоне, результаты приходят по одной через taskProgressMsg
+func (m *DiagnosticsModel) runChecks(name string, checks []monitor.Check) tea.Cmd {
 	m.results = []types.CheckResult{}
+	m.total = len(checks)
+	m.notice = ""
 	m.updateContent()
 
-	checks := []struct {
-		name string
-		cmd  string
-	}{
-		{"Service Status", "systemctl is-active asterisk"},
-		{"Asterisk Process", "ps aux | grep -v grep | grep asterisk | head -1"},
-		{"Version Info", "asterisk -rx 'core show version' | head -1"},
-		{"Codecs", "asterisk -rx 'core show translation' | head -5"},
-		{"Dialplan", "asterisk -rx 'dialplan show' | grep -c 'Context'"},
-		{"Modules", "asterisk -rx 'module show' | grep -c 'Loaded'"},
-		{"Network", "ping -c 2 8.8.8.8 | grep 'packet loss' || echo 'Network test failed'"},
-		{"Ports", "netstat -tlnp | grep -E ':(5060|5038)' | grep LISTEN || echo 'No SIP/AMI ports found'"},
-		{"System Load", "uptime"},
-	}
-
-	for _, check := range checks {
-		result := m.monitor.ExecuteCommand(check.name, check.cmd)
-		m.results = append(m.results, result)
-		m.updateContent()
-		time.Sleep(200 * time.Millisecond)
-	}
-
-	// Добавляем SIP и каналы
-	online, total := m.monitor.GetSIPPeersCount()
-	sipResult := types.CheckResult{
-		Name:      "SIP Peers",
-		Status:    "success",
-		Message:   fmt.Sprintf("%d online out of %d total", online, total),
-		Timestamp: time.Now(),
-	}
-	if online == 0 && total > 0 {
-		sipResult.Status = "warning"
-		sipResult.Message = fmt.Sprintf("No peers online (total: %d)", total)
-	}
-	m.results = append(m.results, sipResult)
-	m.updateContent()
-
-	count := m.monitor.GetActiveCallsCount()
-	channelsResult := types.CheckResult{
-		Name:      "Active Channels",
-		Status:    "success",
-		Message:   fmt.Sprintf("%d active channels", count),
-		Timestamp: time.Now(),
-	}
-	if count > 10 {
-		channelsResult.Status = "warning"
-		channelsResult.Message = fmt.Sprintf("High channel count: %d", count)
-	}
-	m.results = append(m.results, channelsResult)
-	m.updateContent()
+	return m.task.Start(name, checksTimeout, checksTask(checks))
 }
 
 func (m *DiagnosticsModel) updateContent() {
@@ -205,6 +184,7 @@ func (m *DiagnosticsModel) updateContent() {
 		content.WriteString("• Press 'r' for quick check\n")
 		content.WriteString("• Press 'f' for full diagnostics\n") 
 		content.WriteString("• Press 'c' to clear results\n")
+		content.WriteString("• Press 'p' to pause automatic re-runs\n")
 		content.WriteString("• Press 'q' to quit\n")
 	} else {
 		content.WriteString(m.renderResults())
@@ -238,7 +218,7 @@ func (m *DiagnosticsModel) renderResults() string {
 
 		builder.WriteString(fmt.Sprintf("%s %s: %s\n", statusIcon, result.Name, result.Message))
 		if result.Error != "" {
-			builder.WriteString(fmt.Sprintf("   Error: %s\n", result.Error))
+			builder.WriteString("   " + FormatCommandError(result) + "\n")
 		}
 	}
 
@@ -255,5 +235,5 @@ func (m *DiagnosticsModel) renderResults() string {
 func (m *DiagnosticsModel) footer() string {
 	return lipgloss.NewStyle().
 		Foreground(colorGray).
-		Render("Press 'r' for quick check, 'f' for full diagnostics, 'c' to clear, 'q' to quit")
+		Render(m.refresh.Status() + " | Press 'r' for quick check, 'f' for full diagnostics, 'c' to clear, Esc to cancel, 'q' to quit")
 }
\ No newline at end of file
@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// internalsPage - страница вкладки Internals, устроена как обычный вид
+type internalsPage struct {
+	title string
+	model tea.Model
+}
+
+// InternalsModel объединяет страницы о внутреннем состоянии Asterisk.
+// Страницы переключаются Tab и Shift+Tab, задачи прежней страницы отменяются.
+type InternalsModel struct {
+	pages   []internalsPage
+	current int
+}
+
+// NewInternalsModel создает вкладку Internals из страниц
+func NewInternalsModel(timeline TimelineModel, watch WatchdogModel, taskprocessors TaskprocessorsModel, modules ModulesModel) InternalsModel {
+	return InternalsModel{
+		pages: []internalsPage{
+			{title: "Restart Timeline", model: timeline},
+			{title: "Watchdog", model: watch},
+			{title: "Taskprocessors", model: taskprocessors},
+			{title: "Modules", model: modules},
+		},
+	}
+}
+
+func (m InternalsModel) Init() tea.Cmd {
+	return m.pages[m.current].model.Init()
+}
+
+func (m InternalsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "tab":
+			return m, m.switchPage((m.current + 1) % len(m.pages))
+		case "shift+tab":
+			return m, m.switchPage((m.current + len(m.pages) - 1) % len(m.pages))
+		}
+	case tea.WindowSizeMsg:
+		// Размер нужен всем страницам, строка вкладок занимает одну строку
+		msg.Height--
+		var cmds []tea.Cmd
+		for i := range m.pages {
+			cmds = append(cmds, m.updatePage(i, msg))
+		}
+		return m, tea.Batch(cmds...)
+	}
+
+	return m, m.updatePage(m.current, msg)
+}
+
+func (m InternalsModel) View() string {
+	return m.renderTabs() + "\n" + m.pages[m.current].model.View()
+}
+
+// switchPage делает страницу i текущей
+func (m *InternalsModel) switchPage(i int) tea.Cmd {
+	if i == m.current {
+		return nil
+	}
+	m.updatePage(m.current, CancelTasksMsg{})
+	m.current = i
+	return m.pages[i].model.Init()
+}
+
+func (m *InternalsModel) updatePage(i int, msg tea.Msg) tea.Cmd {
+	model, cmd := m.pages[i].model.Update(msg)
+	// Страницы хранятся по значению, срез копируется, чтобы не менять прежнюю модель
+	pages := append([]internalsPage(nil), m.pages...)
+	pages[i].model = model
+	m.pages = pages
+	return cmd
+}
+
+func (m InternalsModel) renderTabs() string {
+	var tabs []string
+	for i, page := range m.pages {
+		if i == m.current {
+			tabs = append(tabs, TitleStyle.Render(page.title))
+		} else {
+			tabs = append(tabs, lipgloss.NewStyle().Foreground(colorGray).Padding(0, 1).Render(page.title))
+		}
+	}
+	return strings.Join(tabs, "│") + "  " + InfoStyle.Render("(Tab to switch)")
+}
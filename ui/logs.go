@@ -1,13 +1,17 @@
 package ui
 
 import (
+	"context"
 	"strconv"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+
+	"asterisk-monitor/types"
 )
 
 type LogsModel struct {
@@ -18,6 +22,8 @@ type LogsModel struct {
 	filterInput textinput.Model
 	logs        string
 	ready       bool
+	task        taskRunner
+	notice      string
 }
 
 func NewLogsModel(mon MonitorInterface) LogsModel {
@@ -43,6 +49,7 @@ func NewLogsModel(mon MonitorInterface) LogsModel {
 		linesInput:  lines,
 		levelInput:  level,
 		filterInput: filter,
+		task:        newTaskRunner(),
 		ready:       true,
 	}
 }
@@ -59,8 +66,11 @@ func (m LogsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "enter":
-			m.loadLogs()
-			m.updateContent()
+			return m, m.loadLogs()
+		case "esc":
+			if m.task.Cancel() {
+				m.notice = taskError("Loading logs", context.Canceled)
+			}
 			return m, nil
 		case "q", "Q", "ctrl+c":
 			return m, tea.Quit
@@ -77,6 +87,22 @@ func (m LogsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.viewport.Height = msg.Height - 6
 		}
 		m.updateContent()
+	case CancelTasksMsg:
+		m.task.Cancel()
+		return m, nil
+	case taskDoneMsg:
+		if !m.task.Finish(msg.id) {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.notice = taskError("Loading logs", msg.err)
+			return m, nil
+		}
+		m.logs = msg.value.(string)
+		m.updateContent()
+		return m, nil
+	case spinner.TickMsg:
+		return m, m.task.Tick(msg)
 	}
 
 	m.linesInput, _ = m.linesInput.Update(msg)
@@ -101,13 +127,18 @@ func (m LogsModel) View() string {
 	return controls.String() + "\n" + m.viewport.View() + "\n" + m.footer()
 }
 
-func (m *LogsModel) loadLogs() {
+// loadLogs читает журнал в фоне
+func (m *LogsModel) loadLogs() tea.Cmd {
 	lines, _ := strconv.Atoi(m.linesInput.Value())
 	if lines == 0 {
 		lines = 50
 	}
 
-	m.logs = m.monitor.GetAsteriskLogs(lines, m.levelInput.Value(), m.filterInput.Value())
+	m.notice = ""
+	mon, level, filter := m.monitor, m.levelInput.Value(), m.filterInput.Value()
+	return m.task.Start("Loading logs", fetchTimeout, func(ctx context.Context, progress func(types.CheckResult)) (any, error) {
+		return mon.GetAsteriskLogs(lines, level, filter), nil
+	})
 }
 
 func (m *LogsModel) updateContent() {
@@ -130,6 +161,12 @@ func (m *LogsModel) updateContent() {
 }
 
 func (m *LogsModel) footer() string {
+	if m.task.Running() {
+		return m.task.Status()
+	}
+	if m.notice != "" {
+		return warningStyle.Render(m.notice)
+	}
 	return lipgloss.NewStyle().
 		Foreground(colorGray).
 		Render("Press ENTER to load logs | 'q' to quit")
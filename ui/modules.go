@@ -0,0 +1,433 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	monitor "asterisk-monitor/monitors"
+	"asterisk-monitor/types"
+)
+
+// Расхождения модуля с базовой линией
+const (
+	driftMissing = "missing"
+	driftFailed  = "failed"
+	driftAdded   = "added"
+)
+
+// moduleKeys - клавиши действий с выбранным модулем
+var moduleKeys = map[string]string{
+	"l": "load",
+	"u": "unload",
+	"e": "reload",
+}
+
+// modulesResult - модули, базовая линия и результат действия, если оно выполнялось
+type modulesResult struct {
+	modules     []types.AsteriskModule
+	baseline    types.ModuleBaseline
+	hasBaseline bool
+	baselineErr error
+	action      *types.CheckResult
+}
+
+// moduleRow - строка таблицы модулей
+type moduleRow struct {
+	module types.AsteriskModule
+	drift  string
+}
+
+// ModulesModel показывает модули Asterisk и их расхождения с базовой линией,
+// загружает, выгружает и перезагружает выбранный модуль
+type ModulesModel struct {
+	monitor      MonitorInterface
+	viewport     viewport.Model
+	data         modulesResult
+	rows         []moduleRow
+	selected     int
+	problemsOnly bool
+	pending      string // действие, ожидающее подтверждения; "baseline" - сохранение базовой линии
+	tableLine    int    // строка первой записи таблицы в содержимом
+	task         taskRunner
+	message      string
+}
+
+// NewModulesModel создает страницу модулей
+func NewModulesModel(mon MonitorInterface) ModulesModel {
+	vp := viewport.New(80, 20)
+	vp.Style = lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("62"))
+
+	return ModulesModel{
+		monitor:  mon,
+		viewport: vp,
+		task:     newTaskRunner(),
+	}
+}
+
+func (m ModulesModel) Init() tea.Cmd {
+	return requestLoad
+}
+
+func (m ModulesModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.pending != "" {
+			return m, m.confirm(msg.String())
+		}
+
+		key := msg.String()
+		switch key {
+		case "up", "k":
+			if m.selected > 0 {
+				m.selected--
+			}
+			m.updateContent()
+			return m, nil
+		case "down", "j":
+			if m.selected < len(m.rows)-1 {
+				m.selected++
+			}
+			m.updateContent()
+			return m, nil
+		case "l", "u", "e":
+			row, ok := m.selectedRow()
+			if !ok {
+				m.message = warningStyle.Render("No module selected")
+				return m, nil
+			}
+			m.pending = moduleKeys[key]
+			m.message = warningStyle.Render(fmt.Sprintf("Module %s %s? Press 'y' to confirm, any other key to cancel", m.pending, row.module.Name))
+			return m, nil
+		case "b", "B":
+			m.pending = "baseline"
+			m.message = warningStyle.Render("Save running modules as the baseline of this server? Press 'y' to confirm")
+			return m, nil
+		case "p", "P":
+			m.problemsOnly = !m.problemsOnly
+			m.selected = 0
+			m.buildRows()
+			m.updateContent()
+			return m, nil
+		case "r", "R":
+			return m, m.load(nil)
+		case "esc":
+			if m.task.Cancel() {
+				m.message = warningStyle.Render(taskError("Loading modules", context.Canceled))
+			}
+			return m, nil
+		case "q", "Q", "ctrl+c":
+			return m, tea.Quit
+		}
+	case tea.WindowSizeMsg:
+		m.viewport.Width = msg.Width
+		m.viewport.Height = msg.Height - 2
+		m.updateContent()
+	case loadMsg:
+		return m, m.load(nil)
+	case CancelTasksMsg:
+		m.task.Cancel()
+		m.pending = ""
+		return m, nil
+	case taskDoneMsg:
+		if !m.task.Finish(msg.id) {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.message = errorStyle.Render(taskError("Loading modules", msg.err))
+		}
+		if result, ok := msg.value.(modulesResult); ok {
+			m.applyResult(result)
+		}
+		m.buildRows()
+		m.updateContent()
+		return m, nil
+	case spinner.TickMsg:
+		return m, m.task.Tick(msg)
+	}
+
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m ModulesModel) View() string {
+	return m.viewport.View() + "\n" + m.footer()
+}
+
+// confirm выполняет ожидающее действие по 'y' и отменяет его по любой другой клавише
+func (m *ModulesModel) confirm(key string) tea.Cmd {
+	pending := m.pending
+	m.pending = ""
+	if key != "y" && key != "Y" {
+		m.message = InfoStyle.Render("Cancelled")
+		return nil
+	}
+
+	mon := m.monitor
+	if pending == "baseline" {
+		return m.load(func(ctx context.Context) types.CheckResult {
+			result := types.CheckResult{Name: "Save module baseline", Status: "success", Timestamp: time.Now()}
+			baseline, err := mon.SaveModuleBaseline(ctx)
+			if err != nil {
+				result.Status = "error"
+				result.Error = err.Error()
+				return result
+			}
+			result.Message = fmt.Sprintf("Saved %d modules as the baseline of %s", len(baseline.Modules), baseline.Host)
+			return result
+		})
+	}
+
+	row, ok := m.selectedRow()
+	if !ok {
+		return nil
+	}
+	name := row.module.Name
+	return m.load(func(ctx context.Context) types.CheckResult {
+		return mon.ModuleAction(ctx, pending, name)
+	})
+}
+
+// load перечитывает модули и базовую линию, перед этим выполняя action, если он задан
+func (m *ModulesModel) load(action func(ctx context.Context) types.CheckResult) tea.Cmd {
+	if m.task.Running() {
+		return nil
+	}
+
+	m.message = ""
+	mon := m.monitor
+	title := "Loading modules"
+	if action != nil {
+		title = "Running module action"
+	}
+	return m.task.Start(title, fetchTimeout, func(ctx context.Context, progress func(types.CheckResult)) (any, error) {
+		var result modulesResult
+		if action != nil {
+			done := action(ctx)
+			result.action = &done
+		}
+		result.baseline, result.hasBaseline, result.baselineErr = mon.ModuleBaseline()
+
+		var err error
+		result.modules, err = mon.GetModules(ctx)
+		return result, err
+	})
+}
+
+// applyResult переносит результат загрузки в модель. При ошибке module show
+// остается прежний список модулей.
+func (m *ModulesModel) applyResult(result modulesResult) {
+	if result.modules != nil {
+		m.data.modules = result.modules
+	}
+	m.data.baseline, m.data.hasBaseline, m.data.baselineErr = result.baseline, result.hasBaseline, result.baselineErr
+
+	if action := result.action; action != nil {
+		if action.Status == "success" {
+			text := action.Name + ": done"
+			if action.Message != "" {
+				text = action.Name + ": " + firstLine(action.Message)
+			}
+			m.message = successStyle.Render(text)
+		} else {
+			m.message = errorStyle.Render(FormatCommandError(*action))
+		}
+	}
+}
+
+// firstLine возвращает первую строку текста
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	return line
+}
+
+// buildRows составляет строки таблицы: модули с расхождениями первыми, затем по имени.
+// Отсутствующие модули базовой линии добавляются отдельными строками.
+func (m *ModulesModel) buildRows() {
+	drifts := make(map[string]string)
+	var missing []string
+	if m.data.hasBaseline {
+		drift := monitor.CompareModules(m.data.baseline, m.data.modules)
+		for _, name := range drift.Failed {
+			drifts[name] = driftFailed
+		}
+		for _, name := range drift.Added {
+			drifts[name] = driftAdded
+		}
+		missing = drift.Missing
+	}
+
+	var rows []moduleRow
+	for _, name := range missing {
+		rows = append(rows, moduleRow{module: types.AsteriskModule{Name: name}, drift: driftMissing})
+	}
+	for _, module := range m.data.modules {
+		row := moduleRow{module: module, drift: drifts[module.Name]}
+		if row.drift == "" && !monitor.ModuleWorking(module) {
+			row.drift = strings.ToLower(module.Status)
+		}
+		if m.problemsOnly && row.drift == "" {
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if (rows[i].drift != "") != (rows[j].drift != "") {
+			return rows[i].drift != ""
+		}
+		return rows[i].module.Name < rows[j].module.Name
+	})
+	m.rows = rows
+	if m.selected >= len(rows) {
+		m.selected = max(len(rows)-1, 0)
+	}
+}
+
+func (m *ModulesModel) selectedRow() (moduleRow, bool) {
+	if m.selected < 0 || m.selected >= len(m.rows) {
+		return moduleRow{}, false
+	}
+	return m.rows[m.selected], true
+}
+
+func (m *ModulesModel) updateContent() {
+	var content strings.Builder
+
+	content.WriteString(TitleStyle.Render("🧩 Asterisk Modules"))
+	content.WriteString("\n\n")
+	content.WriteString(m.renderSummary())
+	content.WriteString("\n\n")
+
+	title := "All modules"
+	if m.problemsOnly {
+		title = "Modules with problems"
+	}
+	content.WriteString(TitleStyle.Render(title))
+	content.WriteString("\n")
+
+	// Рамка таблицы и две строки заголовка
+	m.tableLine = strings.Count(content.String(), "\n") + 3
+	content.WriteString(m.renderModules())
+
+	m.viewport.SetContent(content.String())
+	m.followSelection()
+}
+
+// followSelection прокручивает содержимое так, чтобы выбранная строка была видна
+func (m *ModulesModel) followSelection() {
+	if len(m.rows) == 0 {
+		return
+	}
+	line := m.tableLine + m.selected
+	visible := max(m.viewport.Height-2, 1)
+	switch {
+	case m.selected == 0:
+		m.viewport.SetYOffset(0)
+	case line < m.viewport.YOffset:
+		m.viewport.SetYOffset(line)
+	case line >= m.viewport.YOffset+visible:
+		m.viewport.SetYOffset(line - visible + 1)
+	}
+}
+
+func (m *ModulesModel) renderSummary() string {
+	modules := m.data.modules
+	running := 0
+	for _, module := range modules {
+		if monitor.ModuleWorking(module) {
+			running++
+		}
+	}
+
+	lines := []string{FormatMetric("Modules", fmt.Sprintf("%d loaded, %d running", len(modules), running))}
+	switch {
+	case m.data.baselineErr != nil:
+		lines = append(lines, labelStyle.Render("Baseline")+": "+errorStyle.Render(m.data.baselineErr.Error()))
+	case !m.data.hasBaseline:
+		lines = append(lines, labelStyle.Render("Baseline")+": "+warningStyle.Render("not saved, press 'b' to save running modules"))
+	default:
+		baseline := m.data.baseline
+		drift := monitor.CompareModules(baseline, modules)
+		lines = append(lines,
+			FormatMetric("Baseline", fmt.Sprintf("%d modules, saved %s for %s", len(baseline.Modules), baseline.SavedAt.Format(time.DateTime), baseline.Host)),
+			labelStyle.Render("Drift")+": "+formatDrift(drift),
+		)
+	}
+	return borderStyle.Render(strings.Join(lines, "\n"))
+}
+
+// formatDrift описывает расхождения с базовой линией
+func formatDrift(drift types.ModuleDrift) string {
+	if len(drift.Missing)+len(drift.Failed)+len(drift.Added) == 0 {
+		return successStyle.Render("none")
+	}
+
+	parts := []string{
+		fmt.Sprintf("%d missing", len(drift.Missing)),
+		fmt.Sprintf("%d failed", len(drift.Failed)),
+		fmt.Sprintf("%d added", len(drift.Added)),
+	}
+	text := strings.Join(parts, ", ")
+	if len(drift.Missing)+len(drift.Failed) > 0 {
+		return errorStyle.Render(text)
+	}
+	return warningStyle.Render(text)
+}
+
+func (m *ModulesModel) renderModules() string {
+	if len(m.rows) == 0 {
+		if m.problemsOnly {
+			return "No modules with problems"
+		}
+		return "No modules"
+	}
+
+	headers := []string{" ", "Module", "Status", "Use", "Support", "Baseline", "Description"}
+	var rows [][]string
+	for i, row := range m.rows {
+		cursor := " "
+		if i == m.selected {
+			cursor = "▶"
+		}
+		status := row.module.Status
+		use := strconv.Itoa(row.module.UseCount)
+		if row.drift == driftMissing {
+			status, use = "not loaded", "-"
+		}
+		rows = append(rows, []string{
+			cursor,
+			row.module.Name,
+			status,
+			use,
+			row.module.Support,
+			strings.ToUpper(row.drift),
+			TruncateString(row.module.Description, 40),
+		})
+	}
+	return FormatTable(headers, rows)
+}
+
+func (m *ModulesModel) footer() string {
+	if m.task.Running() {
+		return m.task.Status()
+	}
+	if m.message != "" {
+		return m.message
+	}
+	return lipgloss.NewStyle().
+		Foreground(colorGray).
+		Render("↑/↓ select | 'l' load | 'u' unload | 'e' reload | 'b' save baseline | 'p' problems only | 'r' refresh")
+}
@@ -0,0 +1,159 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"asterisk-monitor/types"
+)
+
+// Интервал обновления, если refresh_interval не задан
+const defaultRefreshInterval = 5 * time.Second
+
+// Сдвиг моментов автообновления вкладок в долях интервала. Вкладки обновляются
+// в разные моменты интервала и не опрашивают Asterisk одновременно.
+var refreshPhases = map[string]float64{
+	"dashboard":   0,
+	"channels":    1.0 / 3,
+	"diagnostics": 2.0 / 3,
+}
+
+// ConfigGetter возвращает текущую конфигурацию
+type ConfigGetter interface {
+	Get() *types.Config
+}
+
+// refreshTickMsg - ежесекундный тик автообновления вкладки view. Тики с устаревшим
+// seq остаются от прежнего открытия вкладки и отбрасываются.
+type refreshTickMsg struct {
+	view string
+	seq  int
+}
+
+// refresher планирует автообновление вкладки. Интервал читается из конфигурации
+// при каждом тике, поэтому изменения в настройках применяются без перезапуска.
+type refresher struct {
+	view     string
+	config   ConfigGetter
+	active   bool
+	paused   bool
+	seq      int
+	interval time.Duration
+	next     time.Time
+}
+
+func newRefresher(view string, config ConfigGetter) refresher {
+	return refresher{view: view, config: config}
+}
+
+// Interval возвращает интервал автообновления вкладки, 0 - автообновление отключено
+func (r refresher) Interval() time.Duration {
+	if r.config == nil {
+		return 0
+	}
+	cfg := r.config.Get()
+
+	var override int
+	switch r.view {
+	case "dashboard":
+		override = cfg.Refresh.Dashboard
+	case "channels":
+		override = cfg.Refresh.Channels
+	case "diagnostics":
+		override = cfg.Refresh.Diagnostics
+	}
+	switch {
+	case override < 0:
+		return 0
+	case override > 0:
+		return time.Duration(override) * time.Second
+	case cfg.Monitoring.RefreshInterval > 0:
+		return time.Duration(cfg.Monitoring.RefreshInterval) * time.Second
+	}
+	return defaultRefreshInterval
+}
+
+// Start запускает тики, когда вкладка становится текущей
+func (r *refresher) Start() tea.Cmd {
+	r.active = true
+	r.seq++
+	r.schedule(time.Now())
+	return r.tick()
+}
+
+// Stop прекращает тики, когда вкладка перестает быть текущей
+func (r *refresher) Stop() {
+	r.active = false
+	r.seq++
+}
+
+// TogglePause приостанавливает или возобновляет автообновление
+func (r *refresher) TogglePause() {
+	r.paused = !r.paused
+	if !r.paused {
+		r.schedule(time.Now())
+	}
+}
+
+// Paused сообщает, приостановлено ли автообновление
+func (r refresher) Paused() bool {
+	return r.paused
+}
+
+// Update обрабатывает тик и сообщает, пора ли обновить вкладку
+func (r *refresher) Update(msg refreshTickMsg) (bool, tea.Cmd) {
+	if !r.active || msg.view != r.view || msg.seq != r.seq {
+		return false, nil
+	}
+
+	now := time.Now()
+	due := false
+	switch {
+	case r.Interval() != r.interval:
+		r.schedule(now)
+	case r.next.IsZero() || r.paused:
+	case !now.Before(r.next):
+		due = true
+		r.schedule(now)
+	}
+	return due, r.tick()
+}
+
+// schedule выбирает следующий момент обновления: моменты выровнены по интервалу
+// и сдвинуты на фазу вкладки
+func (r *refresher) schedule(now time.Time) {
+	r.interval = r.Interval()
+	if r.interval <= 0 {
+		r.next = time.Time{}
+		return
+	}
+
+	offset := time.Duration(float64(r.interval) * refreshPhases[r.view])
+	next := now.Truncate(r.interval).Add(offset)
+	for !next.After(now) {
+		next = next.Add(r.interval)
+	}
+	r.next = next
+}
+
+func (r refresher) tick() tea.Cmd {
+	view, seq := r.view, r.seq
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return refreshTickMsg{view: view, seq: seq}
+	})
+}
+
+// Status возвращает обратный отсчет до следующего обновления
+func (r refresher) Status() string {
+	switch {
+	case r.paused:
+		return "Auto-refresh paused ('p' to resume)"
+	case r.next.IsZero():
+		return "Auto-refresh off"
+	}
+	left := math.Ceil(time.Until(r.next).Seconds())
+	return fmt.Sprintf("Next refresh in %.0fs ('p' to pause)", math.Max(left, 0))
+}
@@ -1,11 +1,13 @@
 package ui
 
 import (
+	monitor "asterisk-monitor/monitors"
 	"asterisk-monitor/types"
+	"context"
 	"fmt"
 	"strings"
-	"time"
 
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -16,6 +18,9 @@ type SecurityModel struct {
 	viewport viewport.Model
 	results  []types.CheckResult
 	ready    bool
+	task     taskRunner
+	total    int
+	notice   string
 }
 
 func NewSecurityModel(mon MonitorInterface) SecurityModel {
@@ -28,6 +33,7 @@ func NewSecurityModel(mon MonitorInterface) SecurityModel {
 		monitor:  mon,
 		viewport: vp,
 		results:  []types.CheckResult{},
+		task:     newTaskRunner(),
 		ready:    true, // Сразу готов
 	}
 }
@@ -44,15 +50,22 @@ func (m SecurityModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "r", "R":
-			m.runQuickSecurityScan()
-			return m, nil
+			return m, m.runQuickSecurityScan()
 		case "f", "F":
-			m.runFullSecurityScan()
-			return m, nil
+			return m, m.runFullSecurityScan()
 		case "c", "C":
+			if m.task.Running() {
+				return m, nil
+			}
 			m.results = []types.CheckResult{}
+			m.notice = ""
 			m.updateContent()
 			return m, nil
+		case "esc":
+			if m.task.Cancel() {
+				m.notice = taskError("Security scan", context.Canceled)
+			}
+			return m, nil
 		case "q", "Q", "ctrl+c":
 			return m, tea.Quit
 		}
@@ -68,6 +81,28 @@ func (m SecurityModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.viewport.Height = msg.Height - 2
 		}
 		m.updateContent()
+	case CancelTasksMsg:
+		if m.task.Cancel() {
+			m.notice = taskError("Security scan", context.Canceled)
+		}
+		return m, nil
+	case taskProgressMsg:
+		if !m.task.Owns(msg.id) {
+			return m, nil
+		}
+		m.results = append(m.results, msg.result)
+		m.updateContent()
+		return m, msg.next
+	case taskDoneMsg:
+		if !m.task.Finish(msg.id) {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.notice = taskError("Security scan", msg.err)
+		}
+		return m, nil
+	case spinner.TickMsg:
+		return m, m.task.Tick(msg)
 	}
 
 	m.viewport, cmd = m.viewport.Update(msg)
@@ -82,174 +117,22 @@ func (m SecurityModel) View() string {
 	return m.viewport.View() + "\n" + m.footer()
 }
 
-func (m *SecurityModel) runQuickSecurityScan() {
-	m.results = []types.CheckResult{}
-	m.updateContent()
-
-	checks := []struct {
-		name string
-		cmd  string
-	}{
-		{"Open SIP Ports", "netstat -tlnp | grep -E ':(5060|5061|5062)' | grep LISTEN"},
-		{"Open AMI Port", "netstat -tlnp | grep ':5038' | grep LISTEN"},
-		{"Fail2Ban Status", "systemctl is-active fail2ban"},
-		{"Firewall Status", "systemctl is-active ufw 2>/dev/null || systemctl is-active firewalld 2>/dev/null || echo 'No firewall detected'"},
-		{"Asterisk Process User", "ps aux | grep asterisk | grep -v grep | awk '{print $1}' | head -1"},
-	}
+func (m *SecurityModel) runQuickSecurityScan() tea.Cmd {
+	return m.runChecks("Running quick security scan", m.monitor.SecurityChecks(false))
+}
 
-	for _, check := range checks {
-		result := m.monitor.ExecuteCommand(check.name, check.cmd)
-		m.analyzeSecurityResult(&result)
-		m.results = append(m.results, result)
-		m.updateContent()
-		time.Sleep(300 * time.Millisecond)
-	}
+func (m *SecurityModel) runFullSecurityScan() tea.Cmd {
+	return m.runChecks("Running full security audit", m.monitor.SecurityChecks(true))
 }
 
-func (m *SecurityModel) runFullSecurityScan() {
+// runChecks запускает проверки в фоне, результаты приходят по одной через taskProgressMsg
+func (m *SecurityModel) runChecks(name string, checks []monitor.Check) tea.Cmd {
 	m.results = []types.CheckResult{}
+	m.total = len(checks)
+	m.notice = ""
 	m.updateContent()
 
-	checks := []struct {
-		name string
-		cmd  string
-	}{
-		// Network Security
-		{"Open Network Ports", "netstat -tlnp | grep -E ':(5060|5061|5062|5038|10000)'"},
-		{"SIP Port Exposure", "ss -tlnp | grep -E ':(5060|5061)' | awk '{print $4}'"},
-		{"AMI Port Exposure", "ss -tlnp | grep ':5038' | awk '{print $4}'"},
-
-		// Service Security
-		{"Fail2Ban Status", "systemctl is-active fail2ban"},
-		{"Firewall Status", "systemctl is-active ufw 2>/dev/null || systemctl is-active firewalld 2>/dev/null || echo 'No firewall detected'"},
-		{"SELinux Status", "getenforce 2>/dev/null || echo 'SELinux not available'"},
-
-		// File Permissions
-		{"Asterisk Config Permissions", "find /etc/asterisk -type f -perm /o+rw -ls | wc -l"},
-		{"Asterisk Directory Permissions", "find /etc/asterisk -type d -perm /o+rwx -ls | wc -l"},
-		{"Asterisk File Ownership", "find /etc/asterisk ! -user asterisk -type f | wc -l"},
-
-		// Process Security
-		{"Asterisk Process User", "ps aux | grep asterisk | grep -v grep | awk '{print $1}' | head -1"},
-		{"Asterisk Running as Root", "ps aux | grep asterisk | grep -v grep | grep root | wc -l"},
-
-		// SSL/TLS Security
-		{"SSL Certificate Check", "find /etc/asterisk -name '*.pem' -exec openssl x509 -checkend 86400 -in {} \\; 2>/dev/null | grep -c 'will expire' || echo 'No SSL certificates found'"},
-		{"TLS Configuration", "grep -r 'tls' /etc/asterisk/*.conf 2>/dev/null | grep -v '^#' | wc -l"},
-
-		// Authentication Security
-		{"Default Passwords Check", "grep -r 'password' /etc/asterisk/sip.conf 2>/dev/null | grep -v '^#' | grep -v '^;' | head -5"},
-		{"AMI Authentication", "grep -r 'secret\\|password' /etc/asterisk/manager.conf 2>/dev/null | grep -v '^#' | grep -v '^;' | head -3"},
-
-		// Logging Security
-		{"Log File Permissions", "ls -la /var/log/asterisk/ 2>/dev/null | head -5"},
-		{"Debug Mode Check", "grep -r 'debug' /etc/asterisk/logger.conf 2>/dev/null | grep -v '^#' | grep -v 'off' | wc -l"},
-	}
-
-	for _, check := range checks {
-		result := m.monitor.ExecuteCommand(check.name, check.cmd)
-		m.analyzeSecurityResult(&result)
-		m.results = append(m.results, result)
-		m.updateContent()
-		time.Sleep(200 * time.Millisecond)
-	}
-}
-
-func (m *SecurityModel) analyzeSecurityResult(result *types.CheckResult) {
-	// Анализируем результат и устанавливаем соответствующий статус
-	switch result.Name {
-	case "Open SIP Ports":
-		if strings.Contains(result.Message, "0.0.0.0") || strings.Contains(result.Message, ":::") {
-			result.Status = "warning"
-			result.Message += " ⚠️  SIP ports exposed to all interfaces"
-		} else if strings.TrimSpace(result.Message) == "" {
-			result.Status = "success"
-			result.Message = "No SIP ports open to public"
-		}
-
-	case "Open AMI Port":
-		if strings.Contains(result.Message, "0.0.0.0") || strings.Contains(result.Message, ":::") {
-			result.Status = "error"
-			result.Message += " ❌ AMI port exposed to all interfaces - SECURITY RISK!"
-		} else if strings.TrimSpace(result.Message) == "" {
-			result.Status = "success"
-			result.Message = "AMI port not exposed to public"
-		}
-
-	case "Fail2Ban Status":
-		if result.Message != "active" {
-			result.Status = "warning"
-			result.Message += " ⚠️  Fail2Ban not active"
-		} else {
-			result.Status = "success"
-		}
-
-	case "Firewall Status":
-		if strings.Contains(result.Message, "inactive") || strings.Contains(result.Message, "No firewall") {
-			result.Status = "warning"
-			result.Message += " ⚠️  Firewall not active"
-		} else if strings.Contains(result.Message, "active") {
-			result.Status = "success"
-		}
-
-	case "Asterisk Config Permissions":
-		if result.Message != "0" && strings.TrimSpace(result.Message) != "" {
-			result.Status = "error"
-			result.Message += " ❌ World-writable config files found"
-		} else {
-			result.Status = "success"
-			result.Message = "Config permissions are secure"
-		}
-
-	case "Asterisk Process User":
-		if result.Message == "root" {
-			result.Status = "warning"
-			result.Message += " ⚠️  Running as root - not recommended"
-		} else if strings.TrimSpace(result.Message) != "" {
-			result.Status = "success"
-			result.Message += " ✓ Running as non-root user"
-		}
-
-	case "Asterisk Running as Root":
-		if result.Message != "0" && strings.TrimSpace(result.Message) != "" {
-			result.Status = "error"
-			result.Message += " ❌ Asterisk should not run as root"
-		} else {
-			result.Status = "success"
-			result.Message = "Asterisk not running as root"
-		}
-
-	case "SSL Certificate Check":
-		if result.Message != "No SSL certificates found" && result.Message != "0" && strings.TrimSpace(result.Message) != "" {
-			result.Status = "warning"
-			result.Message += " ⚠️  SSL certificates expiring soon"
-		} else {
-			result.Status = "success"
-			if result.Message == "No SSL certificates found" {
-				result.Message = "No SSL certificates configured"
-			} else {
-				result.Message = "SSL certificates are valid"
-			}
-		}
-
-	case "Default Passwords Check":
-		if strings.TrimSpace(result.Message) != "" {
-			result.Status = "warning"
-			result.Message += " ⚠️  Check for default passwords"
-		} else {
-			result.Status = "success"
-			result.Message = "No obvious default passwords found"
-		}
-	}
-
-	// Если статус еще не установлен, устанавливаем по умолчанию
-	if result.Status == "" {
-		if result.Error != "" {
-			result.Status = "error"
-		} else {
-			result.Status = "success"
-		}
-	}
+	return m.task.Start(name, checksTimeout, checksTask(checks))
 }
 
 func (m *SecurityModel) updateContent() {
@@ -294,7 +177,7 @@ func (m *SecurityModel) renderSecurityResults() string {
 		builder.WriteString(fmt.Sprintf("%s %s\n", statusIcon, result.Name))
 		builder.WriteString(fmt.Sprintf("   %s\n", result.Message))
 		if result.Error != "" {
-			builder.WriteString(fmt.Sprintf("   Error: %s\n", result.Error))
+			builder.WriteString("   " + FormatCommandError(result) + "\n")
 		}
 		builder.WriteString("\n")
 	}
@@ -334,7 +217,7 @@ func (m *SecurityModel) renderSecuritySummary() string {
 	// Общая оценка безопасности
 	totalChecks := criticalCount + warningCount + successCount
 	if totalChecks > 0 {
-		score := (successCount * 100) / totalChecks
+		score := monitor.SecurityScore(m.results)
 		summary.WriteString(fmt.Sprintf("\nSecurity Score: %d%%\n", score))
 
 		if score >= 80 {
@@ -373,7 +256,13 @@ func (m *SecurityModel) renderSecurityTips() string {
 }
 
 func (m *SecurityModel) footer() string {
+	if m.task.Running() {
+		return fmt.Sprintf("%s %s", m.task.Status(), InfoStyle.Render(fmt.Sprintf("[%d/%d]", len(m.results), m.total)))
+	}
+	if m.notice != "" {
+		return warningStyle.Render(m.notice)
+	}
 	return lipgloss.NewStyle().
 		Foreground(colorGray).
-		Render("Press 'r' for quick scan, 'f' for full audit, 'c' to clear, 'q' to quit")
+		Render("Press 'r' for quick scan, 'f' for full audit, 'c' to clear, Esc to cancel, 'q' to quit")
 }
\ No newline at end of file
@@ -22,6 +22,7 @@ type ConfigManagerInterface interface {
 // SettingsModel manages the settings view
 type SettingsModel struct {
 	config     ConfigManagerInterface
+	apply      func(config *types.Config) error // применяет сохраненные настройки без перезапуска
 	viewport   viewport.Model
 	inputs     []textinput.Model
 	focusIndex int
@@ -29,8 +30,9 @@ type SettingsModel struct {
 	ready      bool
 }
 
-// NewSettingsModel creates a new settings model
-func NewSettingsModel(cfg ConfigManagerInterface) SettingsModel {
+// NewSettingsModel creates a new settings model. apply вызывается после каждого
+// сохранения, чтобы правила оповещений и пороги проверок менялись без перезапуска.
+func NewSettingsModel(cfg ConfigManagerInterface, apply func(config *types.Config) error) SettingsModel {
 	currentConfig := cfg.Get()
 
 	// Create input fields
@@ -96,6 +98,7 @@ func NewSettingsModel(cfg ConfigManagerInterface) SettingsModel {
 
 	return SettingsModel{
 		config:     cfg,
+		apply:      apply,
 		viewport:   vp,
 		inputs:     inputs,
 		focusIndex: 0,
@@ -190,7 +193,9 @@ func (m SettingsModel) View() string {
 }
 
 func (m *SettingsModel) saveSettings() {
-	newConfig := &types.Config{}
+	// Копируем текущую конфигурацию, чтобы не потерять секции без полей ввода
+	current := *m.config.Get()
+	newConfig := &current
 
 	// Parse Asterisk settings
 	newConfig.Asterisk.Host = m.inputs[0].Value()
@@ -211,8 +216,6 @@ func (m *SettingsModel) saveSettings() {
 		newConfig.Monitoring.LogRetention = 30
 	}
 
-	newConfig.Monitoring.EnableAlerts = true
-
 	// Parse Security settings
 	newConfig.Security.CheckFirewall = m.inputs[6].Value() == "true"
 	newConfig.Security.CheckPasswords = m.inputs[7].Value() == "true"
@@ -238,6 +241,8 @@ func (m *SettingsModel) saveSettings() {
 	// Save configuration
 	if err := m.config.Update(newConfig); err != nil {
 		m.savedMsg = errorStyle.Render(fmt.Sprintf("Failed to save settings: %v", err))
+	} else if err := m.applySaved(); err != nil {
+		m.savedMsg = warningStyle.Render(fmt.Sprintf("Settings saved, some alert rules are invalid: %v", err))
 	} else {
 		m.savedMsg = successStyle.Render("✅ Settings saved successfully!")
 	}
@@ -245,6 +250,14 @@ func (m *SettingsModel) saveSettings() {
 	m.updateContent()
 }
 
+// applySaved применяет сохраненную конфигурацию к работающему монитору
+func (m *SettingsModel) applySaved() error {
+	if m.apply == nil {
+		return nil
+	}
+	return m.apply(m.config.Get())
+}
+
 func (m *SettingsModel) resetToDefaults() {
 	if err := m.config.CreateDefault(); err != nil {
 		m.savedMsg = errorStyle.Render(fmt.Sprintf("Failed to reset settings: %v", err))
@@ -265,6 +278,9 @@ func (m *SettingsModel) resetToDefaults() {
 	m.inputs[8].SetValue(fmt.Sprintf("%t", defaultConfig.Security.CheckSSL))
 
 	m.savedMsg = successStyle.Render("✅ Settings reset to defaults!")
+	if err := m.applySaved(); err != nil {
+		m.savedMsg = warningStyle.Render(fmt.Sprintf("Settings reset, some alert rules are invalid: %v", err))
+	}
 	m.updateContent()
 }
 
@@ -0,0 +1,219 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"asterisk-monitor/collector"
+	monitor "asterisk-monitor/monitors"
+	"asterisk-monitor/types"
+)
+
+// Сроки фоновых задач видов
+const (
+	checksTimeout  = 2 * time.Minute
+	fetchTimeout   = 30 * time.Second
+	backupTimeout  = 30 * time.Minute
+	commandTimeout = 15 * time.Second
+)
+
+// taskSeq выдает номера фоновых задач, уникальные для всех видов
+var taskSeq atomic.Uint64
+
+// taskFunc выполняет работу фоновой задачи. Промежуточные результаты передаются
+// через progress, итоговое значение приходит виду в taskDoneMsg.
+type taskFunc func(ctx context.Context, progress func(types.CheckResult)) (any, error)
+
+// taskProgressMsg - промежуточный результат задачи. next ожидает следующее сообщение
+// задачи и должен быть возвращен из Update.
+type taskProgressMsg struct {
+	id     uint64
+	result types.CheckResult
+	next   tea.Cmd
+}
+
+// taskDoneMsg сообщает о завершении задачи. При отмене или истечении срока
+// err содержит ошибку контекста.
+type taskDoneMsg struct {
+	id    uint64
+	value any
+	err   error
+}
+
+// CancelTasksMsg отправляется виду, который перестает быть текущим:
+// его фоновые задачи отменяются
+type CancelTasksMsg struct{}
+
+// loadMsg просит текущий вид загрузить данные. Init получает копию модели
+// и не может запомнить задачу, поэтому загрузка запускается из Update.
+type loadMsg struct{}
+
+func requestLoad() tea.Msg {
+	return loadMsg{}
+}
+
+// taskRunner запускает одну фоновую задачу вида и показывает спиннер, пока она выполняется
+type taskRunner struct {
+	id      uint64
+	name    string
+	started time.Time
+	cancel  context.CancelFunc
+	spinner spinner.Model
+}
+
+func newTaskRunner() taskRunner {
+	return taskRunner{
+		spinner: spinner.New(
+			spinner.WithSpinner(spinner.Dot),
+			spinner.WithStyle(lipgloss.NewStyle().Foreground(colorBlue)),
+		),
+	}
+}
+
+// Start отменяет предыдущую задачу и запускает run в отдельной горутине со сроком timeout
+func (t *taskRunner) Start(name string, timeout time.Duration, run taskFunc) tea.Cmd {
+	t.Cancel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	t.id = taskSeq.Add(1)
+	t.name = name
+	t.started = time.Now()
+	t.cancel = cancel
+
+	id := t.id
+	progress := make(chan types.CheckResult)
+	done := make(chan taskDoneMsg, 1)
+
+	go func() {
+		defer cancel()
+
+		value, err := run(ctx, func(result types.CheckResult) {
+			// Если вид больше не ждет задачу, результат отбрасывается
+			select {
+			case progress <- result:
+			case <-ctx.Done():
+			}
+		})
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		done <- taskDoneMsg{id: id, value: value, err: err}
+	}()
+
+	var wait tea.Cmd
+	wait = func() tea.Msg {
+		select {
+		case result := <-progress:
+			return taskProgressMsg{id: id, result: result, next: wait}
+		case msg := <-done:
+			return msg
+		}
+	}
+
+	return tea.Batch(wait, t.spinner.Tick)
+}
+
+// Cancel отменяет выполняемую задачу
+func (t *taskRunner) Cancel() bool {
+	if !t.Running() {
+		return false
+	}
+	t.cancel()
+	t.cancel = nil
+	t.id = 0
+	return true
+}
+
+// Running сообщает, выполняется ли задача
+func (t taskRunner) Running() bool {
+	return t.cancel != nil
+}
+
+// Owns сообщает, относится ли сообщение с номером id к текущей задаче
+func (t taskRunner) Owns(id uint64) bool {
+	return t.Running() && id == t.id
+}
+
+// Finish отмечает задачу id завершенной. Сообщения отмененных задач
+// и задач других видов отбрасываются.
+func (t *taskRunner) Finish(id uint64) bool {
+	if !t.Owns(id) {
+		return false
+	}
+	t.cancel()
+	t.cancel = nil
+	t.id = 0
+	return true
+}
+
+// Tick продвигает спиннер, пока задача выполняется
+func (t *taskRunner) Tick(msg spinner.TickMsg) tea.Cmd {
+	if !t.Running() {
+		return nil
+	}
+	var cmd tea.Cmd
+	t.spinner, cmd = t.spinner.Update(msg)
+	return cmd
+}
+
+// Status возвращает строку со спиннером и временем выполнения задачи или пустую строку
+func (t taskRunner) Status() string {
+	if !t.Running() {
+		return ""
+	}
+	elapsed := time.Since(t.started).Truncate(time.Second)
+	return fmt.Sprintf("%s %s... %s", t.spinner.View(), t.name, InfoStyle.Render(fmt.Sprintf("(%s, Esc to cancel)", elapsed)))
+}
+
+// checksTask выполняет проверки по одной и передает результат каждой виду.
+// После отмены оставшиеся проверки не запускаются.
+func checksTask(checks []monitor.Check) taskFunc {
+	return func(ctx context.Context, progress func(types.CheckResult)) (any, error) {
+		for _, check := range checks {
+			if ctx.Err() != nil {
+				break
+			}
+			progress(check.Run(ctx))
+		}
+		return nil, nil
+	}
+}
+
+// refreshSources обновляет источники коллектора параллельно. Значения не старше
+// maxAge берутся из кэша, нулевой maxAge опрашивает источники заново, отрицательный
+// использует TTL источника. Возвращается первая ошибка.
+func refreshSources(ctx context.Context, cache *collector.Collector, maxAge time.Duration, names ...string) error {
+	errs := make(chan error, len(names))
+	for _, name := range names {
+		go func() {
+			_, err := cache.GetWithin(ctx, name, maxAge)
+			errs <- err
+		}()
+	}
+
+	var first error
+	for range names {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// taskError описывает ошибку завершенной задачи для пользователя
+func taskError(name string, err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return fmt.Sprintf("%s cancelled", name)
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Sprintf("%s timed out", name)
+	}
+	return fmt.Sprintf("%s failed: %v", name, err)
+}
@@ -0,0 +1,282 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"asterisk-monitor/collector"
+	monitor "asterisk-monitor/monitors"
+	"asterisk-monitor/types"
+)
+
+// Порядок сортировки очередей задач
+const (
+	sortByQueue    = "queue"     // текущая очередь, затем максимальная глубина
+	sortByMaxDepth = "max depth" // максимальная глубина, затем текущая очередь
+)
+
+// topThreadGroups - сколько групп потоков показывается
+const topThreadGroups = 15
+
+// TaskprocessorsModel показывает очереди задач Asterisk и его потоки
+type TaskprocessorsModel struct {
+	cache    *collector.Collector
+	viewport viewport.Model
+	report   types.TaskprocessorReport
+	threads  int // число потоков процесса по /proc
+	sortBy   string
+	task     taskRunner
+	notice   string
+}
+
+// NewTaskprocessorsModel создает страницу очередей задач, данные читаются из кэша коллектора
+func NewTaskprocessorsModel(cache *collector.Collector) TaskprocessorsModel {
+	vp := viewport.New(80, 20)
+	vp.Style = lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("62"))
+
+	return TaskprocessorsModel{
+		cache:    cache,
+		viewport: vp,
+		sortBy:   sortByQueue,
+		task:     newTaskRunner(),
+	}
+}
+
+func (m TaskprocessorsModel) Init() tea.Cmd {
+	return requestLoad
+}
+
+func (m TaskprocessorsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "r", "R":
+			return m, m.load(0)
+		case "s", "S":
+			if m.sortBy == sortByQueue {
+				m.sortBy = sortByMaxDepth
+			} else {
+				m.sortBy = sortByQueue
+			}
+			m.updateContent()
+			return m, nil
+		case "esc":
+			if m.task.Cancel() {
+				m.notice = taskError("Loading taskprocessors", context.Canceled)
+			}
+			return m, nil
+		case "q", "Q", "ctrl+c":
+			return m, tea.Quit
+		}
+	case tea.WindowSizeMsg:
+		m.viewport.Width = msg.Width
+		m.viewport.Height = msg.Height - 2
+		m.updateContent()
+	case loadMsg:
+		return m, m.load(-1)
+	case CollectorUpdatedMsg:
+		if msg.Source == collector.SourceTaskprocessors || msg.Source == collector.SourceSystem {
+			m.syncFromCache()
+		}
+	case CancelTasksMsg:
+		m.task.Cancel()
+		return m, nil
+	case taskDoneMsg:
+		if !m.task.Finish(msg.id) {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.notice = taskError("Loading taskprocessors", msg.err)
+		}
+		m.syncFromCache()
+		return m, nil
+	case spinner.TickMsg:
+		return m, m.task.Tick(msg)
+	}
+
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m TaskprocessorsModel) View() string {
+	return m.viewport.View() + "\n" + m.footer()
+}
+
+// load обновляет очереди задач, если они старше maxAge: по 'r' - всегда,
+// иначе - старше TTL источника
+func (m *TaskprocessorsModel) load(maxAge time.Duration) tea.Cmd {
+	if m.task.Running() {
+		return nil
+	}
+
+	m.notice = ""
+	m.syncFromCache()
+	cache := m.cache
+	return m.task.Start("Loading taskprocessors", fetchTimeout, func(ctx context.Context, progress func(types.CheckResult)) (any, error) {
+		return nil, refreshSources(ctx, cache, maxAge, collector.SourceTaskprocessors)
+	})
+}
+
+// syncFromCache переносит в модель последние очереди задач и число потоков процесса
+func (m *TaskprocessorsModel) syncFromCache() {
+	m.report, _ = collector.Cached[types.TaskprocessorReport](m.cache, collector.SourceTaskprocessors)
+	m.threads = -1
+	if metrics, _ := collector.Cached[types.SystemMetrics](m.cache, collector.SourceSystem); metrics.Process != nil {
+		m.threads = metrics.Process.Threads
+	}
+	m.updateContent()
+}
+
+func (m *TaskprocessorsModel) updateContent() {
+	var content strings.Builder
+
+	content.WriteString(TitleStyle.Render("⚙️ Asterisk Taskprocessors"))
+	content.WriteString("\n\n")
+	content.WriteString(m.renderSummary())
+	content.WriteString("\n\n")
+
+	content.WriteString(TitleStyle.Render("Queues by " + m.sortBy))
+	content.WriteString("\n")
+	content.WriteString(m.renderProcessors())
+	content.WriteString("\n\n")
+
+	content.WriteString(TitleStyle.Render("Threads by start function"))
+	content.WriteString("\n")
+	content.WriteString(m.renderThreads())
+
+	m.viewport.SetContent(content.String())
+}
+
+func (m *TaskprocessorsModel) renderSummary() string {
+	report := m.report
+	if report.Time.IsZero() {
+		return borderStyle.Render("Taskprocessors:\nNo data yet")
+	}
+	if report.Error != "" {
+		return borderStyle.Render("Taskprocessors: " + errorStyle.Render(report.Error))
+	}
+
+	over := 0
+	queued := 0
+	for _, tp := range report.Processors {
+		queued += tp.InQueue
+		if monitor.OverHighWater(tp) {
+			over++
+		}
+	}
+
+	overText := successStyle.Render("0")
+	if over > 0 {
+		overText = errorStyle.Render(strconv.Itoa(over))
+	}
+	threads := "unknown"
+	if report.Threads >= 0 {
+		threads = strconv.Itoa(report.Threads)
+	}
+	if m.threads >= 0 {
+		threads += fmt.Sprintf(" (%d in /proc)", m.threads)
+	}
+
+	lines := []string{
+		FormatMetric("Taskprocessors", strconv.Itoa(len(report.Processors))),
+		FormatMetric("Queued Tasks", strconv.Itoa(queued)),
+		labelStyle.Render("Over High Water") + ": " + overText,
+		FormatMetric("Threads", threads),
+		FormatMetric("Updated", report.Time.Format(time.DateTime)),
+	}
+	return borderStyle.Render(strings.Join(lines, "\n"))
+}
+
+func (m *TaskprocessorsModel) renderProcessors() string {
+	if len(m.report.Processors) == 0 {
+		return "No taskprocessors"
+	}
+
+	processors := append([]types.Taskprocessor(nil), m.report.Processors...)
+	sort.SliceStable(processors, func(i, j int) bool {
+		a, b := processors[i], processors[j]
+		if m.sortBy == sortByMaxDepth && a.MaxDepth != b.MaxDepth {
+			return a.MaxDepth > b.MaxDepth
+		}
+		if a.InQueue != b.InQueue {
+			return a.InQueue > b.InQueue
+		}
+		if a.MaxDepth != b.MaxDepth {
+			return a.MaxDepth > b.MaxDepth
+		}
+		return a.Name < b.Name
+	})
+
+	headers := []string{"Processor", "In Queue", "Max Depth", "Low Water", "High Water", "Processed", "Status"}
+	var rows [][]string
+	for _, tp := range processors {
+		status := "ok"
+		switch {
+		case monitor.OverHighWater(tp):
+			status = "OVER HIGH WATER"
+		case tp.HighWater > 0 && tp.MaxDepth >= tp.HighWater:
+			status = "reached high water"
+		}
+		rows = append(rows, []string{
+			TruncateString(tp.Name, 50),
+			strconv.Itoa(tp.InQueue),
+			strconv.Itoa(tp.MaxDepth),
+			waterLevel(tp.LowWater),
+			waterLevel(tp.HighWater),
+			strconv.FormatInt(tp.Processed, 10),
+			status,
+		})
+	}
+	return FormatTable(headers, rows)
+}
+
+// waterLevel возвращает уровень очереди или прочерк, если Asterisk его не сообщает
+func waterLevel(level int) string {
+	if level <= 0 {
+		return "-"
+	}
+	return strconv.Itoa(level)
+}
+
+func (m *TaskprocessorsModel) renderThreads() string {
+	groups := m.report.ThreadGroups
+	if len(groups) == 0 {
+		return "No thread list (core show threads unavailable)"
+	}
+
+	headers := []string{"Function", "Threads"}
+	var rows [][]string
+	for i, group := range groups {
+		if i == topThreadGroups {
+			rows = append(rows, []string{fmt.Sprintf("... %d more", len(groups)-topThreadGroups), ""})
+			break
+		}
+		rows = append(rows, []string{group.Function, strconv.Itoa(group.Count)})
+	}
+	return FormatTable(headers, rows)
+}
+
+func (m *TaskprocessorsModel) footer() string {
+	if m.task.Running() {
+		return m.task.Status()
+	}
+	if m.notice != "" {
+		return warningStyle.Render(m.notice)
+	}
+	return lipgloss.NewStyle().
+		Foreground(colorGray).
+		Render(fmt.Sprintf("Sorted by %s | Press 's' to change sort | 'r' to refresh | 'q' to quit", m.sortBy))
+}
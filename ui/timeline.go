@@ -0,0 +1,261 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"asterisk-monitor/collector"
+	"asterisk-monitor/lifecycle"
+	"asterisk-monitor/types"
+)
+
+// timelineDays - за сколько дней показываются события и счетчики
+const timelineDays = 14
+
+// TimelineModel показывает хронологию запусков, перезагрузок, перезапусков
+// и сбоев Asterisk и число событий по дням
+type TimelineModel struct {
+	history  RestartHistory
+	cache    *collector.Collector
+	viewport viewport.Model
+	events   []lifecycle.Event
+	snapshot types.LifecycleSnapshot
+	updated  time.Time
+	task     taskRunner
+	notice   string
+}
+
+// NewTimelineModel создает страницу хронологии. События читаются из history,
+// состояние текущего процесса - из кэша коллектора cache.
+func NewTimelineModel(history RestartHistory, cache *collector.Collector) TimelineModel {
+	vp := viewport.New(80, 20)
+	vp.Style = lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("62"))
+
+	return TimelineModel{
+		history:  history,
+		cache:    cache,
+		viewport: vp,
+		task:     newTaskRunner(),
+	}
+}
+
+func (m TimelineModel) Init() tea.Cmd {
+	return requestLoad
+}
+
+func (m TimelineModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "r", "R":
+			return m, m.load(0)
+		case "esc":
+			if m.task.Cancel() {
+				m.notice = taskError("Loading restart history", context.Canceled)
+			}
+			return m, nil
+		case "q", "Q", "ctrl+c":
+			return m, tea.Quit
+		}
+	case tea.WindowSizeMsg:
+		m.viewport.Width = msg.Width
+		m.viewport.Height = msg.Height - 2
+		m.updateContent()
+	case loadMsg:
+		return m, m.load(-1)
+	case CollectorUpdatedMsg:
+		// Трекер записывает события после каждого снимка, хронологию нужно перечитать
+		if msg.Source == collector.SourceLifecycle {
+			return m, m.load(-1)
+		}
+	case CancelTasksMsg:
+		m.task.Cancel()
+		return m, nil
+	case taskDoneMsg:
+		if !m.task.Finish(msg.id) {
+			return m, nil
+		}
+		if events, ok := msg.value.([]lifecycle.Event); ok {
+			m.events = events
+			m.updated = time.Now()
+		}
+		if msg.err != nil {
+			m.notice = taskError("Loading restart history", msg.err)
+		}
+		m.syncFromCache()
+		return m, nil
+	case spinner.TickMsg:
+		return m, m.task.Tick(msg)
+	}
+
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m TimelineModel) View() string {
+	return m.viewport.View() + "\n" + m.footer()
+}
+
+// load перечитывает хронологию. Снимок процесса обновляется, если он старше
+// maxAge: по 'r' - всегда, иначе - старше TTL источника.
+func (m *TimelineModel) load(maxAge time.Duration) tea.Cmd {
+	if m.task.Running() {
+		return nil
+	}
+
+	m.notice = ""
+	m.syncFromCache()
+	cache, history := m.cache, m.history
+	return m.task.Start("Loading restart history", fetchTimeout, func(ctx context.Context, progress func(types.CheckResult)) (any, error) {
+		if err := refreshSources(ctx, cache, maxAge, collector.SourceLifecycle); err != nil {
+			return nil, err
+		}
+		return history.Events(time.Now().AddDate(0, 0, -timelineDays))
+	})
+}
+
+// syncFromCache переносит в модель последний снимок процесса
+func (m *TimelineModel) syncFromCache() {
+	m.snapshot, _ = collector.Cached[types.LifecycleSnapshot](m.cache, collector.SourceLifecycle)
+	m.updateContent()
+}
+
+func (m *TimelineModel) updateContent() {
+	var content strings.Builder
+
+	content.WriteString(TitleStyle.Render("🔄 Asterisk Restart Timeline"))
+	content.WriteString("\n\n")
+	content.WriteString(m.renderCurrent())
+	content.WriteString("\n\n")
+
+	content.WriteString(TitleStyle.Render(fmt.Sprintf("Last %d days", timelineDays)))
+	content.WriteString("\n")
+	content.WriteString(m.renderDaily())
+	content.WriteString("\n\n")
+
+	content.WriteString(TitleStyle.Render("Events"))
+	content.WriteString("\n")
+	content.WriteString(m.renderEvents())
+
+	m.viewport.SetContent(content.String())
+}
+
+func (m *TimelineModel) renderCurrent() string {
+	snapshot := m.snapshot
+	if snapshot.Time.IsZero() {
+		return borderStyle.Render("Current Process:\nNo data yet")
+	}
+
+	process := snapshot.Process
+	var lines []string
+	lines = append(lines, "Current Process: "+FormatStatus(process.State))
+	if process.PID != 0 {
+		pid := strconv.Itoa(process.PID)
+		if process.WrapperPID != 0 {
+			pid += fmt.Sprintf(" (safe_asterisk %d)", process.WrapperPID)
+		}
+		lines = append(lines, FormatMetric("PID", pid))
+	}
+	if !snapshot.StartedAt.IsZero() {
+		uptime := snapshot.Time.Sub(snapshot.StartedAt).Round(time.Second)
+		lines = append(lines, FormatMetric("Started", fmt.Sprintf("%s (up %s)", snapshot.StartedAt.Format(time.DateTime), uptime)))
+		lines = append(lines, FormatMetric("Last Reload", snapshot.ReloadedAt.Format(time.DateTime)))
+	}
+	if unit := snapshot.Unit; unit.ActiveState != "" {
+		state := fmt.Sprintf("%s, result %s", unit.ActiveState, unit.Result)
+		if unit.NRestarts >= 0 {
+			state += fmt.Sprintf(", %d automatic restarts", unit.NRestarts)
+		}
+		lines = append(lines, FormatMetric("Systemd Unit", state))
+	}
+
+	cores := "none"
+	if n := len(snapshot.CoreFiles); n > 0 {
+		latest := snapshot.CoreFiles[0]
+		for _, core := range snapshot.CoreFiles {
+			if core.ModTime.After(latest.ModTime) {
+				latest = core
+			}
+		}
+		cores = fmt.Sprintf("%d, latest %s (%s)", n, latest.Path, latest.ModTime.Format(time.DateTime))
+	}
+	lines = append(lines, FormatMetric("Core Files", cores))
+
+	return borderStyle.Render(strings.Join(lines, "\n"))
+}
+
+func (m *TimelineModel) renderDaily() string {
+	headers := []string{"Day", "Starts", "Reloads", "Restarts", "Stops", "Crashes"}
+	var rows [][]string
+	for _, day := range lifecycle.DailyCounts(m.events, timelineDays, time.Now()) {
+		rows = append(rows, []string{
+			day.Day.Format("Mon 2006-01-02"),
+			strconv.Itoa(day.Starts),
+			strconv.Itoa(day.Reloads),
+			strconv.Itoa(day.Restarts),
+			strconv.Itoa(day.Stops),
+			strconv.Itoa(day.Crashes),
+		})
+	}
+	return FormatTable(headers, rows)
+}
+
+func (m *TimelineModel) renderEvents() string {
+	if len(m.events) == 0 {
+		return "No events recorded"
+	}
+
+	headers := []string{"Time", "Event", "PID", "Previous", "Lifetime", "Details"}
+	var rows [][]string
+	for i := len(m.events) - 1; i >= 0; i-- {
+		event := m.events[i]
+		rows = append(rows, []string{
+			event.Time.Local().Format(time.DateTime),
+			strings.ToUpper(event.Kind),
+			formatPID(event.PID),
+			formatPID(event.PrevPID),
+			event.Lifetime,
+			TruncateString(event.Detail, 60),
+		})
+	}
+	return FormatTable(headers, rows)
+}
+
+// formatPID возвращает PID или прочерк, если процесса нет
+func formatPID(pid int) string {
+	if pid == 0 {
+		return "-"
+	}
+	return strconv.Itoa(pid)
+}
+
+func (m *TimelineModel) footer() string {
+	if m.task.Running() {
+		return m.task.Status()
+	}
+	if m.notice != "" {
+		return warningStyle.Render(m.notice)
+	}
+
+	crashes := 0
+	for _, event := range m.events {
+		if event.Unexpected() && time.Since(event.Time) < 24*time.Hour {
+			crashes++
+		}
+	}
+	return lipgloss.NewStyle().
+		Foreground(colorGray).
+		Render(fmt.Sprintf("Crashes in 24h: %d | Updated: %s | Press 'r' to refresh | 'q' to quit", crashes, FormatTimestamp(m.updated)))
+}
@@ -0,0 +1,247 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"asterisk-monitor/types"
+	"asterisk-monitor/watchdog"
+)
+
+// watchdogDays - за сколько дней показывается журнал восстановления
+const watchdogDays = 7
+
+// watchdogResult - результат загрузки страницы сторожа
+type watchdogResult struct {
+	probe   types.HealthProbe
+	entries []watchdog.Entry
+	err     error
+}
+
+// WatchdogModel показывает проверку отзывчивости Asterisk и журнал сторожа.
+// Сам сторож работает в фоновом режиме, страница только проверяет и читает журнал.
+type WatchdogModel struct {
+	monitor  MonitorInterface
+	log      RecoveryLog
+	config   ConfigGetter
+	viewport viewport.Model
+	probe    types.HealthProbe
+	entries  []watchdog.Entry
+	task     taskRunner
+	notice   string
+}
+
+// NewWatchdogModel создает страницу сторожа. log может быть nil.
+func NewWatchdogModel(mon MonitorInterface, log RecoveryLog, cfg ConfigGetter) WatchdogModel {
+	vp := viewport.New(80, 20)
+	vp.Style = lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("62"))
+
+	return WatchdogModel{
+		monitor:  mon,
+		log:      log,
+		config:   cfg,
+		viewport: vp,
+		task:     newTaskRunner(),
+	}
+}
+
+func (m WatchdogModel) Init() tea.Cmd {
+	return requestLoad
+}
+
+func (m WatchdogModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "r", "R":
+			return m, m.load()
+		case "esc":
+			if m.task.Cancel() {
+				m.notice = taskError("Probing Asterisk", context.Canceled)
+			}
+			return m, nil
+		case "q", "Q", "ctrl+c":
+			return m, tea.Quit
+		}
+	case tea.WindowSizeMsg:
+		m.viewport.Width = msg.Width
+		m.viewport.Height = msg.Height - 2
+		m.updateContent()
+	case loadMsg:
+		return m, m.load()
+	case CancelTasksMsg:
+		m.task.Cancel()
+		return m, nil
+	case taskDoneMsg:
+		if !m.task.Finish(msg.id) {
+			return m, nil
+		}
+		if result, ok := msg.value.(watchdogResult); ok {
+			m.probe = result.probe
+			m.entries = result.entries
+			if result.err != nil {
+				m.notice = taskError("Reading recovery log", result.err)
+			}
+		}
+		if msg.err != nil {
+			m.notice = taskError("Probing Asterisk", msg.err)
+		}
+		m.updateContent()
+		return m, nil
+	case spinner.TickMsg:
+		return m, m.task.Tick(msg)
+	}
+
+	m.viewport, cmd = m.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m WatchdogModel) View() string {
+	return m.viewport.View() + "\n" + m.footer()
+}
+
+// load проверяет Asterisk с таймаутом из настроек сторожа и перечитывает журнал
+func (m *WatchdogModel) load() tea.Cmd {
+	if m.task.Running() {
+		return nil
+	}
+
+	m.notice = ""
+	mon, log := m.monitor, m.log
+	cfg := m.config.Get().Watchdog
+	timeout := time.Duration(cfg.ProbeTimeout) * time.Second
+	return m.task.Start("Probing Asterisk", fetchTimeout, func(ctx context.Context, progress func(types.CheckResult)) (any, error) {
+		result := watchdogResult{probe: mon.ProbeAsterisk(ctx, timeout, cfg.ProbeAMI)}
+		if log != nil {
+			result.entries, result.err = log.Entries(time.Now().AddDate(0, 0, -watchdogDays))
+		}
+		return result, ctx.Err()
+	})
+}
+
+func (m *WatchdogModel) updateContent() {
+	var content strings.Builder
+
+	content.WriteString(TitleStyle.Render("🐕 Asterisk Watchdog"))
+	content.WriteString("\n\n")
+	content.WriteString(m.renderProbe())
+	content.WriteString("\n\n")
+	content.WriteString(m.renderConfig())
+	content.WriteString("\n\n")
+
+	content.WriteString(TitleStyle.Render(fmt.Sprintf("Recovery Log, last %d days", watchdogDays)))
+	content.WriteString("\n")
+	content.WriteString(m.renderEntries())
+
+	m.viewport.SetContent(content.String())
+}
+
+func (m *WatchdogModel) renderProbe() string {
+	probe := m.probe
+	if probe.Time.IsZero() {
+		return borderStyle.Render("Responsiveness:\nNot probed yet")
+	}
+
+	lines := []string{
+		"Responsiveness: " + FormatStatus(probe.State),
+		FormatMetric("Process", FormatStatus(probe.Process.State)),
+	}
+	if probe.Process.PID != 0 {
+		lines = append(lines, FormatMetric("PID", formatPID(probe.Process.PID)))
+	}
+	if probe.State != types.HealthGone {
+		lines = append(lines, FormatMetric("CLI", probeResult(probe.CLILatency, probe.CLIError)))
+		if probe.AMIChecked {
+			lines = append(lines, FormatMetric("AMI", probeResult(probe.AMILatency, probe.AMIError)))
+		}
+	}
+	if probe.Process.Detail != "" {
+		lines = append(lines, FormatMetric("Detail", probe.Process.Detail))
+	}
+	lines = append(lines, FormatMetric("Probed", probe.Time.Format(time.DateTime)))
+
+	return borderStyle.Render(strings.Join(lines, "\n"))
+}
+
+// probeResult описывает одну проверку: время ответа или ошибку
+func probeResult(latency time.Duration, err string) string {
+	if err != "" {
+		return errorStyle.Render(TruncateString(err, 70))
+	}
+	return successStyle.Render(fmt.Sprintf("answered in %s", latency.Round(time.Millisecond)))
+}
+
+func (m *WatchdogModel) renderConfig() string {
+	cfg := m.config.Get().Watchdog
+
+	enabled := "disabled, enable [watchdog] and run --daemon"
+	if cfg.Enabled {
+		enabled = fmt.Sprintf("every %ds in daemon mode", cfg.Interval)
+	}
+	action := cfg.Action
+	if cfg.Action == "restart" {
+		action = fmt.Sprintf("restart %s, at most %d per %ds, cooldown %ds", cfg.Unit, cfg.MaxAttempts, cfg.AttemptWindow, cfg.Cooldown)
+		if cfg.RestartWhenGone {
+			action += ", also when gone"
+		}
+	}
+
+	lines := []string{
+		FormatMetric("Watchdog", enabled),
+		FormatMetric("Probe", fmt.Sprintf("timeout %ds, AMI %t, %d failures to act", cfg.ProbeTimeout, cfg.ProbeAMI, cfg.Failures)),
+		FormatMetric("Action", action),
+		FormatMetric("Capture", fmt.Sprintf("%t", cfg.Capture)),
+	}
+	return borderStyle.Render(strings.Join(lines, "\n"))
+}
+
+func (m *WatchdogModel) renderEntries() string {
+	if len(m.entries) == 0 {
+		return "No recovery actions recorded"
+	}
+
+	headers := []string{"Time", "Event", "State", "PID", "Failures", "Details"}
+	var rows [][]string
+	for i := len(m.entries) - 1; i >= 0; i-- {
+		entry := m.entries[i]
+		details := entry.Detail
+		if entry.Report != "" {
+			details += " " + entry.Report
+		}
+		if entry.Error != "" {
+			details += " error: " + entry.Error
+		}
+		rows = append(rows, []string{
+			entry.Time.Local().Format(time.DateTime),
+			strings.ToUpper(entry.Kind),
+			entry.State,
+			formatPID(entry.PID),
+			fmt.Sprintf("%d", entry.Failures),
+			TruncateString(details, 70),
+		})
+	}
+	return FormatTable(headers, rows)
+}
+
+func (m *WatchdogModel) footer() string {
+	if m.task.Running() {
+		return m.task.Status()
+	}
+	if m.notice != "" {
+		return warningStyle.Render(m.notice)
+	}
+	return lipgloss.NewStyle().
+		Foreground(colorGray).
+		Render("Press 'r' to probe again | 'q' to quit")
+}
@@ -0,0 +1,279 @@
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"asterisk-monitor/types"
+)
+
+// Виды записей журнала восстановления
+const (
+	KindFailed    = "probe_failed" // проверка перешла в неудачное состояние
+	KindCapture   = "capture"      // снят отчет о состоянии зависшего процесса
+	KindRestart   = "restart"      // выполнено действие восстановления
+	KindSkipped   = "skipped"      // восстановление нужно, но не выполнено
+	KindRecovered = "recovered"    // Asterisk снова отвечает
+)
+
+// Entry - запись журнала восстановления
+type Entry struct {
+	Time     time.Time `json:"time"`
+	Kind     string    `json:"kind"`
+	State    string    `json:"state,omitempty"` // результат проверки, types.Health*
+	PID      int       `json:"pid,omitempty"`
+	Failures int       `json:"failures,omitempty"`
+	Report   string    `json:"report,omitempty"` // путь к отчету о зависании
+	Error    string    `json:"error,omitempty"`
+	Detail   string    `json:"detail,omitempty"`
+}
+
+// RestartTimeout ограничивает перезапуск юнита. Он не зависит от срока проверки:
+// systemctl restart зависшего Asterisk ждет TimeoutStopSec (по умолчанию 90 с),
+// и прерванный клиент записал бы неудачу перезапуска, который systemd завершит.
+const RestartTimeout = 3 * time.Minute
+
+// Prober проверяет Asterisk и выполняет действия восстановления
+type Prober interface {
+	ProbeAsterisk(ctx context.Context, timeout time.Duration, ami bool) types.HealthProbe
+	CaptureHangReport(ctx context.Context, pid int, timeout time.Duration) string
+	RestartUnit(ctx context.Context, unit string) types.CheckResult
+}
+
+// Watchdog следит за отзывчивостью Asterisk. После config.Failures неудачных
+// проверок подряд снимает отчет о зависании и, если разрешено, перезапускает
+// юнит. Перезапуски ограничены max_attempts за attempt_window и паузой cooldown.
+type Watchdog struct {
+	mu        sync.Mutex
+	config    types.WatchdogConfig
+	prober    Prober
+	journal   *Journal
+	reportDir string
+
+	last     types.HealthProbe
+	failures int
+	captured int    // PID, для которого уже снят отчет о зависании
+	skipped  string // причина последнего пропуска, чтобы не повторять запись
+	attempts []time.Time
+}
+
+// New создает сторожа. Время прежних перезапусков читается из journal, поэтому
+// ограничения действуют и после перезапуска монитора. journal может быть nil.
+func New(config types.WatchdogConfig, prober Prober, journal *Journal, reportDir string, now time.Time) (*Watchdog, error) {
+	w := &Watchdog{
+		config:    config,
+		prober:    prober,
+		journal:   journal,
+		reportDir: reportDir,
+	}
+	if journal == nil {
+		return w, nil
+	}
+
+	entries, err := journal.Entries(now.Add(-w.window()))
+	for _, entry := range entries {
+		if entry.Kind == KindRestart {
+			w.attempts = append(w.attempts, entry.Time)
+		}
+	}
+	return w, err
+}
+
+// Configure применяет новые настройки, счетчики сохраняются
+func (w *Watchdog) Configure(config types.WatchdogConfig) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.config = config
+}
+
+// Last возвращает результат последней проверки
+func (w *Watchdog) Last() types.HealthProbe {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.last
+}
+
+// Entries возвращает записи журнала новее since
+func (w *Watchdog) Entries(since time.Time) ([]Entry, error) {
+	if w.journal == nil {
+		return nil, nil
+	}
+	return w.journal.Entries(since)
+}
+
+// Check выполняет одну проверку и, если нужно, восстановление. Возвращает
+// записи, добавленные в журнал.
+func (w *Watchdog) Check(ctx context.Context) ([]Entry, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	config := w.config
+	probe := w.prober.ProbeAsterisk(ctx, seconds(config.ProbeTimeout), config.ProbeAMI)
+	previous := w.last
+	w.last = probe
+
+	var entries []Entry
+	if probe.State == types.HealthOK {
+		if w.failures > 0 {
+			entries = append(entries, Entry{
+				Time:     probe.Time,
+				Kind:     KindRecovered,
+				State:    probe.State,
+				PID:      probe.Process.PID,
+				Failures: w.failures,
+				Detail:   fmt.Sprintf("asterisk responds again after %d failed probes", w.failures),
+			})
+		}
+		w.failures, w.captured, w.skipped = 0, 0, ""
+		return entries, w.record(entries)
+	}
+
+	w.failures++
+	if probe.State != previous.State {
+		entries = append(entries, Entry{
+			Time:     probe.Time,
+			Kind:     KindFailed,
+			State:    probe.State,
+			PID:      probe.Process.PID,
+			Failures: w.failures,
+			Detail:   describe(probe),
+		})
+	}
+	if w.failures < max(config.Failures, 1) {
+		return entries, w.record(entries)
+	}
+
+	if probe.State == types.HealthUnresponsive && config.Capture && probe.Process.PID != w.captured {
+		entries = append(entries, w.capture(ctx, probe, config))
+		w.captured = probe.Process.PID
+	}
+	if entry, ok := w.recover(ctx, probe, config); ok {
+		entries = append(entries, entry)
+	}
+	return entries, w.record(entries)
+}
+
+// capture сохраняет отчет о зависшем процессе в каталог отчетов
+func (w *Watchdog) capture(ctx context.Context, probe types.HealthProbe, config types.WatchdogConfig) Entry {
+	entry := Entry{
+		Time:     probe.Time,
+		Kind:     KindCapture,
+		State:    probe.State,
+		PID:      probe.Process.PID,
+		Failures: w.failures,
+	}
+
+	report := w.prober.CaptureHangReport(ctx, probe.Process.PID, seconds(config.ProbeTimeout))
+	path := filepath.Join(w.reportDir, fmt.Sprintf("hang-%s-%d.txt", probe.Time.Format("20060102-150405"), probe.Process.PID))
+	if err := os.MkdirAll(w.reportDir, 0755); err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+	if err := os.WriteFile(path, []byte(report), 0600); err != nil {
+		entry.Error = err.Error()
+		return entry
+	}
+
+	entry.Report = path
+	entry.Detail = "captured core show locks and thread states"
+	return entry
+}
+
+// recover перезапускает юнит, если это разрешено настройками и ограничениями.
+// Пропуск записывается один раз, пока не изменится его причина.
+func (w *Watchdog) recover(ctx context.Context, probe types.HealthProbe, config types.WatchdogConfig) (Entry, bool) {
+	entry := Entry{
+		Time:     probe.Time,
+		State:    probe.State,
+		PID:      probe.Process.PID,
+		Failures: w.failures,
+	}
+
+	reason := ""
+	now := probe.Time
+	w.attempts = recent(w.attempts, now.Add(-w.window()))
+	switch {
+	case config.Action != "restart":
+		reason = fmt.Sprintf("recovery action is %q, restart disabled", config.Action)
+	case probe.State == types.HealthGone && !config.RestartWhenGone:
+		reason = "asterisk is not running and restart_when_gone is off"
+	case len(w.attempts) > 0 && now.Sub(w.attempts[len(w.attempts)-1]) < seconds(config.Cooldown):
+		// После перезапуска Asterisk нужно время, пауза не записывается
+		return entry, false
+	case len(w.attempts) >= config.MaxAttempts:
+		reason = fmt.Sprintf("%d restarts in the last %s, giving up", len(w.attempts), w.window())
+	}
+
+	if reason != "" {
+		if reason == w.skipped {
+			return entry, false
+		}
+		w.skipped = reason
+		entry.Kind = KindSkipped
+		entry.Detail = reason
+		return entry, true
+	}
+
+	restartCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), RestartTimeout)
+	defer cancel()
+	result := w.prober.RestartUnit(restartCtx, config.Unit)
+	w.attempts = append(w.attempts, now)
+	w.skipped = ""
+
+	entry.Kind = KindRestart
+	entry.Detail = fmt.Sprintf("systemctl restart %s (attempt %d of %d)", config.Unit, len(w.attempts), config.MaxAttempts)
+	if result.Status != "success" {
+		entry.Error = result.Message
+		if result.Error != "" {
+			entry.Error += ": " + result.Error
+		}
+	}
+	return entry, true
+}
+
+func (w *Watchdog) record(entries []Entry) error {
+	if w.journal == nil {
+		return nil
+	}
+	return w.journal.Append(entries...)
+}
+
+// window возвращает окно подсчета попыток восстановления
+func (w *Watchdog) window() time.Duration {
+	return seconds(w.config.AttemptWindow)
+}
+
+// describe объясняет неудачную проверку
+func describe(probe types.HealthProbe) string {
+	if probe.State == types.HealthGone {
+		if probe.Process.Detail != "" {
+			return "asterisk process not found: " + probe.Process.Detail
+		}
+		return "asterisk process not found"
+	}
+
+	detail := "cli: " + probe.CLIError
+	if probe.AMIChecked {
+		detail += "; ami: " + probe.AMIError
+	}
+	return detail
+}
+
+// recent оставляет моменты новее since
+func recent(times []time.Time, since time.Time) []time.Time {
+	var kept []time.Time
+	for _, t := range times {
+		if t.After(since) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}